@@ -33,7 +33,9 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/pkg/grpcapi"
 	"github.com/optimizely/agent/pkg/metrics"
+	"github.com/optimizely/agent/pkg/notificationwebhook"
 	"github.com/optimizely/agent/pkg/optimizely"
 	"github.com/optimizely/agent/pkg/routers"
 	"github.com/optimizely/agent/pkg/server"
@@ -45,6 +47,12 @@ import (
 	_ "github.com/optimizely/agent/plugins/userprofileservice/all"
 	// Initiate the loading of the odpCache plugins
 	_ "github.com/optimizely/agent/plugins/odpcache/all"
+	// Initiate the loading of the eventDispatcher plugins
+	_ "github.com/optimizely/agent/plugins/eventdispatcher/all"
+	// Initiate the loading of the forced decisions plugins
+	_ "github.com/optimizely/agent/plugins/forceddecisions/all"
+	// Initiate the loading of the user attributes plugins
+	_ "github.com/optimizely/agent/plugins/userattributes/all"
 	"github.com/optimizely/go-sdk/pkg/logging"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
@@ -106,6 +114,21 @@ func loadConfig(v *viper.Viper) *config.AgentConfig {
 		conf.Client.UserProfileService = userProfileService
 	}
 
+	// Check if JSON string was set using OPTIMIZELY_CLIENT_EVENTDISPATCHERS environment variable
+	if eventDispatchers := v.GetStringMap("client.eventDispatchers"); eventDispatchers != nil {
+		conf.Client.EventDispatchers = eventDispatchers
+	}
+
+	// Check if JSON string was set using OPTIMIZELY_CLIENT_FORCEDDECISIONS environment variable
+	if forcedDecisions := v.GetStringMap("client.forcedDecisions"); forcedDecisions != nil {
+		conf.Client.ForcedDecisions = forcedDecisions
+	}
+
+	// Check if JSON string was set using OPTIMIZELY_CLIENT_USERATTRIBUTES environment variable
+	if userAttributes := v.GetStringMap("client.userAttributes"); userAttributes != nil {
+		conf.Client.UserAttributes = userAttributes
+	}
+
 	// Check if JSON string was set using OPTIMIZELY_CLIENT_ODP_SEGMENTSCACHE environment variable
 	if odpSegmentsCache := v.GetStringMap("client.odp.segmentsCache"); odpSegmentsCache != nil {
 		conf.Client.ODP.SegmentsCache = odpSegmentsCache
@@ -267,9 +290,20 @@ func main() {
 
 	ctx, cancel := context.WithCancel(context.Background()) // Create default service context
 	sg := server.NewGroup(ctx, conf.Server)                 // Create a new server group to manage the individual http listeners
-	optlyCache := optimizely.NewCache(ctx, *conf, sdkMetricsRegistry)
+	// The clients get their own context so that they are only closed once the listeners stopped serving requests
+	cacheCtx, cacheCancel := context.WithCancel(context.Background())
+	optlyCache := optimizely.NewCache(cacheCtx, *conf, sdkMetricsRegistry)
 	optlyCache.Init(conf.SDKKeys)
 
+	// Report not ready until every configured SDK key has a datafile and the Redis backends can be reached
+	readiness := sg.Readiness()
+	readiness.AddCheck(optimizely.DatafilesCheck, func(_ context.Context) error {
+		return optlyCache.CheckDatafiles(conf.SDKKeys)
+	})
+	for name, check := range optimizely.RedisReadinessChecks(*conf) {
+		readiness.AddCheck(name, check)
+	}
+
 	// goroutine to check for signals to gracefully shutdown listeners
 	go func() {
 		signalChannel := make(chan os.Signal, 1)
@@ -282,19 +316,37 @@ func main() {
 	}()
 
 	apiRouter := routers.NewDefaultAPIRouter(optlyCache, *conf, agentMetricsRegistry)
-	adminRouter := routers.NewAdminRouter(*conf)
+	adminRouter := routers.NewAdminRouter(*conf, optlyCache)
+	var grpcService server.GRPCService
+	if service, err := grpcapi.NewDefaultService(optlyCache, *conf); err != nil {
+		log.Error().Err(err).Msg("unable to initialize grpc service.")
+	} else {
+		grpcService = service
+	}
+
+	notificationWebhooks := notificationwebhook.NewDefaultWebhooks(conf.NotificationWebhooks, agentMetricsRegistry)
+	if err := notificationWebhooks.Start(); err != nil {
+		log.Error().Err(err).Msg("unable to start notification webhooks.")
+	}
 
 	log.Info().Str("version", conf.Version).Msg("Starting services.")
 	sg.GoListenAndServe("api", conf.API.Port, apiRouter)
 	sg.GoListenAndServe("webhook", conf.Webhook.Port, routers.NewWebhookRouter(optlyCache, conf.Webhook))
+	sg.GoServeGRPC("grpc", conf.API.GRPC.Port, grpcService)
 	sg.GoListenAndServe("admin", conf.Admin.Port, adminRouter) // Admin should be added last.
 
-	// wait for server group to shutdown
-	if err := sg.Wait(); err == nil {
+	// wait for server group to shutdown, the group returns the cancellation error once shut down by a signal
+	err := sg.Wait()
+
+	// flush the events of every client once no more requests are served
+	cacheCancel()
+	optlyCache.Drain(conf.Client.DrainTimeout)
+	// deliver the notifications queued for the webhooks
+	notificationWebhooks.Close()
+
+	if err == nil || errors.Is(err, context.Canceled) {
 		log.Info().Msg("Exiting.")
 	} else {
 		log.Fatal().Err(err).Msg("Exiting.")
 	}
-
-	optlyCache.Wait()
 }
@@ -44,6 +44,8 @@ func assertServer(t *testing.T, actual config.ServerConfig, assertPlugins bool)
 	assert.Equal(t, 5*time.Second, actual.ReadTimeout)
 	assert.Equal(t, 10*time.Second, actual.WriteTimeout)
 	assert.Equal(t, "/healthcheck", actual.HealthCheckPath)
+	assert.Equal(t, "/readycheck", actual.ReadinessCheckPath)
+	assert.Equal(t, 15*time.Second, actual.ShutdownTimeout)
 	assert.Equal(t, "keyfile", actual.KeyFile)
 	assert.Equal(t, "certfile", actual.CertFile)
 	assert.Equal(t, []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}, actual.DisabledCiphers)
@@ -64,6 +66,34 @@ func assertClient(t *testing.T, actual config.ClientConfig) {
 	assert.Equal(t, "https://localhost/v1/%s.json", actual.DatafileURLTemplate)
 	assert.Equal(t, "https://logx.localhost.com/v1", actual.EventURL)
 	assert.Equal(t, "custom-regex", actual.SdkKeyRegex)
+	assert.Equal(t, "/tmp/snapshots", actual.DatafileSnapshotDir)
+	assert.Equal(t, 100, actual.Eviction.MaxClients)
+	assert.Equal(t, 10*time.Minute, actual.Eviction.IdleTTL)
+	assert.Equal(t, 30*time.Second, actual.Eviction.Interval)
+	assert.Equal(t, 1*time.Second, actual.LoadBackoff.Initial)
+	assert.Equal(t, 1*time.Minute, actual.LoadBackoff.Max)
+	assert.Equal(t, "/tmp/datafiles", actual.StaticDatafiles.Dir)
+	assert.Equal(t, 1*time.Minute, actual.StaticDatafiles.ReloadInterval)
+	assert.Equal(t, 1*time.Minute, actual.DrainTimeout)
+	assert.Equal(t, config.AttributeValidationWarn, actual.AttributeValidation)
+	assert.Equal(t, config.EventQueueTypeDisk, actual.EventQueue.Type)
+	assert.Equal(t, "/tmp/events", actual.EventQueue.Disk.Dir)
+	assert.Equal(t, int64(1024), actual.EventQueue.Disk.MaxBytes)
+	assert.Equal(t, 5*time.Second, actual.EventQueue.Disk.SyncInterval)
+	assert.Equal(t, config.DeadLetterStoreRedis, actual.DeadLetter.Store)
+	assert.Equal(t, 3, actual.DeadLetter.MaxAttempts)
+	assert.Equal(t, "/tmp/dead-letters", actual.DeadLetter.Dir)
+	assert.Equal(t, "localhost:6379", actual.DeadLetter.Redis.Host)
+	assert.Equal(t, "secret", actual.DeadLetter.Redis.Password)
+	assert.Equal(t, 1, actual.DeadLetter.Redis.Database)
+	assert.Equal(t, "dead-letters", actual.DeadLetter.Redis.Key)
+	assert.Equal(t, config.OverridesStoreRedis, actual.Overrides.Store)
+	assert.Equal(t, "localhost:6379", actual.Overrides.Redis.Host)
+	assert.Equal(t, "secret", actual.Overrides.Redis.Password)
+	assert.Equal(t, 2, actual.Overrides.Redis.Database)
+	assert.Equal(t, "overrides", actual.Overrides.Redis.Prefix)
+	assert.Equal(t, 5*time.Second, actual.Overrides.Redis.RefreshInterval)
+	assert.Equal(t, "salt", actual.Scrub.Salt)
 	assert.True(t, actual.ODP.Disable)
 	assert.Equal(t, 5*time.Second, actual.ODP.EventsFlushInterval)
 	assert.Equal(t, 5*time.Second, actual.ODP.EventsRequestTimeout)
@@ -92,6 +122,27 @@ func assertClient(t *testing.T, actual config.ClientConfig) {
 	}
 	assert.Equal(t, userProfileServices, actual.UserProfileService["services"])
 
+	assert.Equal(t, []interface{}{"file", "http"}, actual.EventDispatchers["sinks"])
+	eventDispatcherServices := actual.EventDispatchers["services"].(map[string]interface{})
+
+	fileSink := eventDispatcherServices["file"].(map[string]interface{})
+	assert.EqualValues(t, "/tmp/events.ndjson", fileSink["path"])
+	assert.EqualValues(t, 3, fileSink["maxfiles"])
+
+	httpForwarder := eventDispatcherServices["http"].(map[string]interface{})
+	assert.EqualValues(t, "http://localhost/events", httpForwarder["url"])
+	assert.Equal(t, map[string]interface{}{"x-api-key": "key"}, httpForwarder["headers"])
+
+	assert.Equal(t, "redis", actual.ForcedDecisions["default"])
+	redisForcedDecisions := actual.ForcedDecisions["services"].(map[string]interface{})["redis"].(map[string]interface{})
+	assert.EqualValues(t, "localhost:6379", redisForcedDecisions["host"])
+	assert.EqualValues(t, "qa", redisForcedDecisions["prefix"])
+
+	assert.Equal(t, "rest", actual.UserAttributes["default"])
+	restUserAttributes := actual.UserAttributes["services"].(map[string]interface{})["rest"].(map[string]interface{})
+	assert.EqualValues(t, "http://localhost", restUserAttributes["host"])
+	assert.EqualValues(t, "/attributes", restUserAttributes["lookuppath"])
+
 	assert.Equal(t, "in-memory", actual.ODP.SegmentsCache["default"])
 	odpCacheServices := map[string]interface{}{
 		"custom": map[string]interface{}{
@@ -143,6 +194,24 @@ func assertAPI(t *testing.T, actual config.APIConfig) {
 	assert.Equal(t, "3000", actual.Port)
 	assert.Equal(t, true, actual.EnableNotifications)
 	assert.Equal(t, true, actual.EnableOverrides)
+	assert.Equal(t, true, actual.EnableUserAttributes)
+	assert.Equal(t, 500, actual.BulkDecide.MaxUsers)
+	assert.Equal(t, 4, actual.BulkDecide.MaxConcurrency)
+	assert.Equal(t, 200, actual.TrackBatch.MaxEvents)
+	assert.Equal(t, config.IdempotencyStoreRedis, actual.TrackBatch.Idempotency.Store)
+	assert.Equal(t, time.Hour, actual.TrackBatch.Idempotency.Window)
+	assert.Equal(t, 5000, actual.TrackBatch.Idempotency.MaxKeys)
+	assert.Equal(t, "localhost:6379", actual.TrackBatch.Idempotency.Redis.Host)
+	assert.Equal(t, "secret", actual.TrackBatch.Idempotency.Redis.Password)
+	assert.Equal(t, 3, actual.TrackBatch.Idempotency.Redis.Database)
+	assert.Equal(t, "idempotency", actual.TrackBatch.Idempotency.Redis.Prefix)
+	assert.Equal(t, "3010", actual.GRPC.Port)
+	assert.Equal(t, uint32(50), actual.GRPC.MaxConcurrentStreams)
+	assert.Equal(t, 15*time.Second, actual.WebSocket.PingInterval)
+	assert.Equal(t, 45*time.Second, actual.WebSocket.PongTimeout)
+	assert.Equal(t, 5*time.Second, actual.WebSocket.WriteTimeout)
+	assert.Equal(t, 20*time.Second, actual.EventStream.HeartbeatInterval)
+	assert.Equal(t, 500, actual.EventStream.BufferSize)
 }
 
 func assertAPIAuth(t *testing.T, actual config.ServiceAuthConfig) {
@@ -178,6 +247,19 @@ func assertWebhook(t *testing.T, actual config.WebhookConfig) {
 	assert.False(t, actual.Projects[20000].SkipSignatureCheck)
 }
 
+func assertNotificationWebhooks(t *testing.T, actual config.NotificationWebhooksConfig) {
+	assert.Equal(t, []config.NotificationWebhookSubscription{
+		{SDKKey: "SDKKey", URL: "https://example.com/notifications", Secret: "webhook-secret", Types: []string{"decision"}},
+	}, actual.Subscriptions)
+	assert.Equal(t, 20, actual.BatchSize)
+	assert.Equal(t, 2*time.Second, actual.FlushInterval)
+	assert.Equal(t, 500, actual.QueueSize)
+	assert.Equal(t, 3, actual.MaxAttempts)
+	assert.Equal(t, 2*time.Second, actual.Backoff.Initial)
+	assert.Equal(t, time.Minute, actual.Backoff.Max)
+	assert.Equal(t, 5*time.Second, actual.Timeout)
+}
+
 func TestViperYaml(t *testing.T) {
 	v := viper.New()
 	v.Set("config.filename", "./testdata/default.yaml")
@@ -190,6 +272,17 @@ func TestViperYaml(t *testing.T) {
 	assertRoot(t, actual)
 	assertServer(t, actual.Server, true)
 	assertClient(t, actual.Client)
+	// SDK keys in list entries keep their case
+	assert.Equal(t, []config.StaticDatafile{{SDKKey: "SDKKey", Path: "/tmp/datafile.json"}}, actual.Client.StaticDatafiles.Files)
+	disable := false
+	assert.Equal(t, []config.SDKKeyOverride{
+		{SDKKey: "SDKKey", PollingInterval: 30 * time.Second},
+		{Pattern: "^dev", ODP: config.OdpOverride{Disable: &disable}},
+	}, actual.Client.SDKKeyOverrides)
+	assert.Equal(t, []config.ScrubRule{
+		{Attributes: []string{"email"}, Tags: []string{"orderId"}, Action: config.ScrubActionHash},
+		{Attributes: []string{"address"}, Action: config.ScrubActionTruncate, MaxLength: 10},
+	}, actual.Client.Scrub.Rules)
 	assertLog(t, actual.Log)
 	assertAdmin(t, actual.Admin)
 	assertAdminAuth(t, actual.Admin.Auth)
@@ -197,6 +290,7 @@ func TestViperYaml(t *testing.T) {
 	assertAPIAuth(t, actual.API.Auth)
 	assertAPICORS(t, actual.API.CORS)
 	assertWebhook(t, actual.Webhook)
+	assertNotificationWebhooks(t, actual.NotificationWebhooks)
 	assertRuntime(t, actual.Runtime)
 }
 
@@ -211,6 +305,8 @@ func TestViperProps(t *testing.T) {
 	v.Set("server.readTimeout", 5*time.Second)
 	v.Set("server.writeTimeout", 10*time.Second)
 	v.Set("server.healthCheckPath", "/healthcheck")
+	v.Set("server.readinessCheckPath", "/readycheck")
+	v.Set("server.shutdownTimeout", 15*time.Second)
 	v.Set("server.certFile", "certfile")
 	v.Set("server.keyFile", "keyfile")
 	v.Set("server.disabledCiphers", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384")
@@ -226,6 +322,34 @@ func TestViperProps(t *testing.T) {
 	v.Set("client.datafileURLTemplate", "https://localhost/v1/%s.json")
 	v.Set("client.eventURL", "https://logx.localhost.com/v1")
 	v.Set("client.sdkKeyRegex", "custom-regex")
+	v.Set("client.datafileSnapshotDir", "/tmp/snapshots")
+	v.Set("client.eviction.maxClients", 100)
+	v.Set("client.eviction.idleTTL", 10*time.Minute)
+	v.Set("client.eviction.interval", 30*time.Second)
+	v.Set("client.loadBackoff.initial", 1*time.Second)
+	v.Set("client.loadBackoff.max", 1*time.Minute)
+	v.Set("client.staticDatafiles.dir", "/tmp/datafiles")
+	v.Set("client.staticDatafiles.reloadInterval", 1*time.Minute)
+	v.Set("client.drainTimeout", 1*time.Minute)
+	v.Set("client.attributeValidation", "warn")
+	v.Set("client.eventQueue.type", "disk")
+	v.Set("client.eventQueue.disk.dir", "/tmp/events")
+	v.Set("client.eventQueue.disk.maxBytes", 1024)
+	v.Set("client.eventQueue.disk.syncInterval", 5*time.Second)
+	v.Set("client.deadLetter.store", "redis")
+	v.Set("client.deadLetter.maxAttempts", 3)
+	v.Set("client.deadLetter.dir", "/tmp/dead-letters")
+	v.Set("client.deadLetter.redis.host", "localhost:6379")
+	v.Set("client.deadLetter.redis.password", "secret")
+	v.Set("client.deadLetter.redis.database", 1)
+	v.Set("client.deadLetter.redis.key", "dead-letters")
+	v.Set("client.overrides.store", "redis")
+	v.Set("client.overrides.redis.host", "localhost:6379")
+	v.Set("client.overrides.redis.password", "secret")
+	v.Set("client.overrides.redis.database", 2)
+	v.Set("client.overrides.redis.prefix", "overrides")
+	v.Set("client.overrides.redis.refreshInterval", 5*time.Second)
+	v.Set("client.scrub.salt", "salt")
 	upsServices := map[string]interface{}{
 		"in-memory": map[string]interface{}{
 			"storageStrategy": "fifo",
@@ -251,6 +375,43 @@ func TestViperProps(t *testing.T) {
 	}
 	v.Set("client.userProfileService", userProfileServices)
 
+	eventDispatchers := map[string]interface{}{
+		"sinks": []interface{}{"file", "http"},
+		"services": map[string]interface{}{
+			"file": map[string]interface{}{
+				"path":     "/tmp/events.ndjson",
+				"maxFiles": 3,
+			},
+			"http": map[string]interface{}{
+				"url":     "http://localhost/events",
+				"headers": map[string]interface{}{"x-api-key": "key"},
+			},
+		},
+	}
+	v.Set("client.eventDispatchers", eventDispatchers)
+
+	forcedDecisions := map[string]interface{}{
+		"default": "redis",
+		"services": map[string]interface{}{
+			"redis": map[string]interface{}{
+				"host":   "localhost:6379",
+				"prefix": "qa",
+			},
+		},
+	}
+	v.Set("client.forcedDecisions", forcedDecisions)
+
+	userAttributes := map[string]interface{}{
+		"default": "rest",
+		"services": map[string]interface{}{
+			"rest": map[string]interface{}{
+				"host":       "http://localhost",
+				"lookuppath": "/attributes",
+			},
+		},
+	}
+	v.Set("client.userAttributes", userAttributes)
+
 	odpCacheServices := map[string]interface{}{
 		"in-memory": map[string]interface{}{
 			"size":    100,
@@ -300,7 +461,25 @@ func TestViperProps(t *testing.T) {
 	v.Set("api.maxConns", 100)
 	v.Set("api.enableNotifications", true)
 	v.Set("api.enableOverrides", true)
+	v.Set("api.enableUserAttributes", true)
 	v.Set("api.port", "3000")
+	v.Set("api.bulkDecide.maxUsers", 500)
+	v.Set("api.bulkDecide.maxConcurrency", 4)
+	v.Set("api.trackBatch.maxEvents", 200)
+	v.Set("api.trackBatch.idempotency.store", "redis")
+	v.Set("api.trackBatch.idempotency.window", "1h")
+	v.Set("api.trackBatch.idempotency.maxKeys", 5000)
+	v.Set("api.trackBatch.idempotency.redis.host", "localhost:6379")
+	v.Set("api.trackBatch.idempotency.redis.password", "secret")
+	v.Set("api.trackBatch.idempotency.redis.database", 3)
+	v.Set("api.trackBatch.idempotency.redis.prefix", "idempotency")
+	v.Set("api.grpc.port", "3010")
+	v.Set("api.grpc.maxConcurrentStreams", 50)
+	v.Set("api.webSocket.pingInterval", "15s")
+	v.Set("api.webSocket.pongTimeout", "45s")
+	v.Set("api.webSocket.writeTimeout", "5s")
+	v.Set("api.eventStream.heartbeatInterval", "20s")
+	v.Set("api.eventStream.bufferSize", 500)
 	v.Set("api.auth.ttl", "30m")
 
 	v.Set("api.auth.hmacSecrets", "abcd,efgh")
@@ -350,6 +529,8 @@ func TestViperEnv(t *testing.T) {
 	_ = os.Setenv("OPTIMIZELY_SERVER_READTIMEOUT", "5s")
 	_ = os.Setenv("OPTIMIZELY_SERVER_WRITETIMEOUT", "10s")
 	_ = os.Setenv("OPTIMIZELY_SERVER_HEALTHCHECKPATH", "/healthcheck")
+	_ = os.Setenv("OPTIMIZELY_SERVER_READINESSCHECKPATH", "/readycheck")
+	_ = os.Setenv("OPTIMIZELY_SERVER_SHUTDOWNTIMEOUT", "15s")
 	_ = os.Setenv("OPTIMIZELY_SERVER_CERTFILE", "certfile")
 	_ = os.Setenv("OPTIMIZELY_SERVER_KEYFILE", "keyfile")
 	_ = os.Setenv("OPTIMIZELY_SERVER_DISABLEDCIPHERS", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384")
@@ -364,8 +545,39 @@ func TestViperEnv(t *testing.T) {
 	_ = os.Setenv("OPTIMIZELY_CLIENT_DATAFILEURLTEMPLATE", "https://localhost/v1/%s.json")
 	_ = os.Setenv("OPTIMIZELY_CLIENT_EVENTURL", "https://logx.localhost.com/v1")
 	_ = os.Setenv("OPTIMIZELY_CLIENT_SDKKEYREGEX", "custom-regex")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_DATAFILESNAPSHOTDIR", "/tmp/snapshots")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_EVICTION_MAXCLIENTS", "100")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_EVICTION_IDLETTL", "10m")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_EVICTION_INTERVAL", "30s")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_LOADBACKOFF_INITIAL", "1s")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_LOADBACKOFF_MAX", "1m")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_STATICDATAFILES_DIR", "/tmp/datafiles")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_STATICDATAFILES_RELOADINTERVAL", "1m")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_DRAINTIMEOUT", "1m")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_ATTRIBUTEVALIDATION", "warn")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_EVENTQUEUE_TYPE", "disk")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_EVENTQUEUE_DISK_DIR", "/tmp/events")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_EVENTQUEUE_DISK_MAXBYTES", "1024")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_EVENTQUEUE_DISK_SYNCINTERVAL", "5s")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_DEADLETTER_STORE", "redis")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_DEADLETTER_MAXATTEMPTS", "3")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_DEADLETTER_DIR", "/tmp/dead-letters")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_DEADLETTER_REDIS_HOST", "localhost:6379")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_DEADLETTER_REDIS_PASSWORD", "secret")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_DEADLETTER_REDIS_DATABASE", "1")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_DEADLETTER_REDIS_KEY", "dead-letters")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_OVERRIDES_STORE", "redis")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_OVERRIDES_REDIS_HOST", "localhost:6379")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_OVERRIDES_REDIS_PASSWORD", "secret")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_OVERRIDES_REDIS_DATABASE", "2")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_OVERRIDES_REDIS_PREFIX", "overrides")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_OVERRIDES_REDIS_REFRESHINTERVAL", "5s")
+	_ = os.Setenv("OPTIMIZELY_CLIENT_SCRUB_SALT", "salt")
 
 	_ = os.Setenv("OPTIMIZELY_CLIENT_USERPROFILESERVICE", `{"default":"in-memory","services":{"in-memory":{"storagestrategy":"fifo"},"redis":{"host":"localhost:6379","password":""},"rest":{"host":"http://localhost","lookuppath":"/ups/lookup","savepath":"/ups/save","headers":{"content-type":"application/json"},"async":true},"custom":{"path":"http://test2.com"}}}`)
+	_ = os.Setenv("OPTIMIZELY_CLIENT_EVENTDISPATCHERS", `{"sinks":["file","http"],"services":{"file":{"path":"/tmp/events.ndjson","maxfiles":3},"http":{"url":"http://localhost/events","headers":{"x-api-key":"key"}}}}`)
+	_ = os.Setenv("OPTIMIZELY_CLIENT_FORCEDDECISIONS", `{"default":"redis","services":{"redis":{"host":"localhost:6379","prefix":"qa"}}}`)
+	_ = os.Setenv("OPTIMIZELY_CLIENT_USERATTRIBUTES", `{"default":"rest","services":{"rest":{"host":"http://localhost","lookuppath":"/attributes"}}}`)
 	_ = os.Setenv("OPTIMIZELY_CLIENT_ODP_SEGMENTSCACHE", `{"default":"in-memory","services":{"in-memory":{"size":100,"timeout":"5s"},"redis":{"host":"localhost:6379","password":"","timeout":"5s","database": "123"},"custom":{"path":"http://test2.com"}}}`)
 	_ = os.Setenv("OPTIMIZELY_CLIENT_ODP_DISABLE", `true`)
 	_ = os.Setenv("OPTIMIZELY_CLIENT_ODP_EVENTSREQUESTTIMEOUT", `5s`)
@@ -383,6 +595,24 @@ func TestViperEnv(t *testing.T) {
 	_ = os.Setenv("OPTIMIZELY_API_PORT", "3000")
 	_ = os.Setenv("OPTIMIZELY_API_ENABLENOTIFICATIONS", "true")
 	_ = os.Setenv("OPTIMIZELY_API_ENABLEOVERRIDES", "true")
+	_ = os.Setenv("OPTIMIZELY_API_ENABLEUSERATTRIBUTES", "true")
+	_ = os.Setenv("OPTIMIZELY_API_BULKDECIDE_MAXUSERS", "500")
+	_ = os.Setenv("OPTIMIZELY_API_BULKDECIDE_MAXCONCURRENCY", "4")
+	_ = os.Setenv("OPTIMIZELY_API_TRACKBATCH_MAXEVENTS", "200")
+	_ = os.Setenv("OPTIMIZELY_API_TRACKBATCH_IDEMPOTENCY_STORE", "redis")
+	_ = os.Setenv("OPTIMIZELY_API_TRACKBATCH_IDEMPOTENCY_WINDOW", "1h")
+	_ = os.Setenv("OPTIMIZELY_API_TRACKBATCH_IDEMPOTENCY_MAXKEYS", "5000")
+	_ = os.Setenv("OPTIMIZELY_API_TRACKBATCH_IDEMPOTENCY_REDIS_HOST", "localhost:6379")
+	_ = os.Setenv("OPTIMIZELY_API_TRACKBATCH_IDEMPOTENCY_REDIS_PASSWORD", "secret")
+	_ = os.Setenv("OPTIMIZELY_API_TRACKBATCH_IDEMPOTENCY_REDIS_DATABASE", "3")
+	_ = os.Setenv("OPTIMIZELY_API_TRACKBATCH_IDEMPOTENCY_REDIS_PREFIX", "idempotency")
+	_ = os.Setenv("OPTIMIZELY_API_GRPC_PORT", "3010")
+	_ = os.Setenv("OPTIMIZELY_API_GRPC_MAXCONCURRENTSTREAMS", "50")
+	_ = os.Setenv("OPTIMIZELY_API_WEBSOCKET_PINGINTERVAL", "15s")
+	_ = os.Setenv("OPTIMIZELY_API_WEBSOCKET_PONGTIMEOUT", "45s")
+	_ = os.Setenv("OPTIMIZELY_API_WEBSOCKET_WRITETIMEOUT", "5s")
+	_ = os.Setenv("OPTIMIZELY_API_EVENTSTREAM_HEARTBEATINTERVAL", "20s")
+	_ = os.Setenv("OPTIMIZELY_API_EVENTSTREAM_BUFFERSIZE", "500")
 
 	_ = os.Setenv("OPTIMIZELY_WEBHOOK_PORT", "3001")
 	_ = os.Setenv("OPTIMIZELY_WEBHOOK_PROJECTS_10000_SECRET", "secret-10000")
@@ -18,6 +18,7 @@
 package config
 
 import (
+	"regexp"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -61,10 +62,39 @@ func NewDefaultConfig() *AgentConfig {
 				AllowedCredentials: false,
 				MaxAge:             300,
 			},
-			MaxConns:            0,
-			Port:                "8080",
-			EnableNotifications: false,
-			EnableOverrides:     false,
+			MaxConns:             0,
+			Port:                 "8080",
+			EnableNotifications:  false,
+			EnableOverrides:      false,
+			EnableUserAttributes: false,
+			BulkDecide: BulkDecideConfig{
+				MaxUsers:       1000,
+				MaxConcurrency: 10,
+			},
+			TrackBatch: TrackBatchConfig{
+				MaxEvents: 1000,
+				Idempotency: IdempotencyConfig{
+					Store:   IdempotencyStoreInMemory,
+					Window:  24 * time.Hour,
+					MaxKeys: 100000,
+					Redis: IdempotencyRedisConfig{
+						Prefix: "optimizely-idempotency",
+					},
+				},
+			},
+			GRPC: GRPCConfig{
+				Port:                 "0",
+				MaxConcurrentStreams: 0,
+			},
+			WebSocket: WebSocketConfig{
+				PingInterval: 30 * time.Second,
+				PongTimeout:  60 * time.Second,
+				WriteTimeout: 10 * time.Second,
+			},
+			EventStream: EventStreamConfig{
+				HeartbeatInterval: 15 * time.Second,
+				BufferSize:        100,
+			},
 		},
 		Log: LogConfig{
 			Pretty:        false,
@@ -83,10 +113,60 @@ func NewDefaultConfig() *AgentConfig {
 			EventURL:            "https://logx.optimizely.com/v1/events",
 			// https://github.com/google/re2/wiki/Syntax
 			SdkKeyRegex: "^\\w+(:\\w+)?$",
+			Eviction: EvictionConfig{
+				MaxClients: 0, // 0 is unlimited
+				IdleTTL:    0, // 0 is disabled
+				Interval:   1 * time.Minute,
+			},
+			LoadBackoff: BackoffConfig{
+				Initial: 5 * time.Second,
+				Max:     5 * time.Minute,
+			},
+			StaticDatafiles: StaticDatafilesConfig{
+				ReloadInterval: 10 * time.Second,
+			},
+			DrainTimeout:        30 * time.Second,
+			AttributeValidation: AttributeValidationOff,
+			EventQueue: EventQueueConfig{
+				Type: EventQueueTypeInMemory,
+				Disk: DiskEventQueueConfig{
+					MaxBytes:     10 * 1024 * 1024,
+					SyncInterval: 1 * time.Second,
+				},
+			},
 			UserProfileService: UserProfileServiceConfigs{
 				"default":  "",
 				"services": map[string]interface{}{},
 			},
+			DeadLetter: DeadLetterConfig{
+				MaxAttempts: 5,
+				Redis: DeadLetterRedisConfig{
+					Key: "optimizely-dead-letters",
+				},
+			},
+			EventDispatchers: EventDispatcherConfigs{
+				"sinks":    []interface{}{},
+				"services": map[string]interface{}{},
+			},
+			Overrides: OverridesConfig{
+				Store: OverridesStoreInMemory,
+				Redis: OverridesRedisConfig{
+					Prefix:          "optimizely-overrides",
+					RefreshInterval: 1 * time.Second,
+				},
+			},
+			ForcedDecisions: ForcedDecisionsConfigs{
+				"default": "in-memory",
+				"services": map[string]interface{}{
+					"in-memory": map[string]interface{}{},
+				},
+			},
+			UserAttributes: UserAttributesConfigs{
+				"default": "in-memory",
+				"services": map[string]interface{}{
+					"in-memory": map[string]interface{}{},
+				},
+			},
 			ODP: OdpConfig{
 				Disable:                false,
 				EventsRequestTimeout:   10 * time.Second,
@@ -109,15 +189,17 @@ func NewDefaultConfig() *AgentConfig {
 		},
 
 		Server: ServerConfig{
-			AllowedHosts:    []string{"localhost"},
-			ReadTimeout:     5 * time.Second,
-			WriteTimeout:    10 * time.Second,
-			HealthCheckPath: "/health",
-			CertFile:        "",
-			KeyFile:         "",
-			DisabledCiphers: make([]string, 0),
-			Host:            "127.0.0.1",
-			Interceptors:    make(map[string]interface{}),
+			AllowedHosts:       []string{"localhost"},
+			ReadTimeout:        5 * time.Second,
+			WriteTimeout:       10 * time.Second,
+			HealthCheckPath:    "/health",
+			ReadinessCheckPath: "/ready",
+			ShutdownTimeout:    5 * time.Second,
+			CertFile:           "",
+			KeyFile:            "",
+			DisabledCiphers:    make([]string, 0),
+			Host:               "127.0.0.1",
+			Interceptors:       make(map[string]interface{}),
 			BatchRequests: BatchRequestsConfig{
 				MaxConcurrency:  10,
 				OperationsLimit: 500,
@@ -140,6 +222,18 @@ func NewDefaultConfig() *AgentConfig {
 				Default: "redis",
 			},
 		},
+		NotificationWebhooks: NotificationWebhooksConfig{
+			Subscriptions: []NotificationWebhookSubscription{},
+			BatchSize:     10,
+			FlushInterval: 1 * time.Second,
+			QueueSize:     1000,
+			MaxAttempts:   5,
+			Backoff: BackoffConfig{
+				Initial: 1 * time.Second,
+				Max:     30 * time.Second,
+			},
+			Timeout: 10 * time.Second,
+		},
 	}
 
 	return &config
@@ -162,6 +256,8 @@ type AgentConfig struct {
 	Server          ServerConfig  `json:"server"`
 	Webhook         WebhookConfig `json:"webhook"`
 	Synchronization SyncConfig    `json:"synchronization"`
+
+	NotificationWebhooks NotificationWebhooksConfig `json:"notificationWebhooks"`
 }
 
 // SyncConfig contains Synchronization configuration for the multiple Agent nodes
@@ -203,6 +299,16 @@ type UserProfileServiceConfigs map[string]interface{}
 // ODPCacheConfigs defines the generic mapping of odp cache plugins
 type ODPCacheConfigs map[string]interface{}
 
+// EventDispatcherConfigs defines the generic mapping of event dispatcher plugins. Every service listed
+// in sinks receives the event batches dispatched to the event endpoint.
+type EventDispatcherConfigs map[string]interface{}
+
+// ForcedDecisionsConfigs defines the generic mapping of forced decisions store plugins
+type ForcedDecisionsConfigs map[string]interface{}
+
+// UserAttributesConfigs defines the generic mapping of user attribute provider plugins
+type UserAttributesConfigs map[string]interface{}
+
 // ClientConfig holds the configuration options for the Optimizely Client.
 type ClientConfig struct {
 	PollingInterval     time.Duration             `json:"pollingInterval"`
@@ -210,10 +316,283 @@ type ClientConfig struct {
 	QueueSize           int                       `json:"queueSize" default:"1000"`
 	FlushInterval       time.Duration             `json:"flushInterval" default:"30s"`
 	DatafileURLTemplate string                    `json:"datafileURLTemplate"`
+	DatafileSnapshotDir string                    `json:"datafileSnapshotDir"`
 	EventURL            string                    `json:"eventURL"`
 	SdkKeyRegex         string                    `json:"sdkKeyRegex"`
 	UserProfileService  UserProfileServiceConfigs `json:"userProfileService"`
 	ODP                 OdpConfig                 `json:"odp"`
+	Eviction            EvictionConfig            `json:"eviction"`
+	LoadBackoff         BackoffConfig             `json:"loadBackoff"`
+	StaticDatafiles     StaticDatafilesConfig     `json:"staticDatafiles"`
+	SDKKeyOverrides     []SDKKeyOverride          `json:"sdkKeyOverrides"`
+	EventQueue          EventQueueConfig          `json:"eventQueue"`
+	DrainTimeout        time.Duration             `json:"drainTimeout"`
+	EventDispatchers    EventDispatcherConfigs    `json:"eventDispatchers"`
+	DeadLetter          DeadLetterConfig          `json:"deadLetter"`
+	Scrub               ScrubConfig               `json:"scrub"`
+	ForcedDecisions     ForcedDecisionsConfigs    `json:"forcedDecisions"`
+	UserAttributes      UserAttributesConfigs     `json:"userAttributes"`
+	AttributeValidation AttributeValidationMode   `json:"attributeValidation"`
+	Overrides           OverridesConfig           `json:"overrides"`
+}
+
+// SDKKeyOverride holds client settings applied to the SDK keys matching either SDKKey exactly or the Pattern regex.
+// Unset (zero) settings keep the global value. It is a list entry rather than a map since configuration map keys
+// are not case sensitive.
+type SDKKeyOverride struct {
+	SDKKey          string        `json:"sdkKey,omitempty"`
+	Pattern         string        `json:"pattern,omitempty"`
+	PollingInterval time.Duration `json:"pollingInterval,omitempty"`
+	BatchSize       int           `json:"batchSize,omitempty"`
+	QueueSize       int           `json:"queueSize,omitempty"`
+	FlushInterval   time.Duration `json:"flushInterval,omitempty"`
+	EventURL        string        `json:"eventURL,omitempty"`
+	ODP             OdpOverride   `json:"odp,omitempty"`
+	Scrub           *ScrubConfig  `json:"scrub,omitempty"`
+
+	AttributeValidation AttributeValidationMode `json:"attributeValidation,omitempty"`
+}
+
+// OdpOverride holds the odp settings of an SDKKeyOverride
+type OdpOverride struct {
+	Disable                *bool         `json:"disable,omitempty"`
+	EventsRequestTimeout   time.Duration `json:"eventsRequestTimeout,omitempty"`
+	EventsFlushInterval    time.Duration `json:"eventsFlushInterval,omitempty"`
+	SegmentsRequestTimeout time.Duration `json:"segmentsRequestTimeout,omitempty"`
+}
+
+// Matches returns true if the override applies to the SDK key
+func (o SDKKeyOverride) Matches(sdkKey string) bool {
+	if o.SDKKey != "" {
+		return o.SDKKey == sdkKey
+	}
+	if o.Pattern != "" {
+		matched, err := regexp.MatchString(o.Pattern, sdkKey)
+		return err == nil && matched
+	}
+	return false
+}
+
+// ForSDKKey returns the client configuration of the SDK key with the matching overrides applied.
+// Pattern overrides are applied in order, followed by the exact SDK key overrides, so the latter take precedence.
+func (c ClientConfig) ForSDKKey(sdkKey string) ClientConfig {
+	merged := c
+	for _, exact := range []bool{false, true} {
+		for _, override := range c.SDKKeyOverrides {
+			if (override.SDKKey != "") == exact && override.Matches(sdkKey) {
+				merged = override.apply(merged)
+			}
+		}
+	}
+	return merged
+}
+
+func (o SDKKeyOverride) apply(c ClientConfig) ClientConfig {
+	if o.PollingInterval != 0 {
+		c.PollingInterval = o.PollingInterval
+	}
+	if o.BatchSize != 0 {
+		c.BatchSize = o.BatchSize
+	}
+	if o.QueueSize != 0 {
+		c.QueueSize = o.QueueSize
+	}
+	if o.FlushInterval != 0 {
+		c.FlushInterval = o.FlushInterval
+	}
+	if o.EventURL != "" {
+		c.EventURL = o.EventURL
+	}
+	if o.ODP.Disable != nil {
+		c.ODP.Disable = *o.ODP.Disable
+	}
+	if o.ODP.EventsRequestTimeout != 0 {
+		c.ODP.EventsRequestTimeout = o.ODP.EventsRequestTimeout
+	}
+	if o.ODP.EventsFlushInterval != 0 {
+		c.ODP.EventsFlushInterval = o.ODP.EventsFlushInterval
+	}
+	if o.ODP.SegmentsRequestTimeout != 0 {
+		c.ODP.SegmentsRequestTimeout = o.ODP.SegmentsRequestTimeout
+	}
+	if o.Scrub != nil {
+		c.Scrub = *o.Scrub
+	}
+	if o.AttributeValidation != "" {
+		c.AttributeValidation = o.AttributeValidation
+	}
+	return c
+}
+
+// StaticDatafilesConfig holds the configuration for loading datafiles from local files instead of the CDN.
+// Static datafiles are enabled when either Dir or Files is set.
+type StaticDatafilesConfig struct {
+	// Dir is a directory containing a <sdkKey>.json datafile per SDK key.
+	Dir string `json:"dir"`
+	// Files maps SDK keys to datafiles, taking precedence over Dir.
+	Files []StaticDatafile `json:"files"`
+	// ReloadInterval is the time between successive checks for changed files. 0 disables reloading.
+	ReloadInterval time.Duration `json:"reloadInterval"`
+}
+
+// Enabled returns true when datafiles are loaded from local files
+func (c StaticDatafilesConfig) Enabled() bool {
+	return c.Dir != "" || len(c.Files) > 0
+}
+
+// StaticDatafile holds the location of the datafile of an SDK key.
+// It is a list entry rather than a map since configuration map keys are not case sensitive.
+type StaticDatafile struct {
+	SDKKey string `json:"sdkKey"`
+	Path   string `json:"path"`
+}
+
+// BackoffConfig holds the configuration for caching failed client loads.
+// The backoff starts at Initial and doubles on every consecutive failure up to Max.
+type BackoffConfig struct {
+	// Initial is the time a failed load is cached for. 0 disables caching of failed loads.
+	Initial time.Duration `json:"initial"`
+	// Max is the upper bound of the backoff.
+	Max time.Duration `json:"max"`
+}
+
+// EvictionConfig holds the configuration for evicting Optimizely clients from the cache.
+// SDK keys listed under sdkKeys are never evicted.
+type EvictionConfig struct {
+	// MaxClients is the maximum number of clients kept in the cache, the least recently used clients
+	// are evicted first. 0 means no limit.
+	MaxClients int `json:"maxClients"`
+	// IdleTTL is the time since the last access after which a client is evicted. 0 disables idle eviction.
+	IdleTTL time.Duration `json:"idleTTL"`
+	// Interval is the time between successive checks for idle clients.
+	Interval time.Duration `json:"interval"`
+}
+
+// EventQueueType is the kind of queue holding the events waiting to be dispatched
+type EventQueueType string
+
+const (
+	// EventQueueTypeInMemory keeps the events in memory, they are lost when Agent stops
+	EventQueueTypeInMemory EventQueueType = "in-memory"
+	// EventQueueTypeDisk writes the events to a log on disk which is replayed when Agent starts
+	EventQueueTypeDisk EventQueueType = "disk"
+)
+
+// EventQueueConfig holds the configuration of the queue of events waiting to be dispatched
+type EventQueueConfig struct {
+	Type EventQueueType       `json:"type"`
+	Disk DiskEventQueueConfig `json:"disk"`
+}
+
+// DiskEventQueueConfig holds the configuration of the disk event queue
+type DiskEventQueueConfig struct {
+	// Dir is the directory holding the event log of each SDK key
+	Dir string `json:"dir"`
+	// MaxBytes is the maximum size of the events queued for an SDK key, new events are discarded
+	// once it is reached. 0 means no limit.
+	MaxBytes int64 `json:"maxBytes"`
+	// SyncInterval is the time between syncs of the event logs to disk. The events queued since the last sync are
+	// lost when the machine crashes, while they are replayed after a crash of Agent. 0 syncs every queued event.
+	SyncInterval time.Duration `json:"syncInterval"`
+}
+
+// DeadLetterStoreType is the kind of store holding the event batches which could not be dispatched
+type DeadLetterStoreType string
+
+const (
+	// DeadLetterStoreDisk writes every batch to a file in a local directory
+	DeadLetterStoreDisk DeadLetterStoreType = "disk"
+	// DeadLetterStoreRedis pushes every batch to a Redis list
+	DeadLetterStoreRedis DeadLetterStoreType = "redis"
+)
+
+// DeadLetterConfig holds the configuration of the store of event batches which could not be dispatched
+type DeadLetterConfig struct {
+	// Store is either "disk" or "redis", empty disables the dead-letter store
+	Store DeadLetterStoreType `json:"store"`
+	// MaxAttempts is the number of failed dispatches after which a batch is moved to the store
+	MaxAttempts int                   `json:"maxAttempts"`
+	Dir         string                `json:"dir"`
+	Redis       DeadLetterRedisConfig `json:"redis"`
+}
+
+// DeadLetterRedisConfig holds the configuration of the Redis dead-letter store
+type DeadLetterRedisConfig struct {
+	Host     string `json:"host"`
+	Password string `json:"password"`
+	Database int    `json:"database"`
+	Key      string `json:"key"`
+}
+
+// OverridesStoreType is the kind of store holding the forced variations set with the override endpoint
+type OverridesStoreType string
+
+const (
+	// OverridesStoreInMemory keeps the forced variations in memory, they are local to an Agent node and lost when it stops
+	OverridesStoreInMemory OverridesStoreType = "in-memory"
+	// OverridesStoreRedis keeps the forced variations in Redis, shared by every Agent node
+	OverridesStoreRedis OverridesStoreType = "redis"
+)
+
+// OverridesConfig holds the configuration of the store of the forced variations set with the override endpoint
+type OverridesConfig struct {
+	// Store is either "in-memory" or "redis"
+	Store OverridesStoreType   `json:"store"`
+	Redis OverridesRedisConfig `json:"redis"`
+}
+
+// OverridesRedisConfig holds the configuration of the Redis overrides store
+type OverridesRedisConfig struct {
+	Host     string `json:"host"`
+	Password string `json:"password"`
+	Database int    `json:"database"`
+	// Prefix of the keys holding the forced variations, one hash per SDK key
+	Prefix string `json:"prefix"`
+	// RefreshInterval is the time between refreshes of the copy of the overrides kept by each node, which decisions
+	// are made with. Overrides set through another node apply once the copy is refreshed.
+	RefreshInterval time.Duration `json:"refreshInterval"`
+}
+
+// AttributeValidationMode is what decide does with request attributes which are missing from the datafile
+// or whose type doesn't match the one expected by the audience conditions
+type AttributeValidationMode string
+
+const (
+	// AttributeValidationOff passes the attributes through unchecked
+	AttributeValidationOff AttributeValidationMode = "off"
+	// AttributeValidationWarn makes the decisions and adds the problems to the response
+	AttributeValidationWarn AttributeValidationMode = "warn"
+	// AttributeValidationReject rejects the request with a 400
+	AttributeValidationReject AttributeValidationMode = "reject"
+)
+
+// ScrubAction is what a scrub rule does to the values of the attributes and tags it names
+type ScrubAction string
+
+const (
+	// ScrubActionDrop removes the value from the event
+	ScrubActionDrop ScrubAction = "drop"
+	// ScrubActionHash replaces the value with its hex encoded HMAC-SHA256, keyed with the salt
+	ScrubActionHash ScrubAction = "hash"
+	// ScrubActionTruncate shortens string values to MaxLength characters
+	ScrubActionTruncate ScrubAction = "truncate"
+)
+
+// ScrubConfig holds the rules applied to the user attributes and event tags of the events before they are
+// queued for dispatch. Decisions are made with the values provided in the request.
+type ScrubConfig struct {
+	// Salt is the key of the HMAC computed by the hash action
+	Salt  string      `json:"salt"`
+	Rules []ScrubRule `json:"rules"`
+}
+
+// ScrubRule applies an action to the named user attributes and event tags
+type ScrubRule struct {
+	Attributes []string    `json:"attributes,omitempty"`
+	Tags       []string    `json:"tags,omitempty"`
+	Action     ScrubAction `json:"action"`
+	// MaxLength is the number of characters kept by the truncate action
+	MaxLength int `json:"maxLength,omitempty"`
 }
 
 // OdpConfig holds the odp configuration
@@ -278,16 +657,18 @@ type PluginConfigs map[string]interface{}
 
 // ServerConfig holds the global http server configs
 type ServerConfig struct {
-	AllowedHosts    []string            `json:"allowedHosts"`
-	ReadTimeout     time.Duration       `json:"readTimeout"`
-	WriteTimeout    time.Duration       `json:"writeTimeout"`
-	CertFile        string              `json:"certFile"`
-	KeyFile         string              `json:"keyFile"`
-	DisabledCiphers []string            `json:"disabledCiphers"`
-	HealthCheckPath string              `json:"healthCheckPath"`
-	Host            string              `json:"host"`
-	BatchRequests   BatchRequestsConfig `json:"batchRequests"`
-	Interceptors    PluginConfigs       `json:"interceptors"`
+	AllowedHosts       []string            `json:"allowedHosts"`
+	ReadTimeout        time.Duration       `json:"readTimeout"`
+	WriteTimeout       time.Duration       `json:"writeTimeout"`
+	CertFile           string              `json:"certFile"`
+	KeyFile            string              `json:"keyFile"`
+	DisabledCiphers    []string            `json:"disabledCiphers"`
+	HealthCheckPath    string              `json:"healthCheckPath"`
+	ReadinessCheckPath string              `json:"readinessCheckPath"`
+	ShutdownTimeout    time.Duration       `json:"shutdownTimeout"`
+	Host               string              `json:"host"`
+	BatchRequests      BatchRequestsConfig `json:"batchRequests"`
+	Interceptors       PluginConfigs       `json:"interceptors"`
 }
 
 func (sc *ServerConfig) isHTTPSEnabled() bool {
@@ -302,12 +683,85 @@ func (sc *ServerConfig) GetAllowedHosts() []string {
 
 // APIConfig holds the REST API configuration
 type APIConfig struct {
-	Auth                ServiceAuthConfig `json:"-"`
-	CORS                CORSConfig        `json:"cors"`
-	MaxConns            int               `json:"maxConns"`
-	Port                string            `json:"port"`
-	EnableNotifications bool              `json:"enableNotifications"`
-	EnableOverrides     bool              `json:"enableOverrides"`
+	Auth                 ServiceAuthConfig `json:"-"`
+	CORS                 CORSConfig        `json:"cors"`
+	MaxConns             int               `json:"maxConns"`
+	Port                 string            `json:"port"`
+	EnableNotifications  bool              `json:"enableNotifications"`
+	EnableOverrides      bool              `json:"enableOverrides"`
+	EnableUserAttributes bool              `json:"enableUserAttributes"`
+	BulkDecide           BulkDecideConfig  `json:"bulkDecide"`
+	TrackBatch           TrackBatchConfig  `json:"trackBatch"`
+	GRPC                 GRPCConfig        `json:"grpc"`
+	WebSocket            WebSocketConfig   `json:"webSocket"`
+	EventStream          EventStreamConfig `json:"eventStream"`
+}
+
+// EventStreamConfig holds the configuration of the notifications event stream endpoint
+type EventStreamConfig struct {
+	// HeartbeatInterval is how often a comment is sent on idle streams, 0 disables the heartbeats
+	HeartbeatInterval time.Duration `json:"heartbeatInterval"`
+	// BufferSize is the number of recent notifications kept per SDK key to be replayed on reconnection
+	BufferSize int `json:"bufferSize"`
+}
+
+// WebSocketConfig holds the configuration of the notifications WebSocket endpoint
+type WebSocketConfig struct {
+	// PingInterval is how often connections are pinged, 0 disables the keepalive
+	PingInterval time.Duration `json:"pingInterval"`
+	// PongTimeout is how long a connection is kept without a pong, it must be longer than PingInterval
+	PongTimeout time.Duration `json:"pongTimeout"`
+	// WriteTimeout bounds the time to write a message to a connection
+	WriteTimeout time.Duration `json:"writeTimeout"`
+}
+
+// GRPCConfig holds the configuration of the gRPC listener, serving the API with the same auth and notifications settings
+type GRPCConfig struct {
+	// Port of the gRPC listener, "0" disables it
+	Port string `json:"port"`
+	// MaxConcurrentStreams is the maximum number of concurrent calls per connection, 0 means no limit
+	MaxConcurrentStreams uint32 `json:"maxConcurrentStreams"`
+}
+
+// BulkDecideConfig holds the configuration of the bulk decide endpoint
+type BulkDecideConfig struct {
+	MaxUsers       int `json:"maxUsers"`
+	MaxConcurrency int `json:"maxConcurrency"`
+}
+
+// TrackBatchConfig holds the configuration of the batch track endpoint
+type TrackBatchConfig struct {
+	MaxEvents   int               `json:"maxEvents"`
+	Idempotency IdempotencyConfig `json:"idempotency"`
+}
+
+// IdempotencyStoreType is the kind of store remembering the idempotency keys of tracked events
+type IdempotencyStoreType string
+
+const (
+	// IdempotencyStoreInMemory remembers the keys in memory, they are local to an Agent node and lost when it stops
+	IdempotencyStoreInMemory IdempotencyStoreType = "in-memory"
+	// IdempotencyStoreRedis remembers the keys in Redis, shared by every Agent node
+	IdempotencyStoreRedis IdempotencyStoreType = "redis"
+)
+
+// IdempotencyConfig holds the configuration of the store remembering the idempotency keys of tracked events
+type IdempotencyConfig struct {
+	// Store is either "in-memory" or "redis"
+	Store IdempotencyStoreType `json:"store"`
+	// Window is how long a key is remembered after its event was tracked
+	Window time.Duration `json:"window"`
+	// MaxKeys bounds the keys remembered by the in-memory store, the oldest are forgotten first
+	MaxKeys int                    `json:"maxKeys"`
+	Redis   IdempotencyRedisConfig `json:"redis"`
+}
+
+// IdempotencyRedisConfig holds the configuration of the Redis idempotency store
+type IdempotencyRedisConfig struct {
+	Host     string `json:"host"`
+	Password string `json:"password"`
+	Database int    `json:"database"`
+	Prefix   string `json:"prefix"`
 }
 
 // BatchRequestsConfig holds the configuration for batching
@@ -346,6 +800,33 @@ type WebhookProject struct {
 	SkipSignatureCheck bool     `json:"skipSignatureCheck" default:"false"`
 }
 
+// NotificationWebhooksConfig holds the configuration of the outbound webhooks delivering notifications
+type NotificationWebhooksConfig struct {
+	Subscriptions []NotificationWebhookSubscription `json:"subscriptions"`
+	// BatchSize is the maximum number of notifications delivered in a single request
+	BatchSize int `json:"batchSize"`
+	// FlushInterval is the maximum time a notification waits for its batch to be complete
+	FlushInterval time.Duration `json:"flushInterval"`
+	// QueueSize is the number of notifications queued per subscription, the notifications are dropped once it is full
+	QueueSize int `json:"queueSize"`
+	// MaxAttempts is the number of requests after which a batch failing to be delivered is dropped
+	MaxAttempts int `json:"maxAttempts"`
+	// Backoff is the time between successive attempts to deliver a batch
+	Backoff BackoffConfig `json:"backoff"`
+	// Timeout is the time limit of a delivery request
+	Timeout time.Duration `json:"timeout"`
+}
+
+// NotificationWebhookSubscription holds the configuration of a webhook receiving the notifications of an SDK key
+type NotificationWebhookSubscription struct {
+	SDKKey string `json:"sdkKey"`
+	URL    string `json:"url"`
+	// Secret signs the deliveries with an HMAC, they are not signed when it is empty
+	Secret string `json:"-"`
+	// Types are the notification types delivered, every type is delivered when it is empty
+	Types []string `json:"types"`
+}
+
 // OAuthClientCredentials are used for issuing access tokens
 type OAuthClientCredentials struct {
 	ID         string   `yaml:"id"`
@@ -37,6 +37,8 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, 5*time.Second, conf.Server.ReadTimeout)
 	assert.Equal(t, 10*time.Second, conf.Server.WriteTimeout)
 	assert.Equal(t, "/health", conf.Server.HealthCheckPath)
+	assert.Equal(t, "/ready", conf.Server.ReadinessCheckPath)
+	assert.Equal(t, 5*time.Second, conf.Server.ShutdownTimeout)
 	assert.Equal(t, "", conf.Server.KeyFile)
 	assert.Equal(t, "", conf.Server.CertFile)
 	assert.Equal(t, []string{}, conf.Server.DisabledCiphers)
@@ -65,6 +67,7 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, "", conf.API.Auth.JwksURL)
 	assert.Equal(t, time.Duration(0), conf.API.Auth.JwksUpdateInterval)
 	assert.Equal(t, false, conf.API.EnableOverrides)
+	assert.Equal(t, false, conf.API.EnableUserAttributes)
 	assert.Equal(t, false, conf.API.EnableNotifications)
 	assert.Equal(t, []string(nil), conf.API.CORS.AllowedOrigins)
 	assert.Equal(t, []string(nil), conf.API.CORS.AllowedMethods)
@@ -72,6 +75,29 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, make([]string, 0), conf.API.CORS.ExposedHeaders)
 	assert.Equal(t, false, conf.API.CORS.AllowedCredentials)
 	assert.Equal(t, 300, conf.API.CORS.MaxAge)
+	assert.Equal(t, 1000, conf.API.BulkDecide.MaxUsers)
+	assert.Equal(t, 10, conf.API.BulkDecide.MaxConcurrency)
+	assert.Equal(t, 1000, conf.API.TrackBatch.MaxEvents)
+	assert.Equal(t, IdempotencyStoreInMemory, conf.API.TrackBatch.Idempotency.Store)
+	assert.Equal(t, 24*time.Hour, conf.API.TrackBatch.Idempotency.Window)
+	assert.Equal(t, 100000, conf.API.TrackBatch.Idempotency.MaxKeys)
+	assert.Equal(t, "optimizely-idempotency", conf.API.TrackBatch.Idempotency.Redis.Prefix)
+	assert.Equal(t, "0", conf.API.GRPC.Port)
+	assert.Equal(t, uint32(0), conf.API.GRPC.MaxConcurrentStreams)
+	assert.Equal(t, 30*time.Second, conf.API.WebSocket.PingInterval)
+	assert.Equal(t, 60*time.Second, conf.API.WebSocket.PongTimeout)
+	assert.Equal(t, 10*time.Second, conf.API.WebSocket.WriteTimeout)
+	assert.Equal(t, 15*time.Second, conf.API.EventStream.HeartbeatInterval)
+	assert.Equal(t, 100, conf.API.EventStream.BufferSize)
+
+	assert.Empty(t, conf.NotificationWebhooks.Subscriptions)
+	assert.Equal(t, 10, conf.NotificationWebhooks.BatchSize)
+	assert.Equal(t, 1*time.Second, conf.NotificationWebhooks.FlushInterval)
+	assert.Equal(t, 1000, conf.NotificationWebhooks.QueueSize)
+	assert.Equal(t, 5, conf.NotificationWebhooks.MaxAttempts)
+	assert.Equal(t, 1*time.Second, conf.NotificationWebhooks.Backoff.Initial)
+	assert.Equal(t, 30*time.Second, conf.NotificationWebhooks.Backoff.Max)
+	assert.Equal(t, 10*time.Second, conf.NotificationWebhooks.Timeout)
 
 	assert.Equal(t, "8085", conf.Webhook.Port)
 	assert.Empty(t, conf.Webhook.Projects)
@@ -83,6 +109,21 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, "https://cdn.optimizely.com/datafiles/%s.json", conf.Client.DatafileURLTemplate)
 	assert.Equal(t, "https://logx.optimizely.com/v1/events", conf.Client.EventURL)
 	assert.Equal(t, "^\\w+(:\\w+)?$", conf.Client.SdkKeyRegex)
+	assert.Equal(t, 0, conf.Client.Eviction.MaxClients)
+	assert.Equal(t, time.Duration(0), conf.Client.Eviction.IdleTTL)
+	assert.Equal(t, 1*time.Minute, conf.Client.Eviction.Interval)
+	assert.Equal(t, "", conf.Client.DatafileSnapshotDir)
+	assert.Equal(t, "", conf.Client.StaticDatafiles.Dir)
+	assert.Empty(t, conf.Client.StaticDatafiles.Files)
+	assert.Equal(t, 10*time.Second, conf.Client.StaticDatafiles.ReloadInterval)
+	assert.Equal(t, 30*time.Second, conf.Client.DrainTimeout)
+	assert.Equal(t, AttributeValidationOff, conf.Client.AttributeValidation)
+	assert.Equal(t, EventQueueTypeInMemory, conf.Client.EventQueue.Type)
+	assert.Equal(t, "", conf.Client.EventQueue.Disk.Dir)
+	assert.Equal(t, int64(10*1024*1024), conf.Client.EventQueue.Disk.MaxBytes)
+	assert.Equal(t, 1*time.Second, conf.Client.EventQueue.Disk.SyncInterval)
+	assert.Equal(t, 5*time.Second, conf.Client.LoadBackoff.Initial)
+	assert.Equal(t, 5*time.Minute, conf.Client.LoadBackoff.Max)
 	assert.Equal(t, "", conf.Client.UserProfileService["default"])
 	assert.Equal(t, false, conf.Client.ODP.Disable)
 	assert.Equal(t, 1*time.Second, conf.Client.ODP.EventsFlushInterval)
@@ -96,6 +137,19 @@ func TestDefaultConfig(t *testing.T) {
 			"timeout": "600s",
 		},
 	}, conf.Client.ODP.SegmentsCache["services"])
+	assert.Equal(t, DeadLetterStoreType(""), conf.Client.DeadLetter.Store)
+	assert.Equal(t, 5, conf.Client.DeadLetter.MaxAttempts)
+	assert.Equal(t, "optimizely-dead-letters", conf.Client.DeadLetter.Redis.Key)
+	assert.Equal(t, OverridesStoreInMemory, conf.Client.Overrides.Store)
+	assert.Equal(t, "optimizely-overrides", conf.Client.Overrides.Redis.Prefix)
+	assert.Equal(t, 1*time.Second, conf.Client.Overrides.Redis.RefreshInterval)
+	assert.Equal(t, ScrubConfig{}, conf.Client.Scrub)
+	assert.Equal(t, []interface{}{}, conf.Client.EventDispatchers["sinks"])
+	assert.Equal(t, map[string]interface{}{}, conf.Client.EventDispatchers["services"])
+	assert.Equal(t, "in-memory", conf.Client.ForcedDecisions["default"])
+	assert.Equal(t, map[string]interface{}{"in-memory": map[string]interface{}{}}, conf.Client.ForcedDecisions["services"])
+	assert.Equal(t, "in-memory", conf.Client.UserAttributes["default"])
+	assert.Equal(t, map[string]interface{}{"in-memory": map[string]interface{}{}}, conf.Client.UserAttributes["services"])
 
 	assert.Equal(t, 0, conf.Runtime.BlockProfileRate)
 	assert.Equal(t, 0, conf.Runtime.MutexProfileFraction)
@@ -233,3 +287,39 @@ func TestServerConfig_GetAllowedHosts(t *testing.T) {
 	assert.Contains(t, allowedHosts, "localhost")
 	assert.Contains(t, allowedHosts, "special.test.host")
 }
+
+func TestClientConfigForSDKKey(t *testing.T) {
+	disable := true
+	conf := NewDefaultConfig().Client
+	conf.SDKKeyOverrides = []SDKKeyOverride{
+		{SDKKey: "prodKey", PollingInterval: 30 * time.Second, QueueSize: 5000},
+		{Pattern: "Key$", BatchSize: 50, QueueSize: 2000, EventURL: "https://localhost/events"},
+		{Pattern: "^dev", FlushInterval: 5 * time.Second, ODP: OdpOverride{Disable: &disable, EventsFlushInterval: 2 * time.Second}, AttributeValidation: AttributeValidationWarn},
+		{Pattern: "["},
+		{SDKKey: "prodKey", Scrub: &ScrubConfig{Salt: "salt", Rules: []ScrubRule{{Attributes: []string{"email"}, Action: ScrubActionHash}}}},
+	}
+
+	actual := conf.ForSDKKey("prodKey")
+	assert.Equal(t, 30*time.Second, actual.PollingInterval)
+	assert.Equal(t, 50, actual.BatchSize)
+	// Exact SDK key overrides take precedence over patterns
+	assert.Equal(t, 5000, actual.QueueSize)
+	assert.Equal(t, "https://localhost/events", actual.EventURL)
+	assert.Equal(t, conf.FlushInterval, actual.FlushInterval)
+	assert.False(t, actual.ODP.Disable)
+	assert.Equal(t, "salt", actual.Scrub.Salt)
+	assert.Len(t, actual.Scrub.Rules, 1)
+	assert.Equal(t, AttributeValidationOff, actual.AttributeValidation)
+
+	actual = conf.ForSDKKey("devKey")
+	assert.Equal(t, conf.PollingInterval, actual.PollingInterval)
+	assert.Equal(t, 2000, actual.QueueSize)
+	assert.Equal(t, 5*time.Second, actual.FlushInterval)
+	assert.True(t, actual.ODP.Disable)
+	assert.Equal(t, 2*time.Second, actual.ODP.EventsFlushInterval)
+	assert.Equal(t, conf.ODP.EventsRequestTimeout, actual.ODP.EventsRequestTimeout)
+	assert.Equal(t, AttributeValidationWarn, actual.AttributeValidation)
+
+	actual = conf.ForSDKKey("other")
+	assert.Equal(t, conf, actual)
+}
@@ -0,0 +1,160 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package deadletter //
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const letterExt = ".json"
+
+// DiskStore keeps every letter in its own file of a local directory
+type DiskStore struct {
+	dir string
+}
+
+// NewDiskStore returns a store writing the letters to dir, creating it if needed
+func NewDiskStore(dir string) (*DiskStore, error) {
+	if dir == "" {
+		return nil, errors.New("dead-letter directory not provided")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskStore{dir: dir}, nil
+}
+
+// Add atomically writes the letter to its file
+func (s *DiskStore) Add(_ context.Context, letter *Letter) error {
+	path, err := s.path(letter.ID)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".letter-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// List reads every letter of the directory, letters which can't be read are skipped
+func (s *DiskStore) List(ctx context.Context) ([]*Letter, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	letters := []*Letter{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != letterExt {
+			continue
+		}
+
+		letter, err := s.Get(ctx, strings.TrimSuffix(name, letterExt))
+		if err != nil {
+			log.Warn().Err(err).Str("file", name).Msg("Skipping unreadable dead letter")
+			continue
+		}
+		letters = append(letters, letter.summary())
+	}
+
+	sortLetters(letters)
+	return letters, nil
+}
+
+// Get reads the letter with the given ID
+func (s *DiskStore) Get(_ context.Context, id string) (*Letter, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	letter := &Letter{}
+	if err := json.Unmarshal(b, letter); err != nil {
+		return nil, err
+	}
+	return letter, nil
+}
+
+// Remove deletes the file of the letter with the given ID
+func (s *DiskStore) Remove(_ context.Context, id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// path returns the file of the letter, IDs which are not plain file names are never found
+func (s *DiskStore) path(id string) (string, error) {
+	if id == "" || strings.HasPrefix(id, ".") || filepath.Base(id) != id {
+		return "", ErrNotFound
+	}
+	return filepath.Join(s.dir, id+letterExt), nil
+}
+
+// sortLetters orders the letters from the oldest to the newest failure
+func sortLetters(letters []*Letter) {
+	sort.SliceStable(letters, func(i, j int) bool {
+		if letters[i].FailedAt.Equal(letters[j].FailedAt) {
+			return letters[i].ID < letters[j].ID
+		}
+		return letters[i].FailedAt.Before(letters[j].FailedAt)
+	})
+}
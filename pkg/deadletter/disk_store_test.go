@@ -0,0 +1,114 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package deadletter //
+package deadletter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/optimizely/go-sdk/pkg/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLetter(id string, failedAt time.Time) *Letter {
+	return &Letter{
+		ID:       id,
+		SDKKey:   "sdkKey",
+		EndPoint: "https://logx.optimizely.com/v1/events",
+		Attempts: 5,
+		Error:    "timeout",
+		FailedAt: failedAt,
+		Event:    &event.Batch{Revision: "1", ProjectID: "project", Visitors: []event.Visitor{{VisitorID: "user"}}},
+	}
+}
+
+func TestDiskStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewDiskStore(filepath.Join(t.TempDir(), "letters"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	now := time.Now().UTC()
+	first := newLetter("first", now.Add(-time.Minute))
+	second := newLetter("second", now)
+	assert.NoError(t, store.Add(ctx, second))
+	assert.NoError(t, store.Add(ctx, first))
+
+	letters, err := store.List(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, letters, 2) {
+		assert.Equal(t, "first", letters[0].ID)
+		assert.Equal(t, "second", letters[1].ID)
+		assert.Nil(t, letters[0].Event)
+	}
+
+	letter, err := store.Get(ctx, "first")
+	assert.NoError(t, err)
+	assert.Equal(t, first.Event, letter.Event)
+	assert.True(t, first.FailedAt.Equal(letter.FailedAt))
+
+	assert.NoError(t, store.Remove(ctx, "first"))
+	assert.Equal(t, ErrNotFound, store.Remove(ctx, "first"))
+	_, err = store.Get(ctx, "first")
+	assert.Equal(t, ErrNotFound, err)
+
+	letters, err = store.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, letters, 1)
+}
+
+func TestDiskStoreRejectsPaths(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewDiskStore(filepath.Join(dir, "letters"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	if !assert.NoError(t, os.WriteFile(filepath.Join(dir, "secret.json"), []byte("{}"), 0o644)) {
+		return
+	}
+
+	_, err = store.Get(ctx, "../secret")
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, ErrNotFound, store.Remove(ctx, "../secret"))
+	assert.Error(t, store.Add(ctx, newLetter("../secret", time.Now())))
+	assert.FileExists(t, filepath.Join(dir, "secret.json"))
+}
+
+func TestDiskStoreSkipsUnreadableLetters(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewDiskStore(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.NoError(t, store.Add(ctx, newLetter("letter", time.Now())))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "corrupt.json"), []byte("{"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0o644))
+
+	letters, err := store.List(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, letters, 1) {
+		assert.Equal(t, "letter", letters[0].ID)
+	}
+}
@@ -0,0 +1,124 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package deadletter //
+package deadletter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/optimizely/go-sdk/pkg/event"
+	"github.com/rs/zerolog/log"
+)
+
+// storeTimeout bounds the time spent writing a letter to the store
+const storeTimeout = 5 * time.Second
+
+// Dispatcher dispatches the events to the primary dispatcher and moves the batches which failed maxAttempts
+// times to the store. Stored batches are reported as dispatched so that the queue moves on to the next ones.
+type Dispatcher struct {
+	sdkKey      string
+	primary     event.Dispatcher
+	store       Store
+	maxAttempts int
+
+	// OnDeadLetter is called every time a batch is moved to the store
+	OnDeadLetter func()
+
+	mu       sync.Mutex
+	attempts map[string]int
+}
+
+// NewDispatcher returns a dispatcher moving the batches primary failed to dispatch maxAttempts times to the store
+func NewDispatcher(sdkKey string, primary event.Dispatcher, store Store, maxAttempts int) *Dispatcher {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &Dispatcher{
+		sdkKey:      sdkKey,
+		primary:     primary,
+		store:       store,
+		maxAttempts: maxAttempts,
+		attempts:    make(map[string]int),
+	}
+}
+
+// DispatchEvent dispatches the event, storing it once it failed to be dispatched maxAttempts times
+func (d *Dispatcher) DispatchEvent(logEvent event.LogEvent) (bool, error) {
+	success, err := d.primary.DispatchEvent(logEvent)
+	key := batchKey(logEvent)
+
+	d.mu.Lock()
+	if success && err == nil {
+		delete(d.attempts, key)
+		d.mu.Unlock()
+		return success, err
+	}
+	d.attempts[key]++
+	attempts := d.attempts[key]
+	d.mu.Unlock()
+
+	if attempts < d.maxAttempts {
+		return success, err
+	}
+
+	letter := &Letter{
+		ID:       uuid.New().String(),
+		SDKKey:   d.sdkKey,
+		EndPoint: logEvent.EndPoint,
+		Attempts: attempts,
+		Error:    "event batch was not accepted",
+		FailedAt: time.Now().UTC(),
+		Event:    &logEvent.Event,
+	}
+	if err != nil {
+		letter.Error = err.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if storeErr := d.store.Add(ctx, letter); storeErr != nil {
+		// The batch stays queued and is stored on its next failure
+		log.Error().Err(storeErr).Msg("Failed to store undispatched event batch")
+		return success, err
+	}
+
+	d.mu.Lock()
+	delete(d.attempts, key)
+	d.mu.Unlock()
+
+	if d.OnDeadLetter != nil {
+		d.OnDeadLetter()
+	}
+	log.Warn().Str("id", letter.ID).Int("attempts", attempts).Msg("Moved undispatched event batch to the dead-letter store")
+	return true, nil
+}
+
+// batchKey identifies the batch across its dispatch attempts
+func batchKey(logEvent event.LogEvent) string {
+	b, err := json.Marshal(logEvent)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
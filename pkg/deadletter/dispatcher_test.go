@@ -0,0 +1,127 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package deadletter //
+package deadletter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/optimizely/go-sdk/pkg/event"
+	"github.com/stretchr/testify/assert"
+)
+
+type testDispatcher struct {
+	success bool
+	err     error
+	calls   int
+}
+
+func (d *testDispatcher) DispatchEvent(logEvent event.LogEvent) (bool, error) {
+	d.calls++
+	return d.success, d.err
+}
+
+type failingStore struct {
+	Store
+}
+
+func (s failingStore) Add(ctx context.Context, letter *Letter) error {
+	return errors.New("store unavailable")
+}
+
+func newLogEvent(revision string) event.LogEvent {
+	return event.LogEvent{
+		EndPoint: "https://logx.optimizely.com/v1/events",
+		Event:    event.Batch{Revision: revision, ProjectID: "project"},
+	}
+}
+
+func TestDispatcherStoresFailedBatches(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewDiskStore(t.TempDir())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	primary := &testDispatcher{err: errors.New("timeout")}
+	dispatcher := NewDispatcher("sdkKey", primary, store, 3)
+	deadLetters := 0
+	dispatcher.OnDeadLetter = func() {
+		deadLetters++
+	}
+
+	logEvent := newLogEvent("1")
+	for i := 0; i < 2; i++ {
+		success, err := dispatcher.DispatchEvent(logEvent)
+		assert.False(t, success)
+		assert.Error(t, err)
+	}
+
+	success, err := dispatcher.DispatchEvent(logEvent)
+	assert.True(t, success)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deadLetters)
+	assert.Empty(t, dispatcher.attempts)
+
+	letters, err := store.List(ctx)
+	assert.NoError(t, err)
+	if !assert.Len(t, letters, 1) {
+		return
+	}
+	assert.Equal(t, "sdkKey", letters[0].SDKKey)
+	assert.Equal(t, 3, letters[0].Attempts)
+	assert.Equal(t, "timeout", letters[0].Error)
+
+	letter, err := store.Get(ctx, letters[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, logEvent, letter.LogEvent())
+}
+
+func TestDispatcherResetsAttemptsOnSuccess(t *testing.T) {
+	store, err := NewDiskStore(t.TempDir())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	primary := &testDispatcher{}
+	dispatcher := NewDispatcher("sdkKey", primary, store, 2)
+
+	logEvent := newLogEvent("1")
+	success, _ := dispatcher.DispatchEvent(logEvent)
+	assert.False(t, success)
+
+	primary.success = true
+	success, _ = dispatcher.DispatchEvent(logEvent)
+	assert.True(t, success)
+	assert.Empty(t, dispatcher.attempts)
+
+	letters, err := store.List(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, letters)
+}
+
+func TestDispatcherKeepsBatchWhenStoreFails(t *testing.T) {
+	primary := &testDispatcher{}
+	dispatcher := NewDispatcher("sdkKey", primary, failingStore{}, 1)
+
+	success, err := dispatcher.DispatchEvent(newLogEvent("1"))
+	assert.False(t, success)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, dispatcher.attempts[batchKey(newLogEvent("1"))])
+}
@@ -0,0 +1,141 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package deadletter //
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/optimizely/agent/config"
+	"github.com/rs/zerolog/log"
+)
+
+// RedisStore keeps the letters in a Redis list, newest last
+type RedisStore struct {
+	client *redis.Client
+	key    string
+}
+
+// NewRedisStore returns a store pushing the letters to the list at conf.Key
+func NewRedisStore(conf config.DeadLetterRedisConfig) (*RedisStore, error) {
+	if conf.Host == "" {
+		return nil, errors.New("dead-letter redis host not provided")
+	}
+	if conf.Key == "" {
+		return nil, errors.New("dead-letter redis key not provided")
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     conf.Host,
+		Password: conf.Password,
+		DB:       conf.Database,
+	})
+	return &RedisStore{client: client, key: conf.Key}, nil
+}
+
+// Add appends the letter to the list
+func (s *RedisStore) Add(ctx context.Context, letter *Letter) error {
+	b, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+	return s.client.RPush(ctx, s.key, b).Err()
+}
+
+// List returns every letter of the list
+func (s *RedisStore) List(ctx context.Context) ([]*Letter, error) {
+	letters := []*Letter{}
+	err := s.scan(ctx, func(_ string, letter *Letter) bool {
+		letters = append(letters, letter.summary())
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortLetters(letters)
+	return letters, nil
+}
+
+// Get returns the letter with the given ID
+func (s *RedisStore) Get(ctx context.Context, id string) (*Letter, error) {
+	var found *Letter
+	err := s.scan(ctx, func(_ string, letter *Letter) bool {
+		if letter.ID == id {
+			found = letter
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+// Remove deletes the letter with the given ID from the list
+func (s *RedisStore) Remove(ctx context.Context, id string) error {
+	var value string
+	err := s.scan(ctx, func(raw string, letter *Letter) bool {
+		if letter.ID == id {
+			value = raw
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return ErrNotFound
+	}
+
+	removed, err := s.client.LRem(ctx, s.key, 1, value).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// scan calls fn with every letter of the list until it returns false, entries which can't be read are skipped
+func (s *RedisStore) scan(ctx context.Context, fn func(raw string, letter *Letter) bool) error {
+	values, err := s.client.LRange(ctx, s.key, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, value := range values {
+		letter := &Letter{}
+		if err := json.Unmarshal([]byte(value), letter); err != nil {
+			log.Warn().Err(err).Msg("Skipping unreadable dead letter")
+			continue
+		}
+		if !fn(value, letter) {
+			return nil
+		}
+	}
+	return nil
+}
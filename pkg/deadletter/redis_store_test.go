@@ -0,0 +1,55 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package deadletter //
+package deadletter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/optimizely/agent/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRedisStore(t *testing.T) {
+	_, err := NewRedisStore(config.DeadLetterRedisConfig{Key: "letters"})
+	assert.Error(t, err)
+
+	_, err = NewRedisStore(config.DeadLetterRedisConfig{Host: "localhost:6379"})
+	assert.Error(t, err)
+
+	store, err := NewRedisStore(config.DeadLetterRedisConfig{Host: "localhost:6379", Key: "letters"})
+	assert.NoError(t, err)
+	assert.Equal(t, "letters", store.key)
+}
+
+func TestRedisStoreUnavailable(t *testing.T) {
+	store, err := NewRedisStore(config.DeadLetterRedisConfig{Host: "127.0.0.1:1", Key: "letters"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Error(t, store.Add(ctx, newLetter("letter", time.Now())))
+	_, err = store.List(ctx)
+	assert.Error(t, err)
+	_, err = store.Get(ctx, "letter")
+	assert.Error(t, err)
+	assert.Error(t, store.Remove(ctx, "letter"))
+}
@@ -0,0 +1,84 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package deadletter keeps the event batches which could not be dispatched so that they can be replayed
+package deadletter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/go-sdk/pkg/event"
+)
+
+// ErrNotFound is returned when no letter has the requested ID
+var ErrNotFound = errors.New("dead letter not found")
+
+// Letter is an event batch which failed to be dispatched
+type Letter struct {
+	ID       string       `json:"id"`
+	SDKKey   string       `json:"sdkKey,omitempty"`
+	EndPoint string       `json:"endPoint"`
+	Attempts int          `json:"attempts"`
+	Error    string       `json:"error,omitempty"`
+	FailedAt time.Time    `json:"failedAt"`
+	Event    *event.Batch `json:"event,omitempty"`
+}
+
+// LogEvent returns the event to dispatch when replaying the letter
+func (l *Letter) LogEvent() event.LogEvent {
+	logEvent := event.LogEvent{EndPoint: l.EndPoint}
+	if l.Event != nil {
+		logEvent.Event = *l.Event
+	}
+	return logEvent
+}
+
+// summary returns a copy of the letter without its event batch
+func (l *Letter) summary() *Letter {
+	s := *l
+	s.Event = nil
+	return &s
+}
+
+// Store holds the letters until they are replayed or purged
+type Store interface {
+	// Add stores the letter
+	Add(ctx context.Context, letter *Letter) error
+	// List returns the letters, oldest first, without their event batch
+	List(ctx context.Context) ([]*Letter, error)
+	// Get returns the letter with the given ID or ErrNotFound
+	Get(ctx context.Context, id string) (*Letter, error)
+	// Remove deletes the letter with the given ID or returns ErrNotFound
+	Remove(ctx context.Context, id string) error
+}
+
+// NewStore returns the store described by conf, nil when the dead-letter store is disabled
+func NewStore(conf config.DeadLetterConfig) (Store, error) {
+	switch conf.Store {
+	case "":
+		return nil, nil
+	case config.DeadLetterStoreDisk:
+		return NewDiskStore(conf.Dir)
+	case config.DeadLetterStoreRedis:
+		return NewRedisStore(conf.Redis)
+	default:
+		return nil, fmt.Errorf("unknown dead-letter store: %q", conf.Store)
+	}
+}
@@ -0,0 +1,48 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package deadletter //
+package deadletter
+
+import (
+	"testing"
+
+	"github.com/optimizely/agent/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStore(t *testing.T) {
+	store, err := NewStore(config.DeadLetterConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, store)
+
+	store, err = NewStore(config.DeadLetterConfig{Store: config.DeadLetterStoreDisk, Dir: t.TempDir()})
+	assert.NoError(t, err)
+	assert.IsType(t, &DiskStore{}, store)
+
+	store, err = NewStore(config.DeadLetterConfig{Store: config.DeadLetterStoreRedis, Redis: config.DeadLetterRedisConfig{Host: "localhost:6379", Key: "letters"}})
+	assert.NoError(t, err)
+	assert.IsType(t, &RedisStore{}, store)
+
+	_, err = NewStore(config.DeadLetterConfig{Store: config.DeadLetterStoreDisk})
+	assert.Error(t, err)
+
+	_, err = NewStore(config.DeadLetterConfig{Store: config.DeadLetterStoreRedis})
+	assert.Error(t, err)
+
+	_, err = NewStore(config.DeadLetterConfig{Store: "s3"})
+	assert.Error(t, err)
+}
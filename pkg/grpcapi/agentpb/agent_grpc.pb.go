@@ -0,0 +1,311 @@
+//***************************************************************************
+// Copyright 2023, Optimizely, Inc. and contributors                        *
+//                                                                          *
+// Licensed under the Apache License, Version 2.0 (the "License");          *
+// you may not use this file except in compliance with the License.         *
+// You may obtain a copy of the License at                                  *
+//                                                                          *
+//    http://www.apache.org/licenses/LICENSE-2.0                            *
+//                                                                          *
+// Unless required by applicable law or agreed to in writing, software      *
+// distributed under the License is distributed on an "AS IS" BASIS,        *
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+// See the License for the specific language governing permissions and      *
+// limitations under the License.                                           *
+//*************************************************************************
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.24.4
+// source: agent.proto
+
+package agentpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Agent_Decide_FullMethodName        = "/optimizely.agent.v1.Agent/Decide"
+	Agent_Track_FullMethodName         = "/optimizely.agent.v1.Agent/Track"
+	Agent_SendOdpEvent_FullMethodName  = "/optimizely.agent.v1.Agent/SendOdpEvent"
+	Agent_GetConfig_FullMethodName     = "/optimizely.agent.v1.Agent/GetConfig"
+	Agent_Notifications_FullMethodName = "/optimizely.agent.v1.Agent/Notifications"
+)
+
+// AgentClient is the client API for Agent service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AgentClient interface {
+	// Decide makes the decisions of the flags for a user, every flag when no key is given
+	Decide(ctx context.Context, in *DecideRequest, opts ...grpc.CallOption) (*DecideResponse, error)
+	// Track tracks a conversion event for a user
+	Track(ctx context.Context, in *TrackRequest, opts ...grpc.CallOption) (*TrackResponse, error)
+	// SendOdpEvent sends an event to the ODP platform
+	SendOdpEvent(ctx context.Context, in *SendOdpEventRequest, opts ...grpc.CallOption) (*SendOdpEventResponse, error)
+	// GetConfig returns the OptimizelyConfig of the SDK key
+	GetConfig(ctx context.Context, in *GetConfigRequest, opts ...grpc.CallOption) (*GetConfigResponse, error)
+	// Notifications streams the notifications of the SDK key until the call is canceled
+	Notifications(ctx context.Context, in *NotificationsRequest, opts ...grpc.CallOption) (Agent_NotificationsClient, error)
+}
+
+type agentClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAgentClient(cc grpc.ClientConnInterface) AgentClient {
+	return &agentClient{cc}
+}
+
+func (c *agentClient) Decide(ctx context.Context, in *DecideRequest, opts ...grpc.CallOption) (*DecideResponse, error) {
+	out := new(DecideResponse)
+	err := c.cc.Invoke(ctx, Agent_Decide_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) Track(ctx context.Context, in *TrackRequest, opts ...grpc.CallOption) (*TrackResponse, error) {
+	out := new(TrackResponse)
+	err := c.cc.Invoke(ctx, Agent_Track_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) SendOdpEvent(ctx context.Context, in *SendOdpEventRequest, opts ...grpc.CallOption) (*SendOdpEventResponse, error) {
+	out := new(SendOdpEventResponse)
+	err := c.cc.Invoke(ctx, Agent_SendOdpEvent_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) GetConfig(ctx context.Context, in *GetConfigRequest, opts ...grpc.CallOption) (*GetConfigResponse, error) {
+	out := new(GetConfigResponse)
+	err := c.cc.Invoke(ctx, Agent_GetConfig_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) Notifications(ctx context.Context, in *NotificationsRequest, opts ...grpc.CallOption) (Agent_NotificationsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Agent_ServiceDesc.Streams[0], Agent_Notifications_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &agentNotificationsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Agent_NotificationsClient interface {
+	Recv() (*Notification, error)
+	grpc.ClientStream
+}
+
+type agentNotificationsClient struct {
+	grpc.ClientStream
+}
+
+func (x *agentNotificationsClient) Recv() (*Notification, error) {
+	m := new(Notification)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AgentServer is the server API for Agent service.
+// All implementations must embed UnimplementedAgentServer
+// for forward compatibility
+type AgentServer interface {
+	// Decide makes the decisions of the flags for a user, every flag when no key is given
+	Decide(context.Context, *DecideRequest) (*DecideResponse, error)
+	// Track tracks a conversion event for a user
+	Track(context.Context, *TrackRequest) (*TrackResponse, error)
+	// SendOdpEvent sends an event to the ODP platform
+	SendOdpEvent(context.Context, *SendOdpEventRequest) (*SendOdpEventResponse, error)
+	// GetConfig returns the OptimizelyConfig of the SDK key
+	GetConfig(context.Context, *GetConfigRequest) (*GetConfigResponse, error)
+	// Notifications streams the notifications of the SDK key until the call is canceled
+	Notifications(*NotificationsRequest, Agent_NotificationsServer) error
+	mustEmbedUnimplementedAgentServer()
+}
+
+// UnimplementedAgentServer must be embedded to have forward compatible implementations.
+type UnimplementedAgentServer struct {
+}
+
+func (UnimplementedAgentServer) Decide(context.Context, *DecideRequest) (*DecideResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Decide not implemented")
+}
+func (UnimplementedAgentServer) Track(context.Context, *TrackRequest) (*TrackResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Track not implemented")
+}
+func (UnimplementedAgentServer) SendOdpEvent(context.Context, *SendOdpEventRequest) (*SendOdpEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendOdpEvent not implemented")
+}
+func (UnimplementedAgentServer) GetConfig(context.Context, *GetConfigRequest) (*GetConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetConfig not implemented")
+}
+func (UnimplementedAgentServer) Notifications(*NotificationsRequest, Agent_NotificationsServer) error {
+	return status.Errorf(codes.Unimplemented, "method Notifications not implemented")
+}
+func (UnimplementedAgentServer) mustEmbedUnimplementedAgentServer() {}
+
+// UnsafeAgentServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AgentServer will
+// result in compilation errors.
+type UnsafeAgentServer interface {
+	mustEmbedUnimplementedAgentServer()
+}
+
+func RegisterAgentServer(s grpc.ServiceRegistrar, srv AgentServer) {
+	s.RegisterService(&Agent_ServiceDesc, srv)
+}
+
+func _Agent_Decide_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DecideRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).Decide(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Agent_Decide_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).Decide(ctx, req.(*DecideRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_Track_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TrackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).Track(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Agent_Track_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).Track(ctx, req.(*TrackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_SendOdpEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendOdpEventRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).SendOdpEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Agent_SendOdpEvent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).SendOdpEvent(ctx, req.(*SendOdpEventRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_GetConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).GetConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Agent_GetConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).GetConfig(ctx, req.(*GetConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_Notifications_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(NotificationsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AgentServer).Notifications(m, &agentNotificationsServer{stream})
+}
+
+type Agent_NotificationsServer interface {
+	Send(*Notification) error
+	grpc.ServerStream
+}
+
+type agentNotificationsServer struct {
+	grpc.ServerStream
+}
+
+func (x *agentNotificationsServer) Send(m *Notification) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Agent_ServiceDesc is the grpc.ServiceDesc for Agent service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Agent_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "optimizely.agent.v1.Agent",
+	HandlerType: (*AgentServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Decide",
+			Handler:    _Agent_Decide_Handler,
+		},
+		{
+			MethodName: "Track",
+			Handler:    _Agent_Track_Handler,
+		},
+		{
+			MethodName: "SendOdpEvent",
+			Handler:    _Agent_SendOdpEvent_Handler,
+		},
+		{
+			MethodName: "GetConfig",
+			Handler:    _Agent_GetConfig_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Notifications",
+			Handler:       _Agent_Notifications_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "agent.proto",
+}
@@ -0,0 +1,140 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package grpcapi //
+package grpcapi
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/optimizely/agent/pkg/handlers"
+	"github.com/optimizely/agent/pkg/middleware"
+	"github.com/optimizely/agent/pkg/optimizely"
+)
+
+// Metadata keys of the calls, gRPC metadata keys are the lowercase http header names
+var (
+	sdkKeyMetadata    = strings.ToLower(middleware.OptlySDKHeader)
+	upsMetadata       = strings.ToLower(middleware.OptlyUPSHeader)
+	odpCacheMetadata  = strings.ToLower(middleware.OptlyODPCacheHeader)
+	requestIDMetadata = strings.ToLower(middleware.OptlyRequestHeader)
+)
+
+func (s *Service) unaryInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.clientContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Service) streamInterceptor(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.clientContext(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+// clientContext checks that the metadata holds an SDK key and authorizes the call for it, then adds the OptlyClient of the SDK key,
+// the SDK key and the logger of the call to its context. The SDK key is resolved as CachedOptlyMiddleware does.
+func (s *Service) clientContext(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	sdkKey := firstValue(md, sdkKeyMetadata)
+	// A missing SDK key is reported before the authorization, as the REST API does
+	if sdkKey == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "missing required %s metadata", sdkKeyMetadata)
+	}
+	logger := newLogger(md, sdkKey)
+
+	token := middleware.ExtractToken(http.Header{
+		"Auth":          md.Get("auth"),
+		"Jwt":           md.Get("jwt"),
+		"Authorization": md.Get("authorization"),
+	})
+	if err := s.auth.AuthorizeAPIToken(token, sdkKey, logger); err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "unauthorized: %v", err)
+	}
+
+	optlyClient, err := s.clients.GetClient(sdkKey, firstValue(md, upsMetadata), firstValue(md, odpCacheMetadata))
+	if err != nil {
+		logger.Error().Err(err).Msg("Initializing OptimizelyClient")
+		switch middleware.ClientErrorStatus(err) {
+		case http.StatusForbidden:
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		case http.StatusBadRequest:
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to instantiate Optimizely for SDK Key: %s", sdkKey)
+		}
+	}
+
+	ctx = context.WithValue(ctx, middleware.OptlyClientKey, optlyClient)
+	ctx = context.WithValue(ctx, handlers.SDKKey, sdkKey)
+	return context.WithValue(ctx, handlers.LoggerKey, logger), nil
+}
+
+// newLogger returns the logger of a call, as middleware.GetLogger does for http requests
+func newLogger(md metadata.MD, sdkKey string) *zerolog.Logger {
+	logger := log.With().Str("requestId", firstValue(md, requestIDMetadata)).Logger()
+	if optimizely.ShouldIncludeSDKKey {
+		logger = logger.With().Str("sdkKey", strings.Split(sdkKey, ":")[0]).Logger()
+	}
+	return &logger
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func getOptlyClient(ctx context.Context) (*optimizely.OptlyClient, error) {
+	optlyClient, ok := ctx.Value(middleware.OptlyClientKey).(*optimizely.OptlyClient)
+	if !ok || optlyClient == nil {
+		return nil, status.Error(codes.Internal, "optlyClient not available")
+	}
+	return optlyClient, nil
+}
+
+func getLogger(ctx context.Context) *zerolog.Logger {
+	logger, ok := ctx.Value(handlers.LoggerKey).(*zerolog.Logger)
+	if !ok {
+		logger = &zerolog.Logger{}
+	}
+	return logger
+}
+
+// serverStream overrides the context of a grpc.ServerStream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context of the stream
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,312 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package grpcapi serves the API over gRPC, with the services defined in api/grpc/agent.proto
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/pkg/grpcapi/agentpb"
+	"github.com/optimizely/agent/pkg/handlers"
+	"github.com/optimizely/agent/pkg/middleware"
+	"github.com/optimizely/agent/pkg/optimizely"
+
+	"github.com/optimizely/go-sdk/pkg/client"
+	"github.com/optimizely/go-sdk/pkg/decide"
+	"github.com/optimizely/go-sdk/pkg/entities"
+	"github.com/optimizely/go-sdk/pkg/odp/segment"
+)
+
+// Service implements the Agent gRPC service
+type Service struct {
+	agentpb.UnimplementedAgentServer
+
+	conf                 config.GRPCConfig
+	clients              *middleware.CachedOptlyMiddleware
+	auth                 *middleware.Auth
+	notificationReceiver handlers.NotificationReceiverFunc
+}
+
+// NewService returns a Service serving the clients of the cache, authorizing the calls with auth.
+// The Notifications call is rejected when notificationReceiver is nil.
+func NewService(optlyCache optimizely.Cache, auth *middleware.Auth, notificationReceiver handlers.NotificationReceiverFunc, conf config.GRPCConfig) *Service {
+	return &Service{
+		conf:                 conf,
+		clients:              &middleware.CachedOptlyMiddleware{Cache: optlyCache},
+		auth:                 auth,
+		notificationReceiver: notificationReceiver,
+	}
+}
+
+// NewDefaultService returns a Service with the auth and notifications settings of the API
+func NewDefaultService(optlyCache optimizely.Cache, conf config.AgentConfig) (*Service, error) {
+	auth := middleware.NewAuth(&conf.API.Auth)
+	if auth == nil {
+		return nil, errors.New("unable to initialize grpc auth")
+	}
+
+	var notificationReceiver handlers.NotificationReceiverFunc
+	if conf.API.EnableNotifications {
+		notificationReceiver = handlers.DefaultNotificationReceiver
+		if conf.Synchronization.Notification.Enable {
+			notificationReceiver = handlers.RedisNotificationReceiver(conf.Synchronization)
+		}
+	}
+
+	return NewService(optlyCache, auth, notificationReceiver, conf.API.GRPC), nil
+}
+
+// Register registers the Agent service
+func (s *Service) Register(registrar grpc.ServiceRegistrar) {
+	agentpb.RegisterAgentServer(registrar, s)
+}
+
+// ServerOptions returns the interceptors authorizing the calls and resolving their SDK key,
+// along with the limits of the configuration
+func (s *Service) ServerOptions() []grpc.ServerOption {
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamInterceptor),
+	}
+	if s.conf.MaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(s.conf.MaxConcurrentStreams))
+	}
+	return opts
+}
+
+// Decide makes the decisions of the flags of the request, as the decide endpoint does
+func (s *Service) Decide(ctx context.Context, req *agentpb.DecideRequest) (*agentpb.DecideResponse, error) {
+	optlyClient, err := getOptlyClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	logger := getLogger(ctx)
+
+	if req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, handlers.ErrEmptyUserID.Error())
+	}
+
+	decideOptions, err := decide.TranslateOptions(req.DecideOptions)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	attributes := req.UserAttributes.AsMap()
+	attributeWarnings, rejected := handlers.ValidateAttributes(optlyClient, attributes, logger)
+	if rejected {
+		messages := make([]string, 0, len(attributeWarnings))
+		for _, warning := range attributeWarnings {
+			messages = append(messages, warning.Message)
+		}
+		return nil, status.Errorf(codes.InvalidArgument, "invalid user attributes: %s", strings.Join(messages, "; "))
+	}
+
+	userContext := optlyClient.CreateUserContext(req.UserId, handlers.MergeUserAttributes(optlyClient, req.UserId, attributes, logger))
+
+	if req.FetchSegments {
+		segmentOptions := make([]segment.OptimizelySegmentOption, 0, len(req.FetchSegmentsOptions))
+		for _, option := range req.FetchSegmentsOptions {
+			segmentOptions = append(segmentOptions, segment.OptimizelySegmentOption(option))
+		}
+		if success := userContext.FetchQualifiedSegments(segmentOptions); !success {
+			return nil, status.Error(codes.Internal, "failed to fetch qualified segments")
+		}
+	}
+
+	forcedDecisions := make([]handlers.ForcedDecision, 0, len(req.ForcedDecisions))
+	for _, fd := range req.ForcedDecisions {
+		forcedDecisions = append(forcedDecisions, handlers.ForcedDecision{FlagKey: fd.FlagKey, RuleKey: fd.RuleKey, VariationKey: fd.VariationKey})
+	}
+	handlers.SetForcedDecisions(optlyClient, &userContext, forcedDecisions, logger)
+
+	var decisions []client.OptimizelyDecision
+	switch len(req.Keys) {
+	case 0:
+		for _, d := range userContext.DecideAll(decideOptions) {
+			decisions = append(decisions, d)
+		}
+	case 1:
+		logger.Debug().Str("featureKey", req.Keys[0]).Msg("fetching feature decision")
+		decisions = append(decisions, userContext.Decide(req.Keys[0], decideOptions))
+	default:
+		for _, d := range userContext.DecideForKeys(req.Keys, decideOptions) {
+			decisions = append(decisions, d)
+		}
+	}
+	sort.Slice(decisions, func(i, j int) bool { return decisions[i].FlagKey < decisions[j].FlagKey })
+
+	resp := &agentpb.DecideResponse{Decisions: make([]*agentpb.Decision, 0, len(decisions))}
+	for _, d := range decisions {
+		variables, err := newStruct(d.Variables.ToMap())
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		resp.Decisions = append(resp.Decisions, &agentpb.Decision{
+			FlagKey:      d.FlagKey,
+			VariationKey: d.VariationKey,
+			RuleKey:      d.RuleKey,
+			Enabled:      d.Enabled,
+			Variables:    variables,
+			Reasons:      d.Reasons,
+		})
+	}
+	for _, warning := range attributeWarnings {
+		resp.AttributeWarnings = append(resp.AttributeWarnings, &agentpb.AttributeWarning{
+			Attribute:     warning.Attribute,
+			Code:          warning.Code,
+			Message:       warning.Message,
+			ExpectedTypes: warning.ExpectedTypes,
+			ActualType:    warning.ActualType,
+		})
+	}
+	return resp, nil
+}
+
+// Track tracks the event of the request, as the track endpoint does
+func (s *Service) Track(ctx context.Context, req *agentpb.TrackRequest) (*agentpb.TrackResponse, error) {
+	optlyClient, err := getOptlyClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.EventKey == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing required event_key")
+	}
+
+	uc := entities.UserContext{
+		ID:         req.UserId,
+		Attributes: req.UserAttributes.AsMap(),
+	}
+
+	track, err := optlyClient.TrackEvent(ctx, req.EventKey, uc, req.EventTags.AsMap())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	getLogger(ctx).Debug().Str("eventKey", req.EventKey).Msg("tracking event")
+	return &agentpb.TrackResponse{UserId: track.UserID, EventKey: track.EventKey, Error: track.Error}, nil
+}
+
+// SendOdpEvent sends the event of the request to the ODP platform, as the send-odp-event endpoint does
+func (s *Service) SendOdpEvent(ctx context.Context, req *agentpb.SendOdpEventRequest) (*agentpb.SendOdpEventResponse, error) {
+	optlyClient, err := getOptlyClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Action == "" {
+		return nil, status.Error(codes.InvalidArgument, `missing "action" in request`)
+	}
+
+	if len(req.Identifiers) == 0 {
+		return nil, status.Error(codes.InvalidArgument, `missing or empty "identifiers" in request`)
+	}
+
+	if err := optlyClient.SendOdpEvent(req.Type, req.Action, req.Identifiers, req.Data.AsMap()); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &agentpb.SendOdpEventResponse{Success: true}, nil
+}
+
+// GetConfig returns the OptimizelyConfig, as the config endpoint does
+func (s *Service) GetConfig(ctx context.Context, _ *agentpb.GetConfigRequest) (*agentpb.GetConfigResponse, error) {
+	optlyClient, err := getOptlyClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conf, err := newStruct(optlyClient.GetOptimizelyConfig())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &agentpb.GetConfigResponse{Config: conf, Stale: optlyClient.IsStale()}, nil
+}
+
+// Notifications streams the notifications of the types of the filter, as the notifications event stream does
+func (s *Service) Notifications(req *agentpb.NotificationsRequest, stream agentpb.Agent_NotificationsServer) error {
+	if s.notificationReceiver == nil {
+		return status.Error(codes.PermissionDenied, "Notification stream not enabled")
+	}
+
+	ctx := stream.Context()
+	logger := getLogger(ctx)
+	notificationsToAdd := handlers.NotificationFilter(req.Filter)
+
+	dataChan, err := s.notificationReceiver(ctx)
+	if err != nil {
+		logger.Err(err).Msg("error from receiver")
+		return status.Error(codes.Internal, "Error from data receiver!")
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Debug().Msg("notification stream is done, closing it")
+			return nil
+		case event := <-dataChan:
+			if _, found := notificationsToAdd[event.Type]; !found {
+				continue
+			}
+
+			message, err := newValue(event.Message)
+			if err != nil {
+				logger.Err(err).Msg("failed to convert notification")
+				continue
+			}
+
+			if err := stream.Send(&agentpb.Notification{Type: string(event.Type), Message: message}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// newStruct converts v to a Struct through its JSON encoding, so that fields are named as in the REST API
+func newStruct(v interface{}) (*structpb.Struct, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	out := &structpb.Struct{}
+	if string(b) == "null" {
+		return out, nil
+	}
+	return out, protojson.Unmarshal(b, out)
+}
+
+// newValue converts v to a Value through its JSON encoding
+func newValue(v interface{}) (*structpb.Value, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	out := &structpb.Value{}
+	return out, protojson.Unmarshal(b, out)
+}
@@ -0,0 +1,403 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package grpcapi //
+package grpcapi
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/pkg/grpcapi/agentpb"
+	"github.com/optimizely/agent/pkg/handlers"
+	"github.com/optimizely/agent/pkg/middleware"
+	"github.com/optimizely/agent/pkg/optimizely"
+	"github.com/optimizely/agent/pkg/optimizely/optimizelytest"
+	"github.com/optimizely/agent/pkg/syncer"
+
+	sdkconfig "github.com/optimizely/go-sdk/pkg/config"
+	"github.com/optimizely/go-sdk/pkg/entities"
+	"github.com/optimizely/go-sdk/pkg/notification"
+)
+
+const hmacSecret = "R8W3PRpnjp6/WmhyeCBZdscrQbMpqf8WIDxx910SlJk="
+
+type MockCache struct {
+	clients map[string]*optimizely.OptlyClient
+}
+
+func (m *MockCache) GetClient(sdkKey string) (*optimizely.OptlyClient, error) {
+	if optlyClient, ok := m.clients[sdkKey]; ok {
+		return optlyClient, nil
+	}
+	if sdkKey == "403" {
+		return nil, errors.New("403 forbidden")
+	}
+	return nil, errors.New("unknown sdk key")
+}
+
+func (m *MockCache) UpdateConfigs(_ string) {}
+
+func (m *MockCache) SetUserProfileService(_, _ string) {}
+
+func (m *MockCache) SetODPCache(_, _ string) {}
+
+type MockConfigManager struct {
+	config sdkconfig.ProjectConfig
+}
+
+func (m MockConfigManager) RemoveOnProjectConfigUpdate(int) error {
+	panic("implement me")
+}
+
+func (m MockConfigManager) OnProjectConfigUpdate(func(notification.ProjectConfigUpdateNotification)) (int, error) {
+	return 0, fmt.Errorf("method OnProjectConfigUpdate does not have any effect on MockConfigManager")
+}
+
+func (m MockConfigManager) GetConfig() (sdkconfig.ProjectConfig, error) {
+	return m.config, nil
+}
+
+func (m MockConfigManager) GetOptimizelyConfig() *sdkconfig.OptimizelyConfig {
+	panic("implement me")
+}
+
+func (m MockConfigManager) SyncConfig() {
+	panic("implement me")
+}
+
+type ServiceTestSuite struct {
+	suite.Suite
+	oc     *optimizely.OptlyClient
+	tc     *optimizelytest.TestClient
+	events chan syncer.Event
+	srv    *grpc.Server
+	conn   *grpc.ClientConn
+	client agentpb.AgentClient
+}
+
+func (suite *ServiceTestSuite) SetupTest() {
+	suite.setup(&middleware.Auth{Verifier: middleware.NoAuth{}}, func(ctx context.Context) (<-chan syncer.Event, error) {
+		if ctx.Value(handlers.SDKKey) != "SDK_KEY" {
+			return nil, errors.New("sdk key not found")
+		}
+		return suite.events, nil
+	})
+}
+
+func (suite *ServiceTestSuite) setup(auth *middleware.Auth, receiver handlers.NotificationReceiverFunc) {
+	testClient := optimizelytest.NewClient()
+	suite.tc = testClient
+	suite.oc = &optimizely.OptlyClient{
+		OptimizelyClient: testClient.OptimizelyClient,
+		ConfigManager:    MockConfigManager{config: testClient.ProjectConfig},
+		ForcedVariations: testClient.ForcedVariations,
+	}
+	suite.events = make(chan syncer.Event)
+
+	cache := &MockCache{clients: map[string]*optimizely.OptlyClient{"SDK_KEY": suite.oc}}
+	service := NewService(cache, auth, receiver, config.GRPCConfig{MaxConcurrentStreams: 10})
+
+	lis := bufconn.Listen(1024 * 1024)
+	suite.srv = grpc.NewServer(service.ServerOptions()...)
+	service.Register(suite.srv)
+	go func() {
+		_ = suite.srv.Serve(lis)
+	}()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	suite.Require().NoError(err)
+	suite.conn = conn
+	suite.client = agentpb.NewAgentClient(conn)
+}
+
+func (suite *ServiceTestSuite) TearDownTest() {
+	suite.conn.Close()
+	suite.srv.Stop()
+}
+
+func (suite *ServiceTestSuite) context(pairs ...string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), append([]string{"x-optimizely-sdk-key", "SDK_KEY"}, pairs...)...)
+}
+
+func (suite *ServiceTestSuite) assertCode(err error, code codes.Code) {
+	suite.Error(err)
+	suite.Equal(code, status.Code(err), err)
+}
+
+func (suite *ServiceTestSuite) TestDecide() {
+	suite.tc.AddFeatureTest(entities.Feature{Key: "one"})
+	suite.tc.AddFeatureRollout(entities.Feature{Key: "two"})
+
+	attributes, err := structpb.NewStruct(map[string]interface{}{"plan": "pro"})
+	suite.NoError(err)
+
+	resp, err := suite.client.Decide(suite.context(), &agentpb.DecideRequest{UserId: "testUser", UserAttributes: attributes, Keys: []string{"one"}})
+	suite.NoError(err)
+	if suite.Len(resp.Decisions, 1) {
+		suite.Equal("one", resp.Decisions[0].FlagKey)
+		suite.NotEmpty(resp.Decisions[0].VariationKey)
+		suite.True(resp.Decisions[0].Enabled)
+	}
+	suite.Empty(resp.AttributeWarnings)
+	suite.Equal(1, len(suite.tc.GetProcessedEvents()))
+
+	resp, err = suite.client.Decide(suite.context(), &agentpb.DecideRequest{UserId: "testUser", DecideOptions: []string{"DISABLE_DECISION_EVENT"}})
+	suite.NoError(err)
+	if suite.Len(resp.Decisions, 2) {
+		suite.Equal("one", resp.Decisions[0].FlagKey)
+		suite.Equal("two", resp.Decisions[1].FlagKey)
+	}
+	suite.Equal(1, len(suite.tc.GetProcessedEvents()))
+}
+
+func (suite *ServiceTestSuite) TestDecideWithVariables() {
+	variable := entities.Variable{DefaultValue: "default", ID: "123", Key: "strvar", Type: "string"}
+	feature := entities.Feature{Key: "one", VariableMap: map[string]entities.Variable{"strvar": variable}}
+	suite.tc.AddFeatureTestWithCustomVariableValue(feature, variable, "custom")
+
+	resp, err := suite.client.Decide(suite.context(), &agentpb.DecideRequest{UserId: "testUser", Keys: []string{"one"}})
+	suite.NoError(err)
+	if suite.Len(resp.Decisions, 1) {
+		suite.Equal(map[string]interface{}{"strvar": "custom"}, resp.Decisions[0].Variables.AsMap())
+	}
+}
+
+func (suite *ServiceTestSuite) TestDecideForcedDecisions() {
+	feature := entities.Feature{Key: "one"}
+	suite.tc.AddFeatureTest(feature)
+	suite.tc.AddFlagVariation(feature, entities.Variation{Key: "forced", FeatureEnabled: true})
+
+	resp, err := suite.client.Decide(suite.context(), &agentpb.DecideRequest{
+		UserId:          "testUser",
+		Keys:            []string{"one"},
+		ForcedDecisions: []*agentpb.ForcedDecision{{FlagKey: "one", VariationKey: "forced"}},
+	})
+	suite.NoError(err)
+	if suite.Len(resp.Decisions, 1) {
+		suite.Equal("forced", resp.Decisions[0].VariationKey)
+	}
+}
+
+func (suite *ServiceTestSuite) TestDecideAttributeValidation() {
+	suite.tc.AddFeatureTest(entities.Feature{Key: "one"})
+	attributes, err := structpb.NewStruct(map[string]interface{}{"user_tier": "gold"})
+	suite.NoError(err)
+	req := &agentpb.DecideRequest{UserId: "testUser", UserAttributes: attributes, Keys: []string{"one"}}
+
+	suite.oc.AttributeValidationMode = config.AttributeValidationWarn
+	resp, err := suite.client.Decide(suite.context(), req)
+	suite.NoError(err)
+	if suite.Len(resp.AttributeWarnings, 1) {
+		suite.Equal("user_tier", resp.AttributeWarnings[0].Attribute)
+		suite.Equal(optimizely.AttributeWarningUnknown, resp.AttributeWarnings[0].Code)
+	}
+
+	suite.oc.AttributeValidationMode = config.AttributeValidationReject
+	_, err = suite.client.Decide(suite.context(), req)
+	suite.assertCode(err, codes.InvalidArgument)
+	suite.Contains(err.Error(), `invalid user attributes: attribute "user_tier" is not defined in the datafile`)
+}
+
+func (suite *ServiceTestSuite) TestDecideInvalidRequest() {
+	_, err := suite.client.Decide(suite.context(), &agentpb.DecideRequest{})
+	suite.assertCode(err, codes.InvalidArgument)
+
+	_, err = suite.client.Decide(suite.context(), &agentpb.DecideRequest{UserId: "testUser", DecideOptions: []string{"INVALID"}})
+	suite.assertCode(err, codes.InvalidArgument)
+}
+
+func (suite *ServiceTestSuite) TestTrack() {
+	suite.tc.AddEvent(entities.Event{Key: "purchase"})
+	tags, err := structpb.NewStruct(map[string]interface{}{"revenue": 100})
+	suite.NoError(err)
+
+	resp, err := suite.client.Track(suite.context(), &agentpb.TrackRequest{EventKey: "purchase", UserId: "testUser", EventTags: tags})
+	suite.NoError(err)
+	suite.Equal("testUser", resp.UserId)
+	suite.Equal("purchase", resp.EventKey)
+	suite.Empty(resp.Error)
+
+	events := suite.tc.GetProcessedEvents()
+	if suite.Len(events, 1) {
+		suite.Equal("purchase", events[0].Conversion.Key)
+		suite.Equal(float64(100), events[0].Conversion.Tags["revenue"])
+	}
+
+	resp, err = suite.client.Track(suite.context(), &agentpb.TrackRequest{EventKey: "missing", UserId: "testUser"})
+	suite.NoError(err)
+	suite.NotEmpty(resp.Error)
+
+	_, err = suite.client.Track(suite.context(), &agentpb.TrackRequest{UserId: "testUser"})
+	suite.assertCode(err, codes.InvalidArgument)
+}
+
+func (suite *ServiceTestSuite) TestSendOdpEvent() {
+	suite.tc.EventAPIManager.SetExpectedNumberEvents(1)
+
+	resp, err := suite.client.SendOdpEvent(suite.context(), &agentpb.SendOdpEventRequest{
+		Action:      "1",
+		Type:        "2",
+		Identifiers: map[string]string{"fs-user-id": "test-user"},
+	})
+	suite.NoError(err)
+	suite.True(resp.Success)
+
+	events := suite.tc.EventAPIManager.GetEvents()
+	if suite.Len(events, 1) {
+		suite.Equal("1", events[0].Action)
+		suite.Equal(map[string]string{"fs_user_id": "test-user"}, events[0].Identifiers)
+	}
+
+	_, err = suite.client.SendOdpEvent(suite.context(), &agentpb.SendOdpEventRequest{Identifiers: map[string]string{"fs-user-id": "test-user"}})
+	suite.assertCode(err, codes.InvalidArgument)
+
+	_, err = suite.client.SendOdpEvent(suite.context(), &agentpb.SendOdpEventRequest{Action: "1"})
+	suite.assertCode(err, codes.InvalidArgument)
+}
+
+func (suite *ServiceTestSuite) TestGetConfig() {
+	suite.tc.AddFeatureTest(entities.Feature{Key: "one"})
+
+	resp, err := suite.client.GetConfig(suite.context(), &agentpb.GetConfigRequest{})
+	suite.NoError(err)
+	suite.False(resp.Stale)
+	suite.Contains(resp.Config.AsMap(), "featuresMap")
+	suite.Contains(resp.Config.AsMap()["featuresMap"], "one")
+}
+
+func (suite *ServiceTestSuite) TestNotifications() {
+	ctx, cancel := context.WithCancel(suite.context())
+	defer cancel()
+
+	stream, err := suite.client.Notifications(ctx, &agentpb.NotificationsRequest{Filter: []string{"track"}})
+	suite.Require().NoError(err)
+
+	go func() {
+		suite.events <- syncer.Event{Type: notification.Decision, Message: map[string]interface{}{"type": "flag"}}
+		suite.events <- syncer.Event{Type: notification.Track, Message: map[string]interface{}{"eventKey": "purchase"}}
+	}()
+
+	n, err := stream.Recv()
+	suite.NoError(err)
+	suite.Equal("track", n.Type)
+	suite.Equal(map[string]interface{}{"eventKey": "purchase"}, n.Message.AsInterface())
+}
+
+func (suite *ServiceTestSuite) TestNotificationsNotEnabled() {
+	suite.TearDownTest()
+	suite.setup(&middleware.Auth{Verifier: middleware.NoAuth{}}, nil)
+
+	stream, err := suite.client.Notifications(suite.context(), &agentpb.NotificationsRequest{})
+	suite.NoError(err)
+	_, err = stream.Recv()
+	suite.assertCode(err, codes.PermissionDenied)
+}
+
+func (suite *ServiceTestSuite) TestClientErrors() {
+	_, err := suite.client.GetConfig(context.Background(), &agentpb.GetConfigRequest{})
+	suite.assertCode(err, codes.InvalidArgument)
+	suite.Contains(err.Error(), "missing required x-optimizely-sdk-key metadata")
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-optimizely-sdk-key", "403")
+	_, err = suite.client.GetConfig(ctx, &agentpb.GetConfigRequest{})
+	suite.assertCode(err, codes.PermissionDenied)
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "x-optimizely-sdk-key", "UNKNOWN")
+	_, err = suite.client.GetConfig(ctx, &agentpb.GetConfigRequest{})
+	suite.assertCode(err, codes.Internal)
+	suite.Contains(err.Error(), "failed to instantiate Optimizely for SDK Key: UNKNOWN")
+
+	stream, err := suite.client.Notifications(ctx, &agentpb.NotificationsRequest{})
+	suite.NoError(err)
+	_, err = stream.Recv()
+	suite.assertCode(err, codes.Internal)
+}
+
+func (suite *ServiceTestSuite) TestAuth() {
+	secret, err := base64.StdEncoding.DecodeString(hmacSecret)
+	suite.NoError(err)
+	suite.TearDownTest()
+	suite.setup(&middleware.Auth{Verifier: middleware.NewJWTVerifier([][]byte{secret})}, nil)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp":      time.Now().Add(time.Hour).Unix(),
+		"sdk_keys": []string{"SDK_KEY"},
+	})
+	signed, err := token.SignedString(secret)
+	suite.NoError(err)
+
+	_, err = suite.client.GetConfig(suite.context(), &agentpb.GetConfigRequest{})
+	suite.assertCode(err, codes.Unauthenticated)
+
+	// The missing SDK key is reported first
+	_, err = suite.client.GetConfig(context.Background(), &agentpb.GetConfigRequest{})
+	suite.assertCode(err, codes.InvalidArgument)
+	suite.Contains(err.Error(), "missing required x-optimizely-sdk-key metadata")
+
+	_, err = suite.client.GetConfig(suite.context("authorization", "Bearer invalid"), &agentpb.GetConfigRequest{})
+	suite.assertCode(err, codes.Unauthenticated)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-optimizely-sdk-key", "OTHER_SDK_KEY", "authorization", "Bearer "+signed)
+	_, err = suite.client.GetConfig(ctx, &agentpb.GetConfigRequest{})
+	suite.assertCode(err, codes.Unauthenticated)
+
+	_, err = suite.client.GetConfig(suite.context("authorization", "Bearer "+signed), &agentpb.GetConfigRequest{})
+	suite.NoError(err)
+
+	stream, err := suite.client.Notifications(suite.context(), &agentpb.NotificationsRequest{})
+	suite.NoError(err)
+	_, err = stream.Recv()
+	suite.assertCode(err, codes.Unauthenticated)
+}
+
+func TestServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ServiceTestSuite))
+}
+
+func TestNewDefaultService(t *testing.T) {
+	conf := config.NewDefaultConfig()
+	conf.API.EnableNotifications = true
+	service, err := NewDefaultService(&MockCache{}, *conf)
+	if assert.NoError(t, err) {
+		assert.NotNil(t, service.notificationReceiver)
+		assert.Len(t, service.ServerOptions(), 2)
+	}
+
+	conf.API.Auth.HMACSecrets = []string{"invalid"}
+	_, err = NewDefaultService(&MockCache{}, *conf)
+	assert.Error(t, err)
+}
@@ -53,6 +53,7 @@ func Activate(w http.ResponseWriter, r *http.Request) {
 		RenderError(err, http.StatusBadRequest, w, r)
 		return
 	}
+	uc.Attributes = MergeUserAttributes(optlyClient, uc.ID, uc.Attributes, logger)
 
 	query := r.URL.Query()
 	oConf := optlyClient.GetOptimizelyConfig()
@@ -0,0 +1,77 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"github.com/optimizely/agent/pkg/optimizely"
+)
+
+// ClientsAdmin exposes the clients loaded in the cache to operators
+type ClientsAdmin struct {
+	manager optimizely.ClientManager
+}
+
+// NewClientsAdmin returns a new instance of ClientsAdmin
+func NewClientsAdmin(manager optimizely.ClientManager) *ClientsAdmin {
+	return &ClientsAdmin{manager: manager}
+}
+
+// ListClients returns every loaded client
+func (a *ClientsAdmin) ListClients(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, a.manager.ListClients())
+}
+
+// GetClient returns the clients loaded for the SDK key in the url
+func (a *ClientsAdmin) GetClient(w http.ResponseWriter, r *http.Request) {
+	sdkKey := chi.URLParam(r, "sdkKey")
+	clients := a.manager.GetClientInfo(sdkKey)
+	if len(clients) == 0 {
+		RenderError(fmt.Errorf("no client loaded for sdkKey"), http.StatusNotFound, w, r)
+		return
+	}
+
+	render.JSON(w, r, clients)
+}
+
+// ResyncClient forces the clients loaded for the SDK key in the url to fetch the latest datafile
+func (a *ClientsAdmin) ResyncClient(w http.ResponseWriter, r *http.Request) {
+	sdkKey := chi.URLParam(r, "sdkKey")
+	if !a.manager.ResyncClient(sdkKey) {
+		RenderError(fmt.Errorf("no client loaded for sdkKey"), http.StatusNotFound, w, r)
+		return
+	}
+
+	render.JSON(w, r, a.manager.GetClientInfo(sdkKey))
+}
+
+// EvictClient removes the clients loaded for the SDK key in the url from the cache
+func (a *ClientsAdmin) EvictClient(w http.ResponseWriter, r *http.Request) {
+	sdkKey := chi.URLParam(r, "sdkKey")
+	if !a.manager.EvictClient(sdkKey) {
+		RenderError(fmt.Errorf("no client loaded for sdkKey"), http.StatusNotFound, w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,142 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/optimizely/agent/pkg/optimizely"
+)
+
+type MockClientManager struct {
+	clients  map[string]optimizely.ClientInfo
+	resynced []string
+}
+
+func (m *MockClientManager) ListClients() []optimizely.ClientInfo {
+	clients := []optimizely.ClientInfo{}
+	for _, info := range m.clients {
+		clients = append(clients, info)
+	}
+	return clients
+}
+
+func (m *MockClientManager) GetClientInfo(sdkKey string) []optimizely.ClientInfo {
+	if info, ok := m.clients[sdkKey]; ok {
+		return []optimizely.ClientInfo{info}
+	}
+	return []optimizely.ClientInfo{}
+}
+
+func (m *MockClientManager) ResyncClient(sdkKey string) bool {
+	if _, ok := m.clients[sdkKey]; ok {
+		m.resynced = append(m.resynced, sdkKey)
+		return true
+	}
+	return false
+}
+
+func (m *MockClientManager) EvictClient(sdkKey string) bool {
+	if _, ok := m.clients[sdkKey]; ok {
+		delete(m.clients, sdkKey)
+		return true
+	}
+	return false
+}
+
+type ClientsAdminTestSuite struct {
+	suite.Suite
+	manager *MockClientManager
+	mux     *chi.Mux
+}
+
+func (suite *ClientsAdminTestSuite) SetupTest() {
+	suite.manager = &MockClientManager{clients: map[string]optimizely.ClientInfo{
+		"one": {SDKKey: "one", Revision: "1", EventQueueSize: 2, UserProfileService: "in-memory"},
+	}}
+
+	a := NewClientsAdmin(suite.manager)
+	mux := chi.NewMux()
+	mux.Get("/clients", a.ListClients)
+	mux.Get("/clients/{sdkKey}", a.GetClient)
+	mux.Post("/clients/{sdkKey}/resync", a.ResyncClient)
+	mux.Delete("/clients/{sdkKey}", a.EvictClient)
+	suite.mux = mux
+}
+
+func (suite *ClientsAdminTestSuite) TestListClients() {
+	req := httptest.NewRequest("GET", "/clients", nil)
+	rec := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual []optimizely.ClientInfo
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal([]optimizely.ClientInfo{suite.manager.clients["one"]}, actual)
+}
+
+func (suite *ClientsAdminTestSuite) TestGetClient() {
+	req := httptest.NewRequest("GET", "/clients/one", nil)
+	rec := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual []optimizely.ClientInfo
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal("1", actual[0].Revision)
+
+	req = httptest.NewRequest("GET", "/clients/two", nil)
+	rec = httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	suite.Equal(http.StatusNotFound, rec.Code)
+}
+
+func (suite *ClientsAdminTestSuite) TestResyncClient() {
+	req := httptest.NewRequest("POST", "/clients/one/resync", nil)
+	rec := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	suite.Equal(http.StatusOK, rec.Code)
+	suite.Equal([]string{"one"}, suite.manager.resynced)
+
+	req = httptest.NewRequest("POST", "/clients/two/resync", nil)
+	rec = httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	suite.Equal(http.StatusNotFound, rec.Code)
+}
+
+func (suite *ClientsAdminTestSuite) TestEvictClient() {
+	req := httptest.NewRequest("DELETE", "/clients/one", nil)
+	rec := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	suite.Equal(http.StatusNoContent, rec.Code)
+	suite.Empty(suite.manager.clients)
+
+	rec = httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	suite.Equal(http.StatusNotFound, rec.Code)
+}
+
+func TestClientsAdminTestSuite(t *testing.T) {
+	suite.Run(t, new(ClientsAdminTestSuite))
+}
@@ -0,0 +1,161 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"github.com/optimizely/agent/pkg/deadletter"
+	"github.com/optimizely/go-sdk/pkg/event"
+)
+
+// ReplayResult reports the outcome of replaying every dead letter
+type ReplayResult struct {
+	Replayed int `json:"replayed"`
+	Failed   int `json:"failed"`
+}
+
+// PurgeResult reports the number of purged dead letters
+type PurgeResult struct {
+	Purged int `json:"purged"`
+}
+
+// DeadLettersAdmin exposes the event batches which could not be dispatched to operators
+type DeadLettersAdmin struct {
+	store      deadletter.Store
+	dispatcher event.Dispatcher
+}
+
+// NewDeadLettersAdmin returns a new instance of DeadLettersAdmin replaying the letters with the given dispatcher
+func NewDeadLettersAdmin(store deadletter.Store, dispatcher event.Dispatcher) *DeadLettersAdmin {
+	return &DeadLettersAdmin{store: store, dispatcher: dispatcher}
+}
+
+// ListDeadLetters returns every dead letter without its event batch
+func (a *DeadLettersAdmin) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	letters, err := a.store.List(r.Context())
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	render.JSON(w, r, letters)
+}
+
+// GetDeadLetter returns the dead letter with the id in the url along with its event batch
+func (a *DeadLettersAdmin) GetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	letter, err := a.store.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		renderStoreError(err, w, r)
+		return
+	}
+
+	render.JSON(w, r, letter)
+}
+
+// ReplayDeadLetter dispatches the dead letter with the id in the url again, removing it once it is dispatched
+func (a *DeadLettersAdmin) ReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	letter, err := a.store.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		renderStoreError(err, w, r)
+		return
+	}
+
+	if err := a.replay(r, letter); err != nil {
+		RenderError(err, http.StatusBadGateway, w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReplayDeadLetters dispatches every dead letter again, removing the ones which are dispatched
+func (a *DeadLettersAdmin) ReplayDeadLetters(w http.ResponseWriter, r *http.Request) {
+	letters, err := a.store.List(r.Context())
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	result := ReplayResult{}
+	for _, summary := range letters {
+		letter, err := a.store.Get(r.Context(), summary.ID)
+		if err == nil {
+			err = a.replay(r, letter)
+		}
+		if err != nil {
+			result.Failed++
+			continue
+		}
+		result.Replayed++
+	}
+
+	render.JSON(w, r, result)
+}
+
+// DeleteDeadLetter removes the dead letter with the id in the url without dispatching it
+func (a *DeadLettersAdmin) DeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if err := a.store.Remove(r.Context(), chi.URLParam(r, "id")); err != nil {
+		renderStoreError(err, w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PurgeDeadLetters removes every dead letter without dispatching them
+func (a *DeadLettersAdmin) PurgeDeadLetters(w http.ResponseWriter, r *http.Request) {
+	letters, err := a.store.List(r.Context())
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	result := PurgeResult{}
+	for _, letter := range letters {
+		if err := a.store.Remove(r.Context(), letter.ID); err == nil {
+			result.Purged++
+		}
+	}
+
+	render.JSON(w, r, result)
+}
+
+func (a *DeadLettersAdmin) replay(r *http.Request, letter *deadletter.Letter) error {
+	success, err := a.dispatcher.DispatchEvent(letter.LogEvent())
+	if err != nil {
+		return err
+	}
+	if !success {
+		return fmt.Errorf("event batch was not accepted")
+	}
+	return a.store.Remove(r.Context(), letter.ID)
+}
+
+func renderStoreError(err error, w http.ResponseWriter, r *http.Request) {
+	if errors.Is(err, deadletter.ErrNotFound) {
+		RenderError(err, http.StatusNotFound, w, r)
+		return
+	}
+	RenderError(err, http.StatusInternalServerError, w, r)
+}
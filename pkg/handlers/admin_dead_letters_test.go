@@ -0,0 +1,191 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/optimizely/agent/pkg/deadletter"
+	"github.com/optimizely/go-sdk/pkg/event"
+)
+
+type MockReplayDispatcher struct {
+	success    bool
+	dispatched []event.LogEvent
+}
+
+func (m *MockReplayDispatcher) DispatchEvent(logEvent event.LogEvent) (bool, error) {
+	m.dispatched = append(m.dispatched, logEvent)
+	return m.success, nil
+}
+
+type DeadLettersAdminTestSuite struct {
+	suite.Suite
+	dir        string
+	store      deadletter.Store
+	dispatcher *MockReplayDispatcher
+	mux        *chi.Mux
+}
+
+func (suite *DeadLettersAdminTestSuite) SetupTest() {
+	dir, err := os.MkdirTemp("", "dead-letters")
+	suite.NoError(err)
+	suite.dir = dir
+
+	store, err := deadletter.NewDiskStore(dir)
+	suite.NoError(err)
+	suite.store = store
+
+	now := time.Now().UTC()
+	for i, id := range []string{"one", "two"} {
+		suite.NoError(store.Add(context.Background(), &deadletter.Letter{
+			ID:       id,
+			SDKKey:   "sdkKey",
+			EndPoint: "https://logx.optimizely.com/v1/events",
+			Attempts: 5,
+			FailedAt: now.Add(time.Duration(i) * time.Second),
+			Event:    &event.Batch{Revision: id},
+		}))
+	}
+
+	suite.dispatcher = &MockReplayDispatcher{success: true}
+	a := NewDeadLettersAdmin(store, suite.dispatcher)
+	mux := chi.NewMux()
+	mux.Get("/dead-letters", a.ListDeadLetters)
+	mux.Delete("/dead-letters", a.PurgeDeadLetters)
+	mux.Post("/dead-letters/replay", a.ReplayDeadLetters)
+	mux.Get("/dead-letters/{id}", a.GetDeadLetter)
+	mux.Delete("/dead-letters/{id}", a.DeleteDeadLetter)
+	mux.Post("/dead-letters/{id}/replay", a.ReplayDeadLetter)
+	suite.mux = mux
+}
+
+func (suite *DeadLettersAdminTestSuite) TearDownTest() {
+	os.RemoveAll(suite.dir)
+}
+
+func (suite *DeadLettersAdminTestSuite) remaining() []string {
+	letters, err := suite.store.List(context.Background())
+	suite.NoError(err)
+	ids := []string{}
+	for _, letter := range letters {
+		ids = append(ids, letter.ID)
+	}
+	return ids
+}
+
+func (suite *DeadLettersAdminTestSuite) serve(method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	rec := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func (suite *DeadLettersAdminTestSuite) TestListDeadLetters() {
+	rec := suite.serve("GET", "/dead-letters")
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual []deadletter.Letter
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	if suite.Len(actual, 2) {
+		suite.Equal("one", actual[0].ID)
+		suite.Equal("two", actual[1].ID)
+		suite.Nil(actual[0].Event)
+	}
+}
+
+func (suite *DeadLettersAdminTestSuite) TestGetDeadLetter() {
+	rec := suite.serve("GET", "/dead-letters/one")
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual deadletter.Letter
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal(&event.Batch{Revision: "one"}, actual.Event)
+
+	rec = suite.serve("GET", "/dead-letters/three")
+	suite.Equal(http.StatusNotFound, rec.Code)
+}
+
+func (suite *DeadLettersAdminTestSuite) TestReplayDeadLetter() {
+	rec := suite.serve("POST", "/dead-letters/one/replay")
+	suite.Equal(http.StatusNoContent, rec.Code)
+	if suite.Len(suite.dispatcher.dispatched, 1) {
+		suite.Equal("one", suite.dispatcher.dispatched[0].Event.Revision)
+		suite.Equal("https://logx.optimizely.com/v1/events", suite.dispatcher.dispatched[0].EndPoint)
+	}
+	suite.Equal([]string{"two"}, suite.remaining())
+
+	rec = suite.serve("POST", "/dead-letters/one/replay")
+	suite.Equal(http.StatusNotFound, rec.Code)
+}
+
+func (suite *DeadLettersAdminTestSuite) TestReplayDeadLetterFailure() {
+	suite.dispatcher.success = false
+	rec := suite.serve("POST", "/dead-letters/one/replay")
+	suite.Equal(http.StatusBadGateway, rec.Code)
+	suite.Equal([]string{"one", "two"}, suite.remaining())
+}
+
+func (suite *DeadLettersAdminTestSuite) TestReplayDeadLetters() {
+	rec := suite.serve("POST", "/dead-letters/replay")
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual ReplayResult
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal(ReplayResult{Replayed: 2}, actual)
+	suite.Empty(suite.remaining())
+
+	suite.dispatcher.success = false
+	rec = suite.serve("POST", "/dead-letters/replay")
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal(ReplayResult{}, actual)
+}
+
+func (suite *DeadLettersAdminTestSuite) TestDeleteDeadLetter() {
+	rec := suite.serve("DELETE", "/dead-letters/one")
+	suite.Equal(http.StatusNoContent, rec.Code)
+	suite.Equal([]string{"two"}, suite.remaining())
+	suite.Empty(suite.dispatcher.dispatched)
+
+	rec = suite.serve("DELETE", "/dead-letters/one")
+	suite.Equal(http.StatusNotFound, rec.Code)
+}
+
+func (suite *DeadLettersAdminTestSuite) TestPurgeDeadLetters() {
+	rec := suite.serve("DELETE", "/dead-letters")
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual PurgeResult
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal(PurgeResult{Purged: 2}, actual)
+	suite.Empty(suite.remaining())
+	suite.Empty(suite.dispatcher.dispatched)
+}
+
+func TestDeadLettersAdminTestSuite(t *testing.T) {
+	suite.Run(t, new(DeadLettersAdminTestSuite))
+}
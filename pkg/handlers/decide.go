@@ -21,7 +21,9 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/optimizely/agent/config"
 	"github.com/optimizely/agent/pkg/middleware"
+	"github.com/optimizely/agent/pkg/optimizely"
 
 	"github.com/optimizely/go-sdk/pkg/client"
 	"github.com/optimizely/go-sdk/pkg/decide"
@@ -29,6 +31,7 @@ import (
 	"github.com/optimizely/go-sdk/pkg/odp/segment"
 
 	"github.com/go-chi/render"
+	"github.com/rs/zerolog"
 )
 
 // DecideBody defines the request body for decide API
@@ -51,7 +54,14 @@ type ForcedDecision struct {
 // DecideOut defines the response
 type DecideOut struct {
 	client.OptimizelyDecision
-	Variables map[string]interface{} `json:"variables,omitempty"`
+	Variables         map[string]interface{}        `json:"variables,omitempty"`
+	AttributeWarnings []optimizely.AttributeWarning `json:"attributeWarnings,omitempty"`
+}
+
+// AttributeValidationErrorResponse defines the response of requests rejected by strict attribute validation
+type AttributeValidationErrorResponse struct {
+	Error             string                        `json:"error"`
+	AttributeWarnings []optimizely.AttributeWarning `json:"attributeWarnings"`
 }
 
 // Decide makes feature decisions for the selected query parameters
@@ -75,7 +85,14 @@ func Decide(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	optimizelyUserContext := optlyClient.CreateUserContext(db.UserID, db.UserAttributes)
+	attributeWarnings, rejected := ValidateAttributes(optlyClient, db.UserAttributes, logger)
+	if rejected {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, AttributeValidationErrorResponse{Error: "invalid user attributes", AttributeWarnings: attributeWarnings})
+		return
+	}
+
+	optimizelyUserContext := optlyClient.CreateUserContext(db.UserID, MergeUserAttributes(optlyClient, db.UserID, db.UserAttributes, logger))
 
 	if db.FetchSegments {
 		success := optimizelyUserContext.FetchQualifiedSegments(db.FetchSegmentsOptions)
@@ -87,11 +104,7 @@ func Decide(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Setting up forced decisions
-	for _, fd := range db.ForcedDecisions {
-		context := decision.OptimizelyDecisionContext{FlagKey: fd.FlagKey, RuleKey: fd.RuleKey}
-		forcedDecision := decision.OptimizelyForcedDecision{VariationKey: fd.VariationKey}
-		optimizelyUserContext.SetForcedDecision(context, forcedDecision)
-	}
+	SetForcedDecisions(optlyClient, &optimizelyUserContext, db.ForcedDecisions, logger)
 
 	keys := []string{}
 	if err := r.ParseForm(); err == nil {
@@ -108,7 +121,7 @@ func Decide(w http.ResponseWriter, r *http.Request) {
 		key := keys[0]
 		logger.Debug().Str("featureKey", key).Msg("fetching feature decision")
 		d := optimizelyUserContext.Decide(key, decideOptions)
-		decideOut := DecideOut{d, d.Variables.ToMap()}
+		decideOut := DecideOut{d, d.Variables.ToMap(), attributeWarnings}
 		render.JSON(w, r, decideOut)
 		return
 	default:
@@ -118,12 +131,58 @@ func Decide(w http.ResponseWriter, r *http.Request) {
 
 	decideOuts := []DecideOut{}
 	for _, d := range decides {
-		decideOut := DecideOut{d, d.Variables.ToMap()}
+		decideOut := DecideOut{d, d.Variables.ToMap(), attributeWarnings}
 		decideOuts = append(decideOuts, decideOut)
 	}
 	render.JSON(w, r, decideOuts)
 }
 
+// SetForcedDecisions sets the forced decisions saved for the user, then the ones of the request which take precedence.
+// Decisions are made without the saved forced decisions when they can't be looked up.
+func SetForcedDecisions(optlyClient *optimizely.OptlyClient, userContext *client.OptimizelyUserContext, forcedDecisions []ForcedDecision, logger *zerolog.Logger) {
+	if err := optlyClient.ApplyForcedDecisions(userContext); err != nil {
+		logger.Warn().Err(err).Msg("failed to look up saved forced decisions")
+	}
+
+	for _, fd := range forcedDecisions {
+		context := decision.OptimizelyDecisionContext{FlagKey: fd.FlagKey, RuleKey: fd.RuleKey}
+		forcedDecision := decision.OptimizelyForcedDecision{VariationKey: fd.VariationKey}
+		userContext.SetForcedDecision(context, forcedDecision)
+	}
+}
+
+// MergeUserAttributes returns the attributes stored for the user overridden by the ones of the request.
+// Decisions are made with the attributes of the request only when the stored ones can't be looked up.
+func MergeUserAttributes(optlyClient *optimizely.OptlyClient, userID string, attributes map[string]interface{}, logger *zerolog.Logger) map[string]interface{} {
+	merged, err := optlyClient.MergeUserAttributes(userID, attributes)
+	if err != nil {
+		logger.Warn().Err(err).Msg("failed to look up stored user attributes")
+	}
+	return merged
+}
+
+// ValidateAttributes checks the attributes of the request when strict attribute validation is enabled for the SDK key.
+// It returns the warnings to add to the response, and whether the request must be rejected instead.
+// Attributes are not checked when the datafile isn't available.
+func ValidateAttributes(optlyClient *optimizely.OptlyClient, attributes map[string]interface{}, logger *zerolog.Logger) ([]optimizely.AttributeWarning, bool) {
+	mode := optlyClient.AttributeValidationMode
+	if mode == "" || mode == config.AttributeValidationOff {
+		return nil, false
+	}
+
+	warnings, err := optlyClient.ValidateAttributes(attributes)
+	if err != nil {
+		logger.Warn().Err(err).Msg("failed to validate user attributes")
+		return nil, false
+	}
+	if len(warnings) == 0 {
+		return nil, false
+	}
+
+	logger.Debug().Int("warnings", len(warnings)).Msg("user attributes don't match the datafile")
+	return warnings, mode == config.AttributeValidationReject
+}
+
 func getUserContextWithOptions(r *http.Request) (DecideBody, error) {
 	var body DecideBody
 	err := ParseRequestBody(r, &body)
@@ -0,0 +1,201 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/optimizely/go-sdk/pkg/client"
+	"github.com/optimizely/go-sdk/pkg/decide"
+	"github.com/rs/zerolog"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/pkg/middleware"
+	"github.com/optimizely/agent/pkg/optimizely"
+)
+
+// ndjsonContentType is the content type of the streamed bulk decide response
+const ndjsonContentType = "application/x-ndjson"
+
+// ErrEmptyUsers is returned when the bulk decide request has no users
+var ErrEmptyUsers = errors.New(`missing "users" in request payload`)
+
+// BulkDecideUser defines a user context of the bulk decide request
+type BulkDecideUser struct {
+	UserID          string                 `json:"userId"`
+	UserAttributes  map[string]interface{} `json:"userAttributes"`
+	ForcedDecisions []ForcedDecision       `json:"forcedDecisions,omitempty"`
+}
+
+// BulkDecideBody defines the request body for the bulk decide API
+type BulkDecideBody struct {
+	Users         []BulkDecideUser `json:"users"`
+	Keys          []string         `json:"keys"`
+	DecideOptions []string         `json:"decideOptions"`
+}
+
+// BulkDecideOut defines a line of the bulk decide response, holding the decisions of one user of the request.
+// The attribute warnings of a user rejected by strict attribute validation come along with its error.
+type BulkDecideOut struct {
+	Index             int                           `json:"index"`
+	UserID            string                        `json:"userId"`
+	Decisions         []DecideOut                   `json:"decisions,omitempty"`
+	Error             string                        `json:"error,omitempty"`
+	AttributeWarnings []optimizely.AttributeWarning `json:"attributeWarnings,omitempty"`
+}
+
+// BulkDecide returns a handler making feature decisions for every user of the request.
+// Users are evaluated concurrently against the same project config and their decisions are
+// streamed back as newline delimited JSON, in the order they complete.
+func BulkDecide(conf config.BulkDecideConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		optlyClient, err := middleware.GetOptlyClient(r)
+		logger := middleware.GetLogger(r)
+		if err != nil {
+			RenderError(err, http.StatusInternalServerError, w, r)
+			return
+		}
+
+		var body BulkDecideBody
+		if err := ParseRequestBody(r, &body); err != nil {
+			RenderError(err, http.StatusBadRequest, w, r)
+			return
+		}
+
+		if len(body.Users) == 0 {
+			RenderError(ErrEmptyUsers, http.StatusBadRequest, w, r)
+			return
+		}
+
+		if conf.MaxUsers > 0 && len(body.Users) > conf.MaxUsers {
+			err := fmt.Errorf("too many users in request payload: %d, the limit is %d", len(body.Users), conf.MaxUsers)
+			RenderError(err, http.StatusBadRequest, w, r)
+			return
+		}
+
+		decideOptions, err := decide.TranslateOptions(body.DecideOptions)
+		if err != nil {
+			RenderError(err, http.StatusBadRequest, w, r)
+			return
+		}
+
+		snapshot, err := optlyClient.Snapshot()
+		if err != nil {
+			RenderError(err, http.StatusInternalServerError, w, r)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		results := bulkDecide(ctx, optlyClient, snapshot, body, decideOptions, conf.MaxConcurrency, logger)
+
+		w.Header().Set("Content-Type", ndjsonContentType)
+		w.WriteHeader(http.StatusOK)
+		flusher, _ := w.(http.Flusher)
+		encoder := json.NewEncoder(w)
+		for result := range results {
+			if err := encoder.Encode(result); err != nil {
+				logger.Error().Err(err).Msg("stopping bulk decide, failed to write decisions")
+				// Stop the remaining evaluations, the results channel is drained so that no worker blocks
+				cancel()
+				continue
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// bulkDecide evaluates the users of the request, at most maxConcurrency at a time.
+// The returned channel is closed once every user has been evaluated or the context is done.
+func bulkDecide(ctx context.Context, optlyClient *optimizely.OptlyClient, snapshot *client.OptimizelyClient, body BulkDecideBody, options []decide.OptimizelyDecideOptions, maxConcurrency int, logger *zerolog.Logger) <-chan BulkDecideOut {
+	if maxConcurrency <= 0 {
+		maxConcurrency = 1
+	}
+
+	results := make(chan BulkDecideOut, maxConcurrency)
+	go func() {
+		defer close(results)
+		sem := make(chan struct{}, maxConcurrency)
+		for i, user := range body.Users {
+			if !acquire(ctx, sem) {
+				break
+			}
+
+			go func(i int, user BulkDecideUser) {
+				defer func() { <-sem }()
+				results <- decideForUser(optlyClient, snapshot, i, user, body.Keys, options, logger)
+			}(i, user)
+		}
+
+		// Wait for the running evaluations
+		for n := 0; n < maxConcurrency; n++ {
+			sem <- struct{}{}
+		}
+	}()
+	return results
+}
+
+// acquire takes a slot of sem, it returns false without taking one once the context is done
+func acquire(ctx context.Context, sem chan struct{}) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case sem <- struct{}{}:
+		return true
+	}
+}
+
+func decideForUser(optlyClient *optimizely.OptlyClient, snapshot *client.OptimizelyClient, index int, user BulkDecideUser, keys []string, options []decide.OptimizelyDecideOptions, logger *zerolog.Logger) BulkDecideOut {
+	out := BulkDecideOut{Index: index, UserID: user.UserID}
+	if user.UserID == "" {
+		out.Error = ErrEmptyUserID.Error()
+		return out
+	}
+
+	attributeWarnings, rejected := ValidateAttributes(optlyClient, user.UserAttributes, logger)
+	if rejected {
+		out.Error = "invalid user attributes"
+		out.AttributeWarnings = attributeWarnings
+		return out
+	}
+
+	optimizelyUserContext := snapshot.CreateUserContext(user.UserID, MergeUserAttributes(optlyClient, user.UserID, user.UserAttributes, logger))
+	SetForcedDecisions(optlyClient, &optimizelyUserContext, user.ForcedDecisions, logger)
+
+	var decides map[string]client.OptimizelyDecision
+	if len(keys) == 0 {
+		decides = optimizelyUserContext.DecideAll(options)
+	} else {
+		decides = optimizelyUserContext.DecideForKeys(keys, options)
+	}
+
+	out.Decisions = []DecideOut{}
+	for _, d := range decides {
+		out.Decisions = append(out.Decisions, DecideOut{d, d.Variables.ToMap(), attributeWarnings})
+	}
+	return out
+}
@@ -0,0 +1,211 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/pkg/middleware"
+	"github.com/optimizely/agent/pkg/optimizely"
+	"github.com/optimizely/agent/pkg/optimizely/optimizelytest"
+
+	"github.com/optimizely/go-sdk/pkg/entities"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type BulkDecideTestSuite struct {
+	suite.Suite
+	oc  *optimizely.OptlyClient
+	tc  *optimizelytest.TestClient
+	mux *chi.Mux
+}
+
+func (suite *BulkDecideTestSuite) ClientCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), middleware.OptlyClientKey, suite.oc)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (suite *BulkDecideTestSuite) SetupTest() {
+	testClient := optimizelytest.NewClient()
+	suite.tc = testClient
+	suite.oc = &optimizely.OptlyClient{
+		OptimizelyClient: testClient.OptimizelyClient,
+		ForcedVariations: testClient.ForcedVariations,
+	}
+
+	suite.mux = chi.NewMux()
+	suite.mux.With(suite.ClientCtx).Post("/decide/bulk", BulkDecide(config.BulkDecideConfig{MaxUsers: 3, MaxConcurrency: 2}))
+}
+
+func (suite *BulkDecideTestSuite) bulkDecide(body BulkDecideBody) *httptest.ResponseRecorder {
+	payload, err := json.Marshal(body)
+	suite.NoError(err)
+
+	req := httptest.NewRequest("POST", "/decide/bulk", bytes.NewBuffer(payload))
+	rec := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	return rec
+}
+
+// results parses the streamed lines of the response, indexed by their position in the request
+func (suite *BulkDecideTestSuite) results(rec *httptest.ResponseRecorder) map[int]BulkDecideOut {
+	suite.Equal(http.StatusOK, rec.Code)
+	suite.Equal("application/x-ndjson", rec.Header().Get("Content-Type"))
+
+	results := make(map[int]BulkDecideOut)
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var result BulkDecideOut
+		suite.NoError(json.Unmarshal(scanner.Bytes(), &result))
+		results[result.Index] = result
+	}
+	return results
+}
+
+func (suite *BulkDecideTestSuite) TestBulkDecide() {
+	feature := entities.Feature{Key: "one"}
+	suite.tc.AddFeatureTest(feature)
+	suite.tc.AddFlagVariation(feature, entities.Variation{Key: "4", FeatureEnabled: true})
+
+	rec := suite.bulkDecide(BulkDecideBody{
+		Users: []BulkDecideUser{
+			{UserID: "user1", UserAttributes: map[string]interface{}{"country": "US"}},
+			{UserID: "user2", ForcedDecisions: []ForcedDecision{{FlagKey: "one", RuleKey: "1", VariationKey: "4"}}},
+			{UserID: ""},
+		},
+		Keys:          []string{"one"},
+		DecideOptions: []string{"DISABLE_DECISION_EVENT"},
+	})
+
+	results := suite.results(rec)
+	suite.Len(results, 3)
+
+	suite.Equal("user1", results[0].UserID)
+	suite.Empty(results[0].Error)
+	if suite.Len(results[0].Decisions, 1) {
+		suite.Equal("one", results[0].Decisions[0].FlagKey)
+		suite.Equal("2", results[0].Decisions[0].VariationKey)
+		suite.Equal(map[string]interface{}{"country": "US"}, results[0].Decisions[0].UserContext.Attributes)
+	}
+
+	suite.Equal("user2", results[1].UserID)
+	if suite.Len(results[1].Decisions, 1) {
+		suite.Equal("4", results[1].Decisions[0].VariationKey)
+	}
+
+	suite.Equal(`missing "userId" in request payload`, results[2].Error)
+	suite.Empty(results[2].Decisions)
+
+	suite.Equal(0, len(suite.tc.GetProcessedEvents()))
+}
+
+func (suite *BulkDecideTestSuite) TestBulkDecideAll() {
+	suite.tc.AddFeatureTest(entities.Feature{Key: "one"})
+	suite.tc.AddFeatureRollout(entities.Feature{Key: "two"})
+
+	rec := suite.bulkDecide(BulkDecideBody{
+		Users: []BulkDecideUser{{UserID: "user1"}, {UserID: "user2"}},
+	})
+
+	results := suite.results(rec)
+	suite.Len(results, 2)
+	for _, result := range results {
+		suite.Len(result.Decisions, 2)
+	}
+
+	// Each user is sent an impression event for the feature test
+	suite.Equal(2, len(suite.tc.GetProcessedEvents()))
+}
+
+func (suite *BulkDecideTestSuite) TestAttributeValidation() {
+	setupAttributeValidation(suite.tc, suite.oc, config.AttributeValidationWarn)
+	body := BulkDecideBody{
+		Users: []BulkDecideUser{
+			{UserID: "user1", UserAttributes: invalidAttributes},
+			{UserID: "user2", UserAttributes: map[string]interface{}{"age": 30}},
+		},
+		Keys:          []string{"one"},
+		DecideOptions: []string{"DISABLE_DECISION_EVENT"},
+	}
+
+	results := suite.results(suite.bulkDecide(body))
+	if suite.Len(results[0].Decisions, 1) {
+		suite.Equal(expectedAttributeWarnings, results[0].Decisions[0].AttributeWarnings)
+	}
+	if suite.Len(results[1].Decisions, 1) {
+		suite.Empty(results[1].Decisions[0].AttributeWarnings)
+	}
+
+	// Users with invalid attributes are rejected on their own
+	suite.oc.AttributeValidationMode = config.AttributeValidationReject
+	results = suite.results(suite.bulkDecide(body))
+	suite.Equal(BulkDecideOut{Index: 0, UserID: "user1", Error: "invalid user attributes", AttributeWarnings: expectedAttributeWarnings}, results[0])
+	suite.Empty(results[1].Error)
+	suite.Len(results[1].Decisions, 1)
+}
+
+func (suite *BulkDecideTestSuite) TestInvalidPayloads() {
+	rec := suite.bulkDecide(BulkDecideBody{})
+	assertError(suite.T(), rec, `missing "users" in request payload`, http.StatusBadRequest)
+
+	users := []BulkDecideUser{{UserID: "1"}, {UserID: "2"}, {UserID: "3"}, {UserID: "4"}}
+	rec = suite.bulkDecide(BulkDecideBody{Users: users})
+	assertError(suite.T(), rec, "too many users in request payload: 4, the limit is 3", http.StatusBadRequest)
+
+	rec = suite.bulkDecide(BulkDecideBody{Users: users[:1], DecideOptions: []string{"UNKNOWN"}})
+	suite.Equal(http.StatusBadRequest, rec.Code)
+}
+
+func (suite *BulkDecideTestSuite) TestUninitializedClient() {
+	suite.oc = &optimizely.OptlyClient{}
+	rec := suite.bulkDecide(BulkDecideBody{Users: []BulkDecideUser{{UserID: "user1"}}})
+	assertError(suite.T(), rec, "client is not initialized", http.StatusInternalServerError)
+}
+
+func TestBulkDecideTestSuite(t *testing.T) {
+	suite.Run(t, new(BulkDecideTestSuite))
+}
+
+func TestBulkDecideStopsWhenContextIsDone(t *testing.T) {
+	testClient := optimizelytest.NewClient()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	body := BulkDecideBody{Users: []BulkDecideUser{{UserID: "user1"}, {UserID: "user2"}}}
+	logger := zerolog.Nop()
+	results := bulkDecide(ctx, &optimizely.OptlyClient{}, testClient.OptimizelyClient, body, nil, 1, &logger)
+
+	count := 0
+	for range results {
+		count++
+	}
+	assert.Equal(t, 0, count)
+}
@@ -0,0 +1,128 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/optimizely/go-sdk/pkg/decide"
+
+	"github.com/optimizely/agent/pkg/middleware"
+	"github.com/optimizely/agent/pkg/optimizely"
+)
+
+// DecideExplainOut defines the response of the decide explain API, a decision along with the trace of how it was made
+type DecideExplainOut struct {
+	DecideOut
+	Trace *optimizely.FlagTrace `json:"trace"`
+}
+
+// DecideExplain makes feature decisions like Decide and traces every step of their evaluation: the rules
+// considered in order, their audience conditions, the bucketing and any forced decision, override or saved
+// user profile which short-circuited the evaluation. No decision event is sent.
+func DecideExplain(w http.ResponseWriter, r *http.Request) {
+	optlyClient, err := middleware.GetOptlyClient(r)
+	logger := middleware.GetLogger(r)
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	db, err := getUserContextWithOptions(r)
+	if err != nil {
+		RenderError(err, http.StatusBadRequest, w, r)
+		return
+	}
+
+	decideOptions, err := decide.TranslateOptions(db.DecideOptions)
+	if err != nil {
+		RenderError(err, http.StatusBadRequest, w, r)
+		return
+	}
+	decideOptions = append(decideOptions, decide.DisableDecisionEvent, decide.IncludeReasons)
+
+	attributeWarnings, rejected := ValidateAttributes(optlyClient, db.UserAttributes, logger)
+	if rejected {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, AttributeValidationErrorResponse{Error: "invalid user attributes", AttributeWarnings: attributeWarnings})
+		return
+	}
+
+	// The traces and the decisions are made against the same project config
+	snapshot, err := optlyClient.Snapshot()
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+	projectConfig, err := snapshot.ConfigManager.GetConfig()
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	optimizelyUserContext := snapshot.CreateUserContext(db.UserID, MergeUserAttributes(optlyClient, db.UserID, db.UserAttributes, logger))
+
+	if db.FetchSegments {
+		success := optimizelyUserContext.FetchQualifiedSegments(db.FetchSegmentsOptions)
+		if !success {
+			err := errors.New("failed to fetch qualified segments")
+			RenderError(err, http.StatusInternalServerError, w, r)
+			return
+		}
+	}
+
+	SetForcedDecisions(optlyClient, &optimizelyUserContext, db.ForcedDecisions, logger)
+
+	keys := []string{}
+	if err := r.ParseForm(); err == nil {
+		keys = r.Form["keys"]
+	}
+
+	decideAll := len(keys) == 0
+	if decideAll {
+		for _, feature := range projectConfig.GetFeatureList() {
+			keys = append(keys, feature.Key)
+		}
+	}
+
+	// The saved user profile is only traced, the decisions don't save bucketing to the user profile service
+	traceOptions := decideOptions
+	decideOptions = append(append([]decide.OptimizelyDecideOptions{}, decideOptions...), decide.IgnoreUserProfileService)
+
+	explainOuts := []DecideExplainOut{}
+	for _, key := range keys {
+		trace, err := optlyClient.Explain(projectConfig, &optimizelyUserContext, key, traceOptions)
+		if err != nil {
+			logger.Debug().Err(err).Str("featureKey", key).Msg("unable to trace feature decision")
+		}
+
+		d := optimizelyUserContext.Decide(key, decideOptions)
+		if decideAll && !d.Enabled && optimizely.HasDecideOption(decideOptions, decide.EnabledFlagsOnly) {
+			continue
+		}
+		explainOuts = append(explainOuts, DecideExplainOut{DecideOut{d, d.Variables.ToMap(), attributeWarnings}, trace})
+	}
+
+	if len(keys) == 1 && !decideAll {
+		render.JSON(w, r, explainOuts[0])
+		return
+	}
+	render.JSON(w, r, explainOuts)
+}
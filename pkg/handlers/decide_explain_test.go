@@ -0,0 +1,227 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/pkg/middleware"
+	"github.com/optimizely/agent/pkg/optimizely"
+	"github.com/optimizely/agent/pkg/optimizely/optimizelytest"
+
+	"github.com/optimizely/go-sdk/pkg/client"
+	sdkconfig "github.com/optimizely/go-sdk/pkg/config"
+	"github.com/optimizely/go-sdk/pkg/decision"
+	"github.com/optimizely/go-sdk/pkg/entities"
+	"github.com/optimizely/go-sdk/pkg/logging"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/suite"
+)
+
+type DecideExplainTestSuite struct {
+	suite.Suite
+	oc  *optimizely.OptlyClient
+	tc  *optimizelytest.TestClient
+	mux *chi.Mux
+}
+
+func (suite *DecideExplainTestSuite) ClientCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), middleware.OptlyClientKey, suite.oc)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (suite *DecideExplainTestSuite) SetupTest() {
+	testClient := optimizelytest.NewClient()
+	suite.tc = testClient
+	suite.oc = &optimizely.OptlyClient{
+		OptimizelyClient: testClient.OptimizelyClient,
+		ForcedVariations: testClient.ForcedVariations,
+	}
+
+	suite.mux = chi.NewMux()
+	suite.mux.With(suite.ClientCtx).Post("/decide/explain", DecideExplain)
+}
+
+func (suite *DecideExplainTestSuite) explain(query string, body DecideBody) *httptest.ResponseRecorder {
+	payload, err := json.Marshal(body)
+	suite.NoError(err)
+
+	req := httptest.NewRequest("POST", "/decide/explain"+query, bytes.NewBuffer(payload))
+	rec := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func (suite *DecideExplainTestSuite) TestExplainFeatureTest() {
+	suite.tc.AddFeatureTest(entities.Feature{Key: "one"})
+
+	rec := suite.explain("?keys=one", DecideBody{UserID: "testUser"})
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual DecideExplainOut
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+
+	suite.Equal("one", actual.FlagKey)
+	suite.Equal("2", actual.VariationKey)
+	suite.NotEmpty(actual.Reasons)
+	if suite.NotNil(actual.Trace) && suite.Len(actual.Trace.Rules, 1) {
+		suite.Equal("testUser", actual.Trace.BucketingID)
+		suite.Equal("2", actual.Trace.VariationKey)
+
+		rule := actual.Trace.Rules[0]
+		suite.Equal("1", rule.RuleKey)
+		suite.Equal(optimizely.RuleTypeExperiment, rule.RuleType)
+		suite.Equal(optimizely.RuleBucketed, rule.Result)
+		suite.True(rule.AudienceMatch)
+		suite.Equal([]optimizely.Allocation{{EntityID: "2", EntityKey: "2", EndOfRange: 10000}}, rule.Bucket.TrafficAllocation)
+	}
+
+	// Explaining a decision never sends an event
+	suite.Equal(0, len(suite.tc.GetProcessedEvents()))
+}
+
+// savingUserProfileService records the saved profiles
+type savingUserProfileService struct {
+	mu    sync.Mutex
+	saved []decision.UserProfile
+}
+
+func (u *savingUserProfileService) Lookup(userID string) decision.UserProfile {
+	return decision.UserProfile{ID: userID}
+}
+
+func (u *savingUserProfileService) Save(profile decision.UserProfile) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.saved = append(u.saved, profile)
+}
+
+func (suite *DecideExplainTestSuite) TestExplainIgnoresUserProfileService() {
+	suite.tc.AddFeatureTest(entities.Feature{Key: "one"})
+	ups := &savingUserProfileService{}
+	optimizelyClient, err := (&client.OptimizelyFactory{}).Client(
+		client.WithConfigManager(sdkconfig.NewStaticProjectConfigManager(suite.tc.ProjectConfig, logging.GetLogger("test", "test"))),
+		client.WithEventProcessor(suite.tc.EventProcessor),
+		client.WithExperimentOverrides(suite.tc.ForcedVariations),
+		client.WithUserProfileService(ups),
+	)
+	suite.Require().NoError(err)
+	suite.oc = &optimizely.OptlyClient{
+		OptimizelyClient:   optimizelyClient,
+		ForcedVariations:   suite.tc.ForcedVariations,
+		UserProfileService: ups,
+	}
+
+	rec := suite.explain("?keys=one", DecideBody{UserID: "testUser"})
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual DecideExplainOut
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal("2", actual.VariationKey)
+	suite.Empty(ups.saved)
+}
+
+func (suite *DecideExplainTestSuite) TestExplainAll() {
+	suite.tc.AddFeatureTest(entities.Feature{Key: "one"})
+	suite.tc.AddFeatureRollout(entities.Feature{Key: "two"})
+
+	rec := suite.explain("", DecideBody{UserID: "testUser"})
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual []DecideExplainOut
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Len(actual, 2)
+	for _, out := range actual {
+		if suite.NotNil(out.Trace) {
+			suite.Equal(out.FlagKey, out.Trace.FlagKey)
+			suite.Equal(out.VariationKey, out.Trace.VariationKey)
+		}
+	}
+}
+
+func (suite *DecideExplainTestSuite) TestExplainForcedDecision() {
+	feature := entities.Feature{Key: "one"}
+	suite.tc.AddFeatureTest(feature)
+	suite.tc.AddFlagVariation(feature, entities.Variation{Key: "3", FeatureEnabled: true})
+
+	rec := suite.explain("?keys=one", DecideBody{
+		UserID:          "testUser",
+		ForcedDecisions: []ForcedDecision{{FlagKey: "one", VariationKey: "3"}},
+	})
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual DecideExplainOut
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal("3", actual.VariationKey)
+	if suite.NotNil(actual.Trace) {
+		suite.Equal("3", actual.Trace.ForcedDecision)
+		suite.Empty(actual.Trace.Rules)
+	}
+}
+
+func (suite *DecideExplainTestSuite) TestExplainUnknownFlag() {
+	rec := suite.explain("?keys=unknown", DecideBody{UserID: "testUser"})
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual DecideExplainOut
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal("unknown", actual.FlagKey)
+	suite.Nil(actual.Trace)
+	suite.NotEmpty(actual.Reasons)
+}
+
+func (suite *DecideExplainTestSuite) TestAttributeValidation() {
+	setupAttributeValidation(suite.tc, suite.oc, config.AttributeValidationWarn)
+	body := DecideBody{UserID: "testUser", UserAttributes: invalidAttributes}
+
+	rec := suite.explain("?keys=one", body)
+	suite.Equal(http.StatusOK, rec.Code)
+	var actual DecideExplainOut
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal(expectedAttributeWarnings, actual.AttributeWarnings)
+	suite.NotNil(actual.Trace)
+
+	suite.oc.AttributeValidationMode = config.AttributeValidationReject
+	rec = suite.explain("?keys=one", body)
+	suite.Equal(http.StatusBadRequest, rec.Code)
+	var rejected AttributeValidationErrorResponse
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &rejected))
+	suite.Equal(AttributeValidationErrorResponse{Error: "invalid user attributes", AttributeWarnings: expectedAttributeWarnings}, rejected)
+}
+
+func (suite *DecideExplainTestSuite) TestInvalidPayload() {
+	rec := suite.explain("", DecideBody{})
+	assertError(suite.T(), rec, `missing "userId" in request payload`, http.StatusBadRequest)
+
+	rec = suite.explain("", DecideBody{UserID: "testUser", DecideOptions: []string{"UNKNOWN"}})
+	suite.Equal(http.StatusBadRequest, rec.Code)
+}
+
+func TestDecideExplainTestSuite(t *testing.T) {
+	suite.Run(t, new(DecideExplainTestSuite))
+}
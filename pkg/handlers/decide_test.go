@@ -26,9 +26,12 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/optimizely/agent/config"
 	"github.com/optimizely/agent/pkg/middleware"
 	"github.com/optimizely/agent/pkg/optimizely"
 	"github.com/optimizely/agent/pkg/optimizely/optimizelytest"
+	"github.com/optimizely/agent/plugins/forceddecisions"
+	"github.com/optimizely/agent/plugins/forceddecisions/services"
 
 	"github.com/optimizely/go-sdk/pkg/client"
 	"github.com/optimizely/go-sdk/pkg/decide"
@@ -283,6 +286,47 @@ func (suite *DecideTestSuite) TestForcedDecisionWithFeatureTest() {
 	suite.Equal(expected, actual)
 }
 
+func (suite *DecideTestSuite) TestSavedForcedDecisions() {
+	feature := entities.Feature{Key: "one"}
+	suite.tc.AddFeatureTest(feature)
+	suite.tc.AddFlagVariation(feature, entities.Variation{Key: "4", FeatureEnabled: true})
+
+	store := services.NewInMemoryStore()
+	suite.oc.ForcedDecisions = store
+	suite.NoError(store.Save("", "testUser", forceddecisions.ForcedDecision{FlagKey: "one", VariationKey: "4"}))
+
+	decideOne := func(db DecideBody) client.OptimizelyDecision {
+		payload, err := json.Marshal(db)
+		suite.NoError(err)
+
+		req := httptest.NewRequest("POST", "/decide?keys=one", bytes.NewBuffer(payload))
+		rec := httptest.NewRecorder()
+		suite.mux.ServeHTTP(rec, req)
+		suite.Equal(http.StatusOK, rec.Code)
+
+		var actual client.OptimizelyDecision
+		suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+		return actual
+	}
+
+	actual := decideOne(DecideBody{UserID: "testUser", DecideOptions: []string{"DISABLE_DECISION_EVENT"}})
+	suite.Equal("4", actual.VariationKey)
+	suite.True(actual.Enabled)
+
+	// Forced decisions of the request take precedence over the saved ones
+	suite.NoError(store.Save("", "testUser", forceddecisions.ForcedDecision{FlagKey: "one", VariationKey: "dne"}))
+	actual = decideOne(DecideBody{
+		UserID:          "testUser",
+		DecideOptions:   []string{"DISABLE_DECISION_EVENT"},
+		ForcedDecisions: []ForcedDecision{{FlagKey: "one", VariationKey: "4"}},
+	})
+	suite.Equal("4", actual.VariationKey)
+
+	// Other users are not affected
+	actual = decideOne(DecideBody{UserID: "otherUser", DecideOptions: []string{"DISABLE_DECISION_EVENT"}})
+	suite.NotEqual("4", actual.VariationKey)
+}
+
 func (suite *DecideTestSuite) TestForcedDecisionFeatureRollout() {
 	feature := entities.Feature{Key: "one"}
 	suite.tc.AddFeatureRollout(feature)
@@ -802,6 +846,104 @@ func (suite *DecideTestSuite) TestFetchQualifiedSegmentsFailure() {
 	suite.assertError(rec, `failed to fetch qualified segments`, http.StatusInternalServerError)
 }
 
+// setupAttributeValidation adds the feature "one" and the "age" attribute, expected to be a number by an audience
+func setupAttributeValidation(tc *optimizelytest.TestClient, oc *optimizely.OptlyClient, mode config.AttributeValidationMode) {
+	tc.ProjectConfig.AttributeKeyToIDMap["age"] = "100"
+	tc.ProjectConfig.AttributeMap["100"] = entities.Attribute{ID: "100", Key: "age"}
+	tc.AddAudience(entities.Audience{ID: "100", ConditionTree: &entities.TreeNode{
+		Item: entities.Condition{Name: "age", Match: "gt", Type: "custom_attribute", Value: 18.0},
+	}})
+	tc.AddFeatureTest(entities.Feature{Key: "one"})
+	oc.ConfigManager = MockConfigManager{config: tc.ProjectConfig}
+	oc.AttributeValidationMode = mode
+}
+
+// invalidAttributes are the attributes of the expectedAttributeWarnings
+var invalidAttributes = map[string]interface{}{"age": "30", "user_tier": "gold"}
+
+func (suite *DecideTestSuite) setupAttributeValidation(mode config.AttributeValidationMode) []byte {
+	setupAttributeValidation(suite.tc, suite.oc, mode)
+
+	payload, err := json.Marshal(DecideBody{
+		UserID:         "testUser",
+		UserAttributes: invalidAttributes,
+		DecideOptions:  []string{"DISABLE_DECISION_EVENT"},
+	})
+	suite.NoError(err)
+	return payload
+}
+
+var expectedAttributeWarnings = []optimizely.AttributeWarning{
+	{
+		Attribute:     "age",
+		Code:          optimizely.AttributeWarningTypeMismatch,
+		Message:       `attribute "age" is a string, audience conditions expect number`,
+		ExpectedTypes: []string{"number"},
+		ActualType:    "string",
+	},
+	{
+		Attribute: "user_tier",
+		Code:      optimizely.AttributeWarningUnknown,
+		Message:   `attribute "user_tier" is not defined in the datafile`,
+	},
+}
+
+func (suite *DecideTestSuite) TestAttributeValidationWarn() {
+	payload := suite.setupAttributeValidation(config.AttributeValidationWarn)
+
+	req := httptest.NewRequest("POST", "/decide?keys=one", bytes.NewBuffer(payload))
+	rec := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual DecideOut
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal("one", actual.FlagKey)
+	suite.Equal(expectedAttributeWarnings, actual.AttributeWarnings)
+
+	req = httptest.NewRequest("POST", "/decide", bytes.NewBuffer(payload))
+	rec = httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actuals []DecideOut
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actuals))
+	suite.Equal(1, len(actuals))
+	suite.Equal(expectedAttributeWarnings, actuals[0].AttributeWarnings)
+}
+
+func (suite *DecideTestSuite) TestAttributeValidationReject() {
+	payload := suite.setupAttributeValidation(config.AttributeValidationReject)
+
+	req := httptest.NewRequest("POST", "/decide?keys=one", bytes.NewBuffer(payload))
+	rec := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	suite.Equal(http.StatusBadRequest, rec.Code)
+
+	var actual AttributeValidationErrorResponse
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal(AttributeValidationErrorResponse{Error: "invalid user attributes", AttributeWarnings: expectedAttributeWarnings}, actual)
+
+	// Valid attributes are decided
+	payload, err := json.Marshal(DecideBody{UserID: "testUser", UserAttributes: map[string]interface{}{"age": 30}})
+	suite.NoError(err)
+	req = httptest.NewRequest("POST", "/decide?keys=one", bytes.NewBuffer(payload))
+	rec = httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	suite.Equal(http.StatusOK, rec.Code)
+	suite.NotContains(rec.Body.String(), "attributeWarnings")
+}
+
+func (suite *DecideTestSuite) TestAttributeValidationOff() {
+	payload := suite.setupAttributeValidation(config.AttributeValidationOff)
+
+	req := httptest.NewRequest("POST", "/decide?keys=one", bytes.NewBuffer(payload))
+	rec := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	suite.Equal(http.StatusOK, rec.Code)
+	suite.NotContains(rec.Body.String(), "attributeWarnings")
+}
+
 func TestDecideTestSuite(t *testing.T) {
 	suite.Run(t, new(DecideTestSuite))
 }
@@ -0,0 +1,144 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"github.com/optimizely/agent/pkg/middleware"
+	"github.com/optimizely/agent/plugins/forceddecisions"
+)
+
+// SaveForcedDecisionBody defines the request body to save a forced decision of a user
+type SaveForcedDecisionBody struct {
+	FlagKey      string `json:"flagKey"`
+	RuleKey      string `json:"ruleKey,omitempty"`
+	VariationKey string `json:"variationKey"`
+	// TTL is a duration, like "1h30m", after which the forced decision expires. It never expires when empty
+	TTL string `json:"ttl,omitempty"`
+}
+
+// GetForcedDecisions returns the forced decisions saved for the user in the url
+func GetForcedDecisions(w http.ResponseWriter, r *http.Request) {
+	optlyClient, err := middleware.GetOptlyClient(r)
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	userForcedDecisions, err := optlyClient.GetForcedDecisions(r.Context(), chi.URLParam(r, "userId"))
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	render.JSON(w, r, userForcedDecisions)
+}
+
+// SaveForcedDecision saves a forced decision for the user in the url, it is applied by every decide request of the user
+func SaveForcedDecision(w http.ResponseWriter, r *http.Request) {
+	optlyClient, err := middleware.GetOptlyClient(r)
+	logger := middleware.GetLogger(r)
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	var body SaveForcedDecisionBody
+	if parseErr := ParseRequestBody(r, &body); parseErr != nil {
+		RenderError(parseErr, http.StatusBadRequest, w, r)
+		return
+	}
+
+	if body.FlagKey == "" {
+		RenderError(errors.New("flagKey cannot be empty"), http.StatusBadRequest, w, r)
+		return
+	}
+
+	if body.VariationKey == "" {
+		RenderError(errors.New("variationKey cannot be empty"), http.StatusBadRequest, w, r)
+		return
+	}
+
+	var ttl time.Duration
+	if body.TTL != "" {
+		if ttl, err = time.ParseDuration(body.TTL); err != nil || ttl <= 0 {
+			RenderError(fmt.Errorf("invalid ttl %q, it must be a positive duration", body.TTL), http.StatusBadRequest, w, r)
+			return
+		}
+	}
+
+	forcedDecision := forceddecisions.ForcedDecision{
+		FlagKey:      body.FlagKey,
+		RuleKey:      body.RuleKey,
+		VariationKey: body.VariationKey,
+	}
+
+	logger.Debug().Str("flagKey", body.FlagKey).Str("variationKey", body.VariationKey).Msg("saving forced decision")
+	saved, err := optlyClient.SaveForcedDecision(r.Context(), chi.URLParam(r, "userId"), forcedDecision, ttl)
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	render.JSON(w, r, saved)
+}
+
+// RemoveForcedDecision removes the forced decision of the user for the flag in the url and the ruleKey query parameter
+func RemoveForcedDecision(w http.ResponseWriter, r *http.Request) {
+	optlyClient, err := middleware.GetOptlyClient(r)
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	removed, err := optlyClient.RemoveForcedDecision(r.Context(), chi.URLParam(r, "userId"), chi.URLParam(r, "flagKey"), r.URL.Query().Get("ruleKey"))
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	if !removed {
+		RenderError(errors.New("forced decision not found"), http.StatusNotFound, w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResetForcedDecisions removes every forced decision of the user in the url
+func ResetForcedDecisions(w http.ResponseWriter, r *http.Request) {
+	optlyClient, err := middleware.GetOptlyClient(r)
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	if err := optlyClient.ResetForcedDecisions(r.Context(), chi.URLParam(r, "userId")); err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,171 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/optimizely/agent/pkg/middleware"
+	"github.com/optimizely/agent/pkg/optimizely"
+	"github.com/optimizely/agent/pkg/optimizely/optimizelytest"
+	"github.com/optimizely/agent/plugins/forceddecisions"
+	"github.com/optimizely/agent/plugins/forceddecisions/services"
+)
+
+type ForcedDecisionsTestSuite struct {
+	suite.Suite
+	oc    *optimizely.OptlyClient
+	store forceddecisions.Store
+	mux   *chi.Mux
+}
+
+func (suite *ForcedDecisionsTestSuite) ClientCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), middleware.OptlyClientKey, suite.oc)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (suite *ForcedDecisionsTestSuite) SetupTest() {
+	testClient := optimizelytest.NewClient()
+	suite.store = services.NewInMemoryStore()
+	suite.oc = &optimizely.OptlyClient{
+		OptimizelyClient: testClient.OptimizelyClient,
+		ForcedDecisions:  suite.store,
+	}
+
+	suite.mux = chi.NewMux()
+	suite.mux.With(suite.ClientCtx).Get("/forced-decisions/{userId}", GetForcedDecisions)
+	suite.mux.With(suite.ClientCtx).Put("/forced-decisions/{userId}", SaveForcedDecision)
+	suite.mux.With(suite.ClientCtx).Delete("/forced-decisions/{userId}", ResetForcedDecisions)
+	suite.mux.With(suite.ClientCtx).Delete("/forced-decisions/{userId}/{flagKey}", RemoveForcedDecision)
+}
+
+func (suite *ForcedDecisionsTestSuite) save(body SaveForcedDecisionBody) *httptest.ResponseRecorder {
+	payload, err := json.Marshal(body)
+	suite.NoError(err)
+
+	req := httptest.NewRequest("PUT", "/forced-decisions/testUser", bytes.NewBuffer(payload))
+	rec := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func (suite *ForcedDecisionsTestSuite) serve(method, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	rec := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func (suite *ForcedDecisionsTestSuite) TestSaveForcedDecision() {
+	rec := suite.save(SaveForcedDecisionBody{FlagKey: "flag", RuleKey: "rule", VariationKey: "on", TTL: "1h"})
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual optimizely.SavedForcedDecision
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal("testUser", actual.UserID)
+	suite.Equal("flag", actual.FlagKey)
+	suite.Equal("rule", actual.RuleKey)
+	suite.Equal("on", actual.VariationKey)
+	suite.NotNil(actual.ExpiresAt)
+	suite.Equal([]string{"flagKey not found in configuration"}, actual.Messages)
+
+	found, err := suite.store.Lookup("", "testUser")
+	suite.NoError(err)
+	suite.Len(found, 1)
+}
+
+func (suite *ForcedDecisionsTestSuite) TestSaveInvalidForcedDecision() {
+	scenarios := []struct {
+		body    SaveForcedDecisionBody
+		message string
+	}{
+		{SaveForcedDecisionBody{VariationKey: "on"}, "flagKey cannot be empty"},
+		{SaveForcedDecisionBody{FlagKey: "flag"}, "variationKey cannot be empty"},
+		{SaveForcedDecisionBody{FlagKey: "flag", VariationKey: "on", TTL: "soon"}, `invalid ttl "soon", it must be a positive duration`},
+		{SaveForcedDecisionBody{FlagKey: "flag", VariationKey: "on", TTL: "-1m"}, `invalid ttl "-1m", it must be a positive duration`},
+	}
+
+	for _, scenario := range scenarios {
+		rec := suite.save(scenario.body)
+		assertError(suite.T(), rec, scenario.message, http.StatusBadRequest)
+	}
+}
+
+func (suite *ForcedDecisionsTestSuite) TestGetForcedDecisions() {
+	suite.NoError(suite.store.Save("", "testUser", forceddecisions.ForcedDecision{FlagKey: "flag", VariationKey: "on"}))
+
+	rec := suite.serve("GET", "/forced-decisions/testUser")
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual optimizely.UserForcedDecisions
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal(optimizely.UserForcedDecisions{
+		UserID:          "testUser",
+		ForcedDecisions: []forceddecisions.ForcedDecision{{FlagKey: "flag", VariationKey: "on"}},
+	}, actual)
+}
+
+func (suite *ForcedDecisionsTestSuite) TestRemoveForcedDecision() {
+	suite.NoError(suite.store.Save("", "testUser", forceddecisions.ForcedDecision{FlagKey: "flag", VariationKey: "on"}))
+	suite.NoError(suite.store.Save("", "testUser", forceddecisions.ForcedDecision{FlagKey: "flag", RuleKey: "rule", VariationKey: "on"}))
+
+	rec := suite.serve("DELETE", "/forced-decisions/testUser/flag?ruleKey=rule")
+	suite.Equal(http.StatusNoContent, rec.Code)
+
+	rec = suite.serve("DELETE", "/forced-decisions/testUser/flag?ruleKey=rule")
+	assertError(suite.T(), rec, "forced decision not found", http.StatusNotFound)
+
+	found, err := suite.store.Lookup("", "testUser")
+	suite.NoError(err)
+	suite.Equal([]forceddecisions.ForcedDecision{{FlagKey: "flag", VariationKey: "on"}}, found)
+}
+
+func (suite *ForcedDecisionsTestSuite) TestResetForcedDecisions() {
+	suite.NoError(suite.store.Save("", "testUser", forceddecisions.ForcedDecision{FlagKey: "flag", VariationKey: "on"}))
+
+	rec := suite.serve("DELETE", "/forced-decisions/testUser")
+	suite.Equal(http.StatusNoContent, rec.Code)
+
+	found, err := suite.store.Lookup("", "testUser")
+	suite.NoError(err)
+	suite.Empty(found)
+}
+
+func (suite *ForcedDecisionsTestSuite) TestStoreUninitialized() {
+	suite.oc.ForcedDecisions = nil
+	message := optimizely.ErrForcedDecisionsUninitialized.Error()
+
+	assertError(suite.T(), suite.serve("GET", "/forced-decisions/testUser"), message, http.StatusInternalServerError)
+	assertError(suite.T(), suite.save(SaveForcedDecisionBody{FlagKey: "flag", VariationKey: "on"}), message, http.StatusInternalServerError)
+	assertError(suite.T(), suite.serve("DELETE", "/forced-decisions/testUser/flag"), message, http.StatusInternalServerError)
+	assertError(suite.T(), suite.serve("DELETE", "/forced-decisions/testUser"), message, http.StatusInternalServerError)
+}
+
+func TestForcedDecisionsTestSuite(t *testing.T) {
+	suite.Run(t, new(ForcedDecisionsTestSuite))
+}
@@ -23,7 +23,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/optimizely/agent/config"
@@ -52,7 +54,9 @@ var types = map[notification.Type]string{
 	notification.ProjectConfigUpdate: string(notification.ProjectConfigUpdate),
 }
 
-func getFilter(filters []string) map[notification.Type]string {
+// NotificationFilter returns the notification types selected by the filters, which may be comma separated lists.
+// Every type is selected when there is no filter.
+func NotificationFilter(filters []string) map[notification.Type]string {
 	notificationsToAdd := make(map[notification.Type]string)
 	// Parse out the any filters that were added
 	if len(filters) == 0 {
@@ -73,7 +77,13 @@ func getFilter(filters []string) map[notification.Type]string {
 	return notificationsToAdd
 }
 
-func NotificationEventStreamHandler(notificationReceiverFn NotificationReceiverFunc) http.HandlerFunc {
+// NotificationEventStreamHandler streams the notifications as Server Sent Events. Each event carries the ID of its
+// notification, and a client reconnecting with the Last-Event-ID header receives the notifications it missed
+// among the last ones kept for its SDK key. Heartbeat comments keep idle streams open.
+// Besides their types, the notifications can be filtered by the fields of their messages, see NewNotificationMessageFilter.
+func NotificationEventStreamHandler(notificationReceiverFn NotificationReceiverFunc, conf config.EventStreamConfig) http.HandlerFunc {
+	history := NewNotificationHistory(notificationReceiverFn, conf.BufferSize)
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Make sure that the writer supports flushing.
 		flusher, ok := w.(http.Flusher)
@@ -90,11 +100,6 @@ func NotificationEventStreamHandler(notificationReceiverFn NotificationReceiverF
 			return
 		}
 
-		// Set the headers related to event streaming.
-		w.Header().Set("Content-Type", "text/event-stream")
-		w.Header().Set("Cache-Control", "no-cache")
-		w.Header().Set("Connection", "keep-alive")
-
 		// "raw" query string option
 		// If provided, send raw JSON lines instead of SSE-compliant strings.
 		raw := len(r.URL.Query()["raw"]) > 0
@@ -105,48 +110,90 @@ func NotificationEventStreamHandler(notificationReceiverFn NotificationReceiverF
 		filters := r.Form["filter"]
 
 		// Parse out the any filters that were added
-		notificationsToAdd := getFilter(filters)
+		notificationsToAdd := NotificationFilter(filters)
+		messageFilter := NewNotificationMessageFilter(r.Form)
 
 		// Listen to connection close and un-register messageChan
 		notify := r.Context().Done()
+		// Close the stream when the server shuts down
+		shutdown := middleware.ShuttingDown(r)
+
+		var lastEventID *uint64
+		if header := r.Header.Get("Last-Event-ID"); header != "" {
+			if id, err := strconv.ParseUint(header, 10, 64); err == nil {
+				lastEventID = &id
+			} else {
+				middleware.GetLogger(r).Debug().Str("lastEventId", header).Msg("ignoring invalid Last-Event-ID")
+			}
+		}
 
 		sdkKey := r.Header.Get(middleware.OptlySDKHeader)
-		ctx := context.WithValue(r.Context(), SDKKey, sdkKey)
-
-		dataChan, err := notificationReceiverFn(context.WithValue(ctx, LoggerKey, middleware.GetLogger(r)))
+		replay, events, err := history.Subscribe(r.Context(), sdkKey, lastEventID)
 		if err != nil {
 			middleware.GetLogger(r).Err(err).Msg("error from receiver")
 			http.Error(w, "Error from data receiver!", http.StatusInternalServerError)
 			return
 		}
 
+		// Set the headers related to event streaming.
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+
+		send := func(event NotificationEvent) {
+			_, found := notificationsToAdd[event.Type]
+			if !found || !messageFilter.Match(event.Event) {
+				return
+			}
+
+			jsonEvent, err := json.Marshal(event.Message)
+			if err != nil {
+				middleware.GetLogger(r).Err(err).Msg("failed to marshal notification into json")
+				return
+			}
+
+			if raw {
+				// Raw JSON events, one per line
+				_, _ = fmt.Fprintf(w, "%s\n", string(jsonEvent))
+			} else {
+				// Server Sent Events compatible
+				_, _ = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", event.ID, string(jsonEvent))
+			}
+			// Flush the data immediately instead of buffering it for later.
+			// The flush will fail if the connection is closed.  That will cause the handler to exit.
+			flusher.Flush()
+		}
+
+		for _, event := range replay {
+			send(event)
+		}
+
+		// Raw JSON lines have no comments to send heartbeats with
+		var heartbeats <-chan time.Time
+		if conf.HeartbeatInterval > 0 && !raw {
+			ticker := time.NewTicker(conf.HeartbeatInterval)
+			defer ticker.Stop()
+			heartbeats = ticker.C
+		}
+
 		for {
 			select {
 			case <-notify:
 				middleware.GetLogger(r).Debug().Msg("received close on the request.  So, we are shutting down this handler")
 				return
-			case event := <-dataChan:
-				_, found := notificationsToAdd[event.Type]
-				if !found {
-					continue
-				}
-
-				jsonEvent, err := json.Marshal(event.Message)
-				if err != nil {
-					middleware.GetLogger(r).Err(err).Msg("failed to marshal notification into json")
-					continue
-				}
-
-				if raw {
-					// Raw JSON events, one per line
-					_, _ = fmt.Fprintf(w, "%s\n", string(jsonEvent))
-				} else {
-					// Server Sent Events compatible
-					_, _ = fmt.Fprintf(w, "data: %s\n\n", string(jsonEvent))
-				}
-				// Flush the data immediately instead of buffering it for later.
-				// The flush will fail if the connection is closed.  That will cause the handler to exit.
+			case <-shutdown:
+				middleware.GetLogger(r).Debug().Msg("server is shutting down.  So, we are closing this stream")
+				return
+			case <-heartbeats:
+				_, _ = fmt.Fprint(w, ": heartbeat\n\n")
 				flusher.Flush()
+			case event, ok := <-events:
+				if !ok {
+					// The client resumes from its last event when it reconnects
+					middleware.GetLogger(r).Debug().Msg("stream fell behind the notifications.  So, we are closing this stream")
+					return
+				}
+				send(event)
 			}
 		}
 	}
@@ -176,12 +223,17 @@ func DefaultNotificationReceiver(ctx context.Context) (<-chan syncer.Event, erro
 	}{}
 
 	for notificationType := range notificationsToAdd {
+		// each handler tags its notifications with its own type rather than the last one of the loop
+		notificationType := notificationType
 		id, e := nc.AddHandler(notificationType, func(n interface{}) {
 			msg := syncer.Event{
 				Type:    notificationType,
 				Message: n,
 			}
-			messageChan <- msg
+			select {
+			case messageChan <- msg:
+			case <-ctx.Done():
+			}
 		})
 		if e != nil {
 			return nil, e
@@ -260,7 +312,10 @@ func RedisNotificationReceiver(conf config.SyncConfig) NotificationReceiverFunc
 						logger.Err(err).Msg("failed to unmarshal redis message")
 						continue
 					}
-					dataChan <- event
+					select {
+					case dataChan <- event:
+					case <-ctx.Done():
+					}
 				}
 			}
 		}()
@@ -0,0 +1,162 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/optimizely/go-sdk/pkg/notification"
+
+	"github.com/optimizely/agent/pkg/syncer"
+)
+
+// NotificationMessageFilter selects the notifications by the fields of their messages. A notification is selected
+// when, for each field filtered, it has one of the filtered values, so notifications without the field are not.
+type NotificationMessageFilter struct {
+	FlagKeys      map[string]struct{}
+	RuleKeys      map[string]struct{}
+	UserIDs       map[string]struct{}
+	DecisionTypes map[string]struct{}
+	EventKeys     map[string]struct{}
+}
+
+// NewNotificationMessageFilter returns the filter of the flagKey, ruleKey, userId, decisionType and eventKey query
+// parameters, whose values may be comma separated lists. It returns nil when none of them is given.
+func NewNotificationMessageFilter(query url.Values) *NotificationMessageFilter {
+	f := &NotificationMessageFilter{
+		FlagKeys:      filterValues(query["flagKey"]),
+		RuleKeys:      filterValues(query["ruleKey"]),
+		UserIDs:       filterValues(query["userId"]),
+		DecisionTypes: filterValues(query["decisionType"]),
+		EventKeys:     filterValues(query["eventKey"]),
+	}
+	if f.FlagKeys == nil && f.RuleKeys == nil && f.UserIDs == nil && f.DecisionTypes == nil && f.EventKeys == nil {
+		return nil
+	}
+	return f
+}
+
+// Match returns whether the notification is selected, every notification is selected by a nil filter
+func (f *NotificationMessageFilter) Match(event syncer.Event) bool {
+	if f == nil {
+		return true
+	}
+
+	fields := getNotificationFields(event)
+	return matchValue(f.FlagKeys, fields.flagKey) &&
+		matchValue(f.RuleKeys, fields.ruleKey) &&
+		matchValue(f.UserIDs, fields.userID) &&
+		matchValue(f.DecisionTypes, fields.decisionType) &&
+		matchValue(f.EventKeys, fields.eventKey)
+}
+
+func filterValues(params []string) map[string]struct{} {
+	var values map[string]struct{}
+	for _, param := range params {
+		for _, value := range strings.Split(param, ",") {
+			if value == "" {
+				continue
+			}
+			if values == nil {
+				values = make(map[string]struct{})
+			}
+			values[value] = struct{}{}
+		}
+	}
+	return values
+}
+
+func matchValue(values map[string]struct{}, value string) bool {
+	if values == nil {
+		return true
+	}
+	_, ok := values[value]
+	return ok
+}
+
+type notificationFields struct {
+	flagKey      string
+	ruleKey      string
+	userID       string
+	decisionType string
+	eventKey     string
+}
+
+// getNotificationFields returns the fields of the notification message, which is either sent by the notification
+// center or decoded from its JSON when it was synchronized.
+func getNotificationFields(event syncer.Event) notificationFields {
+	var fields notificationFields
+
+	switch event.Type {
+	case notification.Decision:
+		var decisionInfo map[string]interface{}
+		switch n := event.Message.(type) {
+		case notification.DecisionNotification:
+			fields.decisionType, fields.userID, decisionInfo = string(n.Type), n.UserContext.ID, n.DecisionInfo
+		case *notification.DecisionNotification:
+			fields.decisionType, fields.userID, decisionInfo = string(n.Type), n.UserContext.ID, n.DecisionInfo
+		case map[string]interface{}:
+			fields.decisionType = lookupString(n, "Type")
+			fields.userID = lookupString(n, "UserContext", "ID")
+			decisionInfo, _ = n["DecisionInfo"].(map[string]interface{})
+		}
+		// Flag decisions have the keys of their flag and rule, feature decisions have those of their feature
+		// and feature test, while experiment decisions only have their experiment key
+		fields.flagKey = firstString(lookupString(decisionInfo, "flagKey"), lookupString(decisionInfo, "feature", "featureKey"))
+		fields.ruleKey = firstString(lookupString(decisionInfo, "ruleKey"), lookupString(decisionInfo, "experimentKey"),
+			lookupString(decisionInfo, "feature", "sourceInfo", "experimentKey"))
+	case notification.Track:
+		switch n := event.Message.(type) {
+		case notification.TrackNotification:
+			fields.eventKey, fields.userID = n.EventKey, n.UserContext.ID
+		case *notification.TrackNotification:
+			fields.eventKey, fields.userID = n.EventKey, n.UserContext.ID
+		case map[string]interface{}:
+			fields.eventKey = lookupString(n, "EventKey")
+			fields.userID = lookupString(n, "UserContext", "ID")
+		}
+	}
+
+	return fields
+}
+
+// lookupString returns the string at the path of nested maps, or an empty string when there is none
+func lookupString(v interface{}, path ...string) string {
+	for _, key := range path {
+		switch m := v.(type) {
+		case map[string]interface{}:
+			v = m[key]
+		case map[string]string:
+			v = m[key]
+		default:
+			return ""
+		}
+	}
+	s, _ := v.(string)
+	return s
+}
+
+func firstString(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,122 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/optimizely/go-sdk/pkg/decision"
+	"github.com/optimizely/go-sdk/pkg/entities"
+	"github.com/optimizely/go-sdk/pkg/notification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optimizely/agent/pkg/syncer"
+)
+
+// synchronized returns the event as it is received from Redis, with its message decoded from JSON
+func synchronized(t *testing.T, event syncer.Event) syncer.Event {
+	b, err := json.Marshal(event)
+	require.NoError(t, err)
+	var out syncer.Event
+	require.NoError(t, json.Unmarshal(b, &out))
+	return out
+}
+
+func TestNotificationMessageFilter(t *testing.T) {
+	user := entities.UserContext{ID: "user1"}
+	flagDecision := syncer.Event{
+		Type:    notification.Decision,
+		Message: *decision.FlagNotification("flag1", "on", "rule1", true, false, user, nil, nil),
+	}
+	featureDecision := syncer.Event{
+		Type: notification.Decision,
+		Message: decision.FeatureNotification("feature1", &decision.FeatureDecision{
+			Source:     decision.FeatureTest,
+			Experiment: entities.Experiment{Key: "test1"},
+			Variation:  &entities.Variation{Key: "variation1"},
+		}, &user),
+	}
+	experimentDecision := syncer.Event{
+		Type: notification.Decision,
+		Message: notification.DecisionNotification{
+			Type:         notification.ABTest,
+			UserContext:  entities.UserContext{ID: "user2"},
+			DecisionInfo: map[string]interface{}{"experimentKey": "experiment1", "variationKey": "variation1"},
+		},
+	}
+	track := syncer.Event{
+		Type:    notification.Track,
+		Message: notification.TrackNotification{EventKey: "purchase", UserContext: user},
+	}
+	configUpdate := syncer.Event{
+		Type:    notification.ProjectConfigUpdate,
+		Message: notification.ProjectConfigUpdateNotification{Type: notification.ProjectConfigUpdate, Revision: "1"},
+	}
+
+	scenarios := []struct {
+		name     string
+		query    string
+		expected []syncer.Event
+	}{
+		{"none", "", []syncer.Event{flagDecision, featureDecision, experimentDecision, track, configUpdate}},
+		{"flag key", "flagKey=flag1,feature1", []syncer.Event{flagDecision, featureDecision}},
+		{"rule key", "ruleKey=rule1&ruleKey=test1&ruleKey=experiment1", []syncer.Event{flagDecision, featureDecision, experimentDecision}},
+		{"user id", "userId=user1", []syncer.Event{flagDecision, featureDecision, track}},
+		{"decision type", "decisionType=flag,ab-test", []syncer.Event{flagDecision, experimentDecision}},
+		{"event key", "eventKey=purchase", []syncer.Event{track}},
+		{"every field", "flagKey=flag1&userId=user1&decisionType=feature", []syncer.Event{}},
+		{"unknown value", "userId=unknown", []syncer.Event{}},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			query, err := url.ParseQuery(scenario.query)
+			require.NoError(t, err)
+			filter := NewNotificationMessageFilter(query)
+			if scenario.query == "" {
+				assert.Nil(t, filter)
+			}
+
+			for _, event := range []syncer.Event{flagDecision, featureDecision, experimentDecision, track, configUpdate} {
+				expected := false
+				for _, e := range scenario.expected {
+					expected = expected || assert.ObjectsAreEqual(e, event)
+				}
+				assert.Equal(t, expected, filter.Match(event), "%v", event.Message)
+				assert.Equal(t, expected, filter.Match(synchronized(t, event)), "synchronized %v", event.Message)
+			}
+		})
+	}
+}
+
+func TestNotificationMessageFilterPointers(t *testing.T) {
+	filter := NewNotificationMessageFilter(url.Values{"userId": {"user1"}})
+
+	assert.True(t, filter.Match(syncer.Event{
+		Type:    notification.Decision,
+		Message: decision.FlagNotification("flag1", "on", "rule1", true, false, entities.UserContext{ID: "user1"}, nil, nil),
+	}))
+	assert.True(t, filter.Match(syncer.Event{
+		Type:    notification.Track,
+		Message: &notification.TrackNotification{EventKey: "purchase", UserContext: entities.UserContext{ID: "user1"}},
+	}))
+	assert.False(t, filter.Match(syncer.Event{Type: notification.Track, Message: "unexpected"}))
+}
@@ -0,0 +1,288 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/optimizely/agent/pkg/optimizely"
+	"github.com/optimizely/agent/pkg/syncer"
+)
+
+// minSubscriberBuffer is the minimum number of notifications a subscriber can fall behind before it is closed
+const minSubscriberBuffer = 16
+
+// defaultHistoryGracePeriod is how long the notifications of an SDK key are still received, and kept, once its last
+// subscriber left. A stream reconnecting within it resumes from the notifications it missed.
+const defaultHistoryGracePeriod = time.Minute
+
+// notificationIDSeed returns the ID after which the notifications of a new log are numbered. Seeding the logs with
+// the time keeps the IDs growing when the log of an SDK key is rebuilt, even after a restart, so that a stream
+// resuming from the ID of a previous log never skips the notifications of the new one.
+var notificationIDSeed = func() uint64 {
+	return uint64(time.Now().UnixNano())
+}
+
+// NotificationEvent is a notification numbered by the notification history of its SDK key
+type NotificationEvent struct {
+	ID uint64
+	syncer.Event
+}
+
+// NotificationHistory numbers the notifications of each SDK key and keeps the most recent ones, so that the streams
+// can resume from the last notification they received. The notifications of an SDK key are received from the
+// notification receiver once a stream subscribes to them, until a grace period after its last subscriber left.
+type NotificationHistory struct {
+	notificationReceiverFn NotificationReceiverFunc
+	size                   int
+	gracePeriod            time.Duration
+
+	mu      sync.Mutex
+	streams map[string]*notificationLog
+	// lastIDs are the last IDs of the logs which ended, the next log of the SDK key is numbered after them
+	lastIDs map[string]uint64
+}
+
+// NewNotificationHistory returns a NotificationHistory keeping the last size notifications of each SDK key
+func NewNotificationHistory(notificationReceiverFn NotificationReceiverFunc, size int) *NotificationHistory {
+	return &NotificationHistory{
+		notificationReceiverFn: notificationReceiverFn,
+		size:                   size,
+		gracePeriod:            defaultHistoryGracePeriod,
+		streams:                make(map[string]*notificationLog),
+		lastIDs:                make(map[string]uint64),
+	}
+}
+
+// Subscribe returns the kept notifications of the SDK key following the lastEventID, when it is set, and the
+// channel of the notifications to come. The channel is closed if the subscriber falls too far behind, it can then
+// subscribe again from its last notification. The subscription ends with the context.
+func (h *NotificationHistory) Subscribe(ctx context.Context, sdkKey string, lastEventID *uint64) ([]NotificationEvent, <-chan NotificationEvent, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	l, err := h.getLog(sdkKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if l.idle != nil {
+		l.idle.Stop()
+		l.idle = nil
+	}
+	replay, events := l.subscribe(lastEventID)
+	go func() {
+		<-ctx.Done()
+		h.unsubscribe(sdkKey, l, events)
+	}()
+
+	return replay, events, nil
+}
+
+// getLog returns the log of the SDK key, starting to receive its notifications if needed. h.mu must be held.
+func (h *NotificationHistory) getLog(sdkKey string) (*notificationLog, error) {
+	if l, ok := h.streams[sdkKey]; ok {
+		return l, nil
+	}
+
+	logger := log.Logger
+	if optimizely.ShouldIncludeSDKKey {
+		logger = logger.With().Str("sdkKey", strings.Split(sdkKey, ":")[0]).Logger()
+	}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), SDKKey, sdkKey))
+	dataChan, err := h.notificationReceiverFn(context.WithValue(ctx, LoggerKey, &logger))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	seed := notificationIDSeed()
+	if lastID := h.lastIDs[sdkKey]; seed < lastID {
+		seed = lastID
+	}
+	l := newNotificationLog(h.size, seed)
+	l.stop = cancel
+	h.streams[sdkKey] = l
+	go func() {
+		defer func() {
+			// The next subscription receives the notifications again
+			h.mu.Lock()
+			if h.streams[sdkKey] == l {
+				h.remove(sdkKey, l)
+			}
+			h.mu.Unlock()
+			cancel()
+			l.closeSubscribers()
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-dataChan:
+				if !ok {
+					return
+				}
+				l.add(event)
+			}
+		}
+	}()
+
+	return l, nil
+}
+
+// unsubscribe ends the subscription, the notifications of the SDK key stop being received once the grace period
+// passes without any other subscription
+func (h *NotificationHistory) unsubscribe(sdkKey string, l *notificationLog, subscriber chan NotificationEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if l.unsubscribe(subscriber) > 0 || h.streams[sdkKey] != l {
+		return
+	}
+
+	var idle *time.Timer
+	idle = time.AfterFunc(h.gracePeriod, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		// The log was subscribed to again since
+		if l.idle != idle {
+			return
+		}
+		h.remove(sdkKey, l)
+		l.stop()
+	})
+	l.idle = idle
+}
+
+// remove removes the log of the SDK key, keeping its last ID. h.mu must be held.
+func (h *NotificationHistory) remove(sdkKey string, l *notificationLog) {
+	delete(h.streams, sdkKey)
+	h.lastIDs[sdkKey] = l.currentID()
+}
+
+// notificationLog is the ring buffer of the last notifications of an SDK key
+type notificationLog struct {
+	// stop stops receiving the notifications, idle does once the grace period passes. Both are guarded by the
+	// NotificationHistory mutex.
+	stop context.CancelFunc
+	idle *time.Timer
+
+	mu          sync.Mutex
+	lastID      uint64
+	events      []NotificationEvent
+	start       int
+	subscribers map[chan NotificationEvent]struct{}
+}
+
+// newNotificationLog returns a log keeping the last size notifications, numbered after lastID
+func newNotificationLog(size int, lastID uint64) *notificationLog {
+	return &notificationLog{
+		lastID:      lastID,
+		events:      make([]NotificationEvent, 0, size),
+		subscribers: make(map[chan NotificationEvent]struct{}),
+	}
+}
+
+func (l *notificationLog) add(event syncer.Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lastID++
+	e := NotificationEvent{ID: l.lastID, Event: event}
+	switch {
+	case cap(l.events) == 0:
+	case len(l.events) < cap(l.events):
+		l.events = append(l.events, e)
+	default:
+		l.events[l.start] = e
+		l.start = (l.start + 1) % len(l.events)
+	}
+
+	for subscriber := range l.subscribers {
+		select {
+		case subscriber <- e:
+		default:
+			// The subscriber fell behind, it resumes from the kept notifications by subscribing again
+			delete(l.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+func (l *notificationLog) subscribe(lastEventID *uint64) ([]NotificationEvent, chan NotificationEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var replay []NotificationEvent
+	if lastEventID != nil {
+		// An ID that was not given yet was given by another node, or before the clock went back, every kept
+		// notification is then missed
+		resumeAfter := *lastEventID
+		if resumeAfter > l.lastID {
+			resumeAfter = 0
+		}
+		for i := range l.events {
+			if e := l.events[(l.start+i)%len(l.events)]; e.ID > resumeAfter {
+				replay = append(replay, e)
+			}
+		}
+	}
+
+	size := cap(l.events)
+	if size < minSubscriberBuffer {
+		size = minSubscriberBuffer
+	}
+	subscriber := make(chan NotificationEvent, size)
+	l.subscribers[subscriber] = struct{}{}
+	return replay, subscriber
+}
+
+func (l *notificationLog) currentID() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lastID
+}
+
+// unsubscribe removes the subscriber, it returns the number of remaining subscribers
+func (l *notificationLog) unsubscribe(subscriber chan NotificationEvent) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.subscribers[subscriber]; ok {
+		delete(l.subscribers, subscriber)
+		close(subscriber)
+	}
+	return len(l.subscribers)
+}
+
+func (l *notificationLog) closeSubscribers() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for subscriber := range l.subscribers {
+		delete(l.subscribers, subscriber)
+		close(subscriber)
+	}
+}
@@ -0,0 +1,278 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/optimizely/go-sdk/pkg/notification"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/optimizely/agent/pkg/syncer"
+)
+
+// channelReceiver returns a NotificationReceiverFunc streaming the events sent on the channel of its SDK key
+func channelReceiver(channels map[string]chan syncer.Event) NotificationReceiverFunc {
+	return func(ctx context.Context) (<-chan syncer.Event, error) {
+		sdkKey, _ := ctx.Value(SDKKey).(string)
+		events, ok := channels[sdkKey]
+		if !ok {
+			return nil, errors.New("unknown sdk key")
+		}
+		return events, nil
+	}
+}
+
+// sendEvents sends a track notification for each message
+func sendEvents(events chan<- syncer.Event, messages ...string) {
+	for _, message := range messages {
+		events <- syncer.Event{Type: notification.Track, Message: message}
+	}
+}
+
+// pinNotificationIDs numbers the notifications of the new logs from 1, the returned func restores the seed
+func pinNotificationIDs() func() {
+	seed := notificationIDSeed
+	notificationIDSeed = func() uint64 { return 0 }
+	return func() { notificationIDSeed = seed }
+}
+
+func eventIDs(events []NotificationEvent) []uint64 {
+	ids := make([]uint64, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestNotificationHistoryReplay(t *testing.T) {
+	defer pinNotificationIDs()()
+	events := make(chan syncer.Event)
+	history := NewNotificationHistory(channelReceiver(map[string]chan syncer.Event{"sdkKey": events}), 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	replay, live, err := history.Subscribe(ctx, "sdkKey", nil)
+	assert.NoError(t, err)
+	assert.Empty(t, replay)
+
+	sendEvents(events, "1", "2", "3", "4", "5")
+	for i := uint64(1); i <= 5; i++ {
+		e := <-live
+		assert.Equal(t, i, e.ID)
+	}
+
+	lastEventID := uint64(3)
+	replay, _, err = history.Subscribe(ctx, "sdkKey", &lastEventID)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{4, 5}, eventIDs(replay))
+	assert.Equal(t, "4", replay[0].Message)
+
+	// The oldest notifications are no longer kept
+	lastEventID = 0
+	replay, _, err = history.Subscribe(ctx, "sdkKey", &lastEventID)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{3, 4, 5}, eventIDs(replay))
+
+	// An ID from another node replays every kept notification
+	lastEventID = 100
+	replay, _, err = history.Subscribe(ctx, "sdkKey", &lastEventID)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{3, 4, 5}, eventIDs(replay))
+}
+
+func TestNotificationHistorySDKKeys(t *testing.T) {
+	defer pinNotificationIDs()()
+	channels := map[string]chan syncer.Event{"one": make(chan syncer.Event), "two": make(chan syncer.Event)}
+	history := NewNotificationHistory(channelReceiver(channels), 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, one, err := history.Subscribe(ctx, "one", nil)
+	assert.NoError(t, err)
+	_, two, err := history.Subscribe(ctx, "two", nil)
+	assert.NoError(t, err)
+
+	sendEvents(channels["one"], "a", "b")
+	sendEvents(channels["two"], "c")
+
+	assert.Equal(t, NotificationEvent{ID: 1, Event: syncer.Event{Type: notification.Track, Message: "a"}}, <-one)
+	assert.Equal(t, NotificationEvent{ID: 2, Event: syncer.Event{Type: notification.Track, Message: "b"}}, <-one)
+	assert.Equal(t, NotificationEvent{ID: 1, Event: syncer.Event{Type: notification.Track, Message: "c"}}, <-two)
+
+	_, _, err = history.Subscribe(ctx, "three", nil)
+	assert.EqualError(t, err, "unknown sdk key")
+}
+
+func TestNotificationHistoryUnsubscribe(t *testing.T) {
+	events := make(chan syncer.Event)
+	history := NewNotificationHistory(channelReceiver(map[string]chan syncer.Event{"sdkKey": events}), 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, live, err := history.Subscribe(ctx, "sdkKey", nil)
+	assert.NoError(t, err)
+
+	cancel()
+	select {
+	case _, ok := <-live:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		assert.Fail(t, "subscription did not end with its context")
+	}
+}
+
+func TestNotificationHistorySlowSubscriber(t *testing.T) {
+	events := make(chan syncer.Event)
+	history := NewNotificationHistory(channelReceiver(map[string]chan syncer.Event{"sdkKey": events}), 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, live, err := history.Subscribe(ctx, "sdkKey", nil)
+	assert.NoError(t, err)
+
+	for i := 0; i <= minSubscriberBuffer; i++ {
+		events <- syncer.Event{Type: notification.Track}
+	}
+
+	received := 0
+	for range live {
+		received++
+	}
+	assert.Equal(t, minSubscriberBuffer, received)
+}
+
+func TestNotificationHistoryReceiverStopped(t *testing.T) {
+	events := make(chan syncer.Event)
+	calls := 0
+	history := NewNotificationHistory(func(ctx context.Context) (<-chan syncer.Event, error) {
+		calls++
+		return events, nil
+	}, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, live, err := history.Subscribe(ctx, "sdkKey", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	close(events)
+	_, ok := <-live
+	assert.False(t, ok)
+
+	events = make(chan syncer.Event)
+	_, _, err = history.Subscribe(ctx, "sdkKey", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestNotificationHistoryGracePeriod(t *testing.T) {
+	defer pinNotificationIDs()()
+	events := make(chan syncer.Event)
+	receivers := make(chan context.Context, 2)
+	history := NewNotificationHistory(func(ctx context.Context) (<-chan syncer.Event, error) {
+		receivers <- ctx
+		return events, nil
+	}, 10)
+	history.gracePeriod = 50 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, live, err := history.Subscribe(ctx, "sdkKey", nil)
+	assert.NoError(t, err)
+	receiver := <-receivers
+	sendEvents(events, "1", "2")
+	<-live
+	<-live
+
+	// The notifications are still received, and kept, within the grace period
+	cancel()
+	assert.Eventually(t, func() bool {
+		_, ok := <-live
+		return !ok
+	}, time.Second, time.Millisecond)
+	sendEvents(events, "3")
+	assert.Eventually(t, func() bool {
+		history.mu.Lock()
+		l := history.streams["sdkKey"]
+		history.mu.Unlock()
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.lastID == 3
+	}, time.Second, time.Millisecond)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	lastEventID := uint64(1)
+	replay, _, err := history.Subscribe(ctx, "sdkKey", &lastEventID)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{2, 3}, eventIDs(replay))
+	assert.Empty(t, receivers)
+
+	// The notifications stop being received once the grace period passes without subscribers
+	time.Sleep(2 * history.gracePeriod)
+	assert.NoError(t, receiver.Err())
+	cancel()
+	select {
+	case <-receiver.Done():
+	case <-time.After(time.Second):
+		assert.Fail(t, "the receiver was not stopped")
+	}
+
+	_, _, err = history.Subscribe(context.Background(), "sdkKey", &lastEventID)
+	assert.NoError(t, err)
+	assert.Len(t, receivers, 1)
+}
+
+func TestNotificationHistoryIDsGrow(t *testing.T) {
+	events := make(chan syncer.Event)
+	history := NewNotificationHistory(func(ctx context.Context) (<-chan syncer.Event, error) {
+		return events, nil
+	}, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, live, err := history.Subscribe(ctx, "sdkKey", nil)
+	assert.NoError(t, err)
+	sendEvents(events, "1")
+	first := <-live
+	assert.Greater(t, first.ID, uint64(1))
+
+	// The IDs of the next log of the SDK key follow the IDs of the previous one
+	close(events)
+	_, ok := <-live
+	assert.False(t, ok)
+	events = make(chan syncer.Event)
+	replay, live, err := history.Subscribe(ctx, "sdkKey", &first.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, replay)
+	sendEvents(events, "2")
+	second := <-live
+	assert.Greater(t, second.ID, first.ID)
+
+	// Even when the clock goes back
+	defer pinNotificationIDs()()
+	close(events)
+	_, ok = <-live
+	assert.False(t, ok)
+	events = make(chan syncer.Event)
+	_, live, err = history.Subscribe(ctx, "sdkKey", &second.ID)
+	assert.NoError(t, err)
+	sendEvents(events, "3")
+	assert.Equal(t, second.ID+1, (<-live).ID)
+}
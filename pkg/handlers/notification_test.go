@@ -36,13 +36,15 @@ import (
 	"github.com/optimizely/go-sdk/pkg/entities"
 	"github.com/optimizely/go-sdk/pkg/notification"
 	"github.com/optimizely/go-sdk/pkg/registry"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
 
 type NotificationTestSuite struct {
 	suite.Suite
-	tc  *optimizelytest.TestClient
-	mux *chi.Mux
+	tc            *optimizelytest.TestClient
+	mux           *chi.Mux
+	restoreIDSeed func()
 }
 
 type NotificationMW struct {
@@ -58,6 +60,7 @@ func (o *NotificationMW) ClientCtx(next http.Handler) http.Handler {
 
 // Setup Mux
 func (suite *NotificationTestSuite) SetupTest() {
+	suite.restoreIDSeed = pinNotificationIDs()
 	testClient := optimizelytest.NewClient()
 	optlyClient := &optimizely.OptlyClient{
 		OptimizelyClient: testClient.OptimizelyClient,
@@ -74,9 +77,13 @@ func (suite *NotificationTestSuite) SetupTest() {
 	suite.tc = testClient
 }
 
+func (suite *NotificationTestSuite) TearDownTest() {
+	suite.restoreIDSeed()
+}
+
 func (suite *NotificationTestSuite) TestFeatureTestFilter() {
 	conf := config.NewDefaultConfig()
-	suite.mux.Get("/notifications/event-stream", NotificationEventStreamHandler(getMockNotificationReceiver(conf.Synchronization, false)))
+	suite.mux.Get("/notifications/event-stream", NotificationEventStreamHandler(getMockNotificationReceiver(conf.Synchronization, false), conf.API.EventStream))
 
 	feature := entities.Feature{Key: "one"}
 	suite.tc.AddFeatureTest(feature)
@@ -112,7 +119,7 @@ func (suite *NotificationTestSuite) TestFeatureTestFilter() {
 func (suite *NotificationTestSuite) TestFilter() {
 	filter := []string{"decision", "track"}
 
-	notifications := getFilter(filter)
+	notifications := NotificationFilter(filter)
 
 	suite.True(len(notifications) == 2)
 	suite.EqualValues(notification.Track, notifications["track"])
@@ -120,7 +127,7 @@ func (suite *NotificationTestSuite) TestFilter() {
 
 	filter = []string{"decision,track", "track"}
 
-	notifications = getFilter(filter)
+	notifications = NotificationFilter(filter)
 
 	suite.True(len(notifications) == 2)
 	suite.EqualValues(notification.Track, notifications["track"])
@@ -134,7 +141,7 @@ func (suite *NotificationTestSuite) TestTrackAndProjectConfig() {
 	req := httptest.NewRequest("GET", "/notifications/event-stream", nil)
 	rec := httptest.NewRecorder()
 
-	expected := `data: {"test":"value"}` + "\n\n" + `data: {"Type":"project_config_update","Revision":"revision"}` + "\n\n"
+	expected := "id: 1\n" + `data: {"test":"value"}` + "\n\n" + "id: 2\n" + `data: {"Type":"project_config_update","Revision":"revision"}` + "\n\n"
 
 	// create a cancelable request context
 	ctx := req.Context()
@@ -162,7 +169,7 @@ func (suite *NotificationTestSuite) TestTrackAndProjectConfig() {
 	}()
 
 	conf := config.NewDefaultConfig()
-	suite.mux.Get("/notifications/event-stream", NotificationEventStreamHandler(getMockNotificationReceiver(conf.Synchronization, false, notifications...)))
+	suite.mux.Get("/notifications/event-stream", NotificationEventStreamHandler(getMockNotificationReceiver(conf.Synchronization, false, notifications...), conf.API.EventStream))
 
 	suite.mux.ServeHTTP(rec, req.WithContext(ctx1))
 
@@ -180,7 +187,7 @@ func (suite *NotificationTestSuite) TestTrackAndProjectConfigWithSynchronization
 	req := httptest.NewRequest("GET", "/notifications/event-stream", nil)
 	rec := httptest.NewRecorder()
 
-	expected := `data: {"test":"value"}` + "\n\n" + `data: {"Type":"project_config_update","Revision":"revision"}` + "\n\n"
+	expected := "id: 1\n" + `data: {"test":"value"}` + "\n\n" + "id: 2\n" + `data: {"Type":"project_config_update","Revision":"revision"}` + "\n\n"
 
 	// create a cancelable request context
 	ctx := req.Context()
@@ -221,7 +228,7 @@ func (suite *NotificationTestSuite) TestTrackAndProjectConfigWithSynchronization
 			Default: "redis",
 		},
 	}
-	suite.mux.Get("/notifications/event-stream", NotificationEventStreamHandler(getMockNotificationReceiver(conf.Synchronization, false, notifications...)))
+	suite.mux.Get("/notifications/event-stream", NotificationEventStreamHandler(getMockNotificationReceiver(conf.Synchronization, false, notifications...), conf.API.EventStream))
 
 	suite.mux.ServeHTTP(rec, req.WithContext(ctx1))
 
@@ -257,7 +264,7 @@ func (suite *NotificationTestSuite) TestActivateExperimentRaw() {
 	}()
 
 	conf := config.NewDefaultConfig()
-	suite.mux.Get("/notifications/event-stream", NotificationEventStreamHandler(getMockNotificationReceiver(conf.Synchronization, false, notifications...)))
+	suite.mux.Get("/notifications/event-stream", NotificationEventStreamHandler(getMockNotificationReceiver(conf.Synchronization, false, notifications...), conf.API.EventStream))
 
 	suite.mux.ServeHTTP(rec, req.WithContext(ctx1))
 
@@ -288,13 +295,111 @@ func (suite *NotificationTestSuite) TestWithFailedNotificationReceiver() {
 	}()
 
 	conf := config.NewDefaultConfig()
-	suite.mux.Get("/notifications/event-stream", NotificationEventStreamHandler(getMockNotificationReceiver(conf.Synchronization, true, notifications...)))
+	suite.mux.Get("/notifications/event-stream", NotificationEventStreamHandler(getMockNotificationReceiver(conf.Synchronization, true, notifications...), conf.API.EventStream))
 
 	suite.mux.ServeHTTP(rec, req.WithContext(ctx1))
 
 	suite.Equal(http.StatusInternalServerError, rec.Code)
 }
 
+func (suite *NotificationTestSuite) TestStreamClosedOnShutdown() {
+	conf := config.NewDefaultConfig()
+	suite.mux.Get("/notifications/event-stream", NotificationEventStreamHandler(getMockNotificationReceiver(conf.Synchronization, false), conf.API.EventStream))
+
+	shutdown := make(chan struct{})
+	req := httptest.NewRequest("GET", "/notifications/event-stream", nil)
+	req = req.WithContext(middleware.WithShutdown(req.Context(), shutdown))
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		suite.mux.ServeHTTP(rec, req)
+		close(done)
+	}()
+
+	close(shutdown)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		suite.Fail("stream was not closed on shutdown")
+	}
+	suite.Equal(http.StatusOK, rec.Code)
+}
+
+func (suite *NotificationTestSuite) TestResumeFromLastEventID() {
+	events := make(chan syncer.Event)
+	conf := config.NewDefaultConfig()
+	suite.mux.Get("/notifications/event-stream", NotificationEventStreamHandler(channelReceiver(map[string]chan syncer.Event{"": events}), conf.API.EventStream))
+
+	// The first stream starts receiving the notifications, then disconnects
+	req := httptest.NewRequest("GET", "/notifications/event-stream?filter=track", nil)
+	ctx, cancel := context.WithCancel(req.Context())
+	cancel()
+	suite.mux.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+
+	// The last notification is filtered out, it is sent once the others are kept
+	sendEvents(events, "one", "two", "three")
+	events <- syncer.Event{Type: notification.Decision, Message: "four"}
+
+	req = httptest.NewRequest("GET", "/notifications/event-stream?filter=track", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	ctx, cancel = context.WithTimeout(req.Context(), 100*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req.WithContext(ctx))
+
+	suite.Equal(http.StatusOK, rec.Code)
+	suite.Equal("id: 2\ndata: \"two\"\n\nid: 3\ndata: \"three\"\n\n", rec.Body.String())
+}
+
+func (suite *NotificationTestSuite) TestMessageFilter() {
+	events := make(chan syncer.Event)
+	conf := config.NewDefaultConfig()
+	suite.mux.Get("/notifications/event-stream", NotificationEventStreamHandler(channelReceiver(map[string]chan syncer.Event{"": events}), conf.API.EventStream))
+
+	req := httptest.NewRequest("GET", "/notifications/event-stream?eventKey=purchase&userId=user1", nil)
+	ctx, cancel := context.WithTimeout(req.Context(), 500*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+
+	go func() {
+		events <- syncer.Event{Type: notification.Track, Message: notification.TrackNotification{EventKey: "purchase", UserContext: entities.UserContext{ID: "user2"}}}
+		events <- syncer.Event{Type: notification.Track, Message: notification.TrackNotification{EventKey: "signup", UserContext: entities.UserContext{ID: "user1"}}}
+		events <- syncer.Event{Type: notification.Track, Message: notification.TrackNotification{EventKey: "purchase", UserContext: entities.UserContext{ID: "user1"}}}
+		events <- syncer.Event{Type: notification.ProjectConfigUpdate}
+	}()
+
+	suite.mux.ServeHTTP(rec, req.WithContext(ctx))
+
+	suite.Equal(http.StatusOK, rec.Code)
+	suite.Equal("id: 3\n"+`data: {"EventKey":"purchase","UserContext":{"ID":"user1","Attributes":null,"QualifiedSegments":null},"EventTags":null,"ConversionEvent":null}`+"\n\n", rec.Body.String())
+}
+
+func (suite *NotificationTestSuite) TestHeartbeat() {
+	conf := config.NewDefaultConfig()
+	conf.API.EventStream.HeartbeatInterval = 10 * time.Millisecond
+	suite.mux.Get("/notifications/event-stream", NotificationEventStreamHandler(getMockNotificationReceiver(conf.Synchronization, false), conf.API.EventStream))
+
+	req := httptest.NewRequest("GET", "/notifications/event-stream", nil)
+	ctx, cancel := context.WithTimeout(req.Context(), 100*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req.WithContext(ctx))
+
+	suite.Equal(http.StatusOK, rec.Code)
+	suite.Contains(rec.Body.String(), ": heartbeat\n\n")
+
+	// Raw JSON lines have no heartbeats
+	req = httptest.NewRequest("GET", "/notifications/event-stream?raw=yes", nil)
+	ctx, cancel = context.WithTimeout(req.Context(), 100*time.Millisecond)
+	defer cancel()
+	rec = httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req.WithContext(ctx))
+
+	suite.Equal(http.StatusOK, rec.Code)
+	suite.Empty(rec.Body.String())
+}
+
 func (suite *NotificationTestSuite) assertError(rec *httptest.ResponseRecorder, msg string, code int) {
 	assertError(suite.T(), rec, msg, code)
 }
@@ -314,7 +419,7 @@ func TestEventStreamMissingOptlyCtx(t *testing.T) {
 
 	conf := config.NewDefaultConfig()
 	handlers := []func(w http.ResponseWriter, r *http.Request){
-		NotificationEventStreamHandler(getMockNotificationReceiver(conf.Synchronization, false)),
+		NotificationEventStreamHandler(getMockNotificationReceiver(conf.Synchronization, false), conf.API.EventStream),
 	}
 
 	for _, handler := range handlers {
@@ -361,6 +466,27 @@ func TestDefaultNotificationReceiver(t *testing.T) {
 	}
 }
 
+func TestDefaultNotificationReceiverTypes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), SDKKey, "receiverTypes"))
+	defer cancel()
+	events, err := DefaultNotificationReceiver(ctx)
+	assert.NoError(t, err)
+
+	nc := registry.GetNotificationCenter("receiverTypes")
+	for _, notificationType := range []notification.Type{notification.Decision, notification.Track, notification.ProjectConfigUpdate} {
+		go func(notificationType notification.Type) {
+			assert.NoError(t, nc.Send(notificationType, string(notificationType)))
+		}(notificationType)
+
+		select {
+		case event := <-events:
+			assert.Equal(t, syncer.Event{Type: notificationType, Message: string(notificationType)}, event)
+		case <-time.After(time.Second):
+			assert.Fail(t, "notification was not received", notificationType)
+		}
+	}
+}
+
 func TestRedisNotificationReceiver(t *testing.T) {
 	conf := config.SyncConfig{
 		Pubsub: map[string]interface{}{
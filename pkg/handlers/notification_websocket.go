@@ -0,0 +1,231 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/optimizely/go-sdk/pkg/notification"
+	"github.com/rs/zerolog"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/pkg/middleware"
+)
+
+// NotificationWebSocketMessage is a notification sent over the WebSocket
+type NotificationWebSocketMessage struct {
+	Type    notification.Type `json:"type"`
+	Message interface{}       `json:"message"`
+}
+
+// NotificationSubscription is the message a client sends over the WebSocket to change the notification types
+// it receives. Every type is received when the filter is empty.
+type NotificationSubscription struct {
+	Filter []string `json:"filter"`
+}
+
+// NotificationWebSocketHandler streams the notifications over a WebSocket, as NotificationEventStreamHandler does
+// over SSE. The notification types are given by the filter query parameter, then by the subscriptions the
+// client sends. Connections are pinged to be kept alive and are closed with the reason they end.
+// Notifications are buffered for each connection, a client falling too far behind is disconnected so that it never
+// holds up the notifications of the others.
+// Cross-origin connections are only accepted from the allowed origins, any origin is allowed when they are empty.
+func NotificationWebSocketHandler(notificationReceiverFn NotificationReceiverFunc, conf config.WebSocketConfig, allowedOrigins []string) http.HandlerFunc {
+	upgrader := websocket.Upgrader{CheckOrigin: checkOrigin(allowedOrigins)}
+	// No notification is kept, the history only buffers the notifications of each connection
+	history := NewNotificationHistory(notificationReceiverFn, 0)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := middleware.GetLogger(r)
+		if _, err := middleware.GetOptlyClient(r); err != nil {
+			RenderError(err, http.StatusUnprocessableEntity, w, r)
+			return
+		}
+
+		_ = r.ParseForm()
+		notificationsToAdd := NotificationFilter(r.Form["filter"])
+
+		// The request context is not canceled when the connection closes once it is hijacked
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		// The upgrader replies with an error when the request isn't a valid WebSocket handshake
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			logger.Debug().Err(err).Msg("failed to upgrade notifications WebSocket")
+			return
+		}
+		defer conn.Close()
+		ws := &notificationWebSocket{conn: conn, conf: conf, logger: logger}
+
+		sdkKey := r.Header.Get(middleware.OptlySDKHeader)
+		_, events, err := history.Subscribe(ctx, sdkKey, nil)
+		if err != nil {
+			logger.Err(err).Msg("error from receiver")
+			ws.close(websocket.CloseInternalServerErr, "Error from data receiver!")
+			return
+		}
+
+		subscriptions, closed := ws.readSubscriptions(ctx.Done())
+
+		var pings <-chan time.Time
+		if conf.PingInterval > 0 {
+			ticker := time.NewTicker(conf.PingInterval)
+			defer ticker.Stop()
+			pings = ticker.C
+		}
+
+		shutdown := middleware.ShuttingDown(r)
+		for {
+			select {
+			case <-shutdown:
+				ws.close(websocket.CloseGoingAway, "server is shutting down")
+				return
+			case err := <-closed:
+				var closeErr *websocket.CloseError
+				if errors.As(err, &closeErr) && closeErr.Code != websocket.CloseAbnormalClosure {
+					logger.Debug().Int("code", closeErr.Code).Str("reason", closeErr.Text).Msg("notifications WebSocket closed by the client")
+					return
+				}
+				logger.Debug().Err(err).Msg("notifications WebSocket connection lost")
+				return
+			case subscription, ok := <-subscriptions:
+				if !ok {
+					ws.close(websocket.CloseUnsupportedData, "invalid subscription message")
+					return
+				}
+				notificationsToAdd = NotificationFilter(subscription.Filter)
+				logger.Debug().Strs("filter", subscription.Filter).Msg("notifications WebSocket subscription changed")
+			case <-pings:
+				if err := conn.WriteControl(websocket.PingMessage, nil, ws.deadline()); err != nil {
+					logger.Debug().Err(err).Msg("failed to ping notifications WebSocket")
+					return
+				}
+			case event, ok := <-events:
+				if !ok {
+					ws.close(websocket.CloseTryAgainLater, "client fell behind the notifications")
+					return
+				}
+				if _, found := notificationsToAdd[event.Type]; !found {
+					continue
+				}
+
+				jsonEvent, err := json.Marshal(NotificationWebSocketMessage{Type: event.Type, Message: event.Message})
+				if err != nil {
+					logger.Err(err).Msg("failed to marshal notification into json")
+					continue
+				}
+
+				if err := conn.SetWriteDeadline(ws.deadline()); err != nil {
+					return
+				}
+				if err := conn.WriteMessage(websocket.TextMessage, jsonEvent); err != nil {
+					logger.Debug().Err(err).Msg("failed to write to notifications WebSocket")
+					return
+				}
+			}
+		}
+	}
+}
+
+type notificationWebSocket struct {
+	conn   *websocket.Conn
+	conf   config.WebSocketConfig
+	logger *zerolog.Logger
+}
+
+// readSubscriptions reads the messages of the client until the connection is closed or done is closed. The
+// subscriptions channel is closed when a message isn't a valid subscription, the closed channel receives the error
+// ending the connection. Reading is also what processes the pongs, each one extends the deadline of the connection.
+func (ws *notificationWebSocket) readSubscriptions(done <-chan struct{}) (<-chan NotificationSubscription, <-chan error) {
+	subscriptions := make(chan NotificationSubscription)
+	closed := make(chan error, 1)
+
+	if ws.conf.PingInterval > 0 && ws.conf.PongTimeout > 0 {
+		_ = ws.conn.SetReadDeadline(time.Now().Add(ws.conf.PongTimeout))
+		ws.conn.SetPongHandler(func(string) error {
+			return ws.conn.SetReadDeadline(time.Now().Add(ws.conf.PongTimeout))
+		})
+	}
+
+	go func() {
+		for {
+			var subscription NotificationSubscription
+			_, message, err := ws.conn.ReadMessage()
+			if err != nil {
+				closed <- err
+				return
+			}
+			if err := json.Unmarshal(message, &subscription); err != nil {
+				ws.logger.Debug().Err(err).Msg("invalid notifications WebSocket subscription")
+				close(subscriptions)
+				return
+			}
+			select {
+			case subscriptions <- subscription:
+			case <-done:
+				// The handler returned, nobody receives the subscription anymore
+				return
+			}
+		}
+	}()
+
+	return subscriptions, closed
+}
+
+// close sends a close message with its reason, the connection is then closed once the handler returns
+func (ws *notificationWebSocket) close(code int, reason string) {
+	if err := ws.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), ws.deadline()); err != nil {
+		ws.logger.Debug().Err(err).Msg("failed to close notifications WebSocket")
+	}
+}
+
+func (ws *notificationWebSocket) deadline() time.Time {
+	if ws.conf.WriteTimeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ws.conf.WriteTimeout)
+}
+
+// checkOrigin accepts same-origin requests and the cross-origin ones from the allowed origins
+func checkOrigin(allowedOrigins []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || len(allowedOrigins) == 0 {
+			return true
+		}
+
+		if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+
+		for _, allowed := range allowedOrigins {
+			if allowed == "*" || strings.EqualFold(allowed, origin) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,298 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+	"github.com/optimizely/go-sdk/pkg/notification"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/pkg/middleware"
+	"github.com/optimizely/agent/pkg/optimizely"
+	"github.com/optimizely/agent/pkg/optimizely/optimizelytest"
+	"github.com/optimizely/agent/pkg/syncer"
+)
+
+type NotificationWebSocketTestSuite struct {
+	suite.Suite
+	optlyClient *optimizely.OptlyClient
+	server      *httptest.Server
+	url         string
+	events      chan syncer.Event
+	done        chan struct{}
+	shutdown    chan struct{}
+	conf        config.WebSocketConfig
+	origins     []string
+	failed      bool
+}
+
+func (suite *NotificationWebSocketTestSuite) SetupTest() {
+	testClient := optimizelytest.NewClient()
+	suite.optlyClient = &optimizely.OptlyClient{
+		OptimizelyClient: testClient.OptimizelyClient,
+		ConfigManager:    nil,
+		ForcedVariations: testClient.ForcedVariations,
+	}
+
+	suite.events = make(chan syncer.Event)
+	suite.done = make(chan struct{})
+	suite.shutdown = make(chan struct{})
+	suite.conf = config.WebSocketConfig{PingInterval: time.Minute, PongTimeout: time.Minute, WriteTimeout: time.Second}
+	suite.origins = nil
+	suite.failed = false
+	suite.server = nil
+}
+
+func (suite *NotificationWebSocketTestSuite) TearDownTest() {
+	if suite.server != nil {
+		suite.server.Close()
+	}
+}
+
+// start serves the handler with the settings of the test. The receiver streams the events sent by the test,
+// done is closed once the handler first returns.
+func (suite *NotificationWebSocketTestSuite) start() {
+	events, done, shutdown, failed := suite.events, suite.done, suite.shutdown, suite.failed
+	receiver := func(ctx context.Context) (<-chan syncer.Event, error) {
+		if failed {
+			return nil, errors.New("mock error")
+		}
+		return events, nil
+	}
+	handler := NotificationWebSocketHandler(receiver, suite.conf, suite.origins)
+	var doneOnce sync.Once
+
+	mux := chi.NewMux()
+	mux.Use((&NotificationMW{suite.optlyClient}).ClientCtx)
+	mux.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(middleware.WithShutdown(r.Context(), shutdown)))
+		})
+	})
+	mux.Get("/notifications/ws", func(w http.ResponseWriter, r *http.Request) {
+		defer doneOnce.Do(func() { close(done) })
+		handler(w, r)
+	})
+
+	suite.server = httptest.NewServer(mux)
+	suite.url = "ws" + strings.TrimPrefix(suite.server.URL, "http") + "/notifications/ws"
+}
+
+func (suite *NotificationWebSocketTestSuite) dial(query string) *websocket.Conn {
+	suite.start()
+	conn, _, err := websocket.DefaultDialer.Dial(suite.url+query, nil)
+	suite.Require().NoError(err)
+	suite.Require().NoError(conn.SetReadDeadline(time.Now().Add(5 * time.Second)))
+	return conn
+}
+
+// send sends the event to the handler, unless it returns first
+func (suite *NotificationWebSocketTestSuite) send(event syncer.Event) {
+	select {
+	case suite.events <- event:
+	case <-suite.done:
+	case <-time.After(time.Second):
+		suite.Fail("event was not received by the handler")
+	}
+}
+
+func (suite *NotificationWebSocketTestSuite) assertDone() {
+	select {
+	case <-suite.done:
+	case <-time.After(time.Second):
+		suite.Fail("handler did not return")
+	}
+}
+
+func (suite *NotificationWebSocketTestSuite) TestFilter() {
+	conn := suite.dial("?filter=track")
+	defer conn.Close()
+
+	suite.send(syncer.Event{Type: notification.Decision, Message: map[string]string{"key": "decision"}})
+	suite.send(syncer.Event{Type: notification.Track, Message: map[string]string{"key": "track"}})
+
+	var message map[string]interface{}
+	suite.NoError(conn.ReadJSON(&message))
+	suite.Equal(map[string]interface{}{"type": "track", "message": map[string]interface{}{"key": "track"}}, message)
+}
+
+func (suite *NotificationWebSocketTestSuite) TestChangeFilter() {
+	conn := suite.dial("?filter=track")
+	defer conn.Close()
+
+	suite.NoError(conn.WriteJSON(NotificationSubscription{Filter: []string{"decision"}}))
+
+	// Decisions are sent until the new subscription applies, slowly enough for the handler to keep up
+	events, done, stop := suite.events, suite.done, make(chan struct{})
+	defer close(stop)
+	go func() {
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for range ticker.C {
+			select {
+			case events <- syncer.Event{Type: notification.Decision, Message: "decision"}:
+			case <-stop:
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var message NotificationWebSocketMessage
+	suite.NoError(conn.ReadJSON(&message))
+	suite.Equal(notification.Decision, message.Type)
+	suite.Equal("decision", message.Message)
+}
+
+func (suite *NotificationWebSocketTestSuite) TestInvalidSubscription() {
+	conn := suite.dial("")
+	defer conn.Close()
+
+	suite.NoError(conn.WriteMessage(websocket.TextMessage, []byte("invalid")))
+
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	suite.True(errors.As(err, &closeErr))
+	suite.Equal(websocket.CloseUnsupportedData, closeErr.Code)
+	suite.Equal("invalid subscription message", closeErr.Text)
+	suite.assertDone()
+}
+
+func (suite *NotificationWebSocketTestSuite) TestClosedByClient() {
+	conn := suite.dial("")
+	defer conn.Close()
+
+	suite.NoError(conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye")))
+	suite.assertDone()
+}
+
+func (suite *NotificationWebSocketTestSuite) TestClosedOnShutdown() {
+	conn := suite.dial("")
+	defer conn.Close()
+
+	close(suite.shutdown)
+
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	suite.True(errors.As(err, &closeErr))
+	suite.Equal(websocket.CloseGoingAway, closeErr.Code)
+	suite.Equal("server is shutting down", closeErr.Text)
+	suite.assertDone()
+}
+
+func (suite *NotificationWebSocketTestSuite) TestPing() {
+	suite.conf.PingInterval = 10 * time.Millisecond
+	conn := suite.dial("")
+	defer conn.Close()
+
+	pinged := make(chan struct{}, 1)
+	conn.SetPingHandler(func(string) error {
+		select {
+		case pinged <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-pinged:
+	case <-time.After(time.Second):
+		suite.Fail("connection was not pinged")
+	}
+}
+
+func (suite *NotificationWebSocketTestSuite) TestPongTimeout() {
+	suite.conf.PingInterval = 10 * time.Millisecond
+	suite.conf.PongTimeout = 50 * time.Millisecond
+	conn := suite.dial("")
+	defer conn.Close()
+
+	// The pings are not answered since the connection isn't read
+	suite.assertDone()
+}
+
+func (suite *NotificationWebSocketTestSuite) TestSlowClient() {
+	suite.conf.WriteTimeout = 0
+	conn := suite.dial("")
+	defer conn.Close()
+
+	// The connection isn't read until the socket buffers are full and the handler falls behind the notifications
+	message := strings.Repeat("x", 256*1024)
+	for i := 0; i < 200; i++ {
+		suite.send(syncer.Event{Type: notification.Track, Message: message})
+	}
+
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			var closeErr *websocket.CloseError
+			suite.True(errors.As(err, &closeErr))
+			suite.Equal(websocket.CloseTryAgainLater, closeErr.Code)
+			suite.Equal("client fell behind the notifications", closeErr.Text)
+			break
+		}
+	}
+	suite.assertDone()
+}
+
+func (suite *NotificationWebSocketTestSuite) TestFailedNotificationReceiver() {
+	suite.failed = true
+	conn := suite.dial("")
+	defer conn.Close()
+
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	suite.True(errors.As(err, &closeErr))
+	suite.Equal(websocket.CloseInternalServerErr, closeErr.Code)
+	suite.Equal("Error from data receiver!", closeErr.Text)
+}
+
+func (suite *NotificationWebSocketTestSuite) TestAllowedOrigins() {
+	suite.origins = []string{"http://allowed.com"}
+	suite.start()
+
+	_, resp, err := websocket.DefaultDialer.Dial(suite.url, http.Header{"Origin": {"http://denied.com"}})
+	suite.Error(err)
+	suite.Equal(http.StatusForbidden, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(suite.url, http.Header{"Origin": {"http://allowed.com"}})
+	suite.NoError(err)
+	conn.Close()
+}
+
+func TestNotificationWebSocketTestSuite(t *testing.T) {
+	suite.Run(t, new(NotificationWebSocketTestSuite))
+}
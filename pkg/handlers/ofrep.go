@@ -0,0 +1,308 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/rs/zerolog"
+
+	"github.com/optimizely/agent/pkg/middleware"
+	"github.com/optimizely/agent/pkg/optimizely"
+
+	"github.com/optimizely/go-sdk/pkg/client"
+	sdkconfig "github.com/optimizely/go-sdk/pkg/config"
+	"github.com/optimizely/go-sdk/pkg/decide"
+	"github.com/optimizely/go-sdk/pkg/decision"
+)
+
+// OFREP reason codes, see https://openfeature.dev/specification/types#resolution-details
+const (
+	// OFREPReasonSplit is the reason of the flags decided by an experiment rule
+	OFREPReasonSplit = "SPLIT"
+	// OFREPReasonTargetingMatch is the reason of the flags decided by a targeted delivery rule
+	OFREPReasonTargetingMatch = "TARGETING_MATCH"
+	// OFREPReasonStatic is the reason of the flags whose variation is forced for the user, by a forced decision,
+	// an override or the whitelist of an experiment
+	OFREPReasonStatic = "STATIC"
+	// OFREPReasonDefault is the reason of the flags decided by the "Everyone Else" delivery rule, or no rule at all
+	OFREPReasonDefault = "DEFAULT"
+	// OFREPReasonUnknown is the reason of the flags whose rule is missing from the datafile
+	OFREPReasonUnknown = "UNKNOWN"
+)
+
+// OFREP error codes
+const (
+	// OFREPErrorParse is the code of the requests whose body can't be parsed
+	OFREPErrorParse = "PARSE_ERROR"
+	// OFREPErrorTargetingKeyMissing is the code of the requests whose context has no targeting key
+	OFREPErrorTargetingKeyMissing = "TARGETING_KEY_MISSING"
+	// OFREPErrorInvalidContext is the code of the requests whose context is rejected by strict attribute validation
+	OFREPErrorInvalidContext = "INVALID_CONTEXT"
+	// OFREPErrorFlagNotFound is the code of the requests for a flag missing from the datafile
+	OFREPErrorFlagNotFound = "FLAG_NOT_FOUND"
+	// OFREPErrorGeneral is the code of the other errors
+	OFREPErrorGeneral = "GENERAL"
+)
+
+// ofrepTargetingKey is the evaluation context field holding the user ID
+const ofrepTargetingKey = "targetingKey"
+
+// OFREPEvaluationRequest defines the request body of the OFREP evaluation endpoints
+type OFREPEvaluationRequest struct {
+	// Context is the evaluation context, its targetingKey is the user ID and the other fields are user attributes
+	Context map[string]interface{} `json:"context"`
+}
+
+// OFREPEvaluation defines the result of the evaluation of a flag.
+// The type of Value is fixed by the definition of the flag in the datafile: it holds the variables of the flag as an
+// object when the flag defines variables, whether the flag is enabled as a boolean otherwise.
+type OFREPEvaluation struct {
+	Key      string                 `json:"key"`
+	Reason   string                 `json:"reason"`
+	Variant  string                 `json:"variant,omitempty"`
+	Value    interface{}            `json:"value"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// OFREPError defines the response of the evaluations which failed
+type OFREPError struct {
+	Key          string `json:"key,omitempty"`
+	ErrorCode    string `json:"errorCode"`
+	ErrorDetails string `json:"errorDetails,omitempty"`
+}
+
+// OFREPBulkEvaluation defines the response of the bulk evaluation endpoint
+type OFREPBulkEvaluation struct {
+	Flags []OFREPEvaluation `json:"flags"`
+}
+
+// OFREPEvaluateFlag evaluates the flag in the url following the OpenFeature Remote Evaluation Protocol.
+// A decision event is sent, as for decide requests.
+func OFREPEvaluateFlag(w http.ResponseWriter, r *http.Request) {
+	optlyClient, err := middleware.GetOptlyClient(r)
+	logger := middleware.GetLogger(r)
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	key := chi.URLParam(r, "key")
+	userID, attributes, ofrepErr := parseOFREPContext(r, optlyClient, logger)
+	if ofrepErr != nil {
+		ofrepErr.Key = key
+		renderOFREPError(ofrepErr, http.StatusBadRequest, w, r)
+		return
+	}
+
+	snapshot, projectConfig, err := ofrepSnapshot(optlyClient)
+	if err != nil {
+		renderOFREPError(&OFREPError{Key: key, ErrorCode: OFREPErrorGeneral, ErrorDetails: err.Error()}, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	if _, err := projectConfig.GetFeatureByKey(key); err != nil {
+		renderOFREPError(&OFREPError{Key: key, ErrorCode: OFREPErrorFlagNotFound, ErrorDetails: err.Error()}, http.StatusNotFound, w, r)
+		return
+	}
+
+	userContext := snapshot.CreateUserContext(userID, attributes)
+	SetForcedDecisions(optlyClient, &userContext, nil, logger)
+
+	logger.Debug().Str("featureKey", key).Msg("evaluating OFREP flag")
+	d := userContext.Decide(key, nil)
+	render.JSON(w, r, newOFREPEvaluation(projectConfig, optlyClient.ForcedVariations, &userContext, d))
+}
+
+// OFREPEvaluateFlags evaluates every flag following the OpenFeature Remote Evaluation Protocol.
+// No decision event is sent since providers cache the evaluations, whether they are used or not.
+// The response carries an ETag, a request whose If-None-Match matches it gets a 304.
+func OFREPEvaluateFlags(w http.ResponseWriter, r *http.Request) {
+	optlyClient, err := middleware.GetOptlyClient(r)
+	logger := middleware.GetLogger(r)
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	userID, attributes, ofrepErr := parseOFREPContext(r, optlyClient, logger)
+	if ofrepErr != nil {
+		renderOFREPError(ofrepErr, http.StatusBadRequest, w, r)
+		return
+	}
+
+	snapshot, projectConfig, err := ofrepSnapshot(optlyClient)
+	if err != nil {
+		renderOFREPError(&OFREPError{ErrorCode: OFREPErrorGeneral, ErrorDetails: err.Error()}, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	userContext := snapshot.CreateUserContext(userID, attributes)
+	SetForcedDecisions(optlyClient, &userContext, nil, logger)
+
+	decisions := userContext.DecideAll([]decide.OptimizelyDecideOptions{decide.DisableDecisionEvent})
+	out := OFREPBulkEvaluation{Flags: make([]OFREPEvaluation, 0, len(decisions))}
+	for _, d := range decisions {
+		out.Flags = append(out.Flags, newOFREPEvaluation(projectConfig, optlyClient.ForcedVariations, &userContext, d))
+	}
+	sort.Slice(out.Flags, func(i, j int) bool { return out.Flags[i].Key < out.Flags[j].Key })
+
+	body, err := json.Marshal(out)
+	if err != nil {
+		renderOFREPError(&OFREPError{ErrorCode: OFREPErrorGeneral, ErrorDetails: err.Error()}, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := fmt.Sprintf(`"%s"`, hex.EncodeToString(sum[:16]))
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body); err != nil {
+		logger.Error().Err(err).Msg("failed to write OFREP evaluations")
+	}
+}
+
+// parseOFREPContext returns the user ID and the attributes of the evaluation context of the request.
+// Stored user attributes are merged under the ones of the context, and strict attribute validation applies.
+func parseOFREPContext(r *http.Request, optlyClient *optimizely.OptlyClient, logger *zerolog.Logger) (string, map[string]interface{}, *OFREPError) {
+	var body OFREPEvaluationRequest
+	if err := ParseRequestBody(r, &body); err != nil {
+		return "", nil, &OFREPError{ErrorCode: OFREPErrorParse, ErrorDetails: err.Error()}
+	}
+
+	userID, _ := body.Context[ofrepTargetingKey].(string)
+	if userID == "" {
+		return "", nil, &OFREPError{ErrorCode: OFREPErrorTargetingKeyMissing, ErrorDetails: `missing "targetingKey" in evaluation context`}
+	}
+
+	attributes := make(map[string]interface{}, len(body.Context))
+	for name, value := range body.Context {
+		if name != ofrepTargetingKey {
+			attributes[name] = value
+		}
+	}
+
+	if warnings, rejected := ValidateAttributes(optlyClient, attributes, logger); rejected {
+		messages := make([]string, 0, len(warnings))
+		for _, warning := range warnings {
+			messages = append(messages, warning.Message)
+		}
+		return "", nil, &OFREPError{ErrorCode: OFREPErrorInvalidContext, ErrorDetails: strings.Join(messages, "; ")}
+	}
+
+	return userID, MergeUserAttributes(optlyClient, userID, attributes, logger), nil
+}
+
+// ofrepSnapshot returns a copy of the client pinned to the current project config, along with that config,
+// so that the reasons are looked up in the datafile revision the decisions are made with
+func ofrepSnapshot(optlyClient *optimizely.OptlyClient) (*client.OptimizelyClient, sdkconfig.ProjectConfig, error) {
+	snapshot, err := optlyClient.Snapshot()
+	if err != nil {
+		return nil, nil, err
+	}
+	projectConfig, err := snapshot.ConfigManager.GetConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	return snapshot, projectConfig, nil
+}
+
+// newOFREPEvaluation maps the decision of a flag to its OFREP evaluation. Its value is an object when the flag
+// defines variables and a boolean otherwise, whatever the decision, so that the type a flag resolves to never changes.
+func newOFREPEvaluation(projectConfig sdkconfig.ProjectConfig, overrides decision.ExperimentOverrideStore, userContext *client.OptimizelyUserContext, d client.OptimizelyDecision) OFREPEvaluation {
+	var value interface{} = d.Enabled
+	if feature, err := projectConfig.GetFeatureByKey(d.FlagKey); err == nil && len(feature.VariableMap) > 0 {
+		value = d.Variables.ToMap()
+	}
+
+	metadata := map[string]interface{}{"enabled": d.Enabled}
+	if d.RuleKey != "" {
+		metadata["ruleKey"] = d.RuleKey
+	}
+
+	return OFREPEvaluation{
+		Key:      d.FlagKey,
+		Reason:   ofrepReason(projectConfig, overrides, userContext, d),
+		Variant:  d.VariationKey,
+		Value:    value,
+		Metadata: metadata,
+	}
+}
+
+// ofrepReason returns STATIC for the flags whose variation is forced for the user, SPLIT for the flags decided
+// by an experiment rule, TARGETING_MATCH for the flags decided by a targeted delivery rule and DEFAULT for the flags
+// decided by the "Everyone Else" delivery rule, which is the last one, or no rule at all
+func ofrepReason(projectConfig sdkconfig.ProjectConfig, overrides decision.ExperimentOverrideStore, userContext *client.OptimizelyUserContext, d client.OptimizelyDecision) string {
+	if d.VariationKey == "" {
+		return OFREPReasonDefault
+	}
+
+	feature, err := projectConfig.GetFeatureByKey(d.FlagKey)
+	if err != nil {
+		return OFREPReasonUnknown
+	}
+	// The forced decision of the rule, or of the flag as a whole when the decision has no rule key
+	if forced, err := userContext.GetForcedDecision(decision.OptimizelyDecisionContext{FlagKey: d.FlagKey, RuleKey: d.RuleKey}); err == nil && forced.VariationKey == d.VariationKey {
+		return OFREPReasonStatic
+	}
+	for _, experiment := range feature.FeatureExperiments {
+		if experiment.Key != d.RuleKey {
+			continue
+		}
+		if overrides != nil {
+			if variationKey, ok := overrides.GetVariation(decision.ExperimentOverrideKey{ExperimentKey: experiment.Key, UserID: userContext.GetUserID()}); ok && variationKey == d.VariationKey {
+				return OFREPReasonStatic
+			}
+		}
+		if experiment.Whitelist[userContext.GetUserID()] == d.VariationKey {
+			return OFREPReasonStatic
+		}
+		return OFREPReasonSplit
+	}
+	rules := feature.Rollout.Experiments
+	for i, experiment := range rules {
+		if experiment.Key != d.RuleKey {
+			continue
+		}
+		if i == len(rules)-1 {
+			return OFREPReasonDefault
+		}
+		return OFREPReasonTargetingMatch
+	}
+	return OFREPReasonUnknown
+}
+
+func renderOFREPError(ofrepErr *OFREPError, status int, w http.ResponseWriter, r *http.Request) {
+	middleware.GetLogger(r).Info().Str("errorCode", ofrepErr.ErrorCode).Str("errorDetails", ofrepErr.ErrorDetails).Int("status", status).Msg("render OFREP error")
+	render.Status(r, status)
+	render.JSON(w, r, ofrepErr)
+}
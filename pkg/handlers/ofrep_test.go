@@ -0,0 +1,293 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/pkg/middleware"
+	"github.com/optimizely/agent/pkg/optimizely"
+	"github.com/optimizely/agent/pkg/optimizely/optimizelytest"
+	"github.com/optimizely/agent/pkg/overrides"
+	"github.com/optimizely/agent/plugins/userattributes/services"
+
+	"github.com/optimizely/go-sdk/pkg/client"
+	"github.com/optimizely/go-sdk/pkg/decide"
+	"github.com/optimizely/go-sdk/pkg/decision"
+	"github.com/optimizely/go-sdk/pkg/entities"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/suite"
+)
+
+type OFREPTestSuite struct {
+	suite.Suite
+	oc  *optimizely.OptlyClient
+	tc  *optimizelytest.TestClient
+	mux *chi.Mux
+}
+
+func (suite *OFREPTestSuite) ClientCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), middleware.OptlyClientKey, suite.oc)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (suite *OFREPTestSuite) SetupTest() {
+	testClient := optimizelytest.NewClient()
+	suite.tc = testClient
+	suite.oc = &optimizely.OptlyClient{
+		OptimizelyClient: testClient.OptimizelyClient,
+		ConfigManager:    MockConfigManager{config: testClient.ProjectConfig},
+		ForcedVariations: testClient.ForcedVariations,
+	}
+
+	suite.mux = chi.NewMux()
+	suite.mux.With(suite.ClientCtx).Post("/ofrep/v1/evaluate/flags/{key}", OFREPEvaluateFlag)
+	suite.mux.With(suite.ClientCtx).Post("/ofrep/v1/evaluate/flags", OFREPEvaluateFlags)
+}
+
+func (suite *OFREPTestSuite) evaluate(path string, evaluationContext map[string]interface{}, header http.Header) *httptest.ResponseRecorder {
+	payload, err := json.Marshal(OFREPEvaluationRequest{Context: evaluationContext})
+	suite.NoError(err)
+
+	req := httptest.NewRequest("POST", "/ofrep/v1/evaluate/flags"+path, bytes.NewBuffer(payload))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	return rec
+}
+
+// addTargetedRollout adds a flag whose rollout has a targeted rule, "targeted", ahead of its "Everyone Else" rule
+func (suite *OFREPTestSuite) addTargetedRollout(key string) {
+	suite.tc.AddFeatureRollout(entities.Feature{Key: key})
+	feature := suite.tc.ProjectConfig.FeatureMap[key]
+	everyoneElse := feature.Rollout.Experiments[0]
+	targeted := everyoneElse
+	targeted.Key, targeted.ID = "targeted", "targeted"
+	feature.Rollout.Experiments = []entities.Experiment{targeted, everyoneElse}
+	suite.tc.ProjectConfig.FeatureMap[key] = feature
+}
+
+func (suite *OFREPTestSuite) TestEvaluateFlagWithVariables() {
+	variable := entities.Variable{DefaultValue: "default", ID: "123", Key: "strvar", Type: "string"}
+	feature := entities.Feature{Key: "one", VariableMap: map[string]entities.Variable{"strvar": variable}}
+	suite.tc.AddFeatureTestWithCustomVariableValue(feature, variable, "custom")
+
+	rec := suite.evaluate("/one", map[string]interface{}{"targetingKey": "testUser"}, nil)
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual OFREPEvaluation
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal(OFREPEvaluation{
+		Key:      "one",
+		Reason:   OFREPReasonSplit,
+		Variant:  actual.Variant,
+		Value:    map[string]interface{}{"strvar": "custom"},
+		Metadata: map[string]interface{}{"enabled": true, "ruleKey": actual.Metadata["ruleKey"]},
+	}, actual)
+	suite.NotEmpty(actual.Variant)
+
+	// Evaluating a single flag sends a decision event
+	suite.Equal(1, len(suite.tc.GetProcessedEvents()))
+}
+
+func (suite *OFREPTestSuite) TestEvaluateFlagWithoutVariables() {
+	suite.addTargetedRollout("two")
+
+	rec := suite.evaluate("/two", map[string]interface{}{"targetingKey": "testUser", "plan": "pro"}, nil)
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual OFREPEvaluation
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal("two", actual.Key)
+	suite.Equal(OFREPReasonTargetingMatch, actual.Reason)
+	suite.Equal("3", actual.Variant)
+	suite.Equal(true, actual.Value)
+}
+
+func (suite *OFREPTestSuite) TestEvaluateFlagDefault() {
+	// A flag without rules
+	suite.tc.ProjectConfig.FeatureMap["three"] = entities.Feature{Key: "three"}
+
+	rec := suite.evaluate("/three", map[string]interface{}{"targetingKey": "testUser"}, nil)
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual OFREPEvaluation
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal(OFREPEvaluation{Key: "three", Reason: OFREPReasonDefault, Value: false, Metadata: map[string]interface{}{"enabled": false}}, actual)
+}
+
+func (suite *OFREPTestSuite) TestReason() {
+	variable := entities.Variable{DefaultValue: "default", ID: "123", Key: "strvar", Type: "string"}
+	suite.tc.AddFeatureTestWithCustomVariableValue(entities.Feature{Key: "one", VariableMap: map[string]entities.Variable{"strvar": variable}}, variable, "custom")
+	suite.addTargetedRollout("two")
+	projectConfig := suite.tc.ProjectConfig
+
+	userContext := suite.oc.CreateUserContext("testUser", nil)
+	d := userContext.Decide("one", nil)
+	suite.Equal(OFREPReasonSplit, ofrepReason(projectConfig, suite.tc.ForcedVariations, &userContext, d))
+
+	// Overrides, whitelisted variations and forced decisions are static
+	override := overrides.Override{UserID: "testUser", ExperimentKey: d.RuleKey, VariationKey: d.VariationKey}
+	_, err := suite.tc.ForcedVariations.Set(context.Background(), override)
+	suite.NoError(err)
+	suite.Equal(OFREPReasonStatic, ofrepReason(projectConfig, suite.tc.ForcedVariations, &userContext, d))
+	_, err = suite.tc.ForcedVariations.Remove(context.Background(), override.Key())
+	suite.NoError(err)
+
+	feature := projectConfig.FeatureMap["one"]
+	feature.FeatureExperiments[0].Whitelist = map[string]string{"testUser": d.VariationKey}
+	suite.Equal(OFREPReasonStatic, ofrepReason(projectConfig, nil, &userContext, d))
+	feature.FeatureExperiments[0].Whitelist = nil
+
+	suite.True(userContext.SetForcedDecision(decision.OptimizelyDecisionContext{FlagKey: "one", RuleKey: d.RuleKey}, decision.OptimizelyForcedDecision{VariationKey: d.VariationKey}))
+	suite.Equal(OFREPReasonStatic, ofrepReason(projectConfig, nil, &userContext, d))
+
+	suite.tc.AddFlagVariation(feature, entities.Variation{Key: "forced", FeatureEnabled: true})
+	flagContext := suite.oc.CreateUserContext("testUser", nil)
+	suite.True(flagContext.SetForcedDecision(decision.OptimizelyDecisionContext{FlagKey: "one"}, decision.OptimizelyForcedDecision{VariationKey: "forced"}))
+	forced := flagContext.Decide("one", nil)
+	suite.Equal("forced", forced.VariationKey)
+	suite.Empty(forced.RuleKey)
+	suite.Equal(OFREPReasonStatic, ofrepReason(projectConfig, nil, &flagContext, forced))
+
+	// The last rule of a rollout is its "Everyone Else" rule
+	everyoneElse := projectConfig.FeatureMap["two"].Rollout.Experiments[1]
+	suite.Equal(OFREPReasonTargetingMatch, ofrepReason(projectConfig, nil, &userContext, client.OptimizelyDecision{FlagKey: "two", RuleKey: "targeted", VariationKey: "3"}))
+	suite.Equal(OFREPReasonDefault, ofrepReason(projectConfig, nil, &userContext, client.OptimizelyDecision{FlagKey: "two", RuleKey: everyoneElse.Key, VariationKey: "3"}))
+	suite.Equal(OFREPReasonDefault, ofrepReason(projectConfig, nil, &userContext, client.OptimizelyDecision{FlagKey: "two"}))
+	suite.Equal(OFREPReasonUnknown, ofrepReason(projectConfig, nil, &userContext, client.OptimizelyDecision{FlagKey: "two", RuleKey: "removed", VariationKey: "3"}))
+}
+
+func (suite *OFREPTestSuite) TestValueType() {
+	variable := entities.Variable{DefaultValue: "default", ID: "123", Key: "strvar", Type: "string"}
+	suite.tc.ProjectConfig.FeatureMap["one"] = entities.Feature{Key: "one", VariableMap: map[string]entities.Variable{"strvar": variable}}
+	suite.tc.ProjectConfig.FeatureMap["two"] = entities.Feature{Key: "two"}
+
+	userContext := suite.oc.CreateUserContext("testUser", nil)
+	// The value of a flag with variables is an object, even when the variables are excluded from the decision
+	d := userContext.Decide("one", []decide.OptimizelyDecideOptions{decide.ExcludeVariables})
+	suite.Equal(map[string]interface{}{}, newOFREPEvaluation(suite.tc.ProjectConfig, nil, &userContext, d).Value)
+	d = userContext.Decide("two", nil)
+	suite.Equal(false, newOFREPEvaluation(suite.tc.ProjectConfig, nil, &userContext, d).Value)
+}
+
+func (suite *OFREPTestSuite) TestEvaluateFlagErrors() {
+	suite.tc.AddFeatureTest(entities.Feature{Key: "one"})
+
+	scenarios := []struct {
+		path     string
+		context  map[string]interface{}
+		status   int
+		expected OFREPError
+	}{
+		{"/missing", map[string]interface{}{"targetingKey": "testUser"}, http.StatusNotFound, OFREPError{Key: "missing", ErrorCode: OFREPErrorFlagNotFound}},
+		{"/one", map[string]interface{}{"plan": "pro"}, http.StatusBadRequest, OFREPError{Key: "one", ErrorCode: OFREPErrorTargetingKeyMissing}},
+		{"/one", map[string]interface{}{"targetingKey": 1}, http.StatusBadRequest, OFREPError{Key: "one", ErrorCode: OFREPErrorTargetingKeyMissing}},
+		{"", nil, http.StatusBadRequest, OFREPError{ErrorCode: OFREPErrorTargetingKeyMissing}},
+	}
+
+	for _, scenario := range scenarios {
+		rec := suite.evaluate(scenario.path, scenario.context, nil)
+		suite.Equal(scenario.status, rec.Code)
+
+		var actual OFREPError
+		suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+		suite.Equal(scenario.expected.Key, actual.Key)
+		suite.Equal(scenario.expected.ErrorCode, actual.ErrorCode)
+		suite.NotEmpty(actual.ErrorDetails)
+	}
+
+	req := httptest.NewRequest("POST", "/ofrep/v1/evaluate/flags/one", bytes.NewBufferString("{"))
+	rec := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	suite.Equal(http.StatusBadRequest, rec.Code)
+	suite.Contains(rec.Body.String(), OFREPErrorParse)
+}
+
+func (suite *OFREPTestSuite) TestEvaluateFlagInvalidContext() {
+	suite.tc.AddFeatureTest(entities.Feature{Key: "one"})
+	suite.oc.AttributeValidationMode = config.AttributeValidationReject
+
+	rec := suite.evaluate("/one", map[string]interface{}{"targetingKey": "testUser", "user_tier": "gold"}, nil)
+	suite.Equal(http.StatusBadRequest, rec.Code)
+
+	var actual OFREPError
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal(OFREPError{Key: "one", ErrorCode: OFREPErrorInvalidContext, ErrorDetails: `attribute "user_tier" is not defined in the datafile`}, actual)
+}
+
+func (suite *OFREPTestSuite) TestParseContextMergesStoredAttributes() {
+	suite.oc.UserAttributes = services.NewInMemoryProvider()
+	suite.NoError(suite.oc.UserAttributes.Upsert("", "testUser", map[string]interface{}{"plan": "free", "country": "US"}))
+
+	userID, attributes, ofrepErr := parseOFREPContext(
+		httptest.NewRequest("POST", "/", bytes.NewBufferString(`{"context":{"targetingKey":"testUser","plan":"pro"}}`)),
+		suite.oc, middleware.GetLogger(httptest.NewRequest("GET", "/", nil)),
+	)
+	suite.Nil(ofrepErr)
+	suite.Equal("testUser", userID)
+	suite.Equal(map[string]interface{}{"plan": "pro", "country": "US"}, attributes)
+}
+
+func (suite *OFREPTestSuite) TestEvaluateFlags() {
+	suite.tc.AddFeatureTest(entities.Feature{Key: "one"})
+	suite.tc.AddFeatureRollout(entities.Feature{Key: "two"})
+
+	rec := suite.evaluate("", map[string]interface{}{"targetingKey": "testUser"}, nil)
+	suite.Equal(http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	suite.NotEmpty(etag)
+
+	var actual OFREPBulkEvaluation
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	if suite.Len(actual.Flags, 2) {
+		suite.Equal("one", actual.Flags[0].Key)
+		suite.Equal(OFREPReasonSplit, actual.Flags[0].Reason)
+		suite.Equal("two", actual.Flags[1].Key)
+		// The only rule of the rollout is its "Everyone Else" rule
+		suite.Equal(OFREPReasonDefault, actual.Flags[1].Reason)
+	}
+
+	// Bulk evaluations never send an event
+	suite.Equal(0, len(suite.tc.GetProcessedEvents()))
+
+	rec = suite.evaluate("", map[string]interface{}{"targetingKey": "testUser"}, http.Header{"If-None-Match": []string{etag}})
+	suite.Equal(http.StatusNotModified, rec.Code)
+	suite.Empty(rec.Body.Bytes())
+
+	// The evaluations change along with the datafile
+	suite.tc.AddFeatureRollout(entities.Feature{Key: "three"})
+	rec = suite.evaluate("", map[string]interface{}{"targetingKey": "testUser"}, http.Header{"If-None-Match": []string{etag}})
+	suite.Equal(http.StatusOK, rec.Code)
+	suite.NotEqual(etag, rec.Header().Get("ETag"))
+}
+
+func TestOFREPTestSuite(t *testing.T) {
+	suite.Run(t, new(OFREPTestSuite))
+}
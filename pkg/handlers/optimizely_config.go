@@ -21,10 +21,18 @@ import (
 	"net/http"
 
 	"github.com/go-chi/render"
+	"github.com/optimizely/go-sdk/pkg/config"
 
 	"github.com/optimizely/agent/pkg/middleware"
 )
 
+// OptimizelyConfigResponse extends the OptimizelyConfig with the freshness of the datafile
+type OptimizelyConfigResponse struct {
+	*config.OptimizelyConfig
+	// Stale is true while the config is based on a datafile snapshot which could not be refreshed yet
+	Stale bool `json:"stale"`
+}
+
 // OptimizelyConfig returns the entire OptimizelyConfig object directly from the SDK
 func OptimizelyConfig(w http.ResponseWriter, r *http.Request) {
 	optlyClient, err := middleware.GetOptlyClient(r)
@@ -34,5 +42,5 @@ func OptimizelyConfig(w http.ResponseWriter, r *http.Request) {
 	}
 
 	conf := optlyClient.GetOptimizelyConfig()
-	render.JSON(w, r, conf)
+	render.JSON(w, r, OptimizelyConfigResponse{OptimizelyConfig: conf, Stale: optlyClient.IsStale()})
 }
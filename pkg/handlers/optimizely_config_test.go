@@ -59,6 +59,10 @@ func (suite *OptimizelyConfigTestSuite) TestConfig() {
 	suite.NoError(err)
 
 	suite.Equal(*suite.oc.GetOptimizelyConfig(), actual)
+
+	var response map[string]interface{}
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &response))
+	suite.Equal(false, response["stale"])
 }
 
 // In order for 'go test' to run this suite, we need to create
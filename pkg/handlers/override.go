@@ -19,11 +19,14 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/render"
 
 	"github.com/optimizely/agent/pkg/middleware"
+	"github.com/optimizely/agent/pkg/overrides"
 )
 
 // OverrideBody defines the request body for an override
@@ -31,6 +34,13 @@ type OverrideBody struct {
 	UserID        string `json:"userId"`
 	ExperimentKey string `json:"experimentKey"`
 	VariationKey  string `json:"variationKey"`
+	// TTL is a duration, like "1h30m", after which the override expires. It never expires when empty
+	TTL string `json:"ttl,omitempty"`
+}
+
+// ClearedOverrides defines the response of a request clearing overrides
+type ClearedOverrides struct {
+	Cleared int `json:"cleared"`
 }
 
 // Override is used to set forced variations for a given experiment or feature test
@@ -69,10 +79,65 @@ func Override(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var ttl time.Duration
+	if body.TTL != "" {
+		if ttl, err = time.ParseDuration(body.TTL); err != nil || ttl <= 0 {
+			RenderError(fmt.Errorf("invalid ttl %q, it must be a positive duration", body.TTL), http.StatusBadRequest, w, r)
+			return
+		}
+	}
+
 	logger.Debug().Str("experimentKey", experimentKey).Str("variationKey", body.VariationKey).Msg("setting override")
-	if override, err := optlyClient.SetForcedVariation(r.Context(), experimentKey, body.UserID, body.VariationKey); err != nil {
+	if override, err := optlyClient.SetForcedVariation(r.Context(), experimentKey, body.UserID, body.VariationKey, ttl); err != nil {
 		RenderError(err, http.StatusInternalServerError, w, r)
 	} else {
 		render.JSON(w, r, override)
 	}
 }
+
+// ListOverrides returns the overrides of the client, optionally filtered by the userId and experimentKey query parameters
+func ListOverrides(w http.ResponseWriter, r *http.Request) {
+	optlyClient, err := middleware.GetOptlyClient(r)
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	list, err := optlyClient.ListForcedVariations(r.Context(), overridesFilter(r))
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+	render.JSON(w, r, list)
+}
+
+// ClearOverrides removes the overrides of a user, of an experiment or of both given as userId and experimentKey query parameters
+func ClearOverrides(w http.ResponseWriter, r *http.Request) {
+	optlyClient, err := middleware.GetOptlyClient(r)
+	logger := middleware.GetLogger(r)
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	filter := overridesFilter(r)
+	if filter.UserID == "" && filter.ExperimentKey == "" {
+		RenderError(errors.New("userId or experimentKey must be provided"), http.StatusBadRequest, w, r)
+		return
+	}
+
+	cleared, err := optlyClient.ClearForcedVariations(r.Context(), filter)
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+	logger.Debug().Str("userId", filter.UserID).Str("experimentKey", filter.ExperimentKey).Int("cleared", cleared).Msg("cleared overrides")
+	render.JSON(w, r, ClearedOverrides{Cleared: cleared})
+}
+
+func overridesFilter(r *http.Request) overrides.Filter {
+	return overrides.Filter{
+		UserID:        r.URL.Query().Get("userId"),
+		ExperimentKey: r.URL.Query().Get("experimentKey"),
+	}
+}
@@ -24,10 +24,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/optimizely/agent/pkg/middleware"
 	"github.com/optimizely/agent/pkg/optimizely"
 	"github.com/optimizely/agent/pkg/optimizely/optimizelytest"
+	"github.com/optimizely/agent/pkg/overrides"
 
 	"github.com/optimizely/go-sdk/pkg/decision"
 	"github.com/optimizely/go-sdk/pkg/entities"
@@ -62,6 +64,8 @@ func (suite *OverrideTestSuite) SetupTest() {
 
 	mux := chi.NewMux()
 	mux.With(suite.ClientCtx).Post("/override", Override)
+	mux.With(suite.ClientCtx).Get("/overrides", ListOverrides)
+	mux.With(suite.ClientCtx).Delete("/overrides", ClearOverrides)
 
 	feature := entities.Feature{Key: "my_feat"}
 	testClient.ProjectConfig.AddMultiVariationFeatureTest(feature, "variation_disabled", "variation_enabled")
@@ -157,6 +161,18 @@ func (suite *OverrideTestSuite) TestInvalidOverrides() {
 		{
 			"userId": true,
 		},
+		{
+			"userId":        "valid",
+			"experimentKey": "valid",
+			"variationKey":  "valid",
+			"ttl":           "-1h",
+		},
+		{
+			"userId":        "valid",
+			"experimentKey": "valid",
+			"variationKey":  "valid",
+			"ttl":           "soon",
+		},
 	}
 
 	for _, payload := range invalid {
@@ -170,6 +186,96 @@ func (suite *OverrideTestSuite) TestInvalidOverrides() {
 	}
 }
 
+func (suite *OverrideTestSuite) TestOverrideTTL() {
+	body, err := json.Marshal(OverrideBody{UserID: "testUser", ExperimentKey: suite.experimentKey, VariationKey: "variation_enabled", TTL: "1h"})
+	suite.NoError(err)
+
+	req := httptest.NewRequest("POST", "/override", bytes.NewBuffer(body))
+	rec := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual optimizely.Override
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	if suite.NotNil(actual.ExpiresAt) {
+		suite.WithinDuration(time.Now().Add(time.Hour), *actual.ExpiresAt, time.Minute)
+	}
+}
+
+func (suite *OverrideTestSuite) TestListOverrides() {
+	ctx := context.Background()
+	_, _ = suite.tc.ForcedVariations.Set(ctx, overrides.Override{UserID: "user1", ExperimentKey: "exp1", VariationKey: "a"})
+	_, _ = suite.tc.ForcedVariations.Set(ctx, overrides.Override{UserID: "user1", ExperimentKey: "exp2", VariationKey: "b"})
+	_, _ = suite.tc.ForcedVariations.Set(ctx, overrides.Override{UserID: "user2", ExperimentKey: "exp1", VariationKey: "c"})
+
+	scenarios := []struct {
+		query    string
+		expected []overrides.Override
+	}{
+		{
+			query: "",
+			expected: []overrides.Override{
+				{UserID: "user1", ExperimentKey: "exp1", VariationKey: "a"},
+				{UserID: "user1", ExperimentKey: "exp2", VariationKey: "b"},
+				{UserID: "user2", ExperimentKey: "exp1", VariationKey: "c"},
+			},
+		},
+		{
+			query: "?userId=user1",
+			expected: []overrides.Override{
+				{UserID: "user1", ExperimentKey: "exp1", VariationKey: "a"},
+				{UserID: "user1", ExperimentKey: "exp2", VariationKey: "b"},
+			},
+		},
+		{
+			query: "?experimentKey=exp1&userId=user2",
+			expected: []overrides.Override{
+				{UserID: "user2", ExperimentKey: "exp1", VariationKey: "c"},
+			},
+		},
+		{
+			query:    "?userId=none",
+			expected: []overrides.Override{},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		req := httptest.NewRequest("GET", "/overrides"+scenario.query, nil)
+		rec := httptest.NewRecorder()
+		suite.mux.ServeHTTP(rec, req)
+		suite.Equal(http.StatusOK, rec.Code)
+
+		var actual []overrides.Override
+		suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+		suite.Equal(scenario.expected, actual, scenario.query)
+	}
+}
+
+func (suite *OverrideTestSuite) TestClearOverrides() {
+	ctx := context.Background()
+	_, _ = suite.tc.ForcedVariations.Set(ctx, overrides.Override{UserID: "user1", ExperimentKey: "exp1", VariationKey: "a"})
+	_, _ = suite.tc.ForcedVariations.Set(ctx, overrides.Override{UserID: "user1", ExperimentKey: "exp2", VariationKey: "b"})
+	_, _ = suite.tc.ForcedVariations.Set(ctx, overrides.Override{UserID: "user2", ExperimentKey: "exp1", VariationKey: "c"})
+
+	req := httptest.NewRequest("DELETE", "/overrides", nil)
+	rec := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	assertError(suite.T(), rec, "userId or experimentKey must be provided", http.StatusBadRequest)
+
+	req = httptest.NewRequest("DELETE", "/overrides?experimentKey=exp1", nil)
+	rec = httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual ClearedOverrides
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal(ClearedOverrides{Cleared: 2}, actual)
+
+	remaining, err := suite.tc.ForcedVariations.List(ctx, overrides.Filter{})
+	suite.NoError(err)
+	suite.Equal([]overrides.Override{{UserID: "user1", ExperimentKey: "exp2", VariationKey: "b"}}, remaining)
+}
+
 // In order for 'go test' to run this suite, we need to create
 // a normal test function and pass our suite to suite.Run
 func TestOverrideTestSuite(t *testing.T) {
@@ -0,0 +1,153 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/optimizely/go-sdk/pkg/entities"
+	"github.com/rs/zerolog"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/pkg/idempotency"
+	"github.com/optimizely/agent/pkg/middleware"
+	"github.com/optimizely/agent/pkg/optimizely"
+)
+
+// Statuses of the events of a batch track request
+const (
+	TrackStatusTracked   = "tracked"
+	TrackStatusDuplicate = "duplicate"
+	TrackStatusFailed    = "failed"
+)
+
+// TrackBatchBody defines the request body of the batch track endpoint
+type TrackBatchBody struct {
+	Events []TrackBatchEvent `json:"events"`
+}
+
+// TrackBatchEvent is an event of a batch track request
+type TrackBatchEvent struct {
+	// IdempotencyKey identifies the event, an event whose key was already tracked is acknowledged without being tracked again
+	IdempotencyKey string                 `json:"idempotencyKey"`
+	EventKey       string                 `json:"eventKey"`
+	UserID         string                 `json:"userId"`
+	UserAttributes map[string]interface{} `json:"userAttributes"`
+	EventTags      map[string]interface{} `json:"eventTags"`
+}
+
+// TrackBatchResult is the status of an event of a batch track request
+type TrackBatchResult struct {
+	Index          int    `json:"index"`
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+	EventKey       string `json:"eventKey"`
+	UserID         string `json:"userId"`
+	Status         string `json:"status"`
+	Error          string `json:"error,omitempty"`
+}
+
+// TrackBatch returns a handler tracking many events per request. Events are tracked in the order of the request
+// and the response gives the status of each of them, an event which failed can be sent again with the same key.
+func TrackBatch(conf config.TrackBatchConfig, keys idempotency.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		optlyClient, err := middleware.GetOptlyClient(r)
+		if err != nil {
+			RenderError(err, http.StatusInternalServerError, w, r)
+			return
+		}
+
+		var body TrackBatchBody
+		if err := ParseRequestBody(r, &body); err != nil {
+			RenderError(err, http.StatusBadRequest, w, r)
+			return
+		}
+
+		if len(body.Events) == 0 {
+			RenderError(errors.New("events cannot be empty"), http.StatusBadRequest, w, r)
+			return
+		}
+
+		if conf.MaxEvents > 0 && len(body.Events) > conf.MaxEvents {
+			RenderError(fmt.Errorf("too many events, at most %d are allowed", conf.MaxEvents), http.StatusBadRequest, w, r)
+			return
+		}
+
+		logger := middleware.GetLogger(r)
+		results := make([]TrackBatchResult, len(body.Events))
+		for i, e := range body.Events {
+			results[i] = trackOnce(r, optlyClient, keys, i, e, logger)
+		}
+
+		render.JSON(w, r, results)
+	}
+}
+
+// trackOnce tracks the event unless its idempotency key is remembered. The key is forgotten when tracking fails.
+func trackOnce(r *http.Request, optlyClient *optimizely.OptlyClient, keys idempotency.Store, i int, e TrackBatchEvent, logger *zerolog.Logger) TrackBatchResult {
+	result := TrackBatchResult{
+		Index:          i,
+		IdempotencyKey: e.IdempotencyKey,
+		EventKey:       e.EventKey,
+		UserID:         e.UserID,
+	}
+	fail := func(err error) TrackBatchResult {
+		result.Status = TrackStatusFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	if e.IdempotencyKey == "" {
+		return fail(errors.New("idempotencyKey cannot be empty"))
+	}
+	if e.EventKey == "" {
+		return fail(errors.New("eventKey cannot be empty"))
+	}
+
+	ctx := r.Context()
+	reserved, err := keys.Reserve(ctx, optlyClient.SDKKey(), e.IdempotencyKey)
+	if err != nil {
+		logger.Error().Err(err).Str("idempotencyKey", e.IdempotencyKey).Msg("failed to check idempotency key")
+		return fail(errors.New("idempotency key could not be checked"))
+	}
+	if !reserved {
+		result.Status = TrackStatusDuplicate
+		return result
+	}
+
+	uc := entities.UserContext{
+		ID:         e.UserID,
+		Attributes: e.UserAttributes,
+	}
+	track, err := optlyClient.TrackEvent(ctx, e.EventKey, uc, e.EventTags)
+	if err == nil && track.Error != "" {
+		err = errors.New(track.Error)
+	}
+	if err != nil {
+		if releaseErr := keys.Release(ctx, optlyClient.SDKKey(), e.IdempotencyKey); releaseErr != nil {
+			logger.Error().Err(releaseErr).Str("idempotencyKey", e.IdempotencyKey).Msg("failed to release idempotency key")
+		}
+		return fail(err)
+	}
+
+	logger.Debug().Str("eventKey", e.EventKey).Msg("tracking event")
+	result.Status = TrackStatusTracked
+	return result
+}
@@ -0,0 +1,181 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/optimizely/go-sdk/pkg/entities"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/pkg/idempotency"
+	"github.com/optimizely/agent/pkg/middleware"
+	"github.com/optimizely/agent/pkg/optimizely"
+	"github.com/optimizely/agent/pkg/optimizely/optimizelytest"
+)
+
+type errorIdempotencyStore struct{}
+
+func (errorIdempotencyStore) Reserve(context.Context, string, string) (bool, error) {
+	return false, errors.New("unavailable")
+}
+
+func (errorIdempotencyStore) Release(context.Context, string, string) error {
+	return errors.New("unavailable")
+}
+
+type TrackBatchTestSuite struct {
+	suite.Suite
+	oc   *optimizely.OptlyClient
+	tc   *optimizelytest.TestClient
+	keys *idempotency.InMemoryStore
+	mux  *chi.Mux
+}
+
+func (suite *TrackBatchTestSuite) ClientCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), middleware.OptlyClientKey, suite.oc)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Setup Mux
+func (suite *TrackBatchTestSuite) SetupTest() {
+	testClient := optimizelytest.NewClient()
+	testClient.AddEvent(entities.Event{Key: "purchase"})
+	optlyClient := &optimizely.OptlyClient{
+		OptimizelyClient: testClient.OptimizelyClient,
+		ConfigManager:    MockConfigManager{config: testClient.ProjectConfig},
+		ForcedVariations: testClient.ForcedVariations,
+	}
+
+	suite.keys = idempotency.NewInMemoryStore(time.Hour, 0)
+	mux := chi.NewMux()
+	mux.With(suite.ClientCtx).Post("/track/batch", TrackBatch(config.TrackBatchConfig{MaxEvents: 3}, suite.keys))
+	mux.With(suite.ClientCtx).Post("/track/batch/unavailable", TrackBatch(config.TrackBatchConfig{}, errorIdempotencyStore{}))
+
+	suite.oc = optlyClient
+	suite.tc = testClient
+	suite.mux = mux
+}
+
+func (suite *TrackBatchTestSuite) trackBatch(path string, events ...TrackBatchEvent) *httptest.ResponseRecorder {
+	body, err := json.Marshal(TrackBatchBody{Events: events})
+	suite.NoError(err)
+
+	req := httptest.NewRequest("POST", path, bytes.NewBuffer(body))
+	rec := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func (suite *TrackBatchTestSuite) results(rec *httptest.ResponseRecorder) []TrackBatchResult {
+	suite.Equal(http.StatusOK, rec.Code)
+	var actual []TrackBatchResult
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	return actual
+}
+
+func (suite *TrackBatchTestSuite) TestTrackBatch() {
+	rec := suite.trackBatch("/track/batch",
+		TrackBatchEvent{IdempotencyKey: "1", EventKey: "purchase", UserID: "user1", EventTags: map[string]interface{}{"revenue": 100}},
+		TrackBatchEvent{IdempotencyKey: "2", EventKey: "purchase", UserID: "user2"},
+		TrackBatchEvent{IdempotencyKey: "1", EventKey: "purchase", UserID: "user1", EventTags: map[string]interface{}{"revenue": 100}},
+	)
+
+	suite.Equal([]TrackBatchResult{
+		{Index: 0, IdempotencyKey: "1", EventKey: "purchase", UserID: "user1", Status: TrackStatusTracked},
+		{Index: 1, IdempotencyKey: "2", EventKey: "purchase", UserID: "user2", Status: TrackStatusTracked},
+		{Index: 2, IdempotencyKey: "1", EventKey: "purchase", UserID: "user1", Status: TrackStatusDuplicate},
+	}, suite.results(rec))
+
+	events := suite.tc.GetProcessedEvents()
+	if suite.Len(events, 2) {
+		suite.Equal("user1", events[0].VisitorID)
+		suite.Equal(map[string]interface{}{"revenue": float64(100)}, events[0].Conversion.Tags)
+		suite.Equal("user2", events[1].VisitorID)
+	}
+
+	// A retried request is acknowledged without tracking its events again
+	rec = suite.trackBatch("/track/batch", TrackBatchEvent{IdempotencyKey: "2", EventKey: "purchase", UserID: "user2"})
+	suite.Equal([]TrackBatchResult{
+		{Index: 0, IdempotencyKey: "2", EventKey: "purchase", UserID: "user2", Status: TrackStatusDuplicate},
+	}, suite.results(rec))
+	suite.Len(suite.tc.GetProcessedEvents(), 2)
+}
+
+func (suite *TrackBatchTestSuite) TestFailedEvents() {
+	rec := suite.trackBatch("/track/batch",
+		TrackBatchEvent{EventKey: "purchase", UserID: "user"},
+		TrackBatchEvent{IdempotencyKey: "1", UserID: "user"},
+		TrackBatchEvent{IdempotencyKey: "2", EventKey: "unknown", UserID: "user"},
+	)
+
+	suite.Equal([]TrackBatchResult{
+		{Index: 0, EventKey: "purchase", UserID: "user", Status: TrackStatusFailed, Error: "idempotencyKey cannot be empty"},
+		{Index: 1, IdempotencyKey: "1", UserID: "user", Status: TrackStatusFailed, Error: "eventKey cannot be empty"},
+		{Index: 2, IdempotencyKey: "2", EventKey: "unknown", UserID: "user", Status: TrackStatusFailed, Error: "Event with key unknown not found"},
+	}, suite.results(rec))
+	suite.Empty(suite.tc.GetProcessedEvents())
+
+	// The key of a failed event is forgotten so that it can be sent again
+	suite.Equal(0, suite.keys.Len())
+}
+
+func (suite *TrackBatchTestSuite) TestIdempotencyStoreUnavailable() {
+	rec := suite.trackBatch("/track/batch/unavailable", TrackBatchEvent{IdempotencyKey: "1", EventKey: "purchase", UserID: "user"})
+
+	suite.Equal([]TrackBatchResult{
+		{Index: 0, IdempotencyKey: "1", EventKey: "purchase", UserID: "user", Status: TrackStatusFailed, Error: "idempotency key could not be checked"},
+	}, suite.results(rec))
+	suite.Empty(suite.tc.GetProcessedEvents())
+}
+
+func (suite *TrackBatchTestSuite) TestInvalidRequests() {
+	rec := suite.trackBatch("/track/batch")
+	assertError(suite.T(), rec, "events cannot be empty", http.StatusBadRequest)
+
+	event := TrackBatchEvent{IdempotencyKey: "1", EventKey: "purchase", UserID: "user"}
+	rec = suite.trackBatch("/track/batch", event, event, event, event)
+	assertError(suite.T(), rec, "too many events, at most 3 are allowed", http.StatusBadRequest)
+
+	req := httptest.NewRequest("POST", "/track/batch", bytes.NewBufferString("{"))
+	rec = httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	suite.Equal(http.StatusBadRequest, rec.Code)
+}
+
+func TestTrackBatchTestSuite(t *testing.T) {
+	suite.Run(t, new(TrackBatchTestSuite))
+}
+
+func TestTrackBatchMissingClientCtx(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", nil)
+	rec := httptest.NewRecorder()
+	TrackBatch(config.TrackBatchConfig{}, idempotency.NewInMemoryStore(time.Hour, 0)).ServeHTTP(rec, req)
+	assertError(t, rec, "optlyClient not available", http.StatusInternalServerError)
+}
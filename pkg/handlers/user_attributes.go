@@ -0,0 +1,81 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"github.com/optimizely/agent/pkg/middleware"
+)
+
+// UpsertUserAttributesBody defines the request body to store attributes of a user
+type UpsertUserAttributesBody struct {
+	// Attributes are stored alongside the ones already stored for the user, an attribute set to null is removed
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// GetUserAttributes returns the attributes stored for the user in the url
+func GetUserAttributes(w http.ResponseWriter, r *http.Request) {
+	optlyClient, err := middleware.GetOptlyClient(r)
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	userAttributes, err := optlyClient.GetUserAttributes(r.Context(), chi.URLParam(r, "userId"))
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	render.JSON(w, r, userAttributes)
+}
+
+// UpsertUserAttributes stores attributes of the user in the url, they are merged into every decide, activate and track request of the user
+func UpsertUserAttributes(w http.ResponseWriter, r *http.Request) {
+	optlyClient, err := middleware.GetOptlyClient(r)
+	logger := middleware.GetLogger(r)
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	var body UpsertUserAttributesBody
+	if parseErr := ParseRequestBody(r, &body); parseErr != nil {
+		RenderError(parseErr, http.StatusBadRequest, w, r)
+		return
+	}
+
+	if len(body.Attributes) == 0 {
+		RenderError(errors.New("attributes cannot be empty"), http.StatusBadRequest, w, r)
+		return
+	}
+
+	logger.Debug().Int("attributes", len(body.Attributes)).Msg("storing user attributes")
+	userAttributes, err := optlyClient.UpsertUserAttributes(r.Context(), chi.URLParam(r, "userId"), body.Attributes)
+	if err != nil {
+		RenderError(err, http.StatusInternalServerError, w, r)
+		return
+	}
+
+	render.JSON(w, r, userAttributes)
+}
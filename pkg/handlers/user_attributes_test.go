@@ -0,0 +1,168 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package handlers //
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/optimizely/go-sdk/pkg/client"
+	"github.com/optimizely/go-sdk/pkg/entities"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/optimizely/agent/pkg/middleware"
+	"github.com/optimizely/agent/pkg/optimizely"
+	"github.com/optimizely/agent/pkg/optimizely/optimizelytest"
+	"github.com/optimizely/agent/plugins/userattributes"
+	"github.com/optimizely/agent/plugins/userattributes/services"
+)
+
+type failingProvider struct{}
+
+func (failingProvider) Lookup(sdkKey, userID string) (map[string]interface{}, error) {
+	return nil, errors.New("unavailable")
+}
+
+func (failingProvider) Upsert(sdkKey, userID string, attributes map[string]interface{}) error {
+	return errors.New("unavailable")
+}
+
+type UserAttributesTestSuite struct {
+	suite.Suite
+	oc       *optimizely.OptlyClient
+	tc       *optimizelytest.TestClient
+	provider userattributes.Provider
+	mux      *chi.Mux
+}
+
+func (suite *UserAttributesTestSuite) ClientCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), middleware.OptlyClientKey, suite.oc)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (suite *UserAttributesTestSuite) SetupTest() {
+	suite.tc = optimizelytest.NewClient()
+	suite.provider = services.NewInMemoryProvider()
+	suite.oc = &optimizely.OptlyClient{
+		OptimizelyClient: suite.tc.OptimizelyClient,
+		ForcedVariations: suite.tc.ForcedVariations,
+		UserAttributes:   suite.provider,
+	}
+
+	suite.mux = chi.NewMux()
+	suite.mux.With(suite.ClientCtx).Get("/user-attributes/{userId}", GetUserAttributes)
+	suite.mux.With(suite.ClientCtx).Put("/user-attributes/{userId}", UpsertUserAttributes)
+	suite.mux.With(suite.ClientCtx).Post("/decide", Decide)
+}
+
+func (suite *UserAttributesTestSuite) serve(method, target string, body interface{}) *httptest.ResponseRecorder {
+	payload, err := json.Marshal(body)
+	suite.NoError(err)
+
+	req := httptest.NewRequest(method, target, bytes.NewBuffer(payload))
+	rec := httptest.NewRecorder()
+	suite.mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func (suite *UserAttributesTestSuite) TestUpsertUserAttributes() {
+	suite.NoError(suite.provider.Upsert("", "testUser", map[string]interface{}{"plan": "free", "country": "US"}))
+
+	rec := suite.serve("PUT", "/user-attributes/testUser", UpsertUserAttributesBody{
+		Attributes: map[string]interface{}{"plan": "pro", "country": nil, "age": 30},
+	})
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual optimizely.UserAttributes
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal("testUser", actual.UserID)
+	suite.Equal(map[string]interface{}{"plan": "pro", "age": float64(30)}, actual.Attributes)
+}
+
+func (suite *UserAttributesTestSuite) TestUpsertInvalidUserAttributes() {
+	rec := suite.serve("PUT", "/user-attributes/testUser", UpsertUserAttributesBody{})
+	assertError(suite.T(), rec, "attributes cannot be empty", http.StatusBadRequest)
+}
+
+func (suite *UserAttributesTestSuite) TestGetUserAttributes() {
+	rec := suite.serve("GET", "/user-attributes/testUser", nil)
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual optimizely.UserAttributes
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal(optimizely.UserAttributes{UserID: "testUser", Attributes: map[string]interface{}{}}, actual)
+
+	suite.NoError(suite.provider.Upsert("", "testUser", map[string]interface{}{"plan": "free"}))
+	rec = suite.serve("GET", "/user-attributes/testUser", nil)
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal(map[string]interface{}{"plan": "free"}, actual.Attributes)
+}
+
+func (suite *UserAttributesTestSuite) TestUninitializedProvider() {
+	suite.oc.UserAttributes = nil
+
+	rec := suite.serve("GET", "/user-attributes/testUser", nil)
+	assertError(suite.T(), rec, optimizely.ErrUserAttributesUninitialized.Error(), http.StatusInternalServerError)
+
+	rec = suite.serve("PUT", "/user-attributes/testUser", UpsertUserAttributesBody{Attributes: map[string]interface{}{"plan": "pro"}})
+	assertError(suite.T(), rec, optimizely.ErrUserAttributesUninitialized.Error(), http.StatusInternalServerError)
+}
+
+func (suite *UserAttributesTestSuite) TestDecideMergesStoredAttributes() {
+	suite.tc.AddFeatureTest(entities.Feature{Key: "one"})
+	suite.NoError(suite.provider.Upsert("", "testUser", map[string]interface{}{"plan": "free", "country": "US"}))
+
+	rec := suite.serve("POST", "/decide?keys=one", DecideBody{
+		UserID:         "testUser",
+		UserAttributes: map[string]interface{}{"plan": "pro"},
+		DecideOptions:  []string{"DISABLE_DECISION_EVENT"},
+	})
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual client.OptimizelyDecision
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal(map[string]interface{}{"plan": "pro", "country": "US"}, actual.UserContext.Attributes)
+}
+
+func (suite *UserAttributesTestSuite) TestDecideWithFailingProvider() {
+	suite.tc.AddFeatureTest(entities.Feature{Key: "one"})
+	suite.oc.UserAttributes = failingProvider{}
+
+	rec := suite.serve("POST", "/decide?keys=one", DecideBody{
+		UserID:         "testUser",
+		UserAttributes: map[string]interface{}{"plan": "pro"},
+		DecideOptions:  []string{"DISABLE_DECISION_EVENT"},
+	})
+	suite.Equal(http.StatusOK, rec.Code)
+
+	var actual client.OptimizelyDecision
+	suite.NoError(json.Unmarshal(rec.Body.Bytes(), &actual))
+	suite.Equal(map[string]interface{}{"plan": "pro"}, actual.UserContext.Attributes)
+}
+
+func TestUserAttributesTestSuite(t *testing.T) {
+	suite.Run(t, new(UserAttributesTestSuite))
+}
@@ -0,0 +1,109 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package idempotency //
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type storeKey struct {
+	sdkKey string
+	key    string
+}
+
+type reservation struct {
+	storeKey
+	expiresAt time.Time
+}
+
+// InMemoryStore remembers the keys in memory, they are local to an Agent node.
+// Once maxKeys are remembered the oldest keys are forgotten before the end of their window.
+type InMemoryStore struct {
+	mu      sync.Mutex
+	window  time.Duration
+	maxKeys int
+	keys    map[storeKey]time.Time
+	// order holds the reservations oldest first, which is also the order they expire in
+	order []reservation
+}
+
+// NewInMemoryStore returns a store remembering the keys for window, maxKeys at most. 0 means no limit.
+func NewInMemoryStore(window time.Duration, maxKeys int) *InMemoryStore {
+	return &InMemoryStore{
+		window:  window,
+		maxKeys: maxKeys,
+		keys:    make(map[storeKey]time.Time),
+	}
+}
+
+// Reserve remembers the key
+func (s *InMemoryStore) Reserve(_ context.Context, sdkKey, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.prune(now)
+
+	k := storeKey{sdkKey: sdkKey, key: key}
+	if _, ok := s.keys[k]; ok {
+		return false, nil
+	}
+
+	expiresAt := now.Add(s.window)
+	s.keys[k] = expiresAt
+	s.order = append(s.order, reservation{storeKey: k, expiresAt: expiresAt})
+	for s.maxKeys > 0 && len(s.keys) > s.maxKeys {
+		s.pop()
+	}
+	return true, nil
+}
+
+// Release forgets the key
+func (s *InMemoryStore) Release(_ context.Context, sdkKey, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, storeKey{sdkKey: sdkKey, key: key})
+	return nil
+}
+
+// Len returns the number of remembered keys
+func (s *InMemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.keys)
+}
+
+// prune forgets the keys whose window has ended
+func (s *InMemoryStore) prune(now time.Time) {
+	for len(s.order) > 0 && !now.Before(s.order[0].expiresAt) {
+		s.pop()
+	}
+}
+
+// pop forgets the oldest reservation, unless its key was released and reserved again since
+func (s *InMemoryStore) pop() {
+	oldest := s.order[0]
+	s.order[0] = reservation{}
+	s.order = s.order[1:]
+	if expiresAt, ok := s.keys[oldest.storeKey]; ok && expiresAt.Equal(oldest.expiresAt) {
+		delete(s.keys, oldest.storeKey)
+	}
+}
@@ -0,0 +1,95 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package idempotency //
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore(time.Hour, 0)
+
+	reserved, err := store.Reserve(ctx, "sdkKey", "key")
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+
+	reserved, err = store.Reserve(ctx, "sdkKey", "key")
+	assert.NoError(t, err)
+	assert.False(t, reserved)
+
+	// Keys are scoped to the SDK key
+	reserved, err = store.Reserve(ctx, "other", "key")
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+
+	assert.NoError(t, store.Release(ctx, "sdkKey", "key"))
+	reserved, err = store.Reserve(ctx, "sdkKey", "key")
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+	assert.Equal(t, 2, store.Len())
+}
+
+func TestInMemoryStoreWindow(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore(time.Millisecond, 0)
+
+	reserved, _ := store.Reserve(ctx, "sdkKey", "key")
+	assert.True(t, reserved)
+
+	time.Sleep(5 * time.Millisecond)
+	reserved, _ = store.Reserve(ctx, "sdkKey", "key")
+	assert.True(t, reserved)
+	assert.Equal(t, 1, store.Len())
+	assert.Len(t, store.order, 1)
+}
+
+func TestInMemoryStoreMaxKeys(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore(time.Hour, 2)
+
+	_, _ = store.Reserve(ctx, "sdkKey", "1")
+	_, _ = store.Reserve(ctx, "sdkKey", "2")
+	_, _ = store.Reserve(ctx, "sdkKey", "3")
+	assert.Equal(t, 2, store.Len())
+
+	// The oldest key is forgotten first
+	reserved, _ := store.Reserve(ctx, "sdkKey", "3")
+	assert.False(t, reserved)
+	reserved, _ = store.Reserve(ctx, "sdkKey", "1")
+	assert.True(t, reserved)
+}
+
+func TestInMemoryStoreReleasedKeyReservedAgain(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore(time.Hour, 2)
+
+	_, _ = store.Reserve(ctx, "sdkKey", "1")
+	_ = store.Release(ctx, "sdkKey", "1")
+	_, _ = store.Reserve(ctx, "sdkKey", "2")
+	_, _ = store.Reserve(ctx, "sdkKey", "1")
+
+	// Popping the stale reservation of the released key doesn't forget its new reservation
+	store.pop()
+	reserved, _ := store.Reserve(ctx, "sdkKey", "1")
+	assert.False(t, reserved)
+}
@@ -0,0 +1,65 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package idempotency //
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/optimizely/agent/config"
+)
+
+// RedisStore remembers the keys in Redis, each one as a Redis key expiring at the end of its window
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+	window time.Duration
+}
+
+// NewRedisStore returns a store remembering the keys for window
+func NewRedisStore(conf config.IdempotencyRedisConfig, window time.Duration) (*RedisStore, error) {
+	if conf.Host == "" {
+		return nil, errors.New("idempotency redis host not provided")
+	}
+	if conf.Prefix == "" {
+		return nil, errors.New("idempotency redis prefix not provided")
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     conf.Host,
+		Password: conf.Password,
+		DB:       conf.Database,
+	})
+	return &RedisStore{client: client, prefix: conf.Prefix, window: window}, nil
+}
+
+// Reserve remembers the key unless it is already set
+func (s *RedisStore) Reserve(ctx context.Context, sdkKey, key string) (bool, error) {
+	return s.client.SetNX(ctx, s.redisKey(sdkKey, key), 1, s.window).Result()
+}
+
+// Release forgets the key
+func (s *RedisStore) Release(ctx context.Context, sdkKey, key string) error {
+	return s.client.Del(ctx, s.redisKey(sdkKey, key)).Err()
+}
+
+func (s *RedisStore) redisKey(sdkKey, key string) string {
+	return s.prefix + ":" + sdkKey + ":" + key
+}
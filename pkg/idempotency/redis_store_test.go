@@ -0,0 +1,52 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package idempotency //
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/optimizely/agent/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRedisStore(t *testing.T) {
+	_, err := NewRedisStore(config.IdempotencyRedisConfig{Prefix: "keys"}, time.Hour)
+	assert.Error(t, err)
+
+	_, err = NewRedisStore(config.IdempotencyRedisConfig{Host: "localhost:6379"}, time.Hour)
+	assert.Error(t, err)
+
+	store, err := NewRedisStore(config.IdempotencyRedisConfig{Host: "localhost:6379", Prefix: "keys"}, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, "keys:sdkKey:key", store.redisKey("sdkKey", "key"))
+}
+
+func TestRedisStoreUnavailable(t *testing.T) {
+	store, err := NewRedisStore(config.IdempotencyRedisConfig{Host: "127.0.0.1:1", Prefix: "keys"}, time.Hour)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = store.Reserve(ctx, "sdkKey", "key")
+	assert.Error(t, err)
+	assert.Error(t, store.Release(ctx, "sdkKey", "key"))
+}
@@ -0,0 +1,49 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package idempotency remembers the idempotency keys of tracked events so that retried events aren't tracked twice
+package idempotency
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/optimizely/agent/config"
+)
+
+// Store remembers the keys of an SDK key for a bounded window
+type Store interface {
+	// Reserve remembers the key, it returns false when the key is already remembered
+	Reserve(ctx context.Context, sdkKey, key string) (bool, error)
+	// Release forgets the key so that its event can be tracked again, when tracking it failed
+	Release(ctx context.Context, sdkKey, key string) error
+}
+
+// NewStore returns the store described by conf
+func NewStore(conf config.IdempotencyConfig) (Store, error) {
+	if conf.Window <= 0 {
+		return nil, fmt.Errorf("invalid idempotency window: %s", conf.Window)
+	}
+
+	switch conf.Store {
+	case "", config.IdempotencyStoreInMemory:
+		return NewInMemoryStore(conf.Window, conf.MaxKeys), nil
+	case config.IdempotencyStoreRedis:
+		return NewRedisStore(conf.Redis, conf.Window)
+	default:
+		return nil, fmt.Errorf("unknown idempotency store: %q", conf.Store)
+	}
+}
@@ -0,0 +1,45 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package idempotency //
+package idempotency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/optimizely/agent/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStore(t *testing.T) {
+	store, err := NewStore(config.IdempotencyConfig{Store: config.IdempotencyStoreInMemory, Window: time.Hour, MaxKeys: 10})
+	assert.NoError(t, err)
+	assert.IsType(t, &InMemoryStore{}, store)
+
+	store, err = NewStore(config.IdempotencyConfig{Store: config.IdempotencyStoreRedis, Window: time.Hour, Redis: config.IdempotencyRedisConfig{Host: "localhost:6379", Prefix: "keys"}})
+	assert.NoError(t, err)
+	assert.IsType(t, &RedisStore{}, store)
+
+	_, err = NewStore(config.IdempotencyConfig{Store: config.IdempotencyStoreRedis, Window: time.Hour})
+	assert.Error(t, err)
+
+	_, err = NewStore(config.IdempotencyConfig{Store: "unknown", Window: time.Hour})
+	assert.Error(t, err)
+
+	_, err = NewStore(config.IdempotencyConfig{Store: config.IdempotencyStoreInMemory})
+	assert.Error(t, err)
+}
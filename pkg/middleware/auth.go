@@ -30,6 +30,7 @@ import (
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
@@ -197,26 +198,30 @@ func (c *JWTVerifierURL) CheckToken(token string) (tk *jwt.Token, err error) {
 }
 
 func (a Auth) verify(r *http.Request) (*jwt.Token, error) {
+	return a.CheckToken(ExtractToken(r.Header))
+}
+
+// ExtractToken returns the token given in the Auth, Jwt or Authorization header
+func ExtractToken(header http.Header) string {
 
 	var token string
 
-	if values, ok := r.Header["Auth"]; ok && len(values) > 0 {
+	if values, ok := header["Auth"]; ok && len(values) > 0 {
 		token = values[0]
 	}
 
-	if values, ok := r.Header["Jwt"]; ok && len(values) > 0 {
+	if values, ok := header["Jwt"]; ok && len(values) > 0 {
 		token = values[0]
 	}
 
-	if values, ok := r.Header["Authorization"]; ok && len(values) > 0 {
+	if values, ok := header["Authorization"]; ok && len(values) > 0 {
 		value := values[0]
 		for _, key := range []string{"JWT", "Bearer"} {
 			token = strings.TrimSpace(strings.TrimLeft(value, key))
 		}
 	}
 
-	return a.CheckToken(token)
-
+	return token
 }
 
 func (a Auth) enabled() bool {
@@ -266,35 +271,9 @@ func (a Auth) AuthorizeAPI(next http.Handler) http.Handler {
 			return
 		}
 
-		if a.enabled() {
-			claims := tk.Claims.(jwt.MapClaims)
-			if expired := (getNumberFromJSON(claims["exp"]) - time.Now().Unix()) <= 0; expired {
-				RenderError(errors.New("token expired"), http.StatusUnauthorized, w, r)
-				return
-			}
-
-			rawClaimsSdkKeys, ok := claims["sdk_keys"].([]interface{})
-			if !ok {
-				RenderError(errors.New("invalid claims: sdk_keys not found, or have the wrong type"), http.StatusUnauthorized, w, r)
-				return
-			}
-			sdkKeyFromHeader := r.Header.Get(OptlySDKHeader)
-			foundMatchingSdkKey := false
-			for _, rawSdkKey := range rawClaimsSdkKeys {
-				sdkKey, ok := rawSdkKey.(string)
-				if !ok {
-					GetLogger(r).Warn().Msgf("Non-string value in token claims sdk_keys: %v", sdkKey)
-					continue
-				}
-				if sdkKey == sdkKeyFromHeader {
-					foundMatchingSdkKey = true
-					break
-				}
-			}
-			if !foundMatchingSdkKey {
-				RenderError(errors.New("SDK key given in X-Optimizely-Sdk-Key header was not found in the SDK keys in this token's claims"), http.StatusUnauthorized, w, r)
-				return
-			}
+		if err := a.checkAPIClaims(tk, r.Header.Get(OptlySDKHeader), GetLogger(r)); err != nil {
+			RenderError(err, http.StatusUnauthorized, w, r)
+			return
 		}
 
 		next.ServeHTTP(w, r)
@@ -302,6 +281,42 @@ func (a Auth) AuthorizeAPI(next http.Handler) http.Handler {
 	return http.HandlerFunc(fn)
 }
 
+// AuthorizeAPIToken checks the token, then that it grants access to the SDK key, as AuthorizeAPI does for requests
+func (a Auth) AuthorizeAPIToken(token, sdkKey string, logger *zerolog.Logger) error {
+	tk, err := a.CheckToken(token)
+	if err != nil {
+		return err
+	}
+	return a.checkAPIClaims(tk, sdkKey, logger)
+}
+
+func (a Auth) checkAPIClaims(tk *jwt.Token, sdkKey string, logger *zerolog.Logger) error {
+	if !a.enabled() {
+		return nil
+	}
+
+	claims := tk.Claims.(jwt.MapClaims)
+	if expired := (getNumberFromJSON(claims["exp"]) - time.Now().Unix()) <= 0; expired {
+		return errors.New("token expired")
+	}
+
+	rawClaimsSdkKeys, ok := claims["sdk_keys"].([]interface{})
+	if !ok {
+		return errors.New("invalid claims: sdk_keys not found, or have the wrong type")
+	}
+	for _, rawSdkKey := range rawClaimsSdkKeys {
+		claimsSdkKey, ok := rawSdkKey.(string)
+		if !ok {
+			logger.Warn().Msgf("Non-string value in token claims sdk_keys: %v", rawSdkKey)
+			continue
+		}
+		if claimsSdkKey == sdkKey {
+			return nil
+		}
+	}
+	return errors.New("SDK key given in X-Optimizely-Sdk-Key header was not found in the SDK keys in this token's claims")
+}
+
 // NewAuth makes Auth middleware
 func NewAuth(authConfig *config.ServiceAuthConfig) *Auth {
 
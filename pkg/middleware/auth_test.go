@@ -345,6 +345,20 @@ func (suite *AuthTestSuite) TestAuthAuthorizeAPITokenValidClaimsMultipleSDKKeys(
 	suite.Equal(http.StatusOK, rec.Code)
 }
 
+func (suite *AuthTestSuite) TestAuthorizeAPIToken() {
+	auth := NewAuth(suite.authConfig)
+	logger := GetLogger(httptest.NewRequest("GET", "/", nil))
+
+	suite.NoError(auth.AuthorizeAPIToken(suite.validAPIToken.Raw, "SDK_KEY", logger))
+	suite.NoError(auth.AuthorizeAPIToken(suite.validAPITokenMultiSdkKey.Raw, "SDK_KEY_2", logger))
+	suite.EqualError(auth.AuthorizeAPIToken("", "SDK_KEY", logger), "empty token")
+	suite.EqualError(auth.AuthorizeAPIToken(suite.expiredToken.Raw, "SDK_KEY", logger), "token expired")
+	suite.EqualError(auth.AuthorizeAPIToken(suite.validAdminToken.Raw, "SDK_KEY", logger), "invalid claims: sdk_keys not found, or have the wrong type")
+	suite.Error(auth.AuthorizeAPIToken(suite.validAPIToken.Raw, "OTHER_SDK_KEY", logger))
+
+	suite.NoError(NewAuth(&config.ServiceAuthConfig{}).AuthorizeAPIToken("", "SDK_KEY", logger))
+}
+
 func (suite *AuthTestSuite) TestAuthAuthorizeAdminTokenAuthorizationValidClaims() {
 
 	auth := NewAuth(suite.authConfig)
@@ -394,6 +408,14 @@ func (suite *AuthTestSuite) TestAuthAuthorizeInvalidJwksURL() {
 	suite.Nil(auth)
 }
 
+func TestExtractToken(t *testing.T) {
+	assert.Equal(t, "", ExtractToken(http.Header{}))
+	assert.Equal(t, "token", ExtractToken(http.Header{"Auth": []string{"token"}}))
+	assert.Equal(t, "token", ExtractToken(http.Header{"Jwt": []string{"token"}}))
+	assert.Equal(t, "token", ExtractToken(http.Header{"Authorization": []string{"Bearer token"}}))
+	assert.Equal(t, "token", ExtractToken(http.Header{"Auth": []string{"other"}, "Authorization": []string{"Bearer token"}}))
+}
+
 func TestAuth(t *testing.T) {
 	suite.Run(t, new(AuthTestSuite))
 }
@@ -50,6 +50,9 @@ const OptlyUPSHeader = "X-Optimizely-UPS-Name"
 // OptlyODPCacheHeader is the header key for an ad-hoc ODP Cache name
 const OptlyODPCacheHeader = "X-Optimizely-ODP-Cache-Name"
 
+// ErrMissingSDKKey is returned when no SDK key is provided
+var ErrMissingSDKKey = fmt.Errorf("missing required %s header", OptlySDKHeader)
+
 // CachedOptlyMiddleware implements OptlyMiddleware backed by a cache
 type CachedOptlyMiddleware struct {
 	Cache optimizely.Cache
@@ -61,39 +64,17 @@ type CachedOptlyMiddleware struct {
 func (mw *CachedOptlyMiddleware) ClientCtx(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		sdkKey := r.Header.Get(OptlySDKHeader)
-		if sdkKey == "" {
-			RenderError(fmt.Errorf("missing required %s header", OptlySDKHeader), http.StatusBadRequest, w, r)
-			return
-		}
-
-		upsKey := r.Header.Get(OptlyUPSHeader)
-		// Storing Provided UserProfileService Key in cache, to be used for requests with the given sdkKey.
-		// This UserProfileService Key will override the default UserProfileService provided in Client Config.
-		if upsKey != "" {
-			mw.Cache.SetUserProfileService(sdkKey, upsKey)
-		}
-
-		odpCacheKey := r.Header.Get(OptlyODPCacheHeader)
-		// Storing Provided odpCache Key in cache, to be used for requests with the given sdkKey.
-		// This odpCache Key will override the default odpCache provided in Client Config.
-		if odpCacheKey != "" {
-			mw.Cache.SetODPCache(sdkKey, odpCacheKey)
-		}
-
-		optlyClient, err := mw.Cache.GetClient(sdkKey)
+		optlyClient, err := mw.GetClient(sdkKey, r.Header.Get(OptlyUPSHeader), r.Header.Get(OptlyODPCacheHeader))
 		if err != nil {
-			GetLogger(r).Error().Err(err).Msg("Initializing OptimizelyClient")
-
-			switch {
-			// Check if error indicates a 403 from the CDN. Ideally we'd use errors.Is(), but the go-sdk isn't 1.13
-			case strings.Contains(err.Error(), "403"):
-				RenderError(err, http.StatusForbidden, w, r)
-			case errors.Is(err, optimizely.ErrValidationFailure):
-				RenderError(err, http.StatusBadRequest, w, r)
-			default:
-				RenderError(fmt.Errorf("failed to instantiate Optimizely for SDK Key: %s", sdkKey), http.StatusInternalServerError, w, r)
+			if !errors.Is(err, ErrMissingSDKKey) {
+				GetLogger(r).Error().Err(err).Msg("Initializing OptimizelyClient")
 			}
 
+			status := ClientErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				err = fmt.Errorf("failed to instantiate Optimizely for SDK Key: %s", sdkKey)
+			}
+			RenderError(err, status, w, r)
 			return
 		}
 
@@ -101,3 +82,37 @@ func (mw *CachedOptlyMiddleware) ClientCtx(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// GetClient returns the OptlyClient of the SDK key from the cache. The UserProfileService and ODP cache names,
+// when provided, are stored in the cache to be used for requests with the given SDK key, overriding the
+// defaults of the Client Config.
+func (mw *CachedOptlyMiddleware) GetClient(sdkKey, upsKey, odpCacheKey string) (*optimizely.OptlyClient, error) {
+	if sdkKey == "" {
+		return nil, ErrMissingSDKKey
+	}
+
+	if upsKey != "" {
+		mw.Cache.SetUserProfileService(sdkKey, upsKey)
+	}
+
+	if odpCacheKey != "" {
+		mw.Cache.SetODPCache(sdkKey, odpCacheKey)
+	}
+
+	return mw.Cache.GetClient(sdkKey)
+}
+
+// ClientErrorStatus returns the http status of an error returned by GetClient
+func ClientErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrMissingSDKKey):
+		return http.StatusBadRequest
+	// Check if error indicates a 403 from the CDN. Ideally we'd use errors.Is(), but the go-sdk isn't 1.13
+	case strings.Contains(err.Error(), "403"):
+		return http.StatusForbidden
+	case errors.Is(err, optimizely.ErrValidationFailure):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -122,6 +122,30 @@ func (suite *OptlyMiddlewareTestSuite) TestGetClientMissingHeader() {
 	suite.Equal(http.StatusBadRequest, rec.Code)
 }
 
+func (suite *OptlyMiddlewareTestSuite) TestGetClient() {
+	suite.mw.Cache.(*MockCache).On("SetUserProfileService", "EXPECTED", "in-memory")
+	suite.mw.Cache.(*MockCache).On("SetODPCache", "EXPECTED", "redis")
+
+	optlyClient, err := suite.mw.GetClient("EXPECTED", "in-memory", "redis")
+	suite.NoError(err)
+	suite.Equal(&expectedClient, optlyClient)
+	suite.mw.Cache.(*MockCache).AssertCalled(suite.T(), "SetUserProfileService", "EXPECTED", "in-memory")
+	suite.mw.Cache.(*MockCache).AssertCalled(suite.T(), "SetODPCache", "EXPECTED", "redis")
+
+	_, err = suite.mw.GetClient("", "", "")
+	suite.Equal(ErrMissingSDKKey, err)
+	suite.Equal(http.StatusBadRequest, ClientErrorStatus(err))
+
+	_, err = suite.mw.GetClient("403", "", "")
+	suite.Equal(http.StatusForbidden, ClientErrorStatus(err))
+
+	_, err = suite.mw.GetClient("INVALID", "", "")
+	suite.Equal(http.StatusBadRequest, ClientErrorStatus(err))
+
+	_, err = suite.mw.GetClient("ERROR", "", "")
+	suite.Equal(http.StatusInternalServerError, ClientErrorStatus(err))
+}
+
 func (suite *OptlyMiddlewareTestSuite) TestGetClientWithUserProfileService() {
 	handler := suite.mw.ClientCtx(AssertOptlyClientHandler(suite, &expectedClient))
 	req := httptest.NewRequest("GET", "/", nil)
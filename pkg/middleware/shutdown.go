@@ -0,0 +1,39 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package middleware //
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+// shutdownKey is the context key of the channel closed when the server starts shutting down
+const shutdownKey = contextKey("shutdown")
+
+// WithShutdown returns a copy of ctx carrying the channel closed when the server starts shutting down
+func WithShutdown(ctx context.Context, shutdown <-chan struct{}) context.Context {
+	return context.WithValue(ctx, shutdownKey, shutdown)
+}
+
+// ShuttingDown returns the channel closed when the server handling the request starts shutting down.
+// Long lived handlers, like event streams, return once it is closed so that they don't hold up the shutdown.
+// The channel is nil, and never closed, when the server didn't provide one.
+func ShuttingDown(r *http.Request) <-chan struct{} {
+	shutdown, _ := r.Context().Value(shutdownKey).(<-chan struct{})
+	return shutdown
+}
@@ -0,0 +1,38 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package middleware //
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShuttingDown(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	assert.Nil(t, ShuttingDown(req))
+
+	shutdown := make(chan struct{})
+	req = req.WithContext(WithShutdown(req.Context(), shutdown))
+	assert.Equal(t, (<-chan struct{})(shutdown), ShuttingDown(req))
+
+	close(shutdown)
+	_, open := <-ShuttingDown(req)
+	assert.False(t, open)
+}
@@ -0,0 +1,357 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package notificationwebhook delivers the notifications of SDK keys to the HTTP endpoints subscribed to them
+package notificationwebhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	go_kit_metrics "github.com/go-kit/kit/metrics"
+	"github.com/optimizely/go-sdk/pkg/notification"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/pkg/handlers"
+	"github.com/optimizely/agent/pkg/metrics"
+	"github.com/optimizely/agent/pkg/optimizely"
+	"github.com/optimizely/agent/pkg/syncer"
+)
+
+// SignatureHeader is the header holding the HMAC-SHA256 of the timestamp and the body of a delivery, signed with the
+// secret of its subscription, as "sha256=" followed by the hex encoded digest
+const SignatureHeader = "X-Optimizely-Signature"
+
+// TimestampHeader is the header holding the Unix time, in seconds, at which a signed delivery was sent. Being signed
+// along with the body, it lets the endpoints reject the deliveries replayed long after they were sent.
+const TimestampHeader = "X-Optimizely-Timestamp"
+
+const signaturePrefix = "sha256="
+
+// Metrics keys of the deliveries
+const (
+	deliveredKey = "notificationWebhooks.delivered"
+	failedKey    = "notificationWebhooks.failed"
+	droppedKey   = "notificationWebhooks.dropped"
+	retriesKey   = "notificationWebhooks.retries"
+	queuedKey    = "notificationWebhooks.queued"
+	deliveryKey  = "notificationWebhooks.delivery"
+)
+
+// Notification is a notification of a delivery
+type Notification struct {
+	Type    notification.Type `json:"type"`
+	Message interface{}       `json:"message"`
+}
+
+// Payload is the body of a delivery
+type Payload struct {
+	SDKKey        string         `json:"sdkKey"`
+	Notifications []Notification `json:"notifications"`
+}
+
+// Webhooks delivers the notifications of the SDK keys to their subscriptions. The notifications of each SDK key are
+// queued for every subscription accepting their type, then delivered in batches. Batches failing to be delivered are
+// retried with a backoff, and the notifications are dropped when the queue of their subscription is full.
+type Webhooks struct {
+	conf                   config.NotificationWebhooksConfig
+	notificationReceiverFn handlers.NotificationReceiverFunc
+	client                 *http.Client
+
+	delivered go_kit_metrics.Counter
+	failed    go_kit_metrics.Counter
+	dropped   go_kit_metrics.Counter
+	retries   go_kit_metrics.Counter
+	queued    go_kit_metrics.Gauge
+	delivery  *metrics.Timer
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type subscription struct {
+	config.NotificationWebhookSubscription
+	types  map[notification.Type]string
+	queue  chan syncer.Event
+	logger zerolog.Logger
+}
+
+// NewWebhooks returns the Webhooks of the configuration, receiving the notifications of the SDK keys from the
+// notification receiver. The deliveries are reported to the metrics registry.
+func NewWebhooks(conf config.NotificationWebhooksConfig, notificationReceiverFn handlers.NotificationReceiverFunc, metricsRegistry *metrics.Registry) *Webhooks {
+	return &Webhooks{
+		conf:                   conf,
+		notificationReceiverFn: notificationReceiverFn,
+		client:                 &http.Client{Timeout: conf.Timeout},
+		delivered:              metricsRegistry.GetCounter(deliveredKey),
+		failed:                 metricsRegistry.GetCounter(failedKey),
+		dropped:                metricsRegistry.GetCounter(droppedKey),
+		retries:                metricsRegistry.GetCounter(retriesKey),
+		queued:                 metricsRegistry.GetGauge(queuedKey),
+		delivery:               metricsRegistry.NewTimer(deliveryKey),
+	}
+}
+
+// NewDefaultWebhooks returns the Webhooks of the configuration, registering with the notification center of each
+// SDK key as the notifications event stream does. The notifications are only those of this Agent, so that they are
+// delivered once when several Agents synchronize their notifications.
+func NewDefaultWebhooks(conf config.NotificationWebhooksConfig, metricsRegistry *metrics.Registry) *Webhooks {
+	return NewWebhooks(conf, handlers.DefaultNotificationReceiver, metricsRegistry)
+}
+
+// Start starts receiving and delivering the notifications of the subscribed SDK keys until Close is called
+func (w *Webhooks) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	subscriptions := make(map[string][]*subscription)
+	for _, conf := range w.conf.Subscriptions {
+		if conf.SDKKey == "" || conf.URL == "" {
+			log.Warn().Str("url", conf.URL).Msg("Ignoring notification webhook subscription missing its sdkKey or url")
+			continue
+		}
+
+		logger := newLogger(conf.SDKKey).With().Str("url", conf.URL).Logger()
+
+		queueSize := w.conf.QueueSize
+		if queueSize < 1 {
+			queueSize = 1
+		}
+		subscriptions[conf.SDKKey] = append(subscriptions[conf.SDKKey], &subscription{
+			NotificationWebhookSubscription: conf,
+			types:                           handlers.NotificationFilter(conf.Types),
+			queue:                           make(chan syncer.Event, queueSize),
+			logger:                          logger,
+		})
+	}
+
+	for sdkKey, subs := range subscriptions {
+		receiverCtx := context.WithValue(ctx, handlers.SDKKey, sdkKey)
+		logger := newLogger(sdkKey)
+		events, err := w.notificationReceiverFn(context.WithValue(receiverCtx, handlers.LoggerKey, &logger))
+		if err != nil {
+			cancel()
+			return fmt.Errorf("failed to receive the notifications of the webhook subscriptions: %w", err)
+		}
+
+		w.wg.Add(1)
+		go func(subs []*subscription) {
+			defer w.wg.Done()
+			w.enqueue(ctx, events, subs)
+		}(subs)
+
+		for _, s := range subs {
+			w.wg.Add(1)
+			go func(sdkKey string, s *subscription) {
+				defer w.wg.Done()
+				w.deliver(ctx, sdkKey, s)
+			}(sdkKey, s)
+		}
+	}
+
+	return nil
+}
+
+// Close stops receiving the notifications, then delivers the queued notifications once before returning
+func (w *Webhooks) Close() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.wg.Wait()
+}
+
+// enqueue queues the notifications for the subscriptions accepting their type, without waiting
+// so that the notification center is never held up by the deliveries. The queues are closed once it returns.
+func (w *Webhooks) enqueue(ctx context.Context, events <-chan syncer.Event, subs []*subscription) {
+	defer func() {
+		for _, s := range subs {
+			close(s.queue)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			for _, s := range subs {
+				if _, found := s.types[event.Type]; !found {
+					continue
+				}
+				select {
+				case s.queue <- event:
+					w.queued.Add(1)
+				default:
+					w.dropped.Add(1)
+					s.logger.Warn().Str("type", string(event.Type)).Msg("Notification webhook queue is full, dropping notification")
+				}
+			}
+		}
+	}
+}
+
+// deliver delivers the queued notifications once the batch is complete or the flush interval elapsed,
+// until the queue is closed
+func (w *Webhooks) deliver(ctx context.Context, sdkKey string, s *subscription) {
+	batchSize := w.conf.BatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	flushInterval := w.conf.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = time.Second
+	}
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Notification, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		payload := Payload{SDKKey: sdkKey, Notifications: batch}
+		// Once closing, the remaining notifications are delivered without retries
+		w.send(ctx, s, payload, ctx.Err() == nil)
+		batch = make([]Notification, 0, batchSize)
+	}
+
+	for {
+		select {
+		case event, ok := <-s.queue:
+			if !ok {
+				flush()
+				return
+			}
+			w.queued.Add(-1)
+			batch = append(batch, Notification{Type: event.Type, Message: event.Message})
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// send delivers the payload, retrying with a backoff until it is delivered or the attempts are exhausted.
+// Once the context is done, the backoff is no longer waited on and the delivery is attempted a last time.
+func (w *Webhooks) send(ctx context.Context, s *subscription, payload Payload, retry bool) {
+	count := float64(len(payload.Notifications))
+	body, err := json.Marshal(payload)
+	if err != nil {
+		w.failed.Add(count)
+		s.logger.Error().Err(err).Msg("Failed to marshal notification webhook payload")
+		return
+	}
+
+	backoff := w.conf.Backoff.Initial
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		err = w.post(s, body)
+		w.delivery.Update(time.Since(start).Seconds() * 1000.0)
+		if err == nil {
+			w.delivered.Add(count)
+			return
+		}
+
+		var permanent permanentError
+		if !retry || errors.As(err, &permanent) || attempt >= w.conf.MaxAttempts {
+			w.failed.Add(count)
+			s.logger.Warn().Err(err).Int("attempts", attempt).Int("notifications", len(payload.Notifications)).Msg("Failed to deliver notifications to webhook")
+			return
+		}
+
+		w.retries.Add(1)
+		s.logger.Debug().Err(err).Int("attempt", attempt).Dur("backoff", backoff).Msg("Retrying notification webhook delivery")
+		select {
+		case <-ctx.Done():
+			retry = false
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if w.conf.Backoff.Max > 0 && backoff > w.conf.Backoff.Max {
+			backoff = w.conf.Backoff.Max
+		}
+	}
+}
+
+// permanentError is the error of a delivery which fails the same way when it is retried
+type permanentError struct {
+	error
+}
+
+// post delivers the body, the request is not canceled on close so that the notifications are not delivered twice
+func (w *Webhooks) post(s *subscription, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Secret != "" {
+		// Each attempt is signed when it is sent, so that retries are not mistaken for replays
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, Sign(timestamp, body, s.Secret))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	default:
+		return permanentError{fmt.Errorf("webhook responded with status %d", resp.StatusCode)}
+	}
+}
+
+func newLogger(sdkKey string) zerolog.Logger {
+	if optimizely.ShouldIncludeSDKKey {
+		return log.With().Str("sdkKey", strings.Split(sdkKey, ":")[0]).Logger()
+	}
+	return log.Logger
+}
+
+// Sign returns the value of the SignatureHeader of the body sent at the timestamp for the secret, which is the
+// HMAC-SHA256 of the timestamp, a "." and the body
+func Sign(timestamp string, body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(timestamp + "."))
+	_, _ = mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
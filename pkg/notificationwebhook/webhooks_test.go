@@ -0,0 +1,360 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package notificationwebhook //
+package notificationwebhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/optimizely/go-sdk/pkg/entities"
+	"github.com/optimizely/go-sdk/pkg/notification"
+	"github.com/optimizely/go-sdk/pkg/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/pkg/handlers"
+	"github.com/optimizely/agent/pkg/metrics"
+	"github.com/optimizely/agent/pkg/syncer"
+)
+
+// The expvar metrics can only be created once, so every test shares the registry and compares the counters
+var metricsRegistry = metrics.NewRegistry("")
+
+type delivery struct {
+	header  http.Header
+	body    []byte
+	payload Payload
+}
+
+// newServer returns a server responding with the status of the handler, and the channel of its deliveries
+func newServer(t *testing.T, status func(attempt int) int) (*httptest.Server, <-chan delivery) {
+	deliveries := make(chan delivery, 100)
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		d := delivery{header: r.Header, body: body}
+		assert.NoError(t, json.Unmarshal(body, &d.payload))
+		deliveries <- d
+		w.WriteHeader(status(int(atomic.AddInt32(&attempts, 1))))
+	}))
+	t.Cleanup(server.Close)
+	return server, deliveries
+}
+
+func ok(int) int {
+	return http.StatusOK
+}
+
+func newConfig(url string) config.NotificationWebhooksConfig {
+	return config.NotificationWebhooksConfig{
+		Subscriptions: []config.NotificationWebhookSubscription{{SDKKey: "sdkKey", URL: url}},
+		BatchSize:     1,
+		FlushInterval: time.Minute,
+		QueueSize:     10,
+		MaxAttempts:   3,
+		Backoff:       config.BackoffConfig{Initial: time.Millisecond, Max: 10 * time.Millisecond},
+		Timeout:       time.Second,
+	}
+}
+
+// channelReceiver returns a NotificationReceiverFunc streaming the events of the channel for the SDK key
+func channelReceiver(sdkKey string, events chan syncer.Event) handlers.NotificationReceiverFunc {
+	return func(ctx context.Context) (<-chan syncer.Event, error) {
+		if key, _ := ctx.Value(handlers.SDKKey).(string); key != sdkKey {
+			return nil, errors.New("unknown sdk key")
+		}
+		return events, nil
+	}
+}
+
+func track(messages ...string) []syncer.Event {
+	events := make([]syncer.Event, 0, len(messages))
+	for _, message := range messages {
+		events = append(events, syncer.Event{Type: notification.Track, Message: message})
+	}
+	return events
+}
+
+func receive(t *testing.T, deliveries <-chan delivery) delivery {
+	select {
+	case d := <-deliveries:
+		return d
+	case <-time.After(time.Second):
+		require.Fail(t, "notifications were not delivered")
+		return delivery{}
+	}
+}
+
+func assertNoDelivery(t *testing.T, deliveries <-chan delivery) {
+	select {
+	case d := <-deliveries:
+		assert.Fail(t, "unexpected delivery", "%s", d.body)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func counter(key string) float64 {
+	if v, ok := expvar.Get(metrics.CounterPrefix + "." + key).(*expvar.Float); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func notifications(messages ...string) []Notification {
+	n := make([]Notification, 0, len(messages))
+	for _, message := range messages {
+		n = append(n, Notification{Type: notification.Track, Message: message})
+	}
+	return n
+}
+
+func TestBatching(t *testing.T) {
+	server, deliveries := newServer(t, ok)
+	conf := newConfig(server.URL)
+	conf.BatchSize = 2
+
+	events := make(chan syncer.Event)
+	webhooks := NewWebhooks(conf, channelReceiver("sdkKey", events), metricsRegistry)
+	require.NoError(t, webhooks.Start())
+
+	delivered := counter(deliveredKey)
+	for _, event := range track("1", "2", "3", "4", "5") {
+		events <- event
+	}
+
+	assert.Equal(t, Payload{SDKKey: "sdkKey", Notifications: notifications("1", "2")}, receive(t, deliveries).payload)
+	assert.Equal(t, Payload{SDKKey: "sdkKey", Notifications: notifications("3", "4")}, receive(t, deliveries).payload)
+	assertNoDelivery(t, deliveries)
+
+	// The incomplete batch is delivered on close
+	webhooks.Close()
+	assert.Equal(t, Payload{SDKKey: "sdkKey", Notifications: notifications("5")}, receive(t, deliveries).payload)
+	assert.Equal(t, delivered+5, counter(deliveredKey))
+}
+
+func TestFlushInterval(t *testing.T) {
+	server, deliveries := newServer(t, ok)
+	conf := newConfig(server.URL)
+	conf.BatchSize = 10
+	conf.FlushInterval = 10 * time.Millisecond
+
+	events := make(chan syncer.Event)
+	webhooks := NewWebhooks(conf, channelReceiver("sdkKey", events), metricsRegistry)
+	require.NoError(t, webhooks.Start())
+	defer webhooks.Close()
+
+	events <- track("1")[0]
+	d := receive(t, deliveries)
+	assert.Equal(t, notifications("1"), d.payload.Notifications)
+	assert.Equal(t, "application/json", d.header.Get("Content-Type"))
+	assert.Empty(t, d.header.Get(SignatureHeader))
+	assert.Empty(t, d.header.Get(TimestampHeader))
+}
+
+func TestSignature(t *testing.T) {
+	server, deliveries := newServer(t, ok)
+	conf := newConfig(server.URL)
+	conf.Subscriptions[0].Secret = "secret"
+
+	events := make(chan syncer.Event)
+	webhooks := NewWebhooks(conf, channelReceiver("sdkKey", events), metricsRegistry)
+	require.NoError(t, webhooks.Start())
+	defer webhooks.Close()
+
+	before := time.Now().Unix()
+	events <- track("1")[0]
+	d := receive(t, deliveries)
+
+	timestamp := d.header.Get(TimestampHeader)
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, sent, before)
+	assert.LessOrEqual(t, sent, time.Now().Unix())
+
+	assert.Equal(t, Sign(timestamp, d.body, "secret"), d.header.Get(SignatureHeader))
+	assert.NotEqual(t, Sign(timestamp, d.body, "other"), d.header.Get(SignatureHeader))
+	assert.NotEqual(t, Sign(strconv.FormatInt(sent+1, 10), d.body, "secret"), d.header.Get(SignatureHeader))
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{"sdkKey":"sdkKey"}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=37638a59a16a7e22ce71b1f4a8fc08133bf729f06c68eb5e70033f33f35bf31a", Sign("1700000000", []byte(`{"sdkKey":"sdkKey"}`), "secret"))
+}
+
+func TestRetry(t *testing.T) {
+	server, deliveries := newServer(t, func(attempt int) int {
+		if attempt == 1 {
+			return http.StatusInternalServerError
+		}
+		return http.StatusOK
+	})
+
+	events := make(chan syncer.Event)
+	webhooks := NewWebhooks(newConfig(server.URL), channelReceiver("sdkKey", events), metricsRegistry)
+	require.NoError(t, webhooks.Start())
+
+	delivered, retries := counter(deliveredKey), counter(retriesKey)
+	events <- track("1")[0]
+	first, second := receive(t, deliveries), receive(t, deliveries)
+	assert.Equal(t, first.body, second.body)
+
+	webhooks.Close()
+	assert.Equal(t, delivered+1, counter(deliveredKey))
+	assert.Equal(t, retries+1, counter(retriesKey))
+}
+
+func TestAttemptsExhausted(t *testing.T) {
+	server, deliveries := newServer(t, func(int) int {
+		return http.StatusServiceUnavailable
+	})
+
+	events := make(chan syncer.Event)
+	webhooks := NewWebhooks(newConfig(server.URL), channelReceiver("sdkKey", events), metricsRegistry)
+	require.NoError(t, webhooks.Start())
+
+	failed := counter(failedKey)
+	events <- track("1")[0]
+	for i := 0; i < 3; i++ {
+		receive(t, deliveries)
+	}
+	assertNoDelivery(t, deliveries)
+
+	webhooks.Close()
+	assert.Equal(t, failed+1, counter(failedKey))
+}
+
+func TestPermanentFailure(t *testing.T) {
+	server, deliveries := newServer(t, func(int) int {
+		return http.StatusBadRequest
+	})
+
+	events := make(chan syncer.Event)
+	webhooks := NewWebhooks(newConfig(server.URL), channelReceiver("sdkKey", events), metricsRegistry)
+	require.NoError(t, webhooks.Start())
+
+	failed, retries := counter(failedKey), counter(retriesKey)
+	events <- track("1")[0]
+	receive(t, deliveries)
+	assertNoDelivery(t, deliveries)
+
+	webhooks.Close()
+	assert.Equal(t, failed+1, counter(failedKey))
+	assert.Equal(t, retries, counter(retriesKey))
+}
+
+func TestQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	server, deliveries := newServer(t, func(int) int {
+		<-release
+		return http.StatusOK
+	})
+	conf := newConfig(server.URL)
+	conf.QueueSize = 1
+
+	events := make(chan syncer.Event)
+	webhooks := NewWebhooks(conf, channelReceiver("sdkKey", events), metricsRegistry)
+	require.NoError(t, webhooks.Start())
+
+	dropped, delivered := counter(droppedKey), counter(deliveredKey)
+	events <- track("1")[0]
+	// The first notification is being delivered, so the second one fills the queue
+	receive(t, deliveries)
+	for _, event := range track("2", "3") {
+		events <- event
+	}
+
+	close(release)
+	assert.Equal(t, notifications("2"), receive(t, deliveries).payload.Notifications)
+	webhooks.Close()
+	assert.Equal(t, dropped+1, counter(droppedKey))
+	assert.Equal(t, delivered+2, counter(deliveredKey))
+}
+
+func TestSubscriptionTypes(t *testing.T) {
+	decisions, decisionDeliveries := newServer(t, ok)
+	all, allDeliveries := newServer(t, ok)
+	conf := newConfig(decisions.URL)
+	conf.Subscriptions[0].Types = []string{string(notification.Decision)}
+	conf.Subscriptions = append(conf.Subscriptions,
+		config.NotificationWebhookSubscription{SDKKey: "sdkKey", URL: all.URL},
+		config.NotificationWebhookSubscription{SDKKey: "sdkKey"},
+	)
+
+	events := make(chan syncer.Event)
+	receivers := 0
+	receiver := channelReceiver("sdkKey", events)
+	webhooks := NewWebhooks(conf, func(ctx context.Context) (<-chan syncer.Event, error) {
+		receivers++
+		return receiver(ctx)
+	}, metricsRegistry)
+	require.NoError(t, webhooks.Start())
+	defer webhooks.Close()
+	// The subscriptions of an SDK key share its notifications
+	assert.Equal(t, 1, receivers)
+
+	decision := syncer.Event{Type: notification.Decision, Message: "decision"}
+	events <- track("1")[0]
+	events <- decision
+
+	assert.Equal(t, []Notification{{Type: notification.Decision, Message: "decision"}}, receive(t, decisionDeliveries).payload.Notifications)
+	assertNoDelivery(t, decisionDeliveries)
+	assert.Equal(t, notifications("1"), receive(t, allDeliveries).payload.Notifications)
+	assert.Equal(t, []Notification{{Type: notification.Decision, Message: "decision"}}, receive(t, allDeliveries).payload.Notifications)
+}
+
+func TestStartError(t *testing.T) {
+	conf := newConfig("http://localhost")
+	conf.Subscriptions[0].SDKKey = "other"
+
+	webhooks := NewWebhooks(conf, channelReceiver("sdkKey", make(chan syncer.Event)), metricsRegistry)
+	assert.EqualError(t, webhooks.Start(), "failed to receive the notifications of the webhook subscriptions: unknown sdk key")
+	webhooks.Close()
+}
+
+func TestDefaultWebhooks(t *testing.T) {
+	server, deliveries := newServer(t, ok)
+	conf := newConfig(server.URL)
+	conf.Subscriptions[0].SDKKey = "defaultWebhooksSDKKey"
+
+	webhooks := NewDefaultWebhooks(conf, metricsRegistry)
+	require.NoError(t, webhooks.Start())
+	defer webhooks.Close()
+
+	nc := registry.GetNotificationCenter("defaultWebhooksSDKKey")
+	require.NoError(t, nc.Send(notification.Track, notification.TrackNotification{
+		EventKey:    "purchase",
+		UserContext: entities.UserContext{ID: "user1"},
+	}))
+
+	d := receive(t, deliveries)
+	assert.Equal(t, "defaultWebhooksSDKKey", d.payload.SDKKey)
+	require.Len(t, d.payload.Notifications, 1)
+	assert.Equal(t, notification.Track, d.payload.Notifications[0].Type)
+	assert.Equal(t, "purchase", d.payload.Notifications[0].Message.(map[string]interface{})["EventKey"])
+}
@@ -0,0 +1,210 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/go-sdk/pkg/decision/evaluator/matchers"
+	"github.com/optimizely/go-sdk/pkg/entities"
+)
+
+// Codes of the attribute warnings
+const (
+	// AttributeWarningUnknown is the code of attributes missing from the datafile attribute list
+	AttributeWarningUnknown = "unknownAttribute"
+	// AttributeWarningTypeMismatch is the code of attributes whose type no audience condition expects
+	AttributeWarningTypeMismatch = "typeMismatch"
+)
+
+// Attribute value types, as expected by audience conditions
+const (
+	attributeTypeString  = "string"
+	attributeTypeNumber  = "number"
+	attributeTypeBoolean = "boolean"
+	attributeTypeOther   = "other"
+)
+
+// reservedAttributePrefix prefixes the attributes reserved by the SDK, like $opt_bucketing_id
+const reservedAttributePrefix = "$opt_"
+
+// AttributeWarning model describing a request attribute the datafile doesn't expect
+type AttributeWarning struct {
+	Attribute     string   `json:"attribute"`
+	Code          string   `json:"code"`
+	Message       string   `json:"message"`
+	ExpectedTypes []string `json:"expectedTypes,omitempty"`
+	ActualType    string   `json:"actualType,omitempty"`
+}
+
+// revisionAttributeTypes are the value types expected by the audiences of a datafile revision
+type revisionAttributeTypes struct {
+	revision string
+	types    map[string]map[string]bool
+}
+
+// attributeTypesCache keeps the expected types of the last datafile revision validated against,
+// so that they are not collected from the audiences for every request
+type attributeTypesCache struct {
+	last atomic.Value
+}
+
+// get returns the expected types of the revision, collecting them from the audiences when the revision changed
+func (c *attributeTypesCache) get(revision string, audiences func() map[string]entities.Audience) map[string]map[string]bool {
+	if last, ok := c.last.Load().(*revisionAttributeTypes); ok && last.revision == revision {
+		return last.types
+	}
+	types := audienceAttributeTypes(audiences())
+	c.last.Store(&revisionAttributeTypes{revision: revision, types: types})
+	return types
+}
+
+// checkAttributeValidation returns an error when the mode is unknown, an empty mode keeps the default
+func checkAttributeValidation(mode config.AttributeValidationMode) error {
+	switch mode {
+	case "", config.AttributeValidationOff, config.AttributeValidationWarn, config.AttributeValidationReject:
+		return nil
+	default:
+		return fmt.Errorf("unknown attribute validation mode: %q", mode)
+	}
+}
+
+// ValidateAttributes checks the attributes against the current datafile. It returns a warning, sorted by attribute,
+// for every attribute missing from the datafile attribute list and for every attribute whose type is not expected
+// by any of the custom attribute audience conditions naming it. Attributes reserved by the SDK are not checked.
+func (c *OptlyClient) ValidateAttributes(attributes map[string]interface{}) ([]AttributeWarning, error) {
+	pc, err := c.ConfigManager.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	expectedTypes := c.attributeTypes.get(pc.GetRevision(), pc.GetAudienceMap)
+	warnings := []AttributeWarning{}
+	for _, key := range keys {
+		if strings.HasPrefix(key, reservedAttributePrefix) {
+			continue
+		}
+
+		if _, err := pc.GetAttributeByKey(key); err != nil {
+			warnings = append(warnings, AttributeWarning{
+				Attribute: key,
+				Code:      AttributeWarningUnknown,
+				Message:   fmt.Sprintf("attribute %q is not defined in the datafile", key),
+			})
+			continue
+		}
+
+		value := attributes[key]
+		expected := expectedTypes[key]
+		if value == nil || len(expected) == 0 {
+			continue
+		}
+
+		actual := attributeType(value)
+		if !expected[actual] {
+			types := make([]string, 0, len(expected))
+			for t := range expected {
+				types = append(types, t)
+			}
+			sort.Strings(types)
+			warnings = append(warnings, AttributeWarning{
+				Attribute:     key,
+				Code:          AttributeWarningTypeMismatch,
+				Message:       fmt.Sprintf("attribute %q is a %s, audience conditions expect %s", key, actual, strings.Join(types, " or ")),
+				ExpectedTypes: types,
+				ActualType:    actual,
+			})
+		}
+	}
+	return warnings, nil
+}
+
+// audienceAttributeTypes returns the value types expected by the custom attribute conditions of the audiences, by attribute.
+// Conditions which accept any type, like exists, don't add an expected type.
+func audienceAttributeTypes(audiences map[string]entities.Audience) map[string]map[string]bool {
+	types := map[string]map[string]bool{}
+	var walk func(node *entities.TreeNode)
+	walk = func(node *entities.TreeNode) {
+		if node == nil {
+			return
+		}
+		for _, child := range node.Nodes {
+			walk(child)
+		}
+
+		condition, ok := node.Item.(entities.Condition)
+		if !ok || condition.Type != "custom_attribute" {
+			return
+		}
+		if expected := conditionType(condition); expected != "" {
+			if types[condition.Name] == nil {
+				types[condition.Name] = map[string]bool{}
+			}
+			types[condition.Name][expected] = true
+		}
+	}
+
+	for _, audience := range audiences {
+		walk(audience.ConditionTree)
+	}
+	return types
+}
+
+// conditionType returns the value type expected by the condition, empty when it accepts any type
+func conditionType(condition entities.Condition) string {
+	switch condition.Match {
+	case matchers.ExistsMatchType:
+		return ""
+	case matchers.LtMatchType, matchers.LeMatchType, matchers.GtMatchType, matchers.GeMatchType:
+		return attributeTypeNumber
+	case matchers.SubstringMatchType, matchers.SemverEqMatchType, matchers.SemverLtMatchType, matchers.SemverLeMatchType,
+		matchers.SemverGtMatchType, matchers.SemverGeMatchType:
+		return attributeTypeString
+	case "", matchers.ExactMatchType:
+		if condition.Value == nil {
+			return ""
+		}
+		return attributeType(condition.Value)
+	default:
+		return ""
+	}
+}
+
+func attributeType(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return attributeTypeString
+	case bool:
+		return attributeTypeBoolean
+	case float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+		return attributeTypeNumber
+	default:
+		return attributeTypeOther
+	}
+}
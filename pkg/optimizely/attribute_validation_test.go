@@ -0,0 +1,140 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/pkg/optimizely/optimizelytest"
+
+	"github.com/optimizely/go-sdk/pkg/entities"
+)
+
+func condition(name, match string, value interface{}) *entities.TreeNode {
+	return &entities.TreeNode{Item: entities.Condition{Name: name, Match: match, Type: "custom_attribute", Value: value}}
+}
+
+func newValidationClient() *OptlyClient {
+	testClient := optimizelytest.NewClient()
+	pc := testClient.ProjectConfig
+	for i, key := range []string{"tier", "age", "beta", "country", "version"} {
+		id := string(rune('1' + i))
+		pc.AttributeKeyToIDMap[key] = id
+		pc.AttributeMap[id] = entities.Attribute{ID: id, Key: key}
+	}
+	testClient.AddAudience(entities.Audience{ID: "1", ConditionTree: &entities.TreeNode{
+		Operator: "and",
+		Nodes: []*entities.TreeNode{
+			condition("tier", "exact", "gold"),
+			condition("age", "gt", 18.0),
+			{Operator: "or", Nodes: []*entities.TreeNode{
+				condition("beta", "exact", true),
+				condition("country", "exists", nil),
+				condition("version", "semver_ge", "1.2.0"),
+			}},
+		},
+	}})
+	// A second audience expecting a number for the same attribute
+	testClient.AddAudience(entities.Audience{ID: "2", ConditionTree: condition("tier", "exact", 3.0)})
+
+	return &OptlyClient{
+		OptimizelyClient: testClient.OptimizelyClient,
+		ConfigManager:    &MockConfigManager{config: pc},
+	}
+}
+
+func TestValidateAttributes(t *testing.T) {
+	optlyClient := newValidationClient()
+
+	warnings, err := optlyClient.ValidateAttributes(map[string]interface{}{
+		"tier":              "gold",
+		"age":               30,
+		"beta":              false,
+		"country":           []string{"US"},
+		"version":           "1.3.0",
+		"$opt_bucketing_id": "id",
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	warnings, err = optlyClient.ValidateAttributes(map[string]interface{}{
+		"user_tier": "gold",
+		"tier":      false,
+		"age":       "30",
+		"version":   1.3,
+		"beta":      nil,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []AttributeWarning{
+		{
+			Attribute:     "age",
+			Code:          AttributeWarningTypeMismatch,
+			Message:       `attribute "age" is a string, audience conditions expect number`,
+			ExpectedTypes: []string{"number"},
+			ActualType:    "string",
+		},
+		{
+			Attribute:     "tier",
+			Code:          AttributeWarningTypeMismatch,
+			Message:       `attribute "tier" is a boolean, audience conditions expect number or string`,
+			ExpectedTypes: []string{"number", "string"},
+			ActualType:    "boolean",
+		},
+		{
+			Attribute: "user_tier",
+			Code:      AttributeWarningUnknown,
+			Message:   `attribute "user_tier" is not defined in the datafile`,
+		},
+		{
+			Attribute:     "version",
+			Code:          AttributeWarningTypeMismatch,
+			Message:       `attribute "version" is a number, audience conditions expect string`,
+			ExpectedTypes: []string{"string"},
+			ActualType:    "number",
+		},
+	}, warnings)
+}
+
+func TestValidateAttributesCachesTypesPerRevision(t *testing.T) {
+	optlyClient := newValidationClient()
+	pc := optlyClient.ConfigManager.(*MockConfigManager).config.(*optimizelytest.TestProjectConfig)
+	attributes := map[string]interface{}{"beta": "yes"}
+
+	warnings, err := optlyClient.ValidateAttributes(attributes)
+	assert.NoError(t, err)
+	assert.Len(t, warnings, 1)
+
+	// The types expected by the audiences are collected once per revision
+	pc.AddAudience(entities.Audience{ID: "3", ConditionTree: condition("beta", "exact", "yes")})
+	warnings, _ = optlyClient.ValidateAttributes(attributes)
+	assert.Len(t, warnings, 1)
+
+	pc.Revision = "2"
+	warnings, _ = optlyClient.ValidateAttributes(attributes)
+	assert.Empty(t, warnings)
+}
+
+func TestCheckAttributeValidation(t *testing.T) {
+	for _, mode := range []config.AttributeValidationMode{"", config.AttributeValidationOff, config.AttributeValidationWarn, config.AttributeValidationReject} {
+		assert.NoError(t, checkAttributeValidation(mode))
+	}
+	assert.EqualError(t, checkAttributeValidation("strict"), `unknown attribute validation mode: "strict"`)
+}
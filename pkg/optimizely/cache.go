@@ -21,13 +21,21 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/pkg/deadletter"
+	"github.com/optimizely/agent/pkg/overrides"
 	"github.com/optimizely/agent/pkg/syncer"
+	"github.com/optimizely/agent/plugins/forceddecisions"
 	"github.com/optimizely/agent/plugins/odpcache"
+	"github.com/optimizely/agent/plugins/userattributes"
 	"github.com/optimizely/agent/plugins/userprofileservice"
 	"github.com/optimizely/go-sdk/pkg/client"
 	sdkconfig "github.com/optimizely/go-sdk/pkg/config"
@@ -41,14 +49,32 @@ import (
 
 	odpCachePkg "github.com/optimizely/go-sdk/pkg/odp/cache"
 	cmap "github.com/orcaman/concurrent-map"
+	gocache "github.com/patrickmn/go-cache"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/singleflight"
 )
 
+// drainProgressInterval is the time between progress logs while draining the clients
+const drainProgressInterval = 5 * time.Second
+
 // User plugin strings required for internal usage
 const (
 	userProfileServicePlugin = "UserProfileService"
 	odpCachePlugin           = "ODP Cache"
+	forcedDecisionsPlugin    = "Forced Decisions Store"
+	userAttributesPlugin     = "User Attributes Provider"
+)
+
+// Metric keys for the cache of Optimizely clients
+const (
+	idleEvictionsKey     = "cache.idleEvictions"
+	capacityEvictionsKey = "cache.capacityEvictions"
+	loadedClientsKey     = "cache.clients"
+	snapshotLoadsKey     = "cache.snapshotLoads"
+	staleClientsKey      = "cache.staleClients"
+	eventQueueBytesKey   = "eventQueue.bytes"
+	deadLettersKey       = "eventQueue.deadLetters"
 )
 
 // OptlyCache implements the Cache interface backed by a concurrent map.
@@ -58,8 +84,38 @@ type OptlyCache struct {
 	optlyMap              cmap.ConcurrentMap
 	userProfileServiceMap cmap.ConcurrentMap
 	odpCacheMap           cmap.ConcurrentMap
+	entryMap              cmap.ConcurrentMap
+	pinnedKeys            map[string]struct{}
+	eviction              config.EvictionConfig
+	metricsRegistry       *MetricsRegistry
+	loadGroup             singleflight.Group
+	failedLoads           *gocache.Cache
+	loadBackoff           config.BackoffConfig
+	sinks                 *EventSinks
 	ctx                   context.Context
 	wg                    sync.WaitGroup
+	openClients           int64 // accessed atomically
+}
+
+// failedLoad records the error of the last attempt to load a client and until when it is returned without reloading
+type failedLoad struct {
+	err     error
+	until   time.Time
+	backoff time.Duration
+}
+
+// cacheEntry tracks the lifecycle of an OptlyClient stored in the cache
+type cacheEntry struct {
+	lastAccess int64 // unix nanoseconds, accessed atomically
+	cancel     context.CancelFunc
+}
+
+func (e *cacheEntry) touch() {
+	atomic.StoreInt64(&e.lastAccess, time.Now().UnixNano())
+}
+
+func (e *cacheEntry) idleSince() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&e.lastAccess))
 }
 
 // NewCache returns a new implementation of OptlyCache interface backed by a concurrent map.
@@ -70,15 +126,42 @@ func NewCache(ctx context.Context, conf config.AgentConfig, metricsRegistry *Met
 		return sdkconfig.NewPollingProjectConfigManager(sdkkey, options...)
 	}
 
+	if staticDatafiles := conf.Client.StaticDatafiles; staticDatafiles.Enabled() {
+		cmLoader = func(sdkkey string, options ...sdkconfig.OptionFunc) SyncedConfigManager {
+			return NewFileProjectConfigManager(ctx, sdkkey, staticDatafilePath(staticDatafiles, sdkkey), staticDatafiles.ReloadInterval)
+		}
+	}
+
 	userProfileServiceMap := cmap.New()
 	odpCacheMap := cmap.New()
+	pinnedKeys := make(map[string]struct{}, len(conf.SDKKeys))
+	for _, sdkKey := range conf.SDKKeys {
+		pinnedKeys[sdkKey] = struct{}{}
+	}
+
+	sinks := newEventSinks(conf.Client.EventDispatchers)
 	cache := &OptlyCache{
 		ctx:                   ctx,
 		wg:                    sync.WaitGroup{},
-		loader:                defaultLoader(conf, metricsRegistry, userProfileServiceMap, odpCacheMap, cmLoader, event.NewBatchEventProcessor),
+		loader:                defaultLoader(conf, metricsRegistry, sinks, userProfileServiceMap, odpCacheMap, cmLoader, event.NewBatchEventProcessor),
 		optlyMap:              cmap.New(),
 		userProfileServiceMap: userProfileServiceMap,
 		odpCacheMap:           odpCacheMap,
+		entryMap:              cmap.New(),
+		pinnedKeys:            pinnedKeys,
+		eviction:              conf.Client.Eviction,
+		metricsRegistry:       metricsRegistry,
+		failedLoads:           gocache.New(gocache.NoExpiration, time.Minute),
+		loadBackoff:           conf.Client.LoadBackoff,
+		sinks:                 sinks,
+	}
+
+	if cache.eviction.IdleTTL > 0 {
+		cache.wg.Add(1)
+		go func() {
+			defer cache.wg.Done()
+			cache.startEvictionTicker()
+		}()
 	}
 
 	return cache
@@ -99,34 +182,100 @@ func (c *OptlyCache) Init(sdkKeys []string) {
 }
 
 // GetClient is used to fetch an instance of the OptlyClient when the SDK Key is explicitly supplied.
+// Concurrent requests for an SDK key which is not loaded yet share a single load, and failed loads
+// are returned without reloading until their backoff expires.
 func (c *OptlyCache) GetClient(sdkKey string) (*OptlyClient, error) {
 	val, ok := c.optlyMap.Get(sdkKey)
 	if ok {
+		if entry, ok := c.entryMap.Get(sdkKey); ok {
+			entry.(*cacheEntry).touch()
+		}
+		return val.(*OptlyClient), nil
+	}
+
+	if err := c.failedLoadError(sdkKey); err != nil {
+		return &OptlyClient{}, err
+	}
+
+	val, err, _ := c.loadGroup.Do(sdkKey, func() (interface{}, error) {
+		return c.load(sdkKey)
+	})
+	return val.(*OptlyClient), err
+}
+
+func (c *OptlyCache) load(sdkKey string) (*OptlyClient, error) {
+	// The client may have been stored by a load which completed after the lookup in GetClient
+	if val, ok := c.optlyMap.Get(sdkKey); ok {
 		return val.(*OptlyClient), nil
 	}
 
 	oc, err := c.loader(sdkKey)
 	if err != nil {
+		c.recordFailedLoad(sdkKey, err)
 		return oc, err
 	}
 
-	set := c.optlyMap.SetIfAbsent(sdkKey, oc)
-	if set {
-		c.wg.Add(1)
-		go func() {
-			defer c.wg.Done()
-			<-c.ctx.Done()
-			oc.Close()
-		}()
-		return oc, err
+	if c.failedLoads != nil {
+		c.failedLoads.Delete(sdkKey)
 	}
 
-	// Clean-up to not leave any lingering un-unused goroutines
-	go oc.Close()
+	ctx, cancel := context.WithCancel(c.ctx)
+	entry := &cacheEntry{cancel: cancel}
+	entry.touch()
+	c.entryMap.Set(sdkKey, entry)
+	c.optlyMap.Set(sdkKey, oc)
+
+	atomic.AddInt64(&c.openClients, 1)
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		<-ctx.Done()
+		oc.Close()
+		atomic.AddInt64(&c.openClients, -1)
+	}()
+
+	c.evictOverCapacity()
+	c.updateLoadedClientsGauge()
+	return oc, nil
+}
+
+// failedLoadError returns the error of the last load of the SDK key if its backoff has not expired yet
+func (c *OptlyCache) failedLoadError(sdkKey string) error {
+	if c.failedLoads == nil {
+		return nil
+	}
 
-	// If we didn't "set" the key in this method execution then it was set in another thread.
-	// Recursively lookuping up the SDK key "should" only happen once.
-	return c.GetClient(sdkKey)
+	if val, ok := c.failedLoads.Get(sdkKey); ok {
+		if failure := val.(*failedLoad); time.Now().Before(failure.until) {
+			return failure.err
+		}
+	}
+	return nil
+}
+
+// recordFailedLoad caches the load error, doubling the backoff of the previous consecutive failure
+func (c *OptlyCache) recordFailedLoad(sdkKey string, err error) {
+	if c.failedLoads == nil || c.loadBackoff.Initial <= 0 {
+		return
+	}
+
+	backoff := c.loadBackoff.Initial
+	if val, ok := c.failedLoads.Get(sdkKey); ok {
+		backoff = val.(*failedLoad).backoff * 2
+	}
+	if c.loadBackoff.Max > 0 && backoff > c.loadBackoff.Max {
+		backoff = c.loadBackoff.Max
+	}
+
+	// The failure is kept for another backoff period after it expires so that consecutive failures keep doubling
+	c.failedLoads.Set(sdkKey, &failedLoad{err: err, until: time.Now().Add(backoff), backoff: backoff}, 2*backoff)
+
+	message := "Failed to load Optimizely instance"
+	if ShouldIncludeSDKKey {
+		log.Warn().Err(err).Str("sdkKey", sdkKey).Dur("backoff", backoff).Msg(message)
+	} else {
+		log.Warn().Err(err).Dur("backoff", backoff).Msg(message)
+	}
 }
 
 // UpdateConfigs is used to update config for all clients corresponding to a particular SDK key.
@@ -157,6 +306,267 @@ func (c *OptlyCache) Wait() {
 	c.wg.Wait()
 }
 
+// Drain waits for the clients to be closed once the cache context is done. Closing a client flushes its
+// event processor and ODP event manager, the event batches queued for the sinks are then forwarded and the sinks
+// closed. Progress is logged while waiting and false is returned when the clients aren't closed and the sinks
+// flushed within timeout, 0 waits without limit.
+func (c *OptlyCache) Drain(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	var deadline <-chan time.Time
+	var expiry time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+		expiry = time.Now().Add(timeout)
+	}
+
+	ticker := time.NewTicker(drainProgressInterval)
+	defer ticker.Stop()
+
+	log.Info().Int64("clients", atomic.LoadInt64(&c.openClients)).Msg("Flushing events of Optimizely instances.")
+	for {
+		select {
+		case <-done:
+			log.Info().Msg("Flushed events of Optimizely instances.")
+			if timeout <= 0 {
+				return c.sinks.Close(0)
+			}
+			// The sinks are closed even once the timeout passed, waiting for them at least briefly
+			remaining := time.Until(expiry)
+			if remaining <= 0 {
+				remaining = time.Millisecond
+			}
+			return c.sinks.Close(remaining)
+		case <-ticker.C:
+			log.Info().Int64("clients", atomic.LoadInt64(&c.openClients)).Msg("Waiting for Optimizely instances to flush events.")
+		case <-deadline:
+			log.Warn().Int64("clients", atomic.LoadInt64(&c.openClients)).Msg("Timed out flushing events of Optimizely instances.")
+			c.sinks.Close(time.Millisecond)
+			return false
+		}
+	}
+}
+
+// ListClients returns the state of every loaded client ordered by SDK key
+func (c *OptlyCache) ListClients() []ClientInfo {
+	clients := []ClientInfo{}
+	for item := range c.optlyMap.IterBuffered() {
+		clients = append(clients, c.clientInfo(item.Key, item.Val))
+	}
+
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].SDKKey < clients[j].SDKKey
+	})
+	return clients
+}
+
+// GetClientInfo returns the state of the clients loaded for the given SDK key.
+// A client is loaded per datafile access token, so several clients can share the same SDK key.
+func (c *OptlyCache) GetClientInfo(sdkKey string) []ClientInfo {
+	clients := []ClientInfo{}
+	for item := range c.optlyMap.IterBuffered() {
+		if matchesSDKKey(item.Key, sdkKey) {
+			clients = append(clients, c.clientInfo(item.Key, item.Val))
+		}
+	}
+	return clients
+}
+
+// ResyncClient forces the clients loaded for the given SDK key to fetch the latest datafile.
+// It returns false when no client is loaded for the SDK key.
+func (c *OptlyCache) ResyncClient(sdkKey string) bool {
+	found := false
+	for item := range c.optlyMap.IterBuffered() {
+		if matchesSDKKey(item.Key, sdkKey) {
+			if optlyClient, ok := item.Val.(*OptlyClient); ok {
+				optlyClient.UpdateConfig()
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+// EvictClient removes and closes the clients loaded for the given SDK key, pending events are flushed.
+// SDK keys configured at startup are evicted as well and are loaded again on the next request.
+// It returns false when no client is loaded for the SDK key.
+func (c *OptlyCache) EvictClient(sdkKey string) bool {
+	found := false
+	for _, key := range c.optlyMap.Keys() {
+		if matchesSDKKey(key, sdkKey) && c.remove(key) {
+			found = true
+		}
+	}
+	c.updateLoadedClientsGauge()
+	return found
+}
+
+func (c *OptlyCache) clientInfo(key string, val interface{}) ClientInfo {
+	var info ClientInfo
+	if optlyClient, ok := val.(*OptlyClient); ok {
+		info = optlyClient.Info()
+	}
+
+	info.SDKKey, info.Authenticated = splitClientKey(key)
+	if entry, ok := c.entryMap.Get(key); ok {
+		lastAccess := entry.(*cacheEntry).idleSince()
+		info.LastAccess = &lastAccess
+	}
+	return info
+}
+
+// splitClientKey returns the SDK key of a cache key and whether it includes a datafile access token
+func splitClientKey(key string) (sdkKey string, authenticated bool) {
+	parts := strings.SplitN(key, ":", 2)
+	return parts[0], len(parts) == 2
+}
+
+func matchesSDKKey(key, sdkKey string) bool {
+	keySDKKey, _ := splitClientKey(key)
+	return keySDKKey == sdkKey
+}
+
+func (c *OptlyCache) startEvictionTicker() {
+	interval := c.eviction.Interval
+	if interval <= 0 {
+		interval = c.eviction.IdleTTL
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			c.evictIdle()
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// evictIdle evicts every client which has not been accessed within the configured idle TTL
+func (c *OptlyCache) evictIdle() {
+	if c.eviction.IdleTTL <= 0 {
+		return
+	}
+
+	deadline := time.Now().Add(-c.eviction.IdleTTL)
+	for item := range c.entryMap.IterBuffered() {
+		if entry, ok := item.Val.(*cacheEntry); ok && entry.idleSince().Before(deadline) {
+			if c.evict(item.Key) {
+				c.incrementCounter(idleEvictionsKey)
+			}
+		}
+	}
+	c.updateLoadedClientsGauge()
+}
+
+// evictOverCapacity evicts the least recently used clients until the cache is within the configured max clients
+func (c *OptlyCache) evictOverCapacity() {
+	if c.eviction.MaxClients <= 0 || c.entryMap.Count() <= c.eviction.MaxClients {
+		return
+	}
+
+	type candidate struct {
+		sdkKey     string
+		lastAccess time.Time
+	}
+	candidates := []candidate{}
+	for item := range c.entryMap.IterBuffered() {
+		if _, pinned := c.pinnedKeys[item.Key]; pinned {
+			continue
+		}
+		if entry, ok := item.Val.(*cacheEntry); ok {
+			candidates = append(candidates, candidate{item.Key, entry.idleSince()})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].lastAccess.Before(candidates[j].lastAccess)
+	})
+
+	overflow := c.entryMap.Count() - c.eviction.MaxClients
+	for i := 0; i < len(candidates) && overflow > 0; i++ {
+		if c.evict(candidates[i].sdkKey) {
+			c.incrementCounter(capacityEvictionsKey)
+			overflow--
+		}
+	}
+}
+
+// evict removes the client from the cache and closes it, which flushes any pending events.
+// Pinned SDK keys are never evicted.
+func (c *OptlyCache) evict(sdkKey string) bool {
+	if _, pinned := c.pinnedKeys[sdkKey]; pinned {
+		return false
+	}
+	return c.remove(sdkKey)
+}
+
+func (c *OptlyCache) remove(sdkKey string) bool {
+	entry, ok := c.entryMap.Pop(sdkKey)
+	if !ok {
+		return false
+	}
+	if val, ok := c.optlyMap.Pop(sdkKey); ok {
+		// Evicted clients are no longer accounted as stale
+		if oc, ok := val.(*OptlyClient); ok && oc.syncStatus != nil {
+			oc.syncStatus.setStale(false)
+		}
+	}
+	// Canceling the entry context triggers OptlyClient.Close
+	entry.(*cacheEntry).cancel()
+
+	message := "Evicted Optimizely instance"
+	if ShouldIncludeSDKKey {
+		log.Info().Str("sdkKey", sdkKey).Msg(message)
+	} else {
+		log.Info().Msg(message)
+	}
+	return true
+}
+
+func (c *OptlyCache) incrementCounter(key string) {
+	if c.metricsRegistry == nil {
+		return
+	}
+	if counter := c.metricsRegistry.GetCounter(key); counter != nil {
+		counter.Add(1)
+	}
+}
+
+func (c *OptlyCache) updateLoadedClientsGauge() {
+	if c.metricsRegistry == nil {
+		return
+	}
+	if gauge := c.metricsRegistry.GetGauge(loadedClientsKey); gauge != nil {
+		gauge.Set(float64(c.optlyMap.Count()))
+	}
+}
+
+// staticDatafilePath returns the local datafile of the SDK key, empty if none is configured
+func staticDatafilePath(conf config.StaticDatafilesConfig, sdkKey string) string {
+	for _, file := range conf.Files {
+		if file.SDKKey == sdkKey {
+			return file.Path
+		}
+	}
+	return datafilePath(conf.Dir, sdkKey)
+}
+
+// closeConfigManager stops config managers which run on their own, such as the FileProjectConfigManager
+func closeConfigManager(configManager SyncedConfigManager) {
+	if closer, ok := configManager.(interface{ Close() }); ok {
+		closer.Close()
+	}
+}
+
 // ErrValidationFailure is returned when the provided SDK key fails initial validation
 var ErrValidationFailure = errors.New("sdkKey failed validation")
 
@@ -172,12 +582,69 @@ func regexValidator(sdkKeyRegex string) func(string) bool {
 func defaultLoader(
 	agentConf config.AgentConfig,
 	metricsRegistry *MetricsRegistry,
+	sinks *EventSinks,
 	userProfileServiceMap cmap.ConcurrentMap,
 	odpCacheMap cmap.ConcurrentMap,
 	pcFactory func(sdkKey string, options ...sdkconfig.OptionFunc) SyncedConfigManager,
 	bpFactory func(options ...event.BPOptionConfig) *event.BatchEventProcessor) func(clientKey string) (*OptlyClient, error) {
 	clientConf := agentConf.Client
 	validator := regexValidator(clientConf.SdkKeyRegex)
+	for _, override := range clientConf.SDKKeyOverrides {
+		if _, err := regexp.Compile(override.Pattern); err != nil {
+			log.Fatal().Err(err).Msgf("invalid sdkKeyOverrides pattern configuration")
+		}
+		if override.Scrub != nil {
+			if _, err := NewScrubber(*override.Scrub); err != nil {
+				log.Fatal().Err(err).Msgf("invalid sdkKeyOverrides scrub configuration")
+			}
+		}
+		if err := checkAttributeValidation(override.AttributeValidation); err != nil {
+			log.Fatal().Err(err).Msgf("invalid sdkKeyOverrides attributeValidation configuration")
+		}
+	}
+	if _, err := NewScrubber(clientConf.Scrub); err != nil {
+		log.Fatal().Err(err).Msgf("invalid scrub configuration")
+	}
+	if err := checkAttributeValidation(clientConf.AttributeValidation); err != nil {
+		log.Fatal().Err(err).Msgf("invalid attributeValidation configuration")
+	}
+
+	var staleClients int64
+	onStaleChange := func(stale bool) {
+		delta := int64(-1)
+		if stale {
+			delta = 1
+		}
+		count := atomic.AddInt64(&staleClients, delta)
+		if metricsRegistry != nil {
+			metricsRegistry.GetGauge(staleClientsKey).Set(float64(count))
+		}
+	}
+
+	var eventQueueBytes int64
+	onEventQueueBytesChange := func(delta int64) {
+		total := atomic.AddInt64(&eventQueueBytes, delta)
+		if metricsRegistry != nil {
+			metricsRegistry.GetGauge(eventQueueBytesKey).Set(float64(total))
+		}
+	}
+
+	deadLetters, err := deadletter.NewStore(clientConf.DeadLetter)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("invalid deadLetter configuration")
+	}
+	dispatch := eventDispatch{
+		sinks:           sinks,
+		deadLetters:     deadLetters,
+		maxAttempts:     clientConf.DeadLetter.MaxAttempts,
+		metricsRegistry: metricsRegistry,
+	}
+	forcedDecisions, forcedDecisionsName := newForcedDecisionsStore(clientConf.ForcedDecisions)
+	userAttributes, userAttributesName := newUserAttributesProvider(clientConf.UserAttributes)
+	newOverridesStore, err := overrides.NewStoreFactory(clientConf.Overrides)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("invalid overrides configuration")
+	}
 
 	return func(clientKey string) (*OptlyClient, error) {
 		var sdkKey string
@@ -204,6 +671,8 @@ func defaultLoader(
 			datafileAccessToken = clientKeySplit[1]
 		}
 
+		clientConf := clientConf.ForSDKKey(sdkKey)
+
 		message := "Loading Optimizely instance"
 		if ShouldIncludeSDKKey {
 			log.Info().Str("sdkKey", sdkKey).Msg(message)
@@ -211,37 +680,69 @@ func defaultLoader(
 			log.Info().Msg(message)
 		}
 
-		if datafileAccessToken != "" {
-			configManager = pcFactory(
-				sdkKey,
-				sdkconfig.WithPollingInterval(clientConf.PollingInterval),
-				sdkconfig.WithDatafileURLTemplate(clientConf.DatafileURLTemplate),
-				sdkconfig.WithDatafileAccessToken(datafileAccessToken),
-			)
-		} else {
-			configManager = pcFactory(
-				sdkKey,
-				sdkconfig.WithPollingInterval(clientConf.PollingInterval),
-				sdkconfig.WithDatafileURLTemplate(clientConf.DatafileURLTemplate),
-			)
+		datafileURLTemplate := clientConf.DatafileURLTemplate
+		if datafileURLTemplate == "" && datafileAccessToken != "" {
+			datafileURLTemplate = sdkconfig.AuthDatafileURLTemplate
+		}
+
+		syncStatus := &SyncStatus{onStaleChange: onStaleChange}
+		snapshot := datafilePath(clientConf.DatafileSnapshotDir, sdkKey)
+		configOptions := []sdkconfig.OptionFunc{
+			sdkconfig.WithPollingInterval(clientConf.PollingInterval),
+			sdkconfig.WithDatafileURLTemplate(datafileURLTemplate),
+			sdkconfig.WithRequester(newDatafileRequester(sdkKey, datafileAccessToken, syncStatus, snapshot)),
 		}
+		configManager = pcFactory(sdkKey, configOptions...)
 
 		if _, err := configManager.GetConfig(); err != nil {
-			return &OptlyClient{}, err
+			closeConfigManager(configManager)
+
+			// Fall back to the last saved datafile, polling carries on and refreshes it once the CDN is reachable
+			datafile, snapshotErr := loadSnapshot(snapshot)
+			if snapshotErr != nil {
+				return &OptlyClient{}, err
+			}
+
+			configManager = pcFactory(sdkKey, append(configOptions, sdkconfig.WithInitialDatafile(datafile))...)
+			if _, snapshotErr = configManager.GetConfig(); snapshotErr != nil {
+				closeConfigManager(configManager)
+				return &OptlyClient{}, err
+			}
+
+			syncStatus.setStale(true)
+			if metricsRegistry != nil {
+				metricsRegistry.GetCounter(snapshotLoadsKey).Add(1)
+			}
+
+			message := "Loaded Optimizely instance from datafile snapshot"
+			if ShouldIncludeSDKKey {
+				log.Warn().Err(err).Str("sdkKey", sdkKey).Msg(message)
+			} else {
+				log.Warn().Err(err).Msg(message)
+			}
 		}
 
-		q := event.NewInMemoryQueue(clientConf.QueueSize)
-		ep := bpFactory(
+		q := newEventQueue(clientConf, sdkKey, datafileAccessToken, onEventQueueBytesChange)
+		var processorQueue event.Queue = q
+		// The scrub rules were validated when the loader was created
+		if scrubber, _ := NewScrubber(clientConf.Scrub); scrubber != nil {
+			processorQueue = NewScrubbingQueue(q, scrubber)
+		}
+		bpOptions := []event.BPOptionConfig{
 			event.WithSDKKey(sdkKey),
 			event.WithQueueSize(clientConf.QueueSize),
 			event.WithBatchSize(clientConf.BatchSize),
 			event.WithEventEndPoint(clientConf.EventURL),
 			event.WithFlushInterval(clientConf.FlushInterval),
-			event.WithQueue(q),
+			event.WithQueue(processorQueue),
 			event.WithEventDispatcherMetrics(metricsRegistry),
-		)
+		}
+		if dispatcher := dispatch.newDispatcher(sdkKey, q); dispatcher != nil {
+			bpOptions = append(bpOptions, event.WithEventDispatcher(dispatcher))
+		}
+		ep := bpFactory(bpOptions...)
 
-		forcedVariations := decision.NewMapExperimentOverridesStore()
+		forcedVariations := newOverridesStore(sdkKey)
 		optimizelyFactory := &client.OptimizelyFactory{SDKKey: sdkKey}
 
 		clientOptions := []client.OptionFunc{
@@ -254,12 +755,15 @@ func defaultLoader(
 		if agentConf.Synchronization.Notification.Enable {
 			redisSyncer, err := syncer.NewRedisSyncer(&zerolog.Logger{}, agentConf.Synchronization, sdkKey)
 			if err != nil {
+				closeConfigManager(configManager)
+				closeEventQueue(q)
 				return nil, err
 			}
 			clientOptions = append(clientOptions, client.WithNotificationCenter(redisSyncer))
 		}
 
 		var clientUserProfileService decision.UserProfileService
+		var userProfileServiceName = getServiceName(sdkKey, userProfileServiceMap, clientConf.UserProfileService)
 		var rawUPS = getServiceWithType(userProfileServicePlugin, sdkKey, userProfileServiceMap, clientConf.UserProfileService)
 		// Check if ups was provided by user
 		if rawUPS != nil {
@@ -271,6 +775,7 @@ func defaultLoader(
 		}
 
 		var clientODPCache odpCachePkg.Cache
+		var odpCacheName = getServiceName(sdkKey, odpCacheMap, clientConf.ODP.SegmentsCache)
 		var rawODPCache = getServiceWithType(odpCachePlugin, sdkKey, odpCacheMap, clientConf.ODP.SegmentsCache)
 		// Check if odp cache was provided by user
 		if rawODPCache != nil {
@@ -311,7 +816,50 @@ func defaultLoader(
 		optimizelyClient, err := optimizelyFactory.Client(
 			clientOptions...,
 		)
-		return &OptlyClient{optimizelyClient, configManager, forcedVariations, clientUserProfileService, clientODPCache}, err
+		if err != nil {
+			closeEventQueue(q)
+		}
+		return &OptlyClient{
+			OptimizelyClient:        optimizelyClient,
+			ConfigManager:           configManager,
+			ForcedVariations:        forcedVariations,
+			UserProfileService:      clientUserProfileService,
+			odpCache:                clientODPCache,
+			syncStatus:              syncStatus,
+			userProfileServiceName:  userProfileServiceName,
+			odpCacheName:            odpCacheName,
+			ForcedDecisions:         forcedDecisions,
+			forcedDecisionsName:     forcedDecisionsName,
+			UserAttributes:          userAttributes,
+			userAttributesName:      userAttributesName,
+			sdkKey:                  sdkKey,
+			settings:                newClientSettings(clientConf),
+			AttributeValidationMode: clientConf.AttributeValidation,
+			eventQueue:              q,
+		}, err
+	}
+}
+
+// newEventQueue returns the queue holding the events of the client until they are dispatched.
+// It falls back to the in-memory queue when the event log can't be opened.
+func newEventQueue(clientConf config.ClientConfig, sdkKey, datafileAccessToken string, onBytesChange func(delta int64)) event.Queue {
+	if clientConf.EventQueue.Type == config.EventQueueTypeDisk {
+		path := eventLogPath(clientConf.EventQueue.Disk.Dir, sdkKey, datafileAccessToken)
+		q, err := NewDiskQueue(path, clientConf.EventQueue.Disk.MaxBytes, clientConf.EventQueue.Disk.SyncInterval, onBytesChange)
+		if err == nil {
+			return q
+		}
+		log.Error().Err(err).Msg("Failed to open event log, events are queued in memory")
+	}
+	return event.NewInMemoryQueue(clientConf.QueueSize)
+}
+
+// closeEventQueue closes the event queue if it holds resources
+func closeEventQueue(q event.Queue) {
+	if closer, ok := q.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Warn().Err(err).Msg("Failed to close event queue")
+		}
 	}
 }
 
@@ -331,6 +879,14 @@ func getServiceWithType(serviceType, sdkKey string, serviceMap cmap.ConcurrentMa
 					if odpCreator, ok := odpcache.Creators[serviceName]; ok {
 						serviceInstance = odpCreator()
 					}
+				case forcedDecisionsPlugin:
+					if storeCreator, ok := forceddecisions.Creators[serviceName]; ok {
+						serviceInstance = storeCreator()
+					}
+				case userAttributesPlugin:
+					if providerCreator, ok := userattributes.Creators[serviceName]; ok {
+						serviceInstance = providerCreator()
+					}
 				default:
 				}
 
@@ -351,16 +907,24 @@ func getServiceWithType(serviceType, sdkKey string, serviceMap cmap.ConcurrentMa
 		return nil
 	}
 
+	if serviceName := getServiceName(sdkKey, serviceMap, serviceConf); serviceName != "" {
+		return intializeServiceWithName(serviceName)
+	}
+	return nil
+}
+
+// getServiceName returns the name of the service configured for the sdkKey, either provided in the request headers or the default one
+func getServiceName(sdkKey string, serviceMap cmap.ConcurrentMap, serviceConf map[string]interface{}) string {
 	// Check if service name was provided in the request headers
 	if service, ok := serviceMap.Get(sdkKey); ok {
 		if serviceNameStr, ok := service.(string); ok && serviceNameStr != "" {
-			return intializeServiceWithName(serviceNameStr)
+			return serviceNameStr
 		}
 	}
 
 	// Check if any default service was provided and if it exists in client config
 	if defaultServiceName, isAvailable := serviceConf["default"].(string); isAvailable && defaultServiceName != "" {
-		return intializeServiceWithName(defaultServiceName)
+		return defaultServiceName
 	}
-	return nil
+	return ""
 }
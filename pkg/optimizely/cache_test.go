@@ -20,7 +20,12 @@ package optimizely
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -36,6 +41,7 @@ import (
 	"github.com/optimizely/agent/plugins/userprofileservice"
 
 	cmap "github.com/orcaman/concurrent-map"
+	gocache "github.com/patrickmn/go-cache"
 	"github.com/stretchr/testify/suite"
 
 	odpCacheServices "github.com/optimizely/agent/plugins/odpcache/services"
@@ -58,6 +64,9 @@ func (suite *CacheTestSuite) SetupTest() {
 		optlyMap:              cmap.New(),
 		userProfileServiceMap: cmap.New(),
 		odpCacheMap:           cmap.New(),
+		entryMap:              cmap.New(),
+		pinnedKeys:            map[string]struct{}{},
+		failedLoads:           gocache.New(gocache.NoExpiration, time.Minute),
 		ctx:                   ctx,
 	}
 
@@ -92,6 +101,86 @@ func (suite *CacheTestSuite) TestGetError() {
 	suite.Error(err1)
 }
 
+func (suite *CacheTestSuite) TestGetClientSingleLoad() {
+	var loads int32
+	release := make(chan struct{})
+	suite.cache.loader = func(sdkKey string) (*OptlyClient, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return mockLoader(sdkKey)
+	}
+
+	var wg sync.WaitGroup
+	clients := make([]*OptlyClient, 10)
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			clients[i], _ = suite.cache.GetClient("one")
+		}(i)
+	}
+
+	// Give the goroutines a chance to join the in-flight load before releasing it
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	suite.Equal(int32(1), atomic.LoadInt32(&loads))
+	for _, client := range clients {
+		suite.Same(clients[0], client)
+	}
+}
+
+func (suite *CacheTestSuite) TestGetClientFailedLoadBackoff() {
+	suite.cache.loadBackoff = config.BackoffConfig{Initial: time.Minute, Max: 3 * time.Minute}
+	var loads int
+	suite.cache.loader = func(sdkKey string) (*OptlyClient, error) {
+		loads++
+		return &OptlyClient{}, ErrValidationFailure
+	}
+
+	_, err := suite.cache.GetClient("bad")
+	suite.Equal(ErrValidationFailure, err)
+	_, err = suite.cache.GetClient("bad")
+	suite.Equal(ErrValidationFailure, err)
+	suite.Equal(1, loads)
+
+	expire := func() {
+		val, ok := suite.cache.failedLoads.Get("bad")
+		suite.True(ok)
+		val.(*failedLoad).until = time.Now()
+	}
+
+	for _, backoff := range []time.Duration{2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		expire()
+		_, err = suite.cache.GetClient("bad")
+		suite.Equal(ErrValidationFailure, err)
+		val, _ := suite.cache.failedLoads.Get("bad")
+		suite.Equal(backoff, val.(*failedLoad).backoff)
+	}
+	suite.Equal(4, loads)
+
+	// A successful load clears the failure
+	expire()
+	suite.cache.loader = mockLoader
+	_, err = suite.cache.GetClient("bad")
+	suite.NoError(err)
+	_, ok := suite.cache.failedLoads.Get("bad")
+	suite.False(ok)
+}
+
+func (suite *CacheTestSuite) TestGetClientFailedLoadBackoffDisabled() {
+	var loads int
+	suite.cache.loader = func(sdkKey string) (*OptlyClient, error) {
+		loads++
+		return &OptlyClient{}, ErrValidationFailure
+	}
+
+	_, _ = suite.cache.GetClient("bad")
+	_, _ = suite.cache.GetClient("bad")
+	suite.Equal(2, loads)
+}
+
 func (suite *CacheTestSuite) TestInit() {
 	suite.cache.Init([]string{"one", "three:four"})
 	suite.True(suite.cache.optlyMap.Has("one"))
@@ -99,6 +188,100 @@ func (suite *CacheTestSuite) TestInit() {
 	suite.False(suite.cache.optlyMap.Has("two"))
 }
 
+func (suite *CacheTestSuite) TestEvictIdle() {
+	suite.cache.eviction = config.EvictionConfig{IdleTTL: time.Minute}
+	suite.cache.pinnedKeys = map[string]struct{}{"pinned": {}}
+
+	_, _ = suite.cache.GetClient("one")
+	_, _ = suite.cache.GetClient("two")
+	_, _ = suite.cache.GetClient("pinned")
+
+	for _, sdkKey := range []string{"one", "pinned"} {
+		entry, ok := suite.cache.entryMap.Get(sdkKey)
+		suite.True(ok)
+		atomic.StoreInt64(&entry.(*cacheEntry).lastAccess, time.Now().Add(-2*time.Minute).UnixNano())
+	}
+
+	suite.cache.evictIdle()
+	suite.False(suite.cache.optlyMap.Has("one"))
+	suite.False(suite.cache.entryMap.Has("one"))
+	suite.True(suite.cache.optlyMap.Has("two"))
+	suite.True(suite.cache.optlyMap.Has("pinned"))
+}
+
+func (suite *CacheTestSuite) TestEvictOverCapacity() {
+	suite.cache.eviction = config.EvictionConfig{MaxClients: 2}
+	suite.cache.pinnedKeys = map[string]struct{}{"pinned": {}}
+
+	_, _ = suite.cache.GetClient("pinned")
+	_, _ = suite.cache.GetClient("one")
+	_, _ = suite.cache.GetClient("two")
+	suite.Equal(2, suite.cache.optlyMap.Count())
+	suite.True(suite.cache.optlyMap.Has("pinned"))
+	suite.False(suite.cache.optlyMap.Has("one"))
+	suite.True(suite.cache.optlyMap.Has("two"))
+
+	// Accessing an evicted key loads a new client
+	_, err := suite.cache.GetClient("one")
+	suite.NoError(err)
+	suite.True(suite.cache.optlyMap.Has("one"))
+	suite.False(suite.cache.optlyMap.Has("two"))
+}
+
+func (suite *CacheTestSuite) TestEvict() {
+	suite.cache.pinnedKeys = map[string]struct{}{"pinned": {}}
+	_, _ = suite.cache.GetClient("one")
+	_, _ = suite.cache.GetClient("pinned")
+
+	suite.True(suite.cache.evict("one"))
+	suite.False(suite.cache.evict("one"))
+	suite.False(suite.cache.evict("pinned"))
+	suite.True(suite.cache.optlyMap.Has("pinned"))
+}
+
+func (suite *CacheTestSuite) TestListClients() {
+	_, _ = suite.cache.GetClient("two")
+	_, _ = suite.cache.GetClient("one:token")
+
+	clients := suite.cache.ListClients()
+	suite.Len(clients, 2)
+	suite.Equal("one", clients[0].SDKKey)
+	suite.True(clients[0].Authenticated)
+	suite.NotNil(clients[0].LastAccess)
+	suite.Equal("two", clients[1].SDKKey)
+	suite.False(clients[1].Authenticated)
+}
+
+func (suite *CacheTestSuite) TestGetClientInfo() {
+	_, _ = suite.cache.GetClient("one")
+	_, _ = suite.cache.GetClient("one:token")
+	_, _ = suite.cache.GetClient("onetwo")
+
+	suite.Len(suite.cache.GetClientInfo("one"), 2)
+	suite.Len(suite.cache.GetClientInfo("onetwo"), 1)
+	suite.Empty(suite.cache.GetClientInfo("three"))
+}
+
+func (suite *CacheTestSuite) TestResyncClient() {
+	_, _ = suite.cache.GetClient("one")
+
+	suite.True(suite.cache.ResyncClient("one"))
+	suite.False(suite.cache.ResyncClient("two"))
+}
+
+func (suite *CacheTestSuite) TestEvictClient() {
+	suite.cache.pinnedKeys = map[string]struct{}{"one": {}}
+	_, _ = suite.cache.GetClient("one")
+	_, _ = suite.cache.GetClient("one:token")
+	_, _ = suite.cache.GetClient("two")
+
+	suite.True(suite.cache.EvictClient("one"))
+	suite.False(suite.cache.optlyMap.Has("one"))
+	suite.False(suite.cache.optlyMap.Has("one:token"))
+	suite.True(suite.cache.optlyMap.Has("two"))
+	suite.False(suite.cache.EvictClient("one"))
+}
+
 func (suite *CacheTestSuite) TestUpdateConfigs() {
 	_, _ = suite.cache.GetClient("one")
 	_, _ = suite.cache.GetClient("one:two")
@@ -312,6 +495,49 @@ func (suite *CacheTestSuite) TestNilCreatorAddedforODPCache() {
 	suite.Nil(odpCache)
 }
 
+func (suite *CacheTestSuite) TestDrain() {
+	_, err := suite.cache.GetClient("one")
+	suite.NoError(err)
+	_, err = suite.cache.GetClient("two")
+	suite.NoError(err)
+	suite.Equal(int64(2), atomic.LoadInt64(&suite.cache.openClients))
+	sink := &testSink{success: true}
+	suite.cache.sinks = NewEventSinks(map[string]event.Dispatcher{"sink": sink})
+
+	suite.cancel()
+	suite.True(suite.cache.Drain(time.Second))
+	suite.Equal(int64(0), atomic.LoadInt64(&suite.cache.openClients))
+	suite.True(sink.isClosed())
+}
+
+func (suite *CacheTestSuite) TestDrainTimeout() {
+	_, err := suite.cache.GetClient("one")
+	suite.NoError(err)
+
+	// The clients are only closed once the cache context is done
+	suite.False(suite.cache.Drain(10 * time.Millisecond))
+}
+
+func (suite *CacheTestSuite) TestCheckDatafiles() {
+	suite.NoError(suite.cache.CheckDatafiles([]string{}))
+
+	// The missing keys are loaded in the background
+	suite.EqualError(suite.cache.CheckDatafiles([]string{"one"}), "1 of 1 SDK keys have no datafile")
+	suite.Eventually(func() bool {
+		return suite.cache.CheckDatafiles([]string{"one"}) == nil
+	}, time.Second, time.Millisecond)
+	_, err := suite.cache.GetClient("two")
+	suite.NoError(err)
+	suite.NoError(suite.cache.CheckDatafiles([]string{"one", "two"}))
+
+	err = suite.cache.CheckDatafiles([]string{"one", "ERROR"})
+	suite.EqualError(err, "1 of 2 SDK keys have no datafile")
+
+	suite.cache.optlyMap.Set("noconfig", &OptlyClient{})
+	err = suite.cache.CheckDatafiles([]string{"noconfig", "ERROR"})
+	suite.EqualError(err, "2 of 2 SDK keys have no datafile")
+}
+
 // In order for 'go test' to run this suite, we need to create
 // a normal test function and pass our suite to suite.Run
 func TestCacheTestSuite(t *testing.T) {
@@ -327,7 +553,7 @@ func mockLoader(sdkKey string) (*OptlyClient, error) {
 	tc := optimizelytest.NewClient()
 	tc.ProjectConfig.ProjectID = sdkKey
 
-	return &OptlyClient{tc.OptimizelyClient, nil, tc.ForcedVariations, nil, nil}, nil
+	return &OptlyClient{OptimizelyClient: tc.OptimizelyClient, ForcedVariations: tc.ForcedVariations}, nil
 }
 
 type MockUserProfileService struct {
@@ -420,7 +646,7 @@ func (s *DefaultLoaderTestSuite) TestDefaultLoader() {
 		},
 	}
 
-	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
+	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, nil, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
 	client, err := loader("sdkkey")
 	s.NoError(err)
 
@@ -430,6 +656,9 @@ func (s *DefaultLoaderTestSuite) TestDefaultLoader() {
 	s.Equal(conf.EventURL, s.bp.EventEndPoint)
 	s.NotNil(client.UserProfileService)
 	s.NotNil(client.odpCache)
+	s.NotNil(client.syncStatus)
+	s.Equal("in-memory", client.userProfileServiceName)
+	s.Equal("in-memory", client.odpCacheName)
 
 	inMemoryUps, ok := client.UserProfileService.(*services.InMemoryUserProfileService)
 	s.True(ok)
@@ -445,6 +674,35 @@ func (s *DefaultLoaderTestSuite) TestDefaultLoader() {
 	s.Error(err)
 }
 
+func (s *DefaultLoaderTestSuite) TestLoaderAppliesSDKKeyOverrides() {
+	conf := config.ClientConfig{
+		FlushInterval: 321 * time.Second,
+		BatchSize:     1234,
+		QueueSize:     5678,
+		EventURL:      "https://localhost/events",
+		SdkKeyRegex:   "sdkkey",
+		SDKKeyOverrides: []config.SDKKeyOverride{
+			{SDKKey: "sdkkey", BatchSize: 10, EventURL: "https://localhost/override", AttributeValidation: config.AttributeValidationReject},
+		},
+	}
+
+	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, nil, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
+	client, err := loader("sdkkey:token")
+	s.NoError(err)
+
+	s.Equal(conf.FlushInterval, s.bp.FlushInterval)
+	s.Equal(10, s.bp.BatchSize)
+	s.Equal(conf.QueueSize, s.bp.MaxQueueSize)
+	s.Equal("https://localhost/override", s.bp.EventEndPoint)
+
+	settings := client.Info().Settings
+	s.Equal(10, settings.BatchSize)
+	s.Equal("https://localhost/override", settings.EventURL)
+	s.Equal(conf.QueueSize, settings.QueueSize)
+	s.Equal(config.AttributeValidationReject, settings.AttributeValidation)
+	s.Equal(config.AttributeValidationReject, client.AttributeValidationMode)
+}
+
 func (s *DefaultLoaderTestSuite) TestUPSAndODPCacheHeaderOverridesDefaultKey() {
 	conf := config.ClientConfig{
 		FlushInterval: 321 * time.Second,
@@ -474,7 +732,7 @@ func (s *DefaultLoaderTestSuite) TestUPSAndODPCacheHeaderOverridesDefaultKey() {
 	tmpOdpCacheMap := cmap.New()
 	tmpOdpCacheMap.Set("sdkkey", "in-memory")
 
-	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, tmpUPSMap, tmpOdpCacheMap, s.pcFactory, s.bpFactory)
+	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, nil, tmpUPSMap, tmpOdpCacheMap, s.pcFactory, s.bpFactory)
 	client, err := loader("sdkkey")
 	s.NoError(err)
 
@@ -538,7 +796,7 @@ func (s *DefaultLoaderTestSuite) TestFirstSaveConfiguresClientForRedisUPSAndODPC
 			}},
 		},
 	}
-	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
+	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, nil, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
 	client, err := loader("sdkkey")
 	s.NoError(err)
 	s.NotNil(client.UserProfileService)
@@ -596,7 +854,7 @@ func (s *DefaultLoaderTestSuite) TestFirstSaveConfiguresLRUCacheForInMemoryCache
 			}},
 		},
 	}
-	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
+	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, nil, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
 	client, err := loader("sdkkey")
 	s.NoError(err)
 	s.NotNil(client.odpCache)
@@ -627,7 +885,7 @@ func (s *DefaultLoaderTestSuite) TestHttpClientInitializesByDefaultRestUPS() {
 			"rest": map[string]interface{}{},
 		}},
 	}
-	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
+	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, nil, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
 	client, err := loader("sdkkey")
 	s.NoError(err)
 	s.NotNil(client.UserProfileService)
@@ -655,7 +913,7 @@ func (s *DefaultLoaderTestSuite) TestLoaderWithValidUserProfileServices() {
 			},
 		}},
 	}
-	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
+	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, nil, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
 	client, err := loader("sdkkey")
 	s.NoError(err)
 
@@ -686,7 +944,7 @@ func (s *DefaultLoaderTestSuite) TestLoaderWithValidODPCache() {
 			}},
 		},
 	}
-	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
+	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, nil, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
 	client, err := loader("sdkkey")
 	s.NoError(err)
 
@@ -700,6 +958,162 @@ func (s *DefaultLoaderTestSuite) TestLoaderWithValidODPCache() {
 	s.Failf("ODPCache not registered", "%s DNE in registry", "mock2")
 }
 
+func (s *DefaultLoaderTestSuite) TestLoaderFallsBackToDatafileSnapshot() {
+	datafile := `{"version": "4", "revision": "42", "projectId": "1", "accountId": "1"}`
+	available := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(datafile))
+	}))
+	defer ts.Close()
+
+	conf := config.ClientConfig{
+		DatafileURLTemplate: ts.URL + "/%s.json",
+		DatafileSnapshotDir: s.T().TempDir(),
+		SdkKeyRegex:         "sdkkey",
+		ODP:                 config.OdpConfig{Disable: true},
+	}
+	pcFactory := func(sdkKey string, options ...sdkconfig.OptionFunc) SyncedConfigManager {
+		return sdkconfig.NewPollingProjectConfigManager(sdkKey, options...)
+	}
+	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, nil, s.upsMap, s.odpCacheMap, pcFactory, s.bpFactory)
+
+	client, err := loader("sdkkey")
+	s.NoError(err)
+	s.False(client.IsStale())
+	s.FileExists(filepath.Join(conf.DatafileSnapshotDir, "sdkkey.json"))
+	client.Close()
+
+	available = false
+	client, err = loader("sdkkey")
+	s.NoError(err)
+	s.True(client.IsStale())
+	s.Equal("42", client.Info().Revision)
+	s.NotEmpty(client.Info().LastSyncError)
+
+	// A successful sync clears the stale flag
+	available = true
+	client.UpdateConfig()
+	s.False(client.IsStale())
+	client.Close()
+}
+
+func (s *DefaultLoaderTestSuite) TestLoaderWithoutDatafileSnapshot() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	conf := config.ClientConfig{
+		DatafileURLTemplate: ts.URL + "/%s.json",
+		DatafileSnapshotDir: s.T().TempDir(),
+		SdkKeyRegex:         "sdkkey",
+	}
+	pcFactory := func(sdkKey string, options ...sdkconfig.OptionFunc) SyncedConfigManager {
+		return sdkconfig.NewPollingProjectConfigManager(sdkKey, options...)
+	}
+	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, nil, s.upsMap, s.odpCacheMap, pcFactory, s.bpFactory)
+
+	_, err := loader("sdkkey")
+	s.Error(err)
+}
+
+func (s *DefaultLoaderTestSuite) TestNewCacheWithStaticDatafiles() {
+	dir := s.T().TempDir()
+	datafile := `{"version": "4", "revision": "42", "projectId": "1", "accountId": "1"}`
+	s.NoError(os.WriteFile(filepath.Join(dir, "sdkkey.json"), []byte(datafile), 0o600))
+
+	conf := config.NewDefaultConfig()
+	conf.Client.ODP.Disable = true
+	conf.Client.StaticDatafiles.Dir = dir
+	ctx, cancel := context.WithCancel(context.Background())
+	optlyCache := NewCache(ctx, *conf, s.registry)
+
+	optlyClient, err := optlyCache.GetClient("sdkkey")
+	s.NoError(err)
+	s.IsType(&FileProjectConfigManager{}, optlyClient.ConfigManager)
+	s.Equal("42", optlyClient.Info().Revision)
+
+	_, err = optlyCache.GetClient("missing")
+	s.Error(err)
+
+	cancel()
+	optlyCache.Wait()
+}
+
+func (s *DefaultLoaderTestSuite) TestLoaderReplaysDiskEventQueue() {
+	var dispatched int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&dispatched, 1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	conf := config.ClientConfig{
+		BatchSize:     10,
+		QueueSize:     10,
+		FlushInterval: 10 * time.Millisecond,
+		EventURL:      ts.URL,
+		SdkKeyRegex:   "sdkkey",
+		ODP:           config.OdpConfig{Disable: true},
+		EventQueue: config.EventQueueConfig{
+			Type: config.EventQueueTypeDisk,
+			Disk: config.DiskEventQueueConfig{Dir: s.T().TempDir()},
+		},
+	}
+
+	// Events left in the log by a previous run
+	q, err := NewDiskQueue(filepath.Join(conf.EventQueue.Disk.Dir, "sdkkey.wal"), 0, 0, nil)
+	s.NoError(err)
+	q.Add(newUserEvent("1"))
+	q.Add(newUserEvent("2"))
+	s.NoError(q.Close())
+
+	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, nil, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
+	client, err := loader("sdkkey")
+	s.NoError(err)
+	s.IsType(&DiskQueue{}, s.bp.Q)
+
+	s.Eventually(func() bool { return atomic.LoadInt32(&dispatched) == 1 }, time.Second, 10*time.Millisecond)
+	s.Eventually(func() bool { return s.bp.Q.Size() == 0 }, time.Second, 10*time.Millisecond)
+	client.Close()
+}
+
+func (s *DefaultLoaderTestSuite) TestLoaderScrubsQueuedEvents() {
+	conf := config.ClientConfig{
+		QueueSize:   10,
+		SdkKeyRegex: "sdkkey",
+		ODP:         config.OdpConfig{Disable: true},
+		SDKKeyOverrides: []config.SDKKeyOverride{{
+			SDKKey: "sdkkey",
+			Scrub: &config.ScrubConfig{Rules: []config.ScrubRule{
+				{Tags: []string{"category"}, Action: config.ScrubActionDrop},
+			}},
+		}},
+	}
+
+	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, nil, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
+	client, err := loader("sdkkey")
+	s.NoError(err)
+	s.IsType(&ScrubbingQueue{}, s.bp.Q)
+
+	s.bp.Q.Add(newUserEvent("1"))
+	queued := s.bp.Q.Remove(1)
+	if s.Len(queued, 1) {
+		s.Empty(queued[0].(event.UserEvent).Conversion.Tags)
+	}
+	client.Close()
+
+	// The queue is not wrapped for SDK keys without rules
+	client, err = loader("othersdkkey")
+	s.NoError(err)
+	s.IsType(&event.InMemoryQueue{}, s.bp.Q)
+	client.Close()
+}
+
 func (s *DefaultLoaderTestSuite) TestLoaderWithEmptyUserProfileServices() {
 	upCreator := func() decision.UserProfileService {
 		return &MockUserProfileService{}
@@ -709,7 +1123,7 @@ func (s *DefaultLoaderTestSuite) TestLoaderWithEmptyUserProfileServices() {
 	conf := config.ClientConfig{
 		UserProfileService: map[string]interface{}{},
 	}
-	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
+	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, nil, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
 	client, err := loader("sdkkey")
 	s.NoError(err)
 	s.Nil(client.UserProfileService)
@@ -726,7 +1140,7 @@ func (s *DefaultLoaderTestSuite) TestLoaderWithEmptyODPCache() {
 			SegmentsCache: map[string]interface{}{},
 		},
 	}
-	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
+	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, nil, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
 	client, err := loader("sdkkey")
 	s.NoError(err)
 	s.Nil(client.odpCache)
@@ -743,7 +1157,7 @@ func (s *DefaultLoaderTestSuite) TestLoaderWithNoDefaultUserProfileServices() {
 			"mock3": map[string]interface{}{},
 		}},
 	}
-	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
+	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, nil, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
 	client, err := loader("sdkkey")
 	s.NoError(err)
 	s.Nil(client.UserProfileService)
@@ -762,7 +1176,7 @@ func (s *DefaultLoaderTestSuite) TestLoaderWithNoDefaultODPCache() {
 			}},
 		},
 	}
-	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
+	loader := defaultLoader(config.AgentConfig{Client: conf}, s.registry, nil, s.upsMap, s.odpCacheMap, s.pcFactory, s.bpFactory)
 	client, err := loader("sdkkey")
 	s.NoError(err)
 	s.Nil(client.odpCache)
@@ -20,11 +20,20 @@ package optimizely
 import (
 	"context"
 	"errors"
+	"time"
 
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/pkg/overrides"
+	"github.com/optimizely/agent/plugins/forceddecisions"
+	"github.com/optimizely/agent/plugins/userattributes"
 	optimizelyclient "github.com/optimizely/go-sdk/pkg/client"
+	sdkconfig "github.com/optimizely/go-sdk/pkg/config"
 	"github.com/optimizely/go-sdk/pkg/decision"
 	"github.com/optimizely/go-sdk/pkg/entities"
+	"github.com/optimizely/go-sdk/pkg/event"
+	"github.com/optimizely/go-sdk/pkg/logging"
 	"github.com/optimizely/go-sdk/pkg/odp/cache"
+	"github.com/rs/zerolog/log"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 )
@@ -39,9 +48,78 @@ var ErrForcedVariationsUninitialized = errors.New("client forced variations stor
 type OptlyClient struct {
 	*optimizelyclient.OptimizelyClient
 	ConfigManager      SyncedConfigManager
-	ForcedVariations   *decision.MapExperimentOverridesStore
+	ForcedVariations   overrides.Store
 	UserProfileService decision.UserProfileService
+	ForcedDecisions    forceddecisions.Store
+	UserAttributes     userattributes.Provider
 	odpCache           cache.Cache
+
+	// AttributeValidationMode is what decide does with request attributes the datafile doesn't expect, empty is off
+	AttributeValidationMode config.AttributeValidationMode
+
+	syncStatus             *SyncStatus
+	sdkKey                 string
+	userProfileServiceName string
+	odpCacheName           string
+	forcedDecisionsName    string
+	userAttributesName     string
+	settings               *ClientSettings
+	eventQueue             event.Queue
+	attributeTypes         attributeTypesCache
+}
+
+// ClientSettings model describing the effective configuration of a client, including SDK key overrides
+type ClientSettings struct {
+	PollingInterval time.Duration `json:"pollingInterval"`
+	BatchSize       int           `json:"batchSize"`
+	QueueSize       int           `json:"queueSize"`
+	FlushInterval   time.Duration `json:"flushInterval"`
+	EventURL        string        `json:"eventURL"`
+	ODP             ODPSettings   `json:"odp"`
+
+	AttributeValidation config.AttributeValidationMode `json:"attributeValidation"`
+}
+
+// ODPSettings model describing the effective odp configuration of a client
+type ODPSettings struct {
+	Disable                bool          `json:"disable"`
+	EventsRequestTimeout   time.Duration `json:"eventsRequestTimeout"`
+	EventsFlushInterval    time.Duration `json:"eventsFlushInterval"`
+	SegmentsRequestTimeout time.Duration `json:"segmentsRequestTimeout"`
+}
+
+func newClientSettings(conf config.ClientConfig) *ClientSettings {
+	return &ClientSettings{
+		PollingInterval: conf.PollingInterval,
+		BatchSize:       conf.BatchSize,
+		QueueSize:       conf.QueueSize,
+		FlushInterval:   conf.FlushInterval,
+		EventURL:        conf.EventURL,
+		ODP: ODPSettings{
+			Disable:                conf.ODP.Disable,
+			EventsRequestTimeout:   conf.ODP.EventsRequestTimeout,
+			EventsFlushInterval:    conf.ODP.EventsFlushInterval,
+			SegmentsRequestTimeout: conf.ODP.SegmentsRequestTimeout,
+		},
+		AttributeValidation: conf.AttributeValidation,
+	}
+}
+
+// ClientInfo model describing a loaded OptlyClient
+type ClientInfo struct {
+	SDKKey             string          `json:"sdkKey"`
+	Authenticated      bool            `json:"authenticated"`
+	Revision           string          `json:"revision,omitempty"`
+	LastSync           *time.Time      `json:"lastSync,omitempty"`
+	LastSyncError      string          `json:"lastSyncError,omitempty"`
+	Stale              bool            `json:"stale"`
+	LastAccess         *time.Time      `json:"lastAccess,omitempty"`
+	EventQueueSize     int             `json:"eventQueueSize"`
+	UserProfileService string          `json:"userProfileService,omitempty"`
+	ODPCache           string          `json:"odpCache,omitempty"`
+	ForcedDecisions    string          `json:"forcedDecisions,omitempty"`
+	UserAttributes     string          `json:"userAttributes,omitempty"`
+	Settings           *ClientSettings `json:"settings,omitempty"`
 }
 
 // Decision Model
@@ -58,11 +136,12 @@ type Decision struct {
 
 // Override model
 type Override struct {
-	UserID           string   `json:"userId"`
-	ExperimentKey    string   `json:"experimentKey"`
-	VariationKey     string   `json:"variationKey"`
-	PrevVariationKey string   `json:"prevVariationKey"`
-	Messages         []string `json:"messages"`
+	UserID           string     `json:"userId"`
+	ExperimentKey    string     `json:"experimentKey"`
+	VariationKey     string     `json:"variationKey"`
+	PrevVariationKey string     `json:"prevVariationKey"`
+	ExpiresAt        *time.Time `json:"expiresAt,omitempty"`
+	Messages         []string   `json:"messages"`
 }
 
 // Track response model
@@ -85,6 +164,78 @@ func (c *OptlyClient) UpdateConfig() {
 	}
 }
 
+// Close closes the client and stops the config manager when it runs on its own
+func (c *OptlyClient) Close() {
+	if c.OptimizelyClient != nil {
+		c.OptimizelyClient.Close()
+	}
+	closeConfigManager(c.ConfigManager)
+	// The event processor flushed the queue while closing, any event left is kept for the next start
+	closeEventQueue(c.eventQueue)
+}
+
+// IsStale returns true while the client is serving a datafile snapshot because the datafile could not be fetched
+func (c *OptlyClient) IsStale() bool {
+	return c.syncStatus != nil && c.syncStatus.Stale()
+}
+
+// SDKKey returns the SDK key of the client
+func (c *OptlyClient) SDKKey() string {
+	return c.sdkKey
+}
+
+// Snapshot returns a copy of the client pinned to the current project config, so that every decision
+// made with it uses the same datafile revision even if a new one is fetched meanwhile.
+// The copy shares the event processor, decision service and notification center of the client.
+func (c *OptlyClient) Snapshot() (*optimizelyclient.OptimizelyClient, error) {
+	if c.OptimizelyClient == nil || c.OptimizelyClient.ConfigManager == nil {
+		return nil, errors.New("client is not initialized")
+	}
+	projectConfig, err := c.OptimizelyClient.ConfigManager.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := *c.OptimizelyClient
+	snapshot.ConfigManager = sdkconfig.NewStaticProjectConfigManager(projectConfig, logging.GetLogger("", "StaticProjectConfigManager"))
+	return &snapshot, nil
+}
+
+// Info returns the current state of the client
+func (c *OptlyClient) Info() ClientInfo {
+	info := ClientInfo{
+		UserProfileService: c.userProfileServiceName,
+		ODPCache:           c.odpCacheName,
+		ForcedDecisions:    c.forcedDecisionsName,
+		UserAttributes:     c.userAttributesName,
+		Settings:           c.settings,
+	}
+
+	if c.ConfigManager != nil {
+		if projectConfig, err := c.ConfigManager.GetConfig(); err == nil && projectConfig != nil {
+			info.Revision = projectConfig.GetRevision()
+		}
+	}
+
+	if c.syncStatus != nil {
+		if lastSync := c.syncStatus.LastSync(); !lastSync.IsZero() {
+			info.LastSync = &lastSync
+		}
+		if err := c.syncStatus.LastError(); err != nil {
+			info.LastSyncError = err.Error()
+		}
+		info.Stale = c.syncStatus.Stale()
+	}
+
+	if c.OptimizelyClient != nil {
+		if ep, ok := c.EventProcessor.(*event.BatchEventProcessor); ok && ep.Q != nil {
+			info.EventQueueSize = ep.Q.Size()
+		}
+	}
+
+	return info
+}
+
 // TrackEvent checks for the existence of the event before calling the OptimizelyClient Track method
 func (c *OptlyClient) TrackEvent(ctx context.Context, eventKey string, uc entities.UserContext, eventTags map[string]interface{}) (*Track, error) {
 	_, span := otel.Tracer("trackHandler").Start(ctx, "TrackEvent")
@@ -103,6 +254,12 @@ func (c *OptlyClient) TrackEvent(ctx context.Context, eventKey string, uc entiti
 		return tr, nil
 	}
 
+	attributes, err := c.MergeUserAttributes(uc.ID, uc.Attributes)
+	if err != nil {
+		log.Warn().Err(err).Str("userId", uc.ID).Msg("failed to look up stored user attributes")
+	}
+	uc.Attributes = attributes
+
 	if err := c.Track(eventKey, uc, eventTags); err != nil {
 		return &Track{}, err
 	}
@@ -110,10 +267,9 @@ func (c *OptlyClient) TrackEvent(ctx context.Context, eventKey string, uc entiti
 	return tr, nil
 }
 
-// SetForcedVariation sets a forced variation for the argument experiment key and user ID
-// Returns false if the same forced variation was already set for the argument experiment and user, true otherwise
-// Returns an error when forced variations are not available on this OptlyClient instance
-func (c *OptlyClient) SetForcedVariation(ctx context.Context, experimentKey, userID, variationKey string) (*Override, error) {
+// SetForcedVariation sets a forced variation for the argument experiment key and user ID, expiring after ttl when it is positive
+// Returns an error when forced variations are not available on this OptlyClient instance or can't be stored
+func (c *OptlyClient) SetForcedVariation(ctx context.Context, experimentKey, userID, variationKey string, ttl time.Duration) (*Override, error) {
 	_, span := otel.Tracer("overrideHandler").Start(ctx, "SetForcedVariation")
 	defer span.End()
 
@@ -137,12 +293,25 @@ func (c *OptlyClient) SetForcedVariation(ctx context.Context, experimentKey, use
 		messages = append(messages, "variationKey not found in configuration")
 	}
 
-	forcedVariationKey := decision.ExperimentOverrideKey{
+	stored := overrides.Override{
 		UserID:        userID,
 		ExperimentKey: experimentKey,
+		VariationKey:  variationKey,
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		stored.ExpiresAt = &expiresAt
+		override.ExpiresAt = &expiresAt
 	}
 
-	if prevVariationKey, ok := c.ForcedVariations.GetVariation(forcedVariationKey); ok {
+	span.SetAttributes(attribute.String("variationKey", override.VariationKey))
+	span.SetAttributes(attribute.String("experimentKey", override.ExperimentKey))
+
+	prevVariationKey, err := c.ForcedVariations.Set(ctx, stored)
+	if err != nil {
+		return &Override{}, err
+	}
+	if prevVariationKey != "" {
 		override.PrevVariationKey = prevVariationKey
 		messages = append(messages, "updating previous override")
 	}
@@ -150,11 +319,6 @@ func (c *OptlyClient) SetForcedVariation(ctx context.Context, experimentKey, use
 	if len(messages) > 0 {
 		override.Messages = messages
 	}
-
-	span.SetAttributes(attribute.String("variationKey", override.VariationKey))
-	span.SetAttributes(attribute.String("experimentKey", override.ExperimentKey))
-
-	c.ForcedVariations.SetVariation(forcedVariationKey, variationKey)
 	return &override, nil
 }
 
@@ -178,8 +342,13 @@ func (c *OptlyClient) RemoveForcedVariation(ctx context.Context, experimentKey,
 		ExperimentKey: experimentKey,
 	}
 
+	prevVariationKey, err := c.ForcedVariations.Remove(ctx, forcedVariationKey)
+	if err != nil {
+		return &Override{}, err
+	}
+
 	messages := make([]string, 0, 1)
-	if prevVariationKey, ok := c.ForcedVariations.GetVariation(forcedVariationKey); ok {
+	if prevVariationKey != "" {
 		override.PrevVariationKey = prevVariationKey
 		messages = append(messages, "removing previous override")
 	} else {
@@ -187,7 +356,6 @@ func (c *OptlyClient) RemoveForcedVariation(ctx context.Context, experimentKey,
 	}
 
 	override.Messages = messages
-	c.ForcedVariations.RemoveVariation(forcedVariationKey)
 
 	span.SetAttributes(attribute.String("variationKey", override.VariationKey))
 	span.SetAttributes(attribute.String("experimentKey", override.ExperimentKey))
@@ -195,6 +363,28 @@ func (c *OptlyClient) RemoveForcedVariation(ctx context.Context, experimentKey,
 	return &override, nil
 }
 
+// ListForcedVariations returns the forced variations selected by the filter, sorted by user and experiment
+func (c *OptlyClient) ListForcedVariations(ctx context.Context, filter overrides.Filter) ([]overrides.Override, error) {
+	_, span := otel.Tracer("overrideHandler").Start(ctx, "ListForcedVariations")
+	defer span.End()
+
+	if c.ForcedVariations == nil {
+		return nil, ErrForcedVariationsUninitialized
+	}
+	return c.ForcedVariations.List(ctx, filter)
+}
+
+// ClearForcedVariations removes the forced variations selected by the filter and returns how many were removed
+func (c *OptlyClient) ClearForcedVariations(ctx context.Context, filter overrides.Filter) (int, error) {
+	_, span := otel.Tracer("overrideHandler").Start(ctx, "ClearForcedVariations")
+	defer span.End()
+
+	if c.ForcedVariations == nil {
+		return 0, ErrForcedVariationsUninitialized
+	}
+	return c.ForcedVariations.Clear(ctx, filter)
+}
+
 // ActivateFeature activates a feature for a given user by getting the feature enabled status and all
 // associated variables
 func (c *OptlyClient) ActivateFeature(ctx context.Context, key string, uc entities.UserContext, disableTracking bool) (*Decision, error) {
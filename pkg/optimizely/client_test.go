@@ -21,6 +21,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/optimizely/go-sdk/pkg/client"
 	"github.com/optimizely/go-sdk/pkg/decision"
@@ -28,6 +29,8 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/optimizely/agent/pkg/optimizely/optimizelytest"
+	"github.com/optimizely/agent/pkg/overrides"
+	"github.com/optimizely/agent/plugins/userattributes/services"
 
 	"github.com/optimizely/go-sdk/pkg/config"
 	"github.com/optimizely/go-sdk/pkg/entities"
@@ -85,6 +88,54 @@ func (suite *ClientTestSuite) TestTrackEvent() {
 	suite.Equal(tags, actualEvent.Conversion.Tags)
 }
 
+func (suite *ClientTestSuite) TestTrackEventMergesStoredAttributes() {
+	eventKey := "eventKey"
+	suite.testClient.AddEvent(entities.Event{Key: eventKey})
+	suite.testClient.ProjectConfig.AttributeKeyToIDMap = map[string]string{"plan": "1", "country": "2"}
+	suite.testClient.ProjectConfig.AttributeMap = map[string]entities.Attribute{"1": {ID: "1", Key: "plan"}, "2": {ID: "2", Key: "country"}}
+	suite.optlyClient.UserAttributes = services.NewInMemoryProvider()
+	suite.NoError(suite.optlyClient.UserAttributes.Upsert("", "userId", map[string]interface{}{"plan": "free", "country": "US"}))
+
+	suite.userContext.Attributes["plan"] = "pro"
+	_, err := suite.optlyClient.TrackEvent(context.Background(), eventKey, suite.userContext, nil)
+	suite.NoError(err)
+
+	events := suite.testClient.GetProcessedEvents()
+	suite.Equal(1, len(events))
+
+	attributes := map[string]interface{}{}
+	for _, attribute := range events[0].Conversion.Attributes {
+		attributes[attribute.Key] = attribute.Value
+	}
+	suite.Equal("pro", attributes["plan"])
+	suite.Equal("US", attributes["country"])
+}
+
+func (suite *ClientTestSuite) TestUserAttributes() {
+	_, err := suite.optlyClient.GetUserAttributes(context.Background(), "userId")
+	suite.Equal(ErrUserAttributesUninitialized, err)
+	merged, err := suite.optlyClient.MergeUserAttributes("userId", map[string]interface{}{"plan": "pro"})
+	suite.NoError(err)
+	suite.Equal(map[string]interface{}{"plan": "pro"}, merged)
+
+	suite.optlyClient.UserAttributes = services.NewInMemoryProvider()
+	stored, err := suite.optlyClient.UpsertUserAttributes(context.Background(), "userId", map[string]interface{}{"plan": "free", "age": 30})
+	suite.NoError(err)
+	suite.Equal(&UserAttributes{UserID: "userId", Attributes: map[string]interface{}{"plan": "free", "age": 30}}, stored)
+
+	stored, err = suite.optlyClient.UpsertUserAttributes(context.Background(), "userId", map[string]interface{}{"age": nil})
+	suite.NoError(err)
+	suite.Equal(map[string]interface{}{"plan": "free"}, stored.Attributes)
+
+	merged, err = suite.optlyClient.MergeUserAttributes("userId", map[string]interface{}{"plan": "pro", "country": "US"})
+	suite.NoError(err)
+	suite.Equal(map[string]interface{}{"plan": "pro", "country": "US"}, merged)
+
+	merged, err = suite.optlyClient.MergeUserAttributes("otherUser", nil)
+	suite.NoError(err)
+	suite.Nil(merged)
+}
+
 func (suite *ClientTestSuite) TestValidSetForcedVariations() {
 	scenarios := []struct {
 		experimentKey string
@@ -123,7 +174,7 @@ func (suite *ClientTestSuite) TestValidSetForcedVariations() {
 
 	userID := "testUser"
 	for _, scenario := range scenarios {
-		actual, err := suite.optlyClient.SetForcedVariation(context.Background(), scenario.experimentKey, userID, scenario.variationKey)
+		actual, err := suite.optlyClient.SetForcedVariation(context.Background(), scenario.experimentKey, userID, scenario.variationKey, 0)
 		suite.NoError(err)
 
 		expected := &Override{
@@ -162,7 +213,7 @@ func (suite *ClientTestSuite) TestRemoveForcedVariation() {
 	}
 
 	userID := "testUser"
-	_, _ = suite.optlyClient.SetForcedVariation(context.Background(), suite.featureExp.Key, userID, "enabled_var")
+	_, _ = suite.optlyClient.SetForcedVariation(context.Background(), suite.featureExp.Key, userID, "enabled_var", 0)
 
 	for _, scenario := range scenarios {
 		actual, err := suite.optlyClient.RemoveForcedVariation(context.Background(), suite.featureExp.Key, userID)
@@ -183,6 +234,59 @@ func (suite *ClientTestSuite) TestRemoveForcedVariation() {
 
 }
 
+func (suite *ClientTestSuite) TestForcedVariationTTL() {
+	userID := suite.userContext.ID
+	actual, err := suite.optlyClient.SetForcedVariation(context.Background(), suite.featureExp.Key, userID, "enabled_var", time.Hour)
+	suite.NoError(err)
+	if suite.NotNil(actual.ExpiresAt) {
+		suite.WithinDuration(time.Now().Add(time.Hour), *actual.ExpiresAt, time.Minute)
+	}
+
+	isEnabled, _ := suite.optlyClient.IsFeatureEnabled("my_feat", suite.userContext)
+	suite.True(isEnabled)
+
+	// An expired override no longer forces the variation
+	_, err = suite.optlyClient.SetForcedVariation(context.Background(), suite.featureExp.Key, userID, "enabled_var", time.Nanosecond)
+	suite.NoError(err)
+	time.Sleep(time.Millisecond)
+	isEnabled, _ = suite.optlyClient.IsFeatureEnabled("my_feat", suite.userContext)
+	suite.False(isEnabled)
+}
+
+func (suite *ClientTestSuite) TestListAndClearForcedVariations() {
+	_, _ = suite.optlyClient.SetForcedVariation(context.Background(), suite.featureExp.Key, "user1", "enabled_var", 0)
+	_, _ = suite.optlyClient.SetForcedVariation(context.Background(), suite.featureExp.Key, "user2", "enabled_var", 0)
+	_, _ = suite.optlyClient.SetForcedVariation(context.Background(), "other", "user1", "enabled_var", 0)
+
+	list, err := suite.optlyClient.ListForcedVariations(context.Background(), overrides.Filter{UserID: "user1"})
+	suite.NoError(err)
+	suite.Equal([]overrides.Override{
+		{UserID: "user1", ExperimentKey: suite.featureExp.Key, VariationKey: "enabled_var"},
+		{UserID: "user1", ExperimentKey: "other", VariationKey: "enabled_var"},
+	}, list)
+
+	cleared, err := suite.optlyClient.ClearForcedVariations(context.Background(), overrides.Filter{ExperimentKey: suite.featureExp.Key})
+	suite.NoError(err)
+	suite.Equal(2, cleared)
+
+	list, err = suite.optlyClient.ListForcedVariations(context.Background(), overrides.Filter{})
+	suite.NoError(err)
+	suite.Equal([]overrides.Override{{UserID: "user1", ExperimentKey: "other", VariationKey: "enabled_var"}}, list)
+}
+
+func TestForcedVariationsUninitialized(t *testing.T) {
+	optlyClient := &OptlyClient{}
+
+	_, err := optlyClient.SetForcedVariation(context.Background(), "experiment", "user", "variation", 0)
+	assert.Equal(t, ErrForcedVariationsUninitialized, err)
+	_, err = optlyClient.RemoveForcedVariation(context.Background(), "experiment", "user")
+	assert.Equal(t, ErrForcedVariationsUninitialized, err)
+	_, err = optlyClient.ListForcedVariations(context.Background(), overrides.Filter{})
+	assert.Equal(t, ErrForcedVariationsUninitialized, err)
+	_, err = optlyClient.ClearForcedVariations(context.Background(), overrides.Filter{UserID: "user"})
+	assert.Equal(t, ErrForcedVariationsUninitialized, err)
+}
+
 func (suite *ClientTestSuite) TestActivateFeature() {
 	var1 := entities.Variable{Key: "var1", DefaultValue: "val1"}
 	var2 := entities.Variable{Key: "var2", DefaultValue: "val2"}
@@ -247,6 +351,27 @@ func (suite *ClientTestSuite) TestActivateExperiment() {
 	suite.Equal(1, len(suite.testClient.GetProcessedEvents()))
 }
 
+func (suite *ClientTestSuite) TestSnapshot() {
+	snapshot, err := suite.optlyClient.Snapshot()
+	suite.NoError(err)
+	suite.NotSame(suite.optlyClient.OptimizelyClient, snapshot)
+	suite.Same(suite.optlyClient.OptimizelyClient.EventProcessor, snapshot.EventProcessor)
+
+	// The snapshot keeps the config it was created with once the client gets a new one
+	suite.optlyClient.OptimizelyClient.ConfigManager = NewErrorConfigManager("config error")
+	actual, err := snapshot.ConfigManager.GetConfig()
+	suite.NoError(err)
+	suite.Equal(suite.testClient.ProjectConfig, actual)
+
+	userContext := snapshot.CreateUserContext("testUser", nil)
+	decision := userContext.Decide("my_feat", nil)
+	suite.Equal("my_feat", decision.FlagKey)
+	suite.NotEmpty(decision.VariationKey)
+
+	_, err = suite.optlyClient.Snapshot()
+	suite.EqualError(err, "config error")
+}
+
 // In order for 'go test' to run this suite, we need to create
 // a normal test function and pass our suite to suite.Run
 func TestClientTestSuite(t *testing.T) {
@@ -322,6 +447,12 @@ func TestTrackErrorConfigManager(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
+func TestSnapshotUninitializedClient(t *testing.T) {
+	optlyClient := &OptlyClient{}
+	_, err := optlyClient.Snapshot()
+	assert.EqualError(t, err, "client is not initialized")
+}
+
 func TestTrackErrorClient(t *testing.T) {
 	// Construct an OptimizelyClient with an erroring config manager
 	factory := client.OptimizelyFactory{}
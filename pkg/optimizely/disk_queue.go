@@ -0,0 +1,422 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/optimizely/go-sdk/pkg/event"
+	"github.com/rs/zerolog/log"
+)
+
+// compactMinBytes is the size below which the event log is never compacted while it still holds events
+const compactMinBytes = 1024 * 1024
+
+// walRecord is a line of the event log, either an added event or the number of events removed from the head
+type walRecord struct {
+	Add    *event.UserEvent `json:"add,omitempty"`
+	Remove int              `json:"remove,omitempty"`
+}
+
+// DiskQueue implements the event.Queue interface on top of a write-ahead log.
+// Every added and removed event is appended to the log so that the events which weren't
+// dispatched yet are replayed when the queue is opened again after a restart or a crash.
+// The appended records survive a crash of Agent right away, while they survive a crash of the machine
+// once the log is synced to disk, which happens every sync interval.
+type DiskQueue struct {
+	mu           sync.Mutex
+	path         string
+	file         *os.File
+	maxBytes     int64
+	syncInterval time.Duration
+	events       []interface{}
+	sizes        []int64
+	bytes        int64
+	fileBytes    int64
+	dirty        bool
+	unsynced     bool
+
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	// onBytesChange is called with the change of the size of the queued events
+	onBytesChange func(delta int64)
+}
+
+// eventLogPath returns the location of the event log of the client, empty if the SDK key can't be used as a file name.
+// Authenticated clients get their own log so that they never share a file with the unauthenticated client.
+func eventLogPath(dir, sdkKey, datafileAccessToken string) string {
+	if dir == "" || sdkKey == "" || filepath.Base(sdkKey) != sdkKey {
+		return ""
+	}
+	name := sdkKey
+	if datafileAccessToken != "" {
+		sum := sha256.Sum256([]byte(datafileAccessToken))
+		name += "-" + hex.EncodeToString(sum[:])[:12]
+	}
+	return filepath.Join(dir, name+".wal")
+}
+
+// NewDiskQueue opens the event log at path, replaying the events it holds.
+// Once maxBytes of events are queued new events are discarded, 0 means no limit.
+// The log is synced to disk every syncInterval, 0 syncs it after every appended record.
+func NewDiskQueue(path string, maxBytes int64, syncInterval time.Duration, onBytesChange func(delta int64)) (*DiskQueue, error) {
+	if path == "" {
+		return nil, errors.New("event log path is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	q := &DiskQueue{
+		path:          path,
+		maxBytes:      maxBytes,
+		syncInterval:  syncInterval,
+		stop:          make(chan struct{}),
+		onBytesChange: onBytesChange,
+	}
+	if err := q.replay(); err != nil {
+		return nil, err
+	}
+
+	// Compacting drops the records of dispatched events and any partially written record
+	if err := q.compact(); err != nil {
+		return nil, err
+	}
+
+	if len(q.events) > 0 {
+		log.Info().Int("events", len(q.events)).Str("path", path).Msg("Replayed queued events from disk")
+	}
+	q.notify(q.bytes)
+
+	if syncInterval > 0 {
+		go q.syncPeriodically()
+	}
+	return q, nil
+}
+
+// syncPeriodically syncs the records appended to the log every sync interval until the queue is closed
+func (q *DiskQueue) syncPeriodically() {
+	ticker := time.NewTicker(q.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		case <-ticker.C:
+			q.mu.Lock()
+			q.sync()
+			q.mu.Unlock()
+		}
+	}
+}
+
+func (q *DiskQueue) replay() error {
+	f, err := os.Open(q.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				log.Warn().Str("path", q.path).Msg("Discarding partially written event")
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var record walRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			log.Warn().Err(err).Str("path", q.path).Msg("Discarding unreadable event log record")
+			continue
+		}
+
+		switch {
+		case record.Add != nil:
+			q.push(*record.Add, int64(len(line)))
+		case record.Remove > 0:
+			q.pop(record.Remove)
+		}
+	}
+}
+
+// Add appends the event to the log and the queue. Events which are not user events can't be replayed and are discarded.
+func (q *DiskQueue) Add(item interface{}) {
+	userEvent, ok := item.(event.UserEvent)
+	if !ok {
+		log.Warn().Msg("Discarding event which can't be written to the event log")
+		return
+	}
+
+	line, err := encodeRecord(walRecord{Add: &userEvent})
+	if err != nil {
+		log.Warn().Err(err).Msg("Discarding event which can't be written to the event log")
+		return
+	}
+	size := int64(len(line))
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.maxBytes > 0 && q.bytes+size > q.maxBytes {
+		log.Warn().Int64("maxBytes", q.maxBytes).Msg("Event queue size limit has been met. Discarding event")
+		return
+	}
+
+	q.write(line)
+	q.push(userEvent, size)
+	q.notify(size)
+}
+
+// Remove removes count events from the head of the queue and returns them
+func (q *DiskQueue) Remove(count int) []interface{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	count = q.safeCount(count)
+	if count == 0 {
+		return []interface{}{}
+	}
+
+	removed := make([]interface{}, count)
+	copy(removed, q.events[:count])
+	// Compacting measures the queued events again, so the gauge is updated with the resulting change
+	before := q.bytes
+	q.pop(count)
+
+	switch {
+	case len(q.events) == 0 && !q.dirty && q.file != nil:
+		if err := q.file.Truncate(0); err != nil {
+			log.Error().Err(err).Str("path", q.path).Msg("Failed to truncate event log")
+			q.dirty = true
+		}
+		q.fileBytes = 0
+	case q.dirty || (q.fileBytes > compactMinBytes && q.fileBytes > 2*q.bytes):
+		dirty := q.dirty
+		if err := q.compact(); err != nil {
+			log.Error().Err(err).Str("path", q.path).Msg("Failed to compact event log")
+			// Without the removal the dispatched events would be replayed, unless the log is rewritten anyway
+			if !dirty {
+				q.writeRemove(count)
+			}
+		}
+	default:
+		q.writeRemove(count)
+	}
+
+	q.notify(q.bytes - before)
+	return removed
+}
+
+// Get returns count events from the head of the queue
+func (q *DiskQueue) Get(count int) []interface{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	count = q.safeCount(count)
+	events := make([]interface{}, count)
+	copy(events, q.events[:count])
+	return events
+}
+
+// Size returns the number of queued events
+func (q *DiskQueue) Size() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.events)
+}
+
+// Bytes returns the size of the queued events in the log
+func (q *DiskQueue) Bytes() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.bytes
+}
+
+// Close syncs and closes the event log, the queued events are kept for the next time the queue is opened
+func (q *DiskQueue) Close() error {
+	q.stopOnce.Do(func() { close(q.stop) })
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.file == nil {
+		return nil
+	}
+	q.sync()
+	err := q.file.Close()
+	q.file = nil
+	q.notify(-q.bytes)
+	return err
+}
+
+func (q *DiskQueue) push(item interface{}, size int64) {
+	q.events = append(q.events, item)
+	q.sizes = append(q.sizes, size)
+	q.bytes += size
+}
+
+// pop removes count events from the head of the queue and returns their size
+func (q *DiskQueue) pop(count int) (size int64) {
+	count = q.safeCount(count)
+	for _, s := range q.sizes[:count] {
+		size += s
+	}
+	q.events = q.events[count:]
+	q.sizes = q.sizes[count:]
+	q.bytes -= size
+	return size
+}
+
+func (q *DiskQueue) safeCount(count int) int {
+	if size := len(q.events); size < count {
+		return size
+	}
+	return count
+}
+
+// write appends the record to the log. The event stays queued when the write fails and
+// the log is rewritten from the queue on the next removal.
+func (q *DiskQueue) write(line []byte) {
+	if q.file == nil {
+		q.dirty = true
+		return
+	}
+	n, err := q.file.Write(line)
+	q.fileBytes += int64(n)
+	if err != nil {
+		log.Error().Err(err).Str("path", q.path).Msg("Failed to write to event log")
+		q.dirty = true
+		return
+	}
+
+	q.unsynced = true
+	if q.syncInterval <= 0 {
+		q.sync()
+	}
+}
+
+// writeRemove appends the removal of count events from the head of the queue to the log
+func (q *DiskQueue) writeRemove(count int) {
+	if line, err := encodeRecord(walRecord{Remove: count}); err == nil {
+		q.write(line)
+	}
+}
+
+// sync flushes the records appended since the last sync to disk
+func (q *DiskQueue) sync() {
+	if !q.unsynced || q.file == nil {
+		return
+	}
+	if err := q.file.Sync(); err != nil {
+		log.Error().Err(err).Str("path", q.path).Msg("Failed to sync event log")
+		return
+	}
+	q.unsynced = false
+}
+
+// compact atomically replaces the log with one holding only the queued events
+func (q *DiskQueue) compact() error {
+	dir := filepath.Dir(q.path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(q.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	var written int64
+	for i, item := range q.events {
+		userEvent := item.(event.UserEvent)
+		line, err := encodeRecord(walRecord{Add: &userEvent})
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		if _, err := writer.Write(line); err != nil {
+			tmp.Close()
+			return err
+		}
+		q.sizes[i] = int64(len(line))
+		written += int64(len(line))
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if q.file != nil {
+		q.file.Close()
+		q.file = nil
+	}
+	if err := os.Rename(tmp.Name(), q.path); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(q.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	q.file = file
+	q.fileBytes = written
+	q.bytes = written
+	q.dirty = false
+	q.unsynced = false
+	return nil
+}
+
+func (q *DiskQueue) notify(delta int64) {
+	if delta != 0 && q.onBytesChange != nil {
+		q.onBytesChange(delta)
+	}
+}
+
+func encodeRecord(record walRecord) ([]byte, error) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
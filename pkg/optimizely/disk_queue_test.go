@@ -0,0 +1,276 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/go-sdk/pkg/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func newUserEvent(uuid string) event.UserEvent {
+	return event.UserEvent{
+		Timestamp:    1,
+		UUID:         uuid,
+		EventContext: event.Context{ProjectID: "project", Revision: "1"},
+		VisitorID:    "user",
+		Conversion:   &event.ConversionEvent{Key: "purchase", Tags: map[string]interface{}{"category": "shoes"}},
+	}
+}
+
+func uuids(items []interface{}) []string {
+	ids := []string{}
+	for _, item := range items {
+		ids = append(ids, item.(event.UserEvent).UUID)
+	}
+	return ids
+}
+
+func TestEventLogPath(t *testing.T) {
+	assert.Equal(t, filepath.Join("events", "sdkKey.wal"), eventLogPath("events", "sdkKey", ""))
+	assert.Empty(t, eventLogPath("", "sdkKey", ""))
+	assert.Empty(t, eventLogPath("events", "../sdkKey", ""))
+
+	authenticated := eventLogPath("events", "sdkKey", "token")
+	assert.NotEqual(t, eventLogPath("events", "sdkKey", ""), authenticated)
+	assert.NotContains(t, authenticated, "token")
+}
+
+func TestDiskQueue(t *testing.T) {
+	q, err := NewDiskQueue(filepath.Join(t.TempDir(), "sdkKey.wal"), 0, 0, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer q.Close()
+
+	q.Add(newUserEvent("1"))
+	q.Add(newUserEvent("2"))
+	q.Add(newUserEvent("3"))
+	q.Add("not a user event")
+
+	assert.Equal(t, 3, q.Size())
+	assert.Equal(t, []string{"1", "2"}, uuids(q.Get(2)))
+	assert.Equal(t, []string{"1", "2", "3"}, uuids(q.Get(5)))
+	assert.Equal(t, []string{"1"}, uuids(q.Remove(1)))
+	assert.Equal(t, []string{"2", "3"}, uuids(q.Remove(5)))
+	assert.Equal(t, 0, q.Size())
+	assert.Empty(t, q.Remove(1))
+	assert.Equal(t, int64(0), q.Bytes())
+}
+
+func TestDiskQueueReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sdkKey.wal")
+	q, err := NewDiskQueue(path, 0, 0, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	q.Add(newUserEvent("1"))
+	q.Add(newUserEvent("2"))
+	q.Add(newUserEvent("3"))
+	q.Remove(1)
+	bytes := q.Bytes()
+
+	// Simulate a crash by leaving the log open and appending a partially written record
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = f.WriteString(`{"add":{"uuid":"4"`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	if !assert.NoError(t, f.Close()) {
+		return
+	}
+
+	replayed, err := NewDiskQueue(path, 0, 0, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer replayed.Close()
+
+	assert.Equal(t, []string{"2", "3"}, uuids(replayed.Get(5)))
+	assert.Equal(t, newUserEvent("2"), replayed.Get(1)[0])
+	assert.Equal(t, bytes, replayed.Bytes())
+
+	info, err := os.Stat(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, bytes, info.Size())
+}
+
+func TestDiskQueueTruncatesWhenEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sdkKey.wal")
+	q, err := NewDiskQueue(path, 0, 0, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer q.Close()
+
+	q.Add(newUserEvent("1"))
+	q.Remove(1)
+
+	info, err := os.Stat(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, int64(0), info.Size())
+
+	q.Add(newUserEvent("2"))
+	replayed, err := NewDiskQueue(path, 0, 0, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer replayed.Close()
+	assert.Equal(t, []string{"2"}, uuids(replayed.Get(5)))
+}
+
+func TestDiskQueueSync(t *testing.T) {
+	unsynced := func(q *DiskQueue) bool {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		return q.unsynced
+	}
+
+	q, err := NewDiskQueue(filepath.Join(t.TempDir(), "sdkKey.wal"), 0, 0, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	q.Add(newUserEvent("1"))
+	assert.False(t, unsynced(q))
+	assert.NoError(t, q.Close())
+
+	path := filepath.Join(t.TempDir(), "sdkKey.wal")
+	q, err = NewDiskQueue(path, 0, 10*time.Millisecond, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	q.Add(newUserEvent("1"))
+	assert.Eventually(t, func() bool { return !unsynced(q) }, time.Second, 10*time.Millisecond)
+
+	q.Add(newUserEvent("2"))
+	assert.NoError(t, q.Close())
+	assert.False(t, unsynced(q))
+	assert.NoError(t, q.Close())
+
+	replayed, err := NewDiskQueue(path, 0, time.Minute, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer replayed.Close()
+	assert.Equal(t, []string{"1", "2"}, uuids(replayed.Get(5)))
+}
+
+func TestDiskQueueMaxBytes(t *testing.T) {
+	var total int64
+	onBytesChange := func(delta int64) {
+		total += delta
+	}
+
+	q, err := NewDiskQueue(filepath.Join(t.TempDir(), "sdkKey.wal"), 0, 0, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	q.Add(newUserEvent("1"))
+	eventBytes := q.Bytes()
+	if !assert.NoError(t, q.Close()) {
+		return
+	}
+
+	q, err = NewDiskQueue(filepath.Join(t.TempDir(), "sdkKey.wal"), 2*eventBytes, 0, onBytesChange)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	q.Add(newUserEvent("1"))
+	q.Add(newUserEvent("2"))
+	q.Add(newUserEvent("3"))
+	assert.Equal(t, 2, q.Size())
+	assert.Equal(t, 2*eventBytes, total)
+
+	q.Remove(1)
+	assert.Equal(t, eventBytes, total)
+	q.Add(newUserEvent("3"))
+	assert.Equal(t, []string{"2", "3"}, uuids(q.Get(5)))
+
+	if !assert.NoError(t, q.Close()) {
+		return
+	}
+	assert.Equal(t, int64(0), total)
+}
+
+func TestNewEventQueue(t *testing.T) {
+	conf := config.NewDefaultConfig().Client
+	q := newEventQueue(conf, "sdkKey", "", nil)
+	assert.IsType(t, &event.InMemoryQueue{}, q)
+
+	conf.EventQueue.Type = config.EventQueueTypeDisk
+	conf.EventQueue.Disk.Dir = t.TempDir()
+	q = newEventQueue(conf, "sdkKey", "", nil)
+	assert.IsType(t, &DiskQueue{}, q)
+	closeEventQueue(q)
+	assert.FileExists(t, filepath.Join(conf.EventQueue.Disk.Dir, "sdkKey.wal"))
+
+	// The in-memory queue is used when the event log can't be opened
+	q = newEventQueue(conf, "../sdkKey", "", nil)
+	assert.IsType(t, &event.InMemoryQueue{}, q)
+}
+
+func TestDiskQueueCompactionFailure(t *testing.T) {
+	var total int64
+	onBytesChange := func(delta int64) {
+		total += delta
+	}
+
+	path := filepath.Join(t.TempDir(), "sdkKey.wal")
+	q, err := NewDiskQueue(path, 0, 0, onBytesChange)
+	if !assert.NoError(t, err) {
+		return
+	}
+	q.Add(newUserEvent("1"))
+	q.Add(newUserEvent("2"))
+	q.Add(newUserEvent("3"))
+
+	// The compacted log can't be created next to the log, which is large enough to be compacted
+	q.mu.Lock()
+	q.path = filepath.Join(t.TempDir(), "missing", "sdkKey.wal")
+	q.fileBytes = 2 * compactMinBytes
+	q.mu.Unlock()
+
+	assert.Equal(t, []string{"1"}, uuids(q.Remove(1)))
+	assert.Equal(t, q.Bytes(), total)
+	if !assert.NoError(t, q.Close()) {
+		return
+	}
+	assert.Equal(t, int64(0), total)
+
+	replayed, err := NewDiskQueue(path, 0, 0, onBytesChange)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer replayed.Close()
+	assert.Equal(t, []string{"2", "3"}, uuids(replayed.Get(5)))
+	assert.Equal(t, replayed.Bytes(), total)
+}
@@ -0,0 +1,73 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"github.com/optimizely/agent/pkg/deadletter"
+	"github.com/optimizely/go-sdk/pkg/event"
+	"github.com/optimizely/go-sdk/pkg/metrics"
+)
+
+// eventDispatch holds the event dispatch settings shared by every client
+type eventDispatch struct {
+	sinks           *EventSinks
+	deadLetters     deadletter.Store
+	maxAttempts     int
+	metricsRegistry *MetricsRegistry
+}
+
+// newDispatcher returns the dispatcher of the event processor, nil to keep the default one.
+// The default dispatcher holds the events in memory until they are sent, the disk queue dispatches them directly
+// so that they are kept in the event log until the request succeeds. Batches failing for good are moved to the
+// dead-letter store and the sinks receive the batches as they are first dispatched.
+func (d eventDispatch) newDispatcher(sdkKey string, q event.Queue) event.Dispatcher {
+	var dispatcher event.Dispatcher = event.NewHTTPEventDispatcher(sdkKey, nil, nil)
+	wrapped := false
+
+	if d.deadLetters != nil {
+		deadLetterDispatcher := deadletter.NewDispatcher(sdkKey, dispatcher, d.deadLetters, d.maxAttempts)
+		if d.metricsRegistry != nil {
+			counter := d.metricsRegistry.GetCounter(deadLettersKey)
+			deadLetterDispatcher.OnDeadLetter = func() {
+				counter.Add(1)
+			}
+		}
+		dispatcher = deadLetterDispatcher
+		wrapped = true
+	}
+
+	if d.sinks.Len() > 0 {
+		dispatcher = NewFanOutDispatcher(dispatcher, d.sinks)
+		wrapped = true
+	}
+
+	if _, ok := q.(*DiskQueue); ok {
+		return dispatcher
+	}
+	if !wrapped {
+		return nil
+	}
+
+	var registry metrics.Registry = metrics.NewNoopRegistry()
+	if d.metricsRegistry != nil {
+		registry = d.metricsRegistry
+	}
+	queueDispatcher := event.NewQueueEventDispatcher(sdkKey, registry)
+	queueDispatcher.Dispatcher = dispatcher
+	return queueDispatcher
+}
@@ -0,0 +1,70 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/optimizely/agent/pkg/deadletter"
+	"github.com/optimizely/go-sdk/pkg/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDispatcher(t *testing.T) {
+	sinks := NewEventSinks(map[string]event.Dispatcher{"sink": &testSink{success: true}})
+	defer sinks.Close(0)
+	inMemoryQueue := event.NewInMemoryQueue(10)
+
+	assert.Nil(t, eventDispatch{}.newDispatcher("sdkKey", inMemoryQueue))
+
+	dispatcher := eventDispatch{sinks: sinks}.newDispatcher("sdkKey", inMemoryQueue)
+	if queueDispatcher, ok := dispatcher.(*event.QueueEventDispatcher); assert.True(t, ok) {
+		assert.IsType(t, &FanOutDispatcher{}, queueDispatcher.Dispatcher)
+	}
+
+	diskQueue, err := NewDiskQueue(filepath.Join(t.TempDir(), "sdkKey.wal"), 0, 0, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer diskQueue.Close()
+	assert.NotNil(t, eventDispatch{}.newDispatcher("sdkKey", diskQueue))
+	assert.IsType(t, &FanOutDispatcher{}, eventDispatch{sinks: sinks}.newDispatcher("sdkKey", diskQueue))
+}
+
+func TestNewDispatcherWithDeadLetters(t *testing.T) {
+	store, err := deadletter.NewDiskStore(t.TempDir())
+	if !assert.NoError(t, err) {
+		return
+	}
+	dispatch := eventDispatch{deadLetters: store, maxAttempts: 3}
+
+	dispatcher := dispatch.newDispatcher("sdkKey", event.NewInMemoryQueue(10))
+	if queueDispatcher, ok := dispatcher.(*event.QueueEventDispatcher); assert.True(t, ok) {
+		assert.IsType(t, &deadletter.Dispatcher{}, queueDispatcher.Dispatcher)
+	}
+
+	dispatch.sinks = NewEventSinks(map[string]event.Dispatcher{"sink": &testSink{success: true}})
+	defer dispatch.sinks.Close(0)
+	dispatcher = dispatch.newDispatcher("sdkKey", event.NewInMemoryQueue(10))
+	if queueDispatcher, ok := dispatcher.(*event.QueueEventDispatcher); assert.True(t, ok) {
+		if fanOut, ok := queueDispatcher.Dispatcher.(*FanOutDispatcher); assert.True(t, ok) {
+			assert.IsType(t, &deadletter.Dispatcher{}, fanOut.primary)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/plugins/eventdispatcher"
+	"github.com/optimizely/go-sdk/pkg/event"
+	"github.com/rs/zerolog/log"
+)
+
+const eventDispatcherPlugin = "Event Dispatcher"
+
+// sinkQueueSize is the number of batches waiting for a sink after which the new batches are dropped
+const sinkQueueSize = 1000
+
+// FanOutDispatcher dispatches the events to the primary dispatcher and forwards every batch to the sinks.
+// A batch reaches the sinks once, when it is first dispatched, whatever the outcome of the primary dispatcher which
+// is returned so that failed batches are retried against it. The sinks receive the batches in the background.
+type FanOutDispatcher struct {
+	primary event.Dispatcher
+	sinks   *EventSinks
+
+	mu sync.Mutex
+	// last is the last batch forwarded, the batches are retried one at a time until they succeed
+	last *event.LogEvent
+}
+
+// NewFanOutDispatcher returns a dispatcher forwarding the batches dispatched by primary to the sinks
+func NewFanOutDispatcher(primary event.Dispatcher, sinks *EventSinks) *FanOutDispatcher {
+	return &FanOutDispatcher{primary: primary, sinks: sinks}
+}
+
+// DispatchEvent queues the event for the sinks unless it is retried, then dispatches it to the primary dispatcher
+func (d *FanOutDispatcher) DispatchEvent(logEvent event.LogEvent) (bool, error) {
+	d.mu.Lock()
+	if d.last == nil || !reflect.DeepEqual(*d.last, logEvent) {
+		d.sinks.forward(logEvent)
+		d.last = &logEvent
+	}
+	d.mu.Unlock()
+
+	return d.primary.DispatchEvent(logEvent)
+}
+
+// EventSinks holds the sinks shared by every client. Each sink receives the batches from its own queue,
+// so that a slow or failing sink neither holds up the dispatch nor the other sinks.
+type EventSinks struct {
+	sinks []*eventSink
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+type eventSink struct {
+	name       string
+	dispatcher event.Dispatcher
+	batches    chan event.LogEvent
+}
+
+// NewEventSinks starts forwarding the batches to the sinks by name
+func NewEventSinks(dispatchers map[string]event.Dispatcher) *EventSinks {
+	names := make([]string, 0, len(dispatchers))
+	for name := range dispatchers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	s := &EventSinks{}
+	for _, name := range names {
+		sink := &eventSink{name: name, dispatcher: dispatchers[name], batches: make(chan event.LogEvent, sinkQueueSize)}
+		s.sinks = append(s.sinks, sink)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for logEvent := range sink.batches {
+				if ok, err := sink.dispatcher.DispatchEvent(logEvent); !ok || err != nil {
+					log.Warn().Err(err).Str("sink", sink.name).Msg("Failed to forward event batch")
+				}
+			}
+		}()
+	}
+	return s
+}
+
+// Len returns the number of sinks
+func (s *EventSinks) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.sinks)
+}
+
+// forward queues the batch for every sink, dropping it for the sinks which fell behind
+func (s *EventSinks) forward(logEvent event.LogEvent) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return
+	}
+	for _, sink := range s.sinks {
+		select {
+		case sink.batches <- logEvent:
+		default:
+			log.Warn().Str("sink", sink.name).Msg("Event sink queue is full. Dropping event batch")
+		}
+	}
+}
+
+// Close stops accepting batches and waits up to timeout, 0 waiting without limit, for the queued ones to be
+// forwarded. The sinks holding resources, such as the file sink, are then closed. It returns false when the
+// queued batches were not all forwarded within timeout.
+func (s *EventSinks) Close(timeout time.Duration) bool {
+	if s == nil {
+		return true
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return true
+	}
+	s.closed = true
+	for _, sink := range s.sinks {
+		close(sink.batches)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	flushed := true
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-done:
+		case <-timer.C:
+			log.Warn().Msg("Timed out forwarding event batches to the sinks.")
+			flushed = false
+		}
+	} else {
+		<-done
+	}
+
+	for _, sink := range s.sinks {
+		if closer, ok := sink.dispatcher.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				log.Warn().Err(err).Str("sink", sink.name).Msg("Failed to close event sink")
+			}
+		}
+	}
+	return flushed
+}
+
+// newEventSinks creates the event dispatcher of every sink listed in the config, nil when there is none.
+// Sinks are shared by every client so that they write to the same destination.
+func newEventSinks(conf config.EventDispatcherConfigs) *EventSinks {
+	sinks := make(map[string]event.Dispatcher)
+	services, _ := conf["services"].(map[string]interface{})
+
+	for _, name := range sinkNames(conf["sinks"]) {
+		creator, ok := eventdispatcher.Creators[name]
+		if !ok {
+			log.Warn().Msgf(`%s not found: %q`, eventDispatcherPlugin, name)
+			continue
+		}
+
+		sink := creator()
+		if serviceConfig, ok := services[name]; ok {
+			// Trying to map service from client config to struct
+			if b, err := json.Marshal(serviceConfig); err != nil {
+				log.Warn().Err(err).Msgf(`Error marshaling %s config: %q`, eventDispatcherPlugin, name)
+				continue
+			} else if err := json.Unmarshal(b, sink); err != nil {
+				log.Warn().Err(err).Msgf(`Error unmarshalling %s config: %q`, eventDispatcherPlugin, name)
+				continue
+			}
+		}
+
+		log.Info().Msgf(`%s of type: %q created`, eventDispatcherPlugin, name)
+		sinks[name] = sink
+	}
+
+	if len(sinks) == 0 {
+		return nil
+	}
+	return NewEventSinks(sinks)
+}
+
+// sinkNames returns the names of the sinks, provided either as a list or as a comma separated string
+func sinkNames(rawSinks interface{}) []string {
+	names := []string{}
+	switch sinks := rawSinks.(type) {
+	case []interface{}:
+		for _, sink := range sinks {
+			if name, ok := sink.(string); ok && name != "" {
+				names = append(names, name)
+			}
+		}
+	case []string:
+		for _, name := range sinks {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+	case string:
+		for _, name := range strings.Split(sinks, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,161 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/plugins/eventdispatcher"
+	"github.com/optimizely/go-sdk/pkg/event"
+	"github.com/stretchr/testify/assert"
+)
+
+type testSink struct {
+	Name string `json:"name"`
+
+	mu      sync.Mutex
+	success bool
+	err     error
+	events  []event.LogEvent
+	closed  bool
+	// release holds up the dispatch until it is closed, when set
+	release chan struct{}
+}
+
+func (s *testSink) DispatchEvent(logEvent event.LogEvent) (bool, error) {
+	if s.release != nil {
+		<-s.release
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, logEvent)
+	return s.success, s.err
+}
+
+func (s *testSink) dispatched() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.events)
+}
+
+func (s *testSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *testSink) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func init() {
+	eventdispatcher.Add("test-sink", func() event.Dispatcher {
+		return &testSink{success: true}
+	})
+}
+
+func TestFanOutDispatcher(t *testing.T) {
+	primary := &testSink{success: true}
+	sink := &testSink{success: true}
+	failing := &testSink{err: errors.New("unavailable")}
+	sinks := NewEventSinks(map[string]event.Dispatcher{"sink": sink, "failing": failing})
+	dispatcher := NewFanOutDispatcher(primary, sinks)
+
+	logEvent := event.LogEvent{EndPoint: "https://logx.optimizely.com/v1/events", Event: event.Batch{Revision: "1"}}
+	success, err := dispatcher.DispatchEvent(logEvent)
+	assert.NoError(t, err)
+	assert.True(t, success)
+	assert.Equal(t, []event.LogEvent{logEvent}, primary.events)
+
+	// Batches failing to be dispatched by the primary dispatcher reach the sinks once, however often they are retried
+	primary.success = false
+	failed := event.LogEvent{EndPoint: "https://logx.optimizely.com/v1/events", Event: event.Batch{Revision: "2"}}
+	for i := 0; i < 3; i++ {
+		success, err = dispatcher.DispatchEvent(failed)
+		assert.NoError(t, err)
+		assert.False(t, success)
+	}
+	assert.Equal(t, 4, primary.dispatched())
+
+	assert.True(t, sinks.Close(time.Second))
+	assert.Equal(t, []event.LogEvent{logEvent, failed}, sink.events)
+	assert.Equal(t, 2, failing.dispatched())
+	assert.True(t, sink.isClosed())
+	assert.True(t, failing.isClosed())
+
+	// Batches dispatched once the sinks are closed are not forwarded
+	primary.success = true
+	success, err = dispatcher.DispatchEvent(logEvent)
+	assert.NoError(t, err)
+	assert.True(t, success)
+	assert.Equal(t, 2, sink.dispatched())
+}
+
+func TestFanOutDispatcherSlowSink(t *testing.T) {
+	primary := &testSink{success: true}
+	slow := &testSink{success: true, release: make(chan struct{})}
+	sinks := NewEventSinks(map[string]event.Dispatcher{"slow": slow})
+	dispatcher := NewFanOutDispatcher(primary, sinks)
+
+	// The dispatch neither waits for the sink nor blocks once its queue is full
+	for i := 0; i < sinkQueueSize+10; i++ {
+		success, err := dispatcher.DispatchEvent(event.LogEvent{Event: event.Batch{Revision: strconv.Itoa(i)}})
+		assert.NoError(t, err)
+		assert.True(t, success)
+	}
+	assert.Equal(t, sinkQueueSize+10, primary.dispatched())
+
+	assert.False(t, sinks.Close(10*time.Millisecond))
+	assert.True(t, slow.isClosed())
+	close(slow.release)
+	// The queued batches are still forwarded, the batch taken by the sink before the queue filled up included
+	assert.Eventually(t, func() bool { return slow.dispatched() >= sinkQueueSize }, time.Second, time.Millisecond)
+	assert.LessOrEqual(t, slow.dispatched(), sinkQueueSize+1)
+}
+
+func TestNewEventSinks(t *testing.T) {
+	sinks := newEventSinks(config.EventDispatcherConfigs{
+		"sinks": []interface{}{"test-sink", "unknown"},
+		"services": map[string]interface{}{
+			"test-sink": map[string]interface{}{"name": "pipeline"},
+		},
+	})
+	if !assert.Equal(t, 1, sinks.Len()) {
+		return
+	}
+	defer sinks.Close(0)
+	assert.Equal(t, "test-sink", sinks.sinks[0].name)
+	assert.Equal(t, "pipeline", sinks.sinks[0].dispatcher.(*testSink).Name)
+
+	assert.Nil(t, newEventSinks(config.NewDefaultConfig().Client.EventDispatchers))
+}
+
+func TestSinkNames(t *testing.T) {
+	assert.Equal(t, []string{"file", "http"}, sinkNames([]interface{}{"file", "", "http"}))
+	assert.Equal(t, []string{"file", "http"}, sinkNames([]string{"file", "http"}))
+	assert.Equal(t, []string{"file", "http"}, sinkNames("file, http"))
+	assert.Empty(t, sinkNames(nil))
+}
@@ -0,0 +1,445 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	optimizelyclient "github.com/optimizely/go-sdk/pkg/client"
+	sdkconfig "github.com/optimizely/go-sdk/pkg/config"
+	"github.com/optimizely/go-sdk/pkg/decide"
+	"github.com/optimizely/go-sdk/pkg/decision"
+	"github.com/optimizely/go-sdk/pkg/decision/bucketer"
+	"github.com/optimizely/go-sdk/pkg/decision/evaluator"
+	"github.com/optimizely/go-sdk/pkg/entities"
+	"github.com/optimizely/go-sdk/pkg/logging"
+)
+
+// Types of the rules of a flag
+const (
+	RuleTypeExperiment = "experiment"
+	RuleTypeRollout    = "rollout"
+)
+
+// Outcomes of the evaluation of a rule. The first four short-circuit the audience evaluation and the bucketing.
+const (
+	RuleForcedDecision   = "forcedDecision"
+	RuleOverride         = "override"
+	RuleWhitelist        = "whitelist"
+	RuleUserProfile      = "userProfile"
+	RuleBucketed         = "bucketed"
+	RuleAudienceMismatch = "audienceMismatch"
+	RuleNotInGroup       = "notInGroup"
+	RuleNotBucketed      = "notBucketed"
+)
+
+// FlagTrace describes, step by step, how the decision of a flag is made for a user
+type FlagTrace struct {
+	FlagKey     string `json:"flagKey"`
+	BucketingID string `json:"bucketingId"`
+	// ForcedDecision is the variation forced for the flag as a whole, no rule is evaluated when it is set
+	ForcedDecision string      `json:"forcedDecision,omitempty"`
+	Rules          []RuleTrace `json:"rules"`
+	RuleKey        string      `json:"ruleKey,omitempty"`
+	VariationKey   string      `json:"variationKey,omitempty"`
+}
+
+// RuleTrace describes the evaluation of a rule of the flag
+type RuleTrace struct {
+	RuleKey  string `json:"ruleKey"`
+	RuleID   string `json:"ruleId"`
+	RuleType string `json:"ruleType"`
+	// Audiences is the evaluated audience condition tree, nil when the rule targets everyone
+	Audiences     *ConditionTrace `json:"audiences,omitempty"`
+	AudienceMatch bool            `json:"audienceMatch"`
+	Group         *GroupTrace     `json:"group,omitempty"`
+	Bucket        *BucketTrace    `json:"bucket,omitempty"`
+	Result        string          `json:"result"`
+	VariationKey  string          `json:"variationKey,omitempty"`
+}
+
+// ConditionTrace describes the evaluation of a node of an audience condition tree.
+// A node is either an operator combining its conditions, an audience, or a condition on a user attribute.
+type ConditionTrace struct {
+	Operator       string           `json:"operator,omitempty"`
+	AudienceID     string           `json:"audienceId,omitempty"`
+	AudienceName   string           `json:"audienceName,omitempty"`
+	Attribute      string           `json:"attribute,omitempty"`
+	Type           string           `json:"type,omitempty"`
+	Match          string           `json:"match,omitempty"`
+	Value          interface{}      `json:"value,omitempty"`
+	AttributeValue interface{}      `json:"attributeValue,omitempty"`
+	Conditions     []ConditionTrace `json:"conditions,omitempty"`
+	// Result is nil when the node could not be evaluated, like when the attribute is missing or has the wrong type
+	Result *bool  `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// GroupTrace describes the bucketing of the user into a mutually exclusive group
+type GroupTrace struct {
+	ID     string      `json:"id"`
+	Policy string      `json:"policy"`
+	Bucket BucketTrace `json:"bucket"`
+}
+
+// BucketTrace describes the bucket value of the user against the traffic allocation of a rule or a group
+type BucketTrace struct {
+	Value             int          `json:"value"`
+	TrafficAllocation []Allocation `json:"trafficAllocation"`
+	EntityID          string       `json:"entityId,omitempty"`
+}
+
+// Allocation is a range of the traffic allocation, users with a bucket value below EndOfRange
+// and above the end of the previous range are bucketed into the entity
+type Allocation struct {
+	EntityID   string `json:"entityId"`
+	EntityKey  string `json:"entityKey,omitempty"`
+	EndOfRange int    `json:"endOfRange"`
+}
+
+// Explain traces the evaluation of the flag for the user, following the same steps as the decision service.
+// It has to be called before making the decision since the decision may be saved to the user profile service.
+func (c *OptlyClient) Explain(projectConfig sdkconfig.ProjectConfig, userContext *optimizelyclient.OptimizelyUserContext, flagKey string, options []decide.OptimizelyDecideOptions) (*FlagTrace, error) {
+	feature, err := projectConfig.GetFeatureByKey(flagKey)
+	if err != nil {
+		return nil, err
+	}
+
+	user := entities.UserContext{
+		ID:                userContext.GetUserID(),
+		Attributes:        userContext.GetUserAttributes(),
+		QualifiedSegments: userContext.GetQualifiedSegments(),
+	}
+	// The decision service falls back to the user ID when the bucketing ID attribute is invalid
+	bucketingID, _ := user.GetBucketingID()
+
+	logger := logging.GetLogger(projectConfig.GetSdkKey(), "DecisionTrace")
+	t := &tracer{
+		projectConfig: projectConfig,
+		userContext:   userContext,
+		user:          user,
+		feature:       feature,
+		bucketingID:   bucketingID,
+		overrides:     c.ForcedVariations,
+		bucketer:      bucketer.NewMurmurhashBucketer(logger, bucketer.DefaultHashSeed),
+		evaluator:     evaluator.NewCustomAttributeConditionEvaluator(logger),
+	}
+	if !HasDecideOption(options, decide.IgnoreUserProfileService) {
+		t.userProfileService = c.UserProfileService
+	}
+
+	trace := &FlagTrace{FlagKey: flagKey, BucketingID: bucketingID, Rules: []RuleTrace{}}
+	if variation := t.forcedDecision(""); variation != nil {
+		trace.ForcedDecision = variation.Key
+		trace.VariationKey = variation.Key
+		return trace, nil
+	}
+
+	decided := func(rule RuleTrace) bool {
+		trace.Rules = append(trace.Rules, rule)
+		if rule.VariationKey == "" {
+			return false
+		}
+		trace.RuleKey = rule.RuleKey
+		trace.VariationKey = rule.VariationKey
+		return true
+	}
+
+	for i := range feature.FeatureExperiments {
+		if decided(t.experimentRule(&feature.FeatureExperiments[i])) {
+			return trace, nil
+		}
+	}
+
+	rules := feature.Rollout.Experiments
+	for i := 0; i < len(rules); i++ {
+		rule := t.rolloutRule(&rules[i])
+		if decided(rule) {
+			return trace, nil
+		}
+		// Users failing the bucketing of a targeted rule go straight to the everyone else rule
+		if rule.Result == RuleNotBucketed && i < len(rules)-2 {
+			i = len(rules) - 2
+		}
+	}
+	return trace, nil
+}
+
+// tracer evaluates the rules of a flag for a user, recording every step
+type tracer struct {
+	projectConfig      sdkconfig.ProjectConfig
+	userContext        *optimizelyclient.OptimizelyUserContext
+	user               entities.UserContext
+	feature            entities.Feature
+	bucketingID        string
+	overrides          decision.ExperimentOverrideStore
+	userProfileService decision.UserProfileService
+	userProfile        *decision.UserProfile
+	bucketer           *bucketer.MurmurhashBucketer
+	evaluator          *evaluator.CustomAttributeConditionEvaluator
+}
+
+// experimentRule goes through the experiment services in the order of the composite experiment service
+func (t *tracer) experimentRule(experiment *entities.Experiment) RuleTrace {
+	rule := RuleTrace{RuleKey: experiment.Key, RuleID: experiment.ID, RuleType: RuleTypeExperiment}
+	if variation := t.forcedDecision(experiment.Key); variation != nil {
+		return rule.decided(RuleForcedDecision, variation)
+	}
+
+	if t.overrides != nil {
+		if variationKey, ok := t.overrides.GetVariation(decision.ExperimentOverrideKey{ExperimentKey: experiment.Key, UserID: t.user.ID}); ok {
+			if variation := variationByKey(experiment, variationKey); variation != nil {
+				return rule.decided(RuleOverride, variation)
+			}
+		}
+	}
+
+	if variationKey, ok := experiment.Whitelist[t.user.ID]; ok {
+		if variation := variationByKey(experiment, variationKey); variation != nil {
+			return rule.decided(RuleWhitelist, variation)
+		}
+	}
+
+	if profile := t.lookupUserProfile(); profile != nil {
+		if variationID, ok := profile.ExperimentBucketMap[decision.NewUserDecisionKey(experiment.ID)]; ok {
+			if variation, ok := experiment.Variations[variationID]; ok {
+				return rule.decided(RuleUserProfile, &variation)
+			}
+		}
+	}
+
+	if !t.evaluateAudiences(&rule, experiment) {
+		rule.Result = RuleAudienceMismatch
+		return rule
+	}
+
+	if experiment.GroupID != "" {
+		group, err := t.projectConfig.GetGroupByID(experiment.GroupID)
+		if err == nil && group.Policy == "random" {
+			rule.Group = &GroupTrace{ID: group.ID, Policy: group.Policy, Bucket: t.bucket(group.ID, group.TrafficAllocation, t.experimentKey)}
+			if rule.Group.Bucket.EntityID != experiment.ID {
+				rule.Result = RuleNotInGroup
+				return rule
+			}
+		}
+	}
+
+	return t.bucketRule(rule, experiment)
+}
+
+// rolloutRule follows the rollout service, which only looks for forced decisions before evaluating the audiences
+func (t *tracer) rolloutRule(experiment *entities.Experiment) RuleTrace {
+	rule := RuleTrace{RuleKey: experiment.Key, RuleID: experiment.ID, RuleType: RuleTypeRollout}
+	if variation := t.forcedDecision(experiment.Key); variation != nil {
+		return rule.decided(RuleForcedDecision, variation)
+	}
+
+	if !t.evaluateAudiences(&rule, experiment) {
+		rule.Result = RuleAudienceMismatch
+		return rule
+	}
+	return t.bucketRule(rule, experiment)
+}
+
+func (t *tracer) bucketRule(rule RuleTrace, experiment *entities.Experiment) RuleTrace {
+	variationKey := func(id string) string {
+		return experiment.Variations[id].Key
+	}
+	bucket := t.bucket(experiment.ID, experiment.TrafficAllocation, variationKey)
+	rule.Bucket = &bucket
+	if variation, ok := experiment.Variations[bucket.EntityID]; ok {
+		return rule.decided(RuleBucketed, &variation)
+	}
+	rule.Result = RuleNotBucketed
+	return rule
+}
+
+func (r RuleTrace) decided(result string, variation *entities.Variation) RuleTrace {
+	r.Result = result
+	r.VariationKey = variation.Key
+	return r
+}
+
+// forcedDecision returns the variation forced for the rule, an empty rule key being the flag itself.
+// Like the forced decision service, it ignores the variations which don't belong to the flag.
+func (t *tracer) forcedDecision(ruleKey string) *entities.Variation {
+	forcedDecision, err := t.userContext.GetForcedDecision(decision.OptimizelyDecisionContext{FlagKey: t.feature.Key, RuleKey: ruleKey})
+	if err != nil {
+		return nil
+	}
+	for _, variation := range t.projectConfig.GetFlagVariationsMap()[t.feature.Key] {
+		if variation.Key == forcedDecision.VariationKey {
+			return &variation
+		}
+	}
+	return nil
+}
+
+// lookupUserProfile returns the saved profile of the user, looking it up once per flag
+func (t *tracer) lookupUserProfile() *decision.UserProfile {
+	if t.userProfileService == nil {
+		return nil
+	}
+	if t.userProfile == nil {
+		profile := t.userProfileService.Lookup(t.user.ID)
+		t.userProfile = &profile
+	}
+	return t.userProfile
+}
+
+// evaluateAudiences records the evaluation of the audience conditions of the rule and returns whether the user matched them
+func (t *tracer) evaluateAudiences(rule *RuleTrace, experiment *entities.Experiment) bool {
+	if experiment.AudienceConditionTree == nil {
+		rule.AudienceMatch = true
+		return true
+	}
+
+	params := entities.NewTreeParameters(&t.user, t.projectConfig.GetAudienceMap())
+	audiences := t.evaluateNode(experiment.AudienceConditionTree, params)
+	rule.Audiences = &audiences
+	rule.AudienceMatch = audiences.Result != nil && *audiences.Result
+	return rule.AudienceMatch
+}
+
+// evaluateNode evaluates every condition of the tree, combining their results like the tree evaluator of the SDK
+func (t *tracer) evaluateNode(node *entities.TreeNode, params *entities.TreeParameters) ConditionTrace {
+	if node == nil {
+		return ConditionTrace{Error: "empty condition tree"}
+	}
+
+	if node.Operator != "" {
+		trace := ConditionTrace{Operator: node.Operator, Conditions: []ConditionTrace{}}
+		for _, child := range node.Nodes {
+			trace.Conditions = append(trace.Conditions, t.evaluateNode(child, params))
+		}
+		trace.Result = combine(node.Operator, trace.Conditions)
+		return trace
+	}
+
+	switch item := node.Item.(type) {
+	case entities.Condition:
+		trace := ConditionTrace{Attribute: item.Name, Type: item.Type, Match: item.Match, Value: item.Value}
+		if value, ok := t.user.Attributes[item.Name]; ok {
+			trace.AttributeValue = value
+		}
+		result, _, err := t.evaluator.Evaluate(item, params, &decide.Options{})
+		if err != nil {
+			trace.Error = err.Error()
+			return trace
+		}
+		trace.Result = &result
+		return trace
+	case string:
+		trace := ConditionTrace{AudienceID: item}
+		audience, ok := params.AudienceMap[item]
+		if !ok {
+			trace.Error = "audience not found"
+			return trace
+		}
+		trace.AudienceName = audience.Name
+		conditions := t.evaluateNode(audience.ConditionTree, params)
+		trace.Conditions = []ConditionTrace{conditions}
+		trace.Result = conditions.Result
+		return trace
+	default:
+		return ConditionTrace{Error: "unknown condition"}
+	}
+}
+
+// combine returns the result of the operator applied to the results of the conditions, nil when it is invalid
+func combine(operator string, conditions []ConditionTrace) *bool {
+	result := func(b bool) *bool {
+		return &b
+	}
+
+	switch operator {
+	case "and":
+		for _, condition := range conditions {
+			if condition.Result == nil || !*condition.Result {
+				return condition.Result
+			}
+		}
+		return result(true)
+	case "not":
+		if len(conditions) == 0 || conditions[0].Result == nil {
+			return nil
+		}
+		return result(!*conditions[0].Result)
+	default:
+		sawInvalid := false
+		for _, condition := range conditions {
+			if condition.Result == nil {
+				sawInvalid = true
+			} else if *condition.Result {
+				return result(true)
+			}
+		}
+		if sawInvalid {
+			return nil
+		}
+		return result(false)
+	}
+}
+
+// bucket computes the bucket value of the user for the entity and the entity of the traffic allocation it falls into
+func (t *tracer) bucket(entityID string, allocations []entities.Range, entityKey func(id string) string) BucketTrace {
+	trace := BucketTrace{
+		Value:             t.bucketer.Generate(t.bucketingID + entityID),
+		TrafficAllocation: make([]Allocation, 0, len(allocations)),
+	}
+	found := false
+	for _, allocation := range allocations {
+		trace.TrafficAllocation = append(trace.TrafficAllocation, Allocation{
+			EntityID:   allocation.EntityID,
+			EntityKey:  entityKey(allocation.EntityID),
+			EndOfRange: allocation.EndOfRange,
+		})
+		// The first range ending above the bucket value wins, even when it has no entity
+		if !found && trace.Value < allocation.EndOfRange {
+			trace.EntityID = allocation.EntityID
+			found = true
+		}
+	}
+	return trace
+}
+
+func (t *tracer) experimentKey(id string) string {
+	for _, experiment := range t.projectConfig.GetExperimentList() {
+		if experiment.ID == id {
+			return experiment.Key
+		}
+	}
+	return ""
+}
+
+func variationByKey(experiment *entities.Experiment, variationKey string) *entities.Variation {
+	if id, ok := experiment.VariationKeyToIDMap[variationKey]; ok {
+		if variation, ok := experiment.Variations[id]; ok {
+			return &variation
+		}
+	}
+	return nil
+}
+
+// HasDecideOption returns whether the option is one of the decide options
+func HasDecideOption(options []decide.OptimizelyDecideOptions, option decide.OptimizelyDecideOptions) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,284 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"context"
+	"testing"
+
+	"github.com/optimizely/go-sdk/pkg/client"
+	"github.com/optimizely/go-sdk/pkg/config"
+	"github.com/optimizely/go-sdk/pkg/config/datafileprojectconfig"
+	"github.com/optimizely/go-sdk/pkg/decide"
+	"github.com/optimizely/go-sdk/pkg/decision"
+	"github.com/optimizely/go-sdk/pkg/logging"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/optimizely/agent/pkg/optimizely/optimizelytest"
+	"github.com/optimizely/agent/pkg/overrides"
+)
+
+// explainDatafile holds a flag with an experiment targeting US adults and a rollout
+// made of a US only rule with no traffic, a rule for everyone and the everyone else rule
+const explainDatafile = `{
+	"version": "4", "projectId": "1", "accountId": "1", "revision": "1", "sdkKey": "explain",
+	"attributes": [{"id": "a1", "key": "country"}, {"id": "a2", "key": "age"}],
+	"audiences": [
+		{"id": "100", "name": "US", "conditions": "[\"or\", {\"name\": \"country\", \"type\": \"custom_attribute\", \"match\": \"exact\", \"value\": \"US\"}]"},
+		{"id": "101", "name": "Adults", "conditions": "[\"or\", {\"name\": \"age\", \"type\": \"custom_attribute\", \"match\": \"ge\", \"value\": 18}]"}
+	],
+	"typedAudiences": [
+		{"id": "101", "name": "Adults", "conditions": ["or", {"name": "age", "type": "custom_attribute", "match": "ge", "value": 18}]}
+	],
+	"experiments": [{
+		"id": "exp1", "key": "experiment", "layerId": "l1", "status": "Running",
+		"audienceIds": ["100", "101"], "audienceConditions": ["and", "100", "101"],
+		"variations": [{"id": "v1", "key": "on", "featureEnabled": true}, {"id": "v2", "key": "off", "featureEnabled": false}],
+		"trafficAllocation": [{"entityId": "v1", "endOfRange": 5000}, {"entityId": "v2", "endOfRange": 10000}],
+		"forcedVariations": {"whitelisted": "off"}
+	}],
+	"rollouts": [{"id": "r1", "experiments": [
+		{
+			"id": "rr1", "key": "targeted", "layerId": "r1", "status": "Running", "audienceIds": ["100"],
+			"variations": [{"id": "v1", "key": "on", "featureEnabled": true}],
+			"trafficAllocation": [{"entityId": "v1", "endOfRange": 0}], "forcedVariations": {}
+		},
+		{
+			"id": "rr2", "key": "everyone", "layerId": "r1", "status": "Running", "audienceIds": [],
+			"variations": [{"id": "v1", "key": "on", "featureEnabled": true}],
+			"trafficAllocation": [{"entityId": "v1", "endOfRange": 10000}], "forcedVariations": {}
+		},
+		{
+			"id": "rr3", "key": "everyone_else", "layerId": "r1", "status": "Running", "audienceIds": [],
+			"variations": [{"id": "v2", "key": "off", "featureEnabled": false}],
+			"trafficAllocation": [{"entityId": "v2", "endOfRange": 10000}], "forcedVariations": {}
+		}
+	]}],
+	"featureFlags": [{"id": "f1", "key": "flag", "rolloutId": "r1", "experimentIds": ["exp1"], "variables": []}],
+	"groups": [], "events": [], "integrations": []
+}`
+
+type mockUserProfileService struct {
+	profile decision.UserProfile
+}
+
+func (m *mockUserProfileService) Lookup(userID string) decision.UserProfile {
+	return m.profile
+}
+
+func (m *mockUserProfileService) Save(profile decision.UserProfile) {
+}
+
+type ExplainTestSuite struct {
+	suite.Suite
+	projectConfig config.ProjectConfig
+	optlyClient   *OptlyClient
+	ups           *mockUserProfileService
+}
+
+func (suite *ExplainTestSuite) SetupTest() {
+	logger := logging.GetLogger("explain", "ExplainTest")
+	projectConfig, err := datafileprojectconfig.NewDatafileProjectConfig([]byte(explainDatafile), logger)
+	suite.NoError(err)
+	suite.projectConfig = projectConfig
+
+	forcedVariations := overrides.NewInMemoryStore()
+	suite.ups = &mockUserProfileService{}
+	factory := client.OptimizelyFactory{}
+	optimizelyClient, err := factory.Client(
+		client.WithConfigManager(config.NewStaticProjectConfigManager(projectConfig, logger)),
+		client.WithEventProcessor(new(optimizelytest.TestEventProcessor)),
+		client.WithExperimentOverrides(forcedVariations),
+		client.WithUserProfileService(suite.ups),
+	)
+	suite.NoError(err)
+
+	suite.optlyClient = &OptlyClient{
+		OptimizelyClient:   optimizelyClient,
+		ForcedVariations:   forcedVariations,
+		UserProfileService: suite.ups,
+	}
+}
+
+func (suite *ExplainTestSuite) TearDownTest() {
+	suite.optlyClient.Close()
+}
+
+// explain traces the flag and checks the trace agrees with the decision of the SDK
+func (suite *ExplainTestSuite) explain(userContext client.OptimizelyUserContext, options ...decide.OptimizelyDecideOptions) *FlagTrace {
+	trace, err := suite.optlyClient.Explain(suite.projectConfig, &userContext, "flag", options)
+	suite.NoError(err)
+
+	decision := userContext.Decide("flag", options)
+	suite.Equal(decision.VariationKey, trace.VariationKey)
+	if trace.ForcedDecision == "" {
+		suite.Equal(decision.RuleKey, trace.RuleKey)
+	}
+	return trace
+}
+
+func (suite *ExplainTestSuite) TestExperimentBucketing() {
+	userContext := suite.optlyClient.CreateUserContext("user1", map[string]interface{}{"country": "US", "age": 30})
+	trace := suite.explain(userContext)
+
+	suite.Equal("user1", trace.BucketingID)
+	suite.Equal("experiment", trace.RuleKey)
+	if !suite.Len(trace.Rules, 1) {
+		return
+	}
+
+	rule := trace.Rules[0]
+	suite.Equal(RuleTypeExperiment, rule.RuleType)
+	suite.Equal(RuleBucketed, rule.Result)
+	suite.True(rule.AudienceMatch)
+
+	suite.Equal("and", rule.Audiences.Operator)
+	if suite.Len(rule.Audiences.Conditions, 2) {
+		us := rule.Audiences.Conditions[0]
+		suite.Equal("100", us.AudienceID)
+		suite.Equal("US", us.AudienceName)
+		suite.True(*us.Result)
+
+		condition := us.Conditions[0].Conditions[0]
+		suite.Equal("country", condition.Attribute)
+		suite.Equal("exact", condition.Match)
+		suite.Equal("US", condition.Value)
+		suite.Equal("US", condition.AttributeValue)
+		suite.True(*condition.Result)
+
+		suite.Equal("Adults", rule.Audiences.Conditions[1].AudienceName)
+		suite.True(*rule.Audiences.Conditions[1].Result)
+	}
+
+	if suite.NotNil(rule.Bucket) {
+		suite.Equal([]Allocation{{EntityID: "v1", EntityKey: "on", EndOfRange: 5000}, {EntityID: "v2", EntityKey: "off", EndOfRange: 10000}}, rule.Bucket.TrafficAllocation)
+		expected := "v1"
+		if rule.Bucket.Value >= 5000 {
+			expected = "v2"
+		}
+		suite.Equal(expected, rule.Bucket.EntityID)
+	}
+}
+
+func (suite *ExplainTestSuite) TestBucketingID() {
+	userContext := suite.optlyClient.CreateUserContext("user1", map[string]interface{}{"country": "US", "age": 30, "$opt_bucketing_id": "device1"})
+	trace := suite.explain(userContext)
+	suite.Equal("device1", trace.BucketingID)
+}
+
+func (suite *ExplainTestSuite) TestRolloutAfterAudienceMismatch() {
+	userContext := suite.optlyClient.CreateUserContext("user1", map[string]interface{}{"country": "FR", "age": 30})
+	trace := suite.explain(userContext)
+
+	suite.Equal("everyone", trace.RuleKey)
+	suite.Equal("on", trace.VariationKey)
+	if !suite.Len(trace.Rules, 3) {
+		return
+	}
+	suite.Equal(RuleAudienceMismatch, trace.Rules[0].Result)
+	suite.False(*trace.Rules[0].Audiences.Conditions[0].Result)
+	suite.Equal("FR", trace.Rules[0].Audiences.Conditions[0].Conditions[0].Conditions[0].AttributeValue)
+
+	suite.Equal(RuleTypeRollout, trace.Rules[1].RuleType)
+	suite.Equal("targeted", trace.Rules[1].RuleKey)
+	suite.Equal(RuleAudienceMismatch, trace.Rules[1].Result)
+
+	suite.Equal("everyone", trace.Rules[2].RuleKey)
+	suite.Nil(trace.Rules[2].Audiences)
+	suite.True(trace.Rules[2].AudienceMatch)
+	suite.Equal(RuleBucketed, trace.Rules[2].Result)
+}
+
+func (suite *ExplainTestSuite) TestRolloutSkipsToEveryoneElse() {
+	// Without an age the adults audience can't be evaluated and the user misses the experiment
+	userContext := suite.optlyClient.CreateUserContext("user1", map[string]interface{}{"country": "US"})
+	trace := suite.explain(userContext)
+
+	suite.Equal("everyone_else", trace.RuleKey)
+	suite.Equal("off", trace.VariationKey)
+	if !suite.Len(trace.Rules, 3) {
+		return
+	}
+
+	adults := trace.Rules[0].Audiences.Conditions[1]
+	suite.Nil(adults.Result)
+	suite.Nil(adults.Conditions[0].Conditions[0].AttributeValue)
+	suite.NotEmpty(adults.Conditions[0].Conditions[0].Error)
+	suite.Nil(trace.Rules[0].Audiences.Result)
+	suite.Equal(RuleAudienceMismatch, trace.Rules[0].Result)
+
+	// The targeted rule has no traffic, the user goes straight to the everyone else rule
+	suite.Equal("targeted", trace.Rules[1].RuleKey)
+	suite.True(trace.Rules[1].AudienceMatch)
+	suite.Equal(RuleNotBucketed, trace.Rules[1].Result)
+	suite.Equal("everyone_else", trace.Rules[2].RuleKey)
+	suite.Equal(RuleBucketed, trace.Rules[2].Result)
+}
+
+func (suite *ExplainTestSuite) TestShortCircuits() {
+	attributes := map[string]interface{}{"country": "US", "age": 30}
+
+	userContext := suite.optlyClient.CreateUserContext("whitelisted", attributes)
+	trace := suite.explain(userContext)
+	suite.Equal(RuleWhitelist, trace.Rules[0].Result)
+	suite.Nil(trace.Rules[0].Audiences)
+	suite.Nil(trace.Rules[0].Bucket)
+
+	_, _ = suite.optlyClient.ForcedVariations.Set(context.Background(), overrides.Override{ExperimentKey: "experiment", UserID: "overridden", VariationKey: "off"})
+	userContext = suite.optlyClient.CreateUserContext("overridden", attributes)
+	trace = suite.explain(userContext)
+	suite.Equal(RuleOverride, trace.Rules[0].Result)
+	suite.Equal("off", trace.VariationKey)
+
+	userContext = suite.optlyClient.CreateUserContext("forced", nil)
+	userContext.SetForcedDecision(decision.OptimizelyDecisionContext{FlagKey: "flag", RuleKey: "experiment"}, decision.OptimizelyForcedDecision{VariationKey: "off"})
+	trace = suite.explain(userContext)
+	suite.Equal(RuleForcedDecision, trace.Rules[0].Result)
+	suite.Equal("off", trace.VariationKey)
+
+	userContext = suite.optlyClient.CreateUserContext("forced", nil)
+	userContext.SetForcedDecision(decision.OptimizelyDecisionContext{FlagKey: "flag"}, decision.OptimizelyForcedDecision{VariationKey: "on"})
+	trace = suite.explain(userContext)
+	suite.Equal("on", trace.ForcedDecision)
+	suite.Empty(trace.Rules)
+}
+
+func (suite *ExplainTestSuite) TestUserProfile() {
+	suite.ups.profile = decision.UserProfile{
+		ID:                  "user1",
+		ExperimentBucketMap: map[decision.UserDecisionKey]string{decision.NewUserDecisionKey("exp1"): "v2"},
+	}
+
+	userContext := suite.optlyClient.CreateUserContext("user1", map[string]interface{}{"country": "FR"})
+	trace := suite.explain(userContext)
+	suite.Equal(RuleUserProfile, trace.Rules[0].Result)
+	suite.Equal("off", trace.VariationKey)
+
+	trace = suite.explain(userContext, decide.IgnoreUserProfileService)
+	suite.Equal(RuleAudienceMismatch, trace.Rules[0].Result)
+}
+
+func (suite *ExplainTestSuite) TestUnknownFlag() {
+	userContext := suite.optlyClient.CreateUserContext("user1", nil)
+	_, err := suite.optlyClient.Explain(suite.projectConfig, &userContext, "unknown", nil)
+	suite.Error(err)
+}
+
+func TestExplainTestSuite(t *testing.T) {
+	suite.Run(t, new(ExplainTestSuite))
+}
@@ -0,0 +1,210 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/optimizely/go-sdk/pkg/config"
+	"github.com/optimizely/go-sdk/pkg/config/datafileprojectconfig"
+	"github.com/optimizely/go-sdk/pkg/logging"
+	"github.com/optimizely/go-sdk/pkg/notification"
+	"github.com/optimizely/go-sdk/pkg/registry"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrNoDatafileFile is returned when no local datafile is configured for an SDK key
+var ErrNoDatafileFile = errors.New("no datafile file configured for sdkKey")
+
+// FileProjectConfigManager loads the project config from a local datafile and reloads it when the file changes.
+// It is used in place of the PollingProjectConfigManager when Agent can't reach the CDN.
+type FileProjectConfigManager struct {
+	sdkKey             string
+	path               string
+	notificationCenter notification.Center
+
+	configLock       sync.RWMutex
+	projectConfig    config.ProjectConfig
+	optimizelyConfig *config.OptimizelyConfig
+	modTime          time.Time
+	size             int64
+	err              error
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewFileProjectConfigManager returns a FileProjectConfigManager which has loaded the datafile at path.
+// The file is checked for changes every reloadInterval until ctx is done or the manager is closed,
+// a reloadInterval of 0 disables reloading.
+func NewFileProjectConfigManager(ctx context.Context, sdkKey, path string, reloadInterval time.Duration) *FileProjectConfigManager {
+	cm := &FileProjectConfigManager{
+		sdkKey:             sdkKey,
+		path:               path,
+		notificationCenter: registry.GetNotificationCenter(sdkKey),
+	}
+
+	if path == "" {
+		cm.err = ErrNoDatafileFile
+		return cm
+	}
+
+	cm.SyncConfig()
+
+	if reloadInterval > 0 {
+		ctx, cm.cancel = context.WithCancel(ctx)
+		cm.wg.Add(1)
+		go func() {
+			defer cm.wg.Done()
+			cm.start(ctx, reloadInterval)
+		}()
+	}
+
+	return cm
+}
+
+func (cm *FileProjectConfigManager) start(ctx context.Context, reloadInterval time.Duration) {
+	t := time.NewTicker(reloadInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			cm.SyncConfig()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Close stops reloading the datafile
+func (cm *FileProjectConfigManager) Close() {
+	if cm.cancel != nil {
+		cm.cancel()
+	}
+	cm.wg.Wait()
+}
+
+// SyncConfig reads the datafile if it changed since it was last read and updates the project config
+func (cm *FileProjectConfigManager) SyncConfig() {
+	if cm.path == "" {
+		return
+	}
+
+	info, err := os.Stat(cm.path)
+	if err != nil {
+		cm.setError(fmt.Errorf("unable to read datafile: %w", err))
+		return
+	}
+
+	cm.configLock.RLock()
+	unchanged := cm.projectConfig != nil && info.ModTime().Equal(cm.modTime) && info.Size() == cm.size
+	cm.configLock.RUnlock()
+	if unchanged {
+		return
+	}
+
+	datafile, err := os.ReadFile(cm.path)
+	if err != nil {
+		cm.setError(fmt.Errorf("unable to read datafile: %w", err))
+		return
+	}
+
+	projectConfig, err := datafileprojectconfig.NewDatafileProjectConfig(datafile, logging.GetLogger(cm.sdkKey, "NewDatafileProjectConfig"))
+	if err != nil {
+		cm.setError(errors.New("unable to parse datafile"))
+		return
+	}
+
+	cm.configLock.Lock()
+	var previousRevision string
+	if cm.projectConfig != nil {
+		previousRevision = cm.projectConfig.GetRevision()
+	}
+	// The config is replaced even when the revision is unchanged since local files are often edited by hand
+	cm.projectConfig = projectConfig
+	cm.optimizelyConfig = nil
+	cm.modTime = info.ModTime()
+	cm.size = info.Size()
+	cm.err = nil
+	cm.configLock.Unlock()
+
+	log.Debug().Str("revision", projectConfig.GetRevision()).Str("previousRevision", previousRevision).Msg("Loaded datafile from file")
+	cm.sendConfigUpdateNotification(projectConfig.GetRevision())
+}
+
+// GetConfig returns the project config
+func (cm *FileProjectConfigManager) GetConfig() (config.ProjectConfig, error) {
+	cm.configLock.RLock()
+	defer cm.configLock.RUnlock()
+	if cm.projectConfig == nil {
+		return nil, cm.err
+	}
+	return cm.projectConfig, nil
+}
+
+// GetOptimizelyConfig returns the optimizely config of the project config
+func (cm *FileProjectConfigManager) GetOptimizelyConfig() *config.OptimizelyConfig {
+	cm.configLock.Lock()
+	defer cm.configLock.Unlock()
+	if cm.optimizelyConfig == nil && cm.projectConfig != nil {
+		cm.optimizelyConfig = config.NewOptimizelyConfig(cm.projectConfig)
+	}
+	return cm.optimizelyConfig
+}
+
+// OnProjectConfigUpdate registers a handler for ProjectConfigUpdate notifications
+func (cm *FileProjectConfigManager) OnProjectConfigUpdate(callback func(notification.ProjectConfigUpdateNotification)) (int, error) {
+	handler := func(payload interface{}) {
+		if projectConfigUpdateNotification, ok := payload.(notification.ProjectConfigUpdateNotification); ok {
+			callback(projectConfigUpdateNotification)
+		}
+	}
+	return cm.notificationCenter.AddHandler(notification.ProjectConfigUpdate, handler)
+}
+
+// RemoveOnProjectConfigUpdate removes handler for ProjectConfigUpdate notification with given id
+func (cm *FileProjectConfigManager) RemoveOnProjectConfigUpdate(id int) error {
+	return cm.notificationCenter.RemoveHandler(id, notification.ProjectConfigUpdate)
+}
+
+func (cm *FileProjectConfigManager) setError(err error) {
+	cm.configLock.Lock()
+	// Only log changes so that a missing file isn't reported on every reload
+	changed := cm.err == nil || cm.err.Error() != err.Error()
+	cm.err = err
+	cm.configLock.Unlock()
+
+	if changed {
+		log.Warn().Err(err).Str("path", cm.path).Msg("Failed to load datafile from file")
+	}
+}
+
+func (cm *FileProjectConfigManager) sendConfigUpdateNotification(revision string) {
+	projectConfigUpdateNotification := notification.ProjectConfigUpdateNotification{
+		Type:     notification.ProjectConfigUpdate,
+		Revision: revision,
+	}
+	if err := cm.notificationCenter.Send(notification.ProjectConfigUpdate, projectConfigUpdateNotification); err != nil {
+		log.Warn().Err(err).Msg("Problem with sending notification")
+	}
+}
@@ -0,0 +1,131 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/optimizely/go-sdk/pkg/notification"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/optimizely/agent/config"
+)
+
+type FileProjectConfigManagerTestSuite struct {
+	suite.Suite
+	path string
+}
+
+func (s *FileProjectConfigManagerTestSuite) SetupTest() {
+	s.path = filepath.Join(s.T().TempDir(), "sdkKey.json")
+}
+
+func (s *FileProjectConfigManagerTestSuite) writeDatafile(revision string) {
+	datafile := fmt.Sprintf(`{"version": "4", "revision": %q, "projectId": "1", "accountId": "1"}`, revision)
+	s.NoError(os.WriteFile(s.path, []byte(datafile), 0o600))
+}
+
+func (s *FileProjectConfigManagerTestSuite) TestGetConfig() {
+	s.writeDatafile("1")
+	cm := NewFileProjectConfigManager(context.Background(), "sdkKey", s.path, 0)
+	defer cm.Close()
+
+	projectConfig, err := cm.GetConfig()
+	s.NoError(err)
+	s.Equal("1", projectConfig.GetRevision())
+	s.Equal("1", cm.GetOptimizelyConfig().Revision)
+}
+
+func (s *FileProjectConfigManagerTestSuite) TestGetConfigMissingFile() {
+	cm := NewFileProjectConfigManager(context.Background(), "sdkKey", s.path, 0)
+	_, err := cm.GetConfig()
+	s.Error(err)
+	s.Nil(cm.GetOptimizelyConfig())
+
+	cm = NewFileProjectConfigManager(context.Background(), "sdkKey", "", 0)
+	_, err = cm.GetConfig()
+	s.Equal(ErrNoDatafileFile, err)
+}
+
+func (s *FileProjectConfigManagerTestSuite) TestGetConfigInvalidFile() {
+	s.NoError(os.WriteFile(s.path, []byte("invalid"), 0o600))
+	cm := NewFileProjectConfigManager(context.Background(), "sdkKey", s.path, 0)
+	_, err := cm.GetConfig()
+	s.Error(err)
+}
+
+func (s *FileProjectConfigManagerTestSuite) TestSyncConfig() {
+	s.writeDatafile("1")
+	cm := NewFileProjectConfigManager(context.Background(), "sdkKey", s.path, 0)
+
+	revisions := make(chan string, 1)
+	_, err := cm.OnProjectConfigUpdate(func(n notification.ProjectConfigUpdateNotification) {
+		revisions <- n.Revision
+	})
+	s.NoError(err)
+
+	// Unchanged files are not read again
+	cm.SyncConfig()
+	s.Empty(revisions)
+
+	s.writeDatafile("2")
+	s.NoError(os.Chtimes(s.path, time.Now(), time.Now().Add(time.Minute)))
+	cm.SyncConfig()
+	s.Equal("2", <-revisions)
+	projectConfig, _ := cm.GetConfig()
+	s.Equal("2", projectConfig.GetRevision())
+
+	// The last valid config is kept when the file is removed
+	s.NoError(os.Remove(s.path))
+	cm.SyncConfig()
+	projectConfig, err = cm.GetConfig()
+	s.NoError(err)
+	s.Equal("2", projectConfig.GetRevision())
+}
+
+func (s *FileProjectConfigManagerTestSuite) TestReload() {
+	s.writeDatafile("1")
+	cm := NewFileProjectConfigManager(context.Background(), "sdkKey", s.path, 10*time.Millisecond)
+	defer cm.Close()
+
+	s.writeDatafile("2")
+	s.NoError(os.Chtimes(s.path, time.Now(), time.Now().Add(time.Minute)))
+	s.Eventually(func() bool {
+		projectConfig, _ := cm.GetConfig()
+		return projectConfig.GetRevision() == "2"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func (s *FileProjectConfigManagerTestSuite) TestStaticDatafilePath() {
+	conf := config.StaticDatafilesConfig{
+		Dir:   "datafiles",
+		Files: []config.StaticDatafile{{SDKKey: "SDKKey", Path: "/tmp/datafile.json"}},
+	}
+	s.Equal("/tmp/datafile.json", staticDatafilePath(conf, "SDKKey"))
+	s.Equal(filepath.Join("datafiles", "sdkKey.json"), staticDatafilePath(conf, "sdkKey"))
+	s.Empty(staticDatafilePath(config.StaticDatafilesConfig{}, "sdkKey"))
+}
+
+func TestFileProjectConfigManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(FileProjectConfigManagerTestSuite))
+}
@@ -0,0 +1,203 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/plugins/forceddecisions"
+	optimizelyclient "github.com/optimizely/go-sdk/pkg/client"
+	"github.com/optimizely/go-sdk/pkg/decision"
+	"github.com/optimizely/go-sdk/pkg/entities"
+	cmap "github.com/orcaman/concurrent-map"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrForcedDecisionsUninitialized is returned when no forced decisions store is configured
+var ErrForcedDecisionsUninitialized = errors.New("client forced decisions store not initialized")
+
+// UserForcedDecisions model describing the forced decisions saved for a user
+type UserForcedDecisions struct {
+	UserID          string                           `json:"userId"`
+	ForcedDecisions []forceddecisions.ForcedDecision `json:"forcedDecisions"`
+}
+
+// SavedForcedDecision model describing a forced decision saved for a user
+type SavedForcedDecision struct {
+	forceddecisions.ForcedDecision
+	UserID           string   `json:"userId"`
+	PrevVariationKey string   `json:"prevVariationKey,omitempty"`
+	Messages         []string `json:"messages,omitempty"`
+}
+
+// newForcedDecisionsStore returns the configured forced decisions store, nil when there is none.
+// A single store is shared by every client since forced decisions are kept per SDK key, so that
+// they outlive the eviction of a client.
+func newForcedDecisionsStore(conf config.ForcedDecisionsConfigs) (forceddecisions.Store, string) {
+	name := getServiceName("", cmap.New(), conf)
+	rawStore := getServiceWithType(forcedDecisionsPlugin, "", cmap.New(), conf)
+	if store, ok := rawStore.(forceddecisions.Store); ok && store != nil {
+		return store, name
+	}
+	return nil, ""
+}
+
+// GetForcedDecisions returns the forced decisions saved for the user which have not expired
+func (c *OptlyClient) GetForcedDecisions(ctx context.Context, userID string) (*UserForcedDecisions, error) {
+	_, span := otel.Tracer("forcedDecisionsHandler").Start(ctx, "GetForcedDecisions")
+	defer span.End()
+
+	if c.ForcedDecisions == nil {
+		return &UserForcedDecisions{}, ErrForcedDecisionsUninitialized
+	}
+
+	found, err := c.ForcedDecisions.Lookup(c.sdkKey, userID)
+	if err != nil {
+		return &UserForcedDecisions{}, err
+	}
+	return &UserForcedDecisions{UserID: userID, ForcedDecisions: found}, nil
+}
+
+// SaveForcedDecision saves a forced decision for the user, replacing any previous one for the same flag and rule keys.
+// It expires after ttl, or never when ttl is 0. Keys which are not part of the configuration are reported as messages,
+// the forced decision is saved anyway since it may be meant for a datafile revision which is not loaded yet.
+func (c *OptlyClient) SaveForcedDecision(ctx context.Context, userID string, forcedDecision forceddecisions.ForcedDecision, ttl time.Duration) (*SavedForcedDecision, error) {
+	_, span := otel.Tracer("forcedDecisionsHandler").Start(ctx, "SaveForcedDecision")
+	defer span.End()
+	span.SetAttributes(attribute.String("flagKey", forcedDecision.FlagKey))
+	span.SetAttributes(attribute.String("variationKey", forcedDecision.VariationKey))
+
+	if c.ForcedDecisions == nil {
+		return &SavedForcedDecision{}, ErrForcedDecisionsUninitialized
+	}
+
+	forcedDecision.ExpiresAt = nil
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl).UTC()
+		forcedDecision.ExpiresAt = &expiresAt
+	}
+
+	saved := SavedForcedDecision{
+		ForcedDecision: forcedDecision,
+		UserID:         userID,
+	}
+
+	messages := make([]string, 0, 2)
+	if message := c.validateForcedDecision(forcedDecision); message != "" {
+		messages = append(messages, message)
+	}
+
+	found, err := c.ForcedDecisions.Lookup(c.sdkKey, userID)
+	if err != nil {
+		return &SavedForcedDecision{}, err
+	}
+	for _, fd := range found {
+		if fd.FlagKey == forcedDecision.FlagKey && fd.RuleKey == forcedDecision.RuleKey {
+			saved.PrevVariationKey = fd.VariationKey
+			messages = append(messages, "updating previous forced decision")
+		}
+	}
+
+	if len(messages) > 0 {
+		saved.Messages = messages
+	}
+
+	if err := c.ForcedDecisions.Save(c.sdkKey, userID, forcedDecision); err != nil {
+		return &SavedForcedDecision{}, err
+	}
+	return &saved, nil
+}
+
+// RemoveForcedDecision removes the forced decision of the user for the flag and rule keys, it returns false when there was none
+func (c *OptlyClient) RemoveForcedDecision(ctx context.Context, userID, flagKey, ruleKey string) (bool, error) {
+	_, span := otel.Tracer("forcedDecisionsHandler").Start(ctx, "RemoveForcedDecision")
+	defer span.End()
+	span.SetAttributes(attribute.String("flagKey", flagKey))
+
+	if c.ForcedDecisions == nil {
+		return false, ErrForcedDecisionsUninitialized
+	}
+	return c.ForcedDecisions.Remove(c.sdkKey, userID, flagKey, ruleKey)
+}
+
+// ResetForcedDecisions removes every forced decision of the user
+func (c *OptlyClient) ResetForcedDecisions(ctx context.Context, userID string) error {
+	_, span := otel.Tracer("forcedDecisionsHandler").Start(ctx, "ResetForcedDecisions")
+	defer span.End()
+
+	if c.ForcedDecisions == nil {
+		return ErrForcedDecisionsUninitialized
+	}
+	return c.ForcedDecisions.Reset(c.sdkKey, userID)
+}
+
+// ApplyForcedDecisions sets the forced decisions saved for the user on the user context.
+// Forced decisions set on the user context afterwards take precedence over them.
+func (c *OptlyClient) ApplyForcedDecisions(userContext *optimizelyclient.OptimizelyUserContext) error {
+	if c.ForcedDecisions == nil || userContext == nil {
+		return nil
+	}
+
+	found, err := c.ForcedDecisions.Lookup(c.sdkKey, userContext.GetUserID())
+	if err != nil {
+		return err
+	}
+	for _, fd := range found {
+		context := decision.OptimizelyDecisionContext{FlagKey: fd.FlagKey, RuleKey: fd.RuleKey}
+		userContext.SetForcedDecision(context, decision.OptimizelyForcedDecision{VariationKey: fd.VariationKey})
+	}
+	return nil
+}
+
+// validateForcedDecision checks the keys of the forced decision exist as part of the project configuration
+func (c *OptlyClient) validateForcedDecision(forcedDecision forceddecisions.ForcedDecision) string {
+	if c.OptimizelyClient == nil || c.OptimizelyClient.ConfigManager == nil {
+		return "forced decision cannot be validated via configuration"
+	}
+	projectConfig, err := c.OptimizelyClient.ConfigManager.GetConfig()
+	if err != nil || projectConfig == nil {
+		return "forced decision cannot be validated via configuration"
+	}
+
+	feature, err := projectConfig.GetFeatureByKey(forcedDecision.FlagKey)
+	if err != nil {
+		return "flagKey not found in configuration"
+	}
+
+	ruleFound := false
+	for _, rule := range append(append([]entities.Experiment{}, feature.FeatureExperiments...), feature.Rollout.Experiments...) {
+		if forcedDecision.RuleKey != "" && rule.Key != forcedDecision.RuleKey {
+			continue
+		}
+		ruleFound = true
+		for _, variation := range rule.Variations {
+			if variation.Key == forcedDecision.VariationKey {
+				return ""
+			}
+		}
+	}
+
+	if forcedDecision.RuleKey != "" && !ruleFound {
+		return "ruleKey not found in configuration"
+	}
+	return "variationKey not found in configuration"
+}
@@ -0,0 +1,222 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/optimizely/go-sdk/pkg/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/pkg/optimizely/optimizelytest"
+	"github.com/optimizely/agent/plugins/forceddecisions"
+	"github.com/optimizely/agent/plugins/forceddecisions/services"
+)
+
+type ForcedDecisionsTestSuite struct {
+	suite.Suite
+	featureExp  entities.Experiment
+	optlyClient *OptlyClient
+	store       forceddecisions.Store
+}
+
+func (suite *ForcedDecisionsTestSuite) SetupTest() {
+	testClient := optimizelytest.NewClient()
+	feature := entities.Feature{Key: "my_feat"}
+	testClient.ProjectConfig.AddMultiVariationFeatureTest(feature, "disabled_var", "enabled_var")
+	suite.featureExp = testClient.ProjectConfig.FeatureMap["my_feat"].FeatureExperiments[0]
+	enabledVariationID := suite.featureExp.VariationKeyToIDMap["enabled_var"]
+	testClient.AddFlagVariation(feature, suite.featureExp.Variations[enabledVariationID])
+	suite.store = services.NewInMemoryStore()
+	suite.optlyClient = &OptlyClient{
+		OptimizelyClient: testClient.OptimizelyClient,
+		ConfigManager:    &MockConfigManager{config: testClient.ProjectConfig},
+		ForcedDecisions:  suite.store,
+		sdkKey:           "sdkKey",
+	}
+}
+
+func (suite *ForcedDecisionsTestSuite) TearDownTest() {
+	suite.optlyClient.Close()
+}
+
+func (suite *ForcedDecisionsTestSuite) TestSaveForcedDecision() {
+	scenarios := []struct {
+		forcedDecision forceddecisions.ForcedDecision
+		previousKey    string
+		messages       []string
+	}{
+		{
+			forcedDecision: forceddecisions.ForcedDecision{FlagKey: "my_feat", VariationKey: "enabled_var"},
+		},
+		{
+			forcedDecision: forceddecisions.ForcedDecision{FlagKey: "my_feat", VariationKey: "disabled_var"},
+			previousKey:    "enabled_var",
+			messages:       []string{"updating previous forced decision"},
+		},
+		{
+			forcedDecision: forceddecisions.ForcedDecision{FlagKey: "my_feat", RuleKey: suite.featureExp.Key, VariationKey: "enabled_var"},
+		},
+		{
+			forcedDecision: forceddecisions.ForcedDecision{FlagKey: "my_feat", RuleKey: suite.featureExp.Key, VariationKey: "dne-var"},
+			previousKey:    "enabled_var",
+			messages:       []string{"variationKey not found in configuration", "updating previous forced decision"},
+		},
+		{
+			forcedDecision: forceddecisions.ForcedDecision{FlagKey: "my_feat", RuleKey: "dne-rule", VariationKey: "enabled_var"},
+			messages:       []string{"ruleKey not found in configuration"},
+		},
+		{
+			forcedDecision: forceddecisions.ForcedDecision{FlagKey: "dne-flag", VariationKey: "enabled_var"},
+			messages:       []string{"flagKey not found in configuration"},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		actual, err := suite.optlyClient.SaveForcedDecision(context.Background(), "testUser", scenario.forcedDecision, 0)
+		suite.NoError(err)
+
+		expected := &SavedForcedDecision{
+			ForcedDecision:   scenario.forcedDecision,
+			UserID:           "testUser",
+			PrevVariationKey: scenario.previousKey,
+			Messages:         scenario.messages,
+		}
+		suite.Equal(expected, actual)
+	}
+
+	actual, err := suite.optlyClient.GetForcedDecisions(context.Background(), "testUser")
+	suite.NoError(err)
+	suite.Equal("testUser", actual.UserID)
+	suite.Len(actual.ForcedDecisions, 4)
+}
+
+func (suite *ForcedDecisionsTestSuite) TestSaveForcedDecisionWithTTL() {
+	before := time.Now()
+	actual, err := suite.optlyClient.SaveForcedDecision(context.Background(), "testUser", forceddecisions.ForcedDecision{FlagKey: "my_feat", VariationKey: "enabled_var"}, time.Hour)
+	suite.NoError(err)
+	if suite.NotNil(actual.ExpiresAt) {
+		suite.False(actual.ExpiresAt.Before(before.Add(time.Hour)))
+	}
+
+	found, err := suite.store.Lookup("sdkKey", "testUser")
+	suite.NoError(err)
+	suite.Equal([]forceddecisions.ForcedDecision{actual.ForcedDecision}, found)
+}
+
+func (suite *ForcedDecisionsTestSuite) TestRemoveAndResetForcedDecisions() {
+	ctx := context.Background()
+	_, _ = suite.optlyClient.SaveForcedDecision(ctx, "testUser", forceddecisions.ForcedDecision{FlagKey: "my_feat", VariationKey: "enabled_var"}, 0)
+	_, _ = suite.optlyClient.SaveForcedDecision(ctx, "testUser", forceddecisions.ForcedDecision{FlagKey: "my_feat", RuleKey: suite.featureExp.Key, VariationKey: "enabled_var"}, 0)
+
+	removed, err := suite.optlyClient.RemoveForcedDecision(ctx, "testUser", "my_feat", "")
+	suite.NoError(err)
+	suite.True(removed)
+
+	removed, err = suite.optlyClient.RemoveForcedDecision(ctx, "testUser", "my_feat", "")
+	suite.NoError(err)
+	suite.False(removed)
+
+	suite.NoError(suite.optlyClient.ResetForcedDecisions(ctx, "testUser"))
+	actual, err := suite.optlyClient.GetForcedDecisions(ctx, "testUser")
+	suite.NoError(err)
+	suite.Empty(actual.ForcedDecisions)
+}
+
+func (suite *ForcedDecisionsTestSuite) TestApplyForcedDecisions() {
+	userContext := suite.optlyClient.CreateUserContext("testUser", nil)
+	suite.NoError(suite.optlyClient.ApplyForcedDecisions(&userContext))
+	suite.Equal("disabled_var", userContext.Decide("my_feat", nil).VariationKey)
+
+	_, _ = suite.optlyClient.SaveForcedDecision(context.Background(), "testUser", forceddecisions.ForcedDecision{FlagKey: "my_feat", VariationKey: "enabled_var"}, 0)
+	userContext = suite.optlyClient.CreateUserContext("testUser", nil)
+	suite.NoError(suite.optlyClient.ApplyForcedDecisions(&userContext))
+	decision := userContext.Decide("my_feat", nil)
+	suite.Equal("enabled_var", decision.VariationKey)
+	suite.True(decision.Enabled)
+
+	// Other users are not affected
+	userContext = suite.optlyClient.CreateUserContext("otherUser", nil)
+	suite.NoError(suite.optlyClient.ApplyForcedDecisions(&userContext))
+	suite.Equal("disabled_var", userContext.Decide("my_feat", nil).VariationKey)
+}
+
+func (suite *ForcedDecisionsTestSuite) TestInfo() {
+	suite.optlyClient.forcedDecisionsName = "in-memory"
+	suite.Equal("in-memory", suite.optlyClient.Info().ForcedDecisions)
+}
+
+func TestForcedDecisionsTestSuite(t *testing.T) {
+	suite.Run(t, new(ForcedDecisionsTestSuite))
+}
+
+type failingStore struct {
+	forceddecisions.Store
+}
+
+func (s failingStore) Lookup(sdkKey, userID string) ([]forceddecisions.ForcedDecision, error) {
+	return nil, errors.New("store unavailable")
+}
+
+func TestForcedDecisionsStoreErrors(t *testing.T) {
+	testClient := optimizelytest.NewClient()
+	optlyClient := &OptlyClient{OptimizelyClient: testClient.OptimizelyClient, ForcedDecisions: failingStore{}}
+	userContext := optlyClient.CreateUserContext("testUser", nil)
+	assert.EqualError(t, optlyClient.ApplyForcedDecisions(&userContext), "store unavailable")
+
+	_, err := optlyClient.GetForcedDecisions(context.Background(), "testUser")
+	assert.EqualError(t, err, "store unavailable")
+}
+
+func TestForcedDecisionsUninitialized(t *testing.T) {
+	ctx := context.Background()
+	optlyClient := &OptlyClient{}
+
+	_, err := optlyClient.GetForcedDecisions(ctx, "testUser")
+	assert.Equal(t, ErrForcedDecisionsUninitialized, err)
+	_, err = optlyClient.SaveForcedDecision(ctx, "testUser", forceddecisions.ForcedDecision{FlagKey: "flag"}, 0)
+	assert.Equal(t, ErrForcedDecisionsUninitialized, err)
+	_, err = optlyClient.RemoveForcedDecision(ctx, "testUser", "flag", "")
+	assert.Equal(t, ErrForcedDecisionsUninitialized, err)
+	assert.Equal(t, ErrForcedDecisionsUninitialized, optlyClient.ResetForcedDecisions(ctx, "testUser"))
+	assert.NoError(t, optlyClient.ApplyForcedDecisions(nil))
+}
+
+func TestNewForcedDecisionsStore(t *testing.T) {
+	store, name := newForcedDecisionsStore(config.NewDefaultConfig().Client.ForcedDecisions)
+	assert.IsType(t, &services.InMemoryStore{}, store)
+	assert.Equal(t, "in-memory", name)
+
+	store, name = newForcedDecisionsStore(config.ForcedDecisionsConfigs{
+		"default":  "redis",
+		"services": map[string]interface{}{"redis": map[string]interface{}{"host": "localhost:6379", "prefix": "qa"}},
+	})
+	if assert.IsType(t, &services.RedisStore{}, store) {
+		assert.Equal(t, "qa", store.(*services.RedisStore).Prefix)
+	}
+	assert.Equal(t, "redis", name)
+
+	store, name = newForcedDecisionsStore(config.ForcedDecisionsConfigs{"default": ""})
+	assert.Nil(t, store)
+	assert.Empty(t, name)
+}
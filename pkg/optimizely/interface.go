@@ -36,3 +36,11 @@ type SyncedConfigManager interface {
 	optimizelyconfig.ProjectConfigManager
 	SyncConfig()
 }
+
+// ClientManager provides operational access to the clients loaded in a Cache
+type ClientManager interface {
+	ListClients() []ClientInfo
+	GetClientInfo(sdkKey string) []ClientInfo
+	ResyncClient(sdkKey string) bool
+	EvictClient(sdkKey string) bool
+}
@@ -20,9 +20,9 @@ package optimizelytest
 import (
 	"time"
 
+	"github.com/optimizely/agent/pkg/overrides"
 	"github.com/optimizely/go-sdk/pkg/client"
 	"github.com/optimizely/go-sdk/pkg/config"
-	"github.com/optimizely/go-sdk/pkg/decision"
 	"github.com/optimizely/go-sdk/pkg/entities"
 	"github.com/optimizely/go-sdk/pkg/event"
 	"github.com/optimizely/go-sdk/pkg/logging"
@@ -36,7 +36,7 @@ type TestClient struct {
 	EventProcessor    *TestEventProcessor
 	ProjectConfig     *TestProjectConfig
 	OptimizelyClient  *client.OptimizelyClient
-	ForcedVariations  *decision.MapExperimentOverridesStore
+	ForcedVariations  *overrides.InMemoryStore
 	EventAPIManager   *TestEventAPIManager
 	SegmentAPIManager *TestSegmentAPIManager
 }
@@ -45,7 +45,7 @@ type TestClient struct {
 func NewClient() *TestClient {
 	projectConfig := NewConfig()
 	eventProcessor := new(TestEventProcessor)
-	forcedVariations := decision.NewMapExperimentOverridesStore()
+	forcedVariations := overrides.NewInMemoryStore()
 
 	segmentAPIManager := new(TestSegmentAPIManager)
 	eventAPIManager := new(TestEventAPIManager)
@@ -0,0 +1,164 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/pkg/syncer"
+)
+
+// Names of the readiness checks
+const (
+	DatafilesCheck            = "datafiles"
+	RedisSyncerCheck          = "redis.syncer"
+	RedisUserProfileCheck     = "redis.userProfileService"
+	RedisODPCacheCheck        = "redis.odpCache"
+	RedisForcedDecisionsCheck = "redis.forcedDecisions"
+	RedisOverridesCheck       = "redis.overrides"
+	RedisIdempotencyCheck     = "redis.idempotency"
+)
+
+const redisServiceName = "redis"
+
+// CheckDatafiles returns an error until a client with a datafile is loaded for every one of the given SDK keys.
+// It only looks at the loaded clients, so it neither waits for a load nor keeps the clients from being evicted.
+// The keys which aren't loaded are loaded in the background, subject to the load backoff.
+func (c *OptlyCache) CheckDatafiles(sdkKeys []string) error {
+	missing := 0
+	for _, sdkKey := range sdkKeys {
+		val, ok := c.optlyMap.Get(sdkKey)
+		if !ok {
+			c.loadInBackground(sdkKey)
+			missing++
+			continue
+		}
+		if !hasDatafile(val.(*OptlyClient)) {
+			missing++
+		}
+	}
+
+	if missing > 0 {
+		return fmt.Errorf("%d of %d SDK keys have no datafile", missing, len(sdkKeys))
+	}
+	return nil
+}
+
+// loadInBackground starts loading the SDK key unless its last load failed within the backoff,
+// sharing the load with the concurrent ones
+func (c *OptlyCache) loadInBackground(sdkKey string) {
+	if c.failedLoadError(sdkKey) != nil {
+		return
+	}
+	c.loadGroup.DoChan(sdkKey, func() (interface{}, error) {
+		return c.load(sdkKey)
+	})
+}
+
+func hasDatafile(optlyClient *OptlyClient) bool {
+	if optlyClient == nil || optlyClient.OptimizelyClient == nil || optlyClient.OptimizelyClient.ConfigManager == nil {
+		return false
+	}
+	projectConfig, err := optlyClient.OptimizelyClient.ConfigManager.GetConfig()
+	return err == nil && projectConfig != nil
+}
+
+// redisOptions is the subset of the Redis plugin configs needed to reach the server
+type redisOptions struct {
+	Host     string `json:"host"`
+	Password string `json:"password"`
+	Database int    `json:"database"`
+}
+
+// RedisReadinessChecks returns a check pinging each Redis backend Agent uses: the notification syncer
+// and the UserProfileService, ODP segments cache and forced decisions store when Redis is their default service,
+// as well as the overrides store and the batch track idempotency store when they are kept in Redis.
+func RedisReadinessChecks(conf config.AgentConfig) map[string]func(ctx context.Context) error {
+	checks := make(map[string]func(ctx context.Context) error)
+
+	if conf.Synchronization.Notification.Enable && conf.Synchronization.Notification.Default == syncer.PubSubRedis {
+		checks[RedisSyncerCheck] = redisPing(conf.Synchronization.Pubsub[syncer.PubSubRedis])
+	}
+
+	if rawConf, ok := defaultRedisService(conf.Client.UserProfileService); ok {
+		checks[RedisUserProfileCheck] = redisPing(rawConf)
+	}
+
+	if rawConf, ok := defaultRedisService(conf.Client.ODP.SegmentsCache); ok {
+		checks[RedisODPCacheCheck] = redisPing(rawConf)
+	}
+
+	if rawConf, ok := defaultRedisService(conf.Client.ForcedDecisions); ok {
+		checks[RedisForcedDecisionsCheck] = redisPing(rawConf)
+	}
+
+	if conf.Client.Overrides.Store == config.OverridesStoreRedis {
+		checks[RedisOverridesCheck] = redisPing(conf.Client.Overrides.Redis)
+	}
+
+	if conf.API.TrackBatch.Idempotency.Store == config.IdempotencyStoreRedis {
+		checks[RedisIdempotencyCheck] = redisPing(conf.API.TrackBatch.Idempotency.Redis)
+	}
+
+	return checks
+}
+
+// defaultRedisService returns the config of the redis service when it is the default one
+func defaultRedisService(serviceConf map[string]interface{}) (interface{}, bool) {
+	if name, ok := serviceConf["default"].(string); !ok || name != redisServiceName {
+		return nil, false
+	}
+	services, ok := serviceConf["services"].(map[string]interface{})
+	if !ok {
+		return nil, true
+	}
+	return services[redisServiceName], true
+}
+
+// redisPing returns a check pinging the Redis server described by rawConf.
+// The client is created once and reused by every check.
+func redisPing(rawConf interface{}) func(ctx context.Context) error {
+	var opts redisOptions
+	b, err := json.Marshal(rawConf)
+	if err == nil {
+		err = json.Unmarshal(b, &opts)
+	}
+	if err == nil && opts.Host == "" {
+		err = errors.New("redis host not provided")
+	}
+	if err != nil {
+		configErr := fmt.Errorf("invalid redis config: %w", err)
+		return func(ctx context.Context) error {
+			return configErr
+		}
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     opts.Host,
+		Password: opts.Password,
+		DB:       opts.Database,
+	})
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
@@ -0,0 +1,88 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/optimizely/agent/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisReadinessChecksNotConfigured(t *testing.T) {
+	conf := config.NewDefaultConfig()
+	assert.Empty(t, RedisReadinessChecks(*conf))
+
+	// Redis services which aren't the default aren't checked
+	conf.Client.UserProfileService = map[string]interface{}{
+		"default": "in-memory",
+		"services": map[string]interface{}{
+			"redis": map[string]interface{}{"host": "localhost:6379"},
+		},
+	}
+	assert.Empty(t, RedisReadinessChecks(*conf))
+}
+
+func TestRedisReadinessChecks(t *testing.T) {
+	// Reserve an address and release it so that nothing listens on it
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	addr := listener.Addr().String()
+	assert.NoError(t, listener.Close())
+
+	conf := config.NewDefaultConfig()
+	conf.Synchronization.Notification.Enable = true
+	conf.Synchronization.Notification.Default = "redis"
+	conf.Synchronization.Pubsub = map[string]interface{}{
+		"redis": map[string]interface{}{"host": addr, "password": "", "database": 0},
+	}
+	conf.Client.UserProfileService = map[string]interface{}{
+		"default": "redis",
+		"services": map[string]interface{}{
+			"redis": map[string]interface{}{"host": addr, "password": "", "database": 1},
+		},
+	}
+	conf.Client.ODP.SegmentsCache = map[string]interface{}{
+		"default":  "redis",
+		"services": map[string]interface{}{},
+	}
+	conf.Client.ForcedDecisions = map[string]interface{}{
+		"default": "redis",
+		"services": map[string]interface{}{
+			"redis": map[string]interface{}{"host": addr},
+		},
+	}
+
+	conf.Client.Overrides.Store = config.OverridesStoreRedis
+	conf.Client.Overrides.Redis.Host = addr
+	conf.API.TrackBatch.Idempotency.Store = config.IdempotencyStoreRedis
+	conf.API.TrackBatch.Idempotency.Redis.Host = addr
+
+	checks := RedisReadinessChecks(*conf)
+	assert.Len(t, checks, 6)
+	assert.Error(t, checks[RedisSyncerCheck](context.Background()))
+	assert.Error(t, checks[RedisUserProfileCheck](context.Background()))
+	assert.EqualError(t, checks[RedisODPCacheCheck](context.Background()), "invalid redis config: redis host not provided")
+	assert.Error(t, checks[RedisForcedDecisionsCheck](context.Background()))
+	assert.Error(t, checks[RedisOverridesCheck](context.Background()))
+	assert.Error(t, checks[RedisIdempotencyCheck](context.Background()))
+}
@@ -0,0 +1,125 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/optimizely/go-sdk/pkg/logging"
+	"github.com/optimizely/go-sdk/pkg/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// SyncStatus holds the outcome of the most recent datafile requests for an SDK key
+type SyncStatus struct {
+	mu        sync.RWMutex
+	lastSync  time.Time
+	lastError error
+	stale     bool
+
+	// onStaleChange is called whenever the stale flag changes
+	onStaleChange func(stale bool)
+}
+
+// LastSync returns the time of the last successful datafile request
+func (s *SyncStatus) LastSync() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastSync
+}
+
+// LastError returns the error of the last datafile request, nil if it succeeded
+func (s *SyncStatus) LastError() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastError
+}
+
+// Stale returns true while the client is serving a datafile snapshot which could not be refreshed yet
+func (s *SyncStatus) Stale() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stale
+}
+
+func (s *SyncStatus) record(err error) {
+	s.mu.Lock()
+	s.lastError = err
+	if err == nil {
+		s.lastSync = time.Now()
+	}
+	s.mu.Unlock()
+
+	if err == nil {
+		s.setStale(false)
+	}
+}
+
+func (s *SyncStatus) setStale(stale bool) {
+	s.mu.Lock()
+	changed := s.stale != stale
+	s.stale = stale
+	s.mu.Unlock()
+
+	if changed && s.onStaleChange != nil {
+		s.onStaleChange(stale)
+	}
+}
+
+// datafileRequester records the outcome of every datafile request in a SyncStatus
+// and saves every fetched datafile as a snapshot
+type datafileRequester struct {
+	utils.Requester
+	status       *SyncStatus
+	snapshotPath string
+}
+
+// Get performs the request with the wrapped requester and records its outcome
+func (r datafileRequester) Get(url string, headers ...utils.Header) (response []byte, responseHeaders http.Header, code int, err error) {
+	response, responseHeaders, code, err = r.Requester.Get(url, headers...)
+	r.status.record(err)
+
+	if err == nil && code == http.StatusOK && r.snapshotPath != "" {
+		if snapshotErr := saveSnapshot(r.snapshotPath, response); snapshotErr != nil {
+			log.Warn().Err(snapshotErr).Msg("Failed to save datafile snapshot")
+		}
+	}
+	return response, responseHeaders, code, err
+}
+
+// newDatafileRequester returns the requester used to poll the datafile of the given SDK key.
+// The SDK replaces any configured requester when a datafile access token is set, so the
+// authorization headers are added here instead.
+// Fetched datafiles are saved to snapshotPath unless it is empty.
+func newDatafileRequester(sdkKey, datafileAccessToken string, status *SyncStatus, snapshotPath string) utils.Requester {
+	var requester utils.Requester
+	if datafileAccessToken != "" {
+		headers := []utils.Header{
+			{Name: utils.HeaderContentType, Value: utils.ContentTypeJSON},
+			{Name: utils.HeaderAccept, Value: utils.ContentTypeJSON},
+			{Name: utils.HeaderAuthorization, Value: "Bearer " + datafileAccessToken},
+		}
+		requester = utils.NewHTTPRequester(logging.GetLogger(sdkKey, "HTTPRequester"), utils.Headers(headers...))
+	} else {
+		requester = utils.NewHTTPRequester(logging.GetLogger(sdkKey, "HTTPRequester"))
+	}
+
+	return datafileRequester{Requester: requester, status: status, snapshotPath: snapshotPath}
+}
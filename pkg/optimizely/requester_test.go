@@ -0,0 +1,65 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatafileRequesterRecordsSyncStatus(t *testing.T) {
+	status := http.StatusOK
+	var authorization string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	syncStatus := &SyncStatus{}
+	requester := newDatafileRequester("sdkKey", "token", syncStatus, "")
+
+	_, _, _, err := requester.Get(ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer token", authorization)
+	assert.NoError(t, syncStatus.LastError())
+	lastSync := syncStatus.LastSync()
+	assert.False(t, lastSync.IsZero())
+
+	status = http.StatusForbidden
+	_, _, _, err = requester.Get(ts.URL)
+	assert.Error(t, err)
+	assert.Error(t, syncStatus.LastError())
+	assert.Equal(t, lastSync, syncStatus.LastSync())
+}
+
+func TestDatafileRequesterWithoutAccessToken(t *testing.T) {
+	var authorization string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer ts.Close()
+
+	requester := newDatafileRequester("sdkKey", "", &SyncStatus{}, "")
+	_, _, _, err := requester.Get(ts.URL)
+	assert.NoError(t, err)
+	assert.Empty(t, authorization)
+}
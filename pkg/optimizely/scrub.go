@@ -0,0 +1,164 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/go-sdk/pkg/event"
+)
+
+// Scrubber applies the scrub rules to the user attributes and event tags of the events
+type Scrubber struct {
+	salt       []byte
+	attributes map[string]config.ScrubRule
+	tags       map[string]config.ScrubRule
+}
+
+// NewScrubber returns the scrubber of the rules, nil when there are no rules
+func NewScrubber(conf config.ScrubConfig) (*Scrubber, error) {
+	if len(conf.Rules) == 0 {
+		return nil, nil
+	}
+
+	s := &Scrubber{
+		salt:       []byte(conf.Salt),
+		attributes: make(map[string]config.ScrubRule),
+		tags:       make(map[string]config.ScrubRule),
+	}
+	for _, rule := range conf.Rules {
+		switch rule.Action {
+		case config.ScrubActionDrop:
+		case config.ScrubActionHash:
+			if conf.Salt == "" {
+				return nil, errors.New("scrub salt is required by the hash action")
+			}
+		case config.ScrubActionTruncate:
+			if rule.MaxLength < 0 {
+				return nil, errors.New("scrub maxLength can't be negative")
+			}
+		default:
+			return nil, fmt.Errorf("unknown scrub action: %q", rule.Action)
+		}
+
+		for _, name := range rule.Attributes {
+			s.attributes[name] = rule
+		}
+		for _, name := range rule.Tags {
+			s.tags[name] = rule
+		}
+	}
+	return s, nil
+}
+
+// Scrub returns a copy of the event with the rules applied. The event itself is left untouched.
+func (s *Scrubber) Scrub(userEvent event.UserEvent) event.UserEvent {
+	if userEvent.Impression != nil {
+		impression := *userEvent.Impression
+		impression.Attributes = s.scrubAttributes(impression.Attributes)
+		userEvent.Impression = &impression
+	}
+
+	if userEvent.Conversion != nil {
+		conversion := *userEvent.Conversion
+		conversion.Attributes = s.scrubAttributes(conversion.Attributes)
+		conversion.Tags = s.scrubTags(conversion.Tags)
+		userEvent.Conversion = &conversion
+	}
+	return userEvent
+}
+
+func (s *Scrubber) scrubAttributes(attributes []event.VisitorAttribute) []event.VisitorAttribute {
+	if attributes == nil {
+		return nil
+	}
+
+	scrubbed := make([]event.VisitorAttribute, 0, len(attributes))
+	for _, attribute := range attributes {
+		if rule, ok := s.attributes[attribute.Key]; ok {
+			value, keep := s.apply(rule, attribute.Value)
+			if !keep {
+				continue
+			}
+			attribute.Value = value
+		}
+		scrubbed = append(scrubbed, attribute)
+	}
+	return scrubbed
+}
+
+func (s *Scrubber) scrubTags(tags map[string]interface{}) map[string]interface{} {
+	if tags == nil {
+		return nil
+	}
+
+	scrubbed := make(map[string]interface{}, len(tags))
+	for name, value := range tags {
+		if rule, ok := s.tags[name]; ok {
+			var keep bool
+			if value, keep = s.apply(rule, value); !keep {
+				continue
+			}
+		}
+		scrubbed[name] = value
+	}
+	return scrubbed
+}
+
+// apply returns the value with the rule applied and false when it must be removed
+func (s *Scrubber) apply(rule config.ScrubRule, value interface{}) (interface{}, bool) {
+	switch rule.Action {
+	case config.ScrubActionDrop:
+		return nil, false
+	case config.ScrubActionHash:
+		mac := hmac.New(sha256.New, s.salt)
+		mac.Write([]byte(fmt.Sprint(value)))
+		return hex.EncodeToString(mac.Sum(nil)), true
+	case config.ScrubActionTruncate:
+		if str, ok := value.(string); ok {
+			if runes := []rune(str); len(runes) > rule.MaxLength {
+				return string(runes[:rule.MaxLength]), true
+			}
+		}
+	}
+	return value, true
+}
+
+// ScrubbingQueue scrubs the events added to the queue so that raw values are never queued, persisted or dispatched
+type ScrubbingQueue struct {
+	event.Queue
+	scrubber *Scrubber
+}
+
+// NewScrubbingQueue returns a queue scrubbing the events before adding them to q
+func NewScrubbingQueue(q event.Queue, scrubber *Scrubber) *ScrubbingQueue {
+	return &ScrubbingQueue{Queue: q, scrubber: scrubber}
+}
+
+// Add scrubs the user events before adding them to the queue
+func (q *ScrubbingQueue) Add(item interface{}) {
+	if userEvent, ok := item.(event.UserEvent); ok {
+		item = q.scrubber.Scrub(userEvent)
+	}
+	q.Queue.Add(item)
+}
@@ -0,0 +1,127 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/go-sdk/pkg/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func hmacHex(salt, value string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newScrubConfig() config.ScrubConfig {
+	return config.ScrubConfig{
+		Salt: "salt",
+		Rules: []config.ScrubRule{
+			{Attributes: []string{"ssn"}, Tags: []string{"card"}, Action: config.ScrubActionDrop},
+			{Attributes: []string{"email"}, Tags: []string{"orderId"}, Action: config.ScrubActionHash},
+			{Attributes: []string{"address"}, Tags: []string{"note"}, Action: config.ScrubActionTruncate, MaxLength: 4},
+		},
+	}
+}
+
+func TestNewScrubber(t *testing.T) {
+	scrubber, err := NewScrubber(config.ScrubConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, scrubber)
+
+	scrubber, err = NewScrubber(newScrubConfig())
+	assert.NoError(t, err)
+	assert.NotNil(t, scrubber)
+
+	_, err = NewScrubber(config.ScrubConfig{Rules: []config.ScrubRule{{Attributes: []string{"email"}, Action: config.ScrubActionHash}}})
+	assert.Error(t, err)
+
+	_, err = NewScrubber(config.ScrubConfig{Rules: []config.ScrubRule{{Attributes: []string{"email"}, Action: "encrypt"}}})
+	assert.Error(t, err)
+
+	_, err = NewScrubber(config.ScrubConfig{Rules: []config.ScrubRule{{Attributes: []string{"email"}, Action: config.ScrubActionTruncate, MaxLength: -1}}})
+	assert.Error(t, err)
+}
+
+func TestScrubImpression(t *testing.T) {
+	scrubber, err := NewScrubber(newScrubConfig())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	attributes := []event.VisitorAttribute{
+		{Key: "ssn", Value: "123-45-6789", AttributeType: "custom"},
+		{Key: "email", Value: "user@example.com", AttributeType: "custom"},
+		{Key: "address", Value: "Straße 1", AttributeType: "custom"},
+		{Key: "plan", Value: "gold", AttributeType: "custom"},
+	}
+	userEvent := event.UserEvent{Impression: &event.ImpressionEvent{Key: "campaign", Attributes: attributes}}
+
+	scrubbed := scrubber.Scrub(userEvent)
+	assert.Equal(t, []event.VisitorAttribute{
+		{Key: "email", Value: hmacHex("salt", "user@example.com"), AttributeType: "custom"},
+		{Key: "address", Value: "Stra", AttributeType: "custom"},
+		{Key: "plan", Value: "gold", AttributeType: "custom"},
+	}, scrubbed.Impression.Attributes)
+	assert.Equal(t, "campaign", scrubbed.Impression.Key)
+
+	// The original event, used for decisions, keeps the raw values
+	assert.Len(t, userEvent.Impression.Attributes, 4)
+	assert.Equal(t, "user@example.com", userEvent.Impression.Attributes[1].Value)
+}
+
+func TestScrubConversion(t *testing.T) {
+	scrubber, err := NewScrubber(newScrubConfig())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tags := map[string]interface{}{"card": "4111", "orderId": 42, "note": "gift", "revenue": 1000}
+	userEvent := event.UserEvent{Conversion: &event.ConversionEvent{
+		Key:        "purchase",
+		Attributes: []event.VisitorAttribute{{Key: "ssn", Value: "123-45-6789"}},
+		Tags:       tags,
+	}}
+
+	scrubbed := scrubber.Scrub(userEvent)
+	assert.Empty(t, scrubbed.Conversion.Attributes)
+	assert.Equal(t, map[string]interface{}{"orderId": hmacHex("salt", "42"), "note": "gift", "revenue": 1000}, scrubbed.Conversion.Tags)
+	assert.Equal(t, "4111", tags["card"])
+}
+
+func TestScrubbingQueue(t *testing.T) {
+	scrubber, err := NewScrubber(newScrubConfig())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	q := NewScrubbingQueue(event.NewInMemoryQueue(10), scrubber)
+	q.Add(event.UserEvent{Conversion: &event.ConversionEvent{Tags: map[string]interface{}{"card": "4111"}}})
+	q.Add("not a user event")
+
+	assert.Equal(t, 2, q.Size())
+	queued := q.Remove(2)
+	assert.Empty(t, queued[0].(event.UserEvent).Conversion.Tags)
+	assert.Equal(t, "not a user event", queued[1])
+}
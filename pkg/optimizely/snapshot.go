@@ -0,0 +1,69 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// datafilePath returns the location of the datafile of the SDK key in dir, either its snapshot or its static datafile,
+// empty if dir is not set or the SDK key can't be used as a file name
+func datafilePath(dir, sdkKey string) string {
+	if dir == "" || sdkKey == "" || filepath.Base(sdkKey) != sdkKey {
+		return ""
+	}
+	return filepath.Join(dir, sdkKey+".json")
+}
+
+// saveSnapshot atomically replaces the snapshot at path with the datafile
+func saveSnapshot(path string, datafile []byte) error {
+	if !json.Valid(datafile) {
+		return errors.New("datafile is not valid json")
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(datafile); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// loadSnapshot returns the datafile saved at path
+func loadSnapshot(path string) ([]byte, error) {
+	if path == "" {
+		return nil, errors.New("datafile snapshots are disabled")
+	}
+	return os.ReadFile(path)
+}
@@ -0,0 +1,55 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatafilePath(t *testing.T) {
+	assert.Equal(t, filepath.Join("snapshots", "sdkKey.json"), datafilePath("snapshots", "sdkKey"))
+	assert.Empty(t, datafilePath("", "sdkKey"))
+	assert.Empty(t, datafilePath("snapshots", "../sdkKey"))
+}
+
+func TestSaveAndLoadSnapshot(t *testing.T) {
+	path := datafilePath(filepath.Join(t.TempDir(), "nested"), "sdkKey")
+
+	assert.NoError(t, saveSnapshot(path, []byte(`{"revision": "1"}`)))
+	assert.NoError(t, saveSnapshot(path, []byte(`{"revision": "2"}`)))
+	assert.Error(t, saveSnapshot(path, []byte(`not json`)))
+
+	datafile, err := loadSnapshot(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"revision": "2"}`, string(datafile))
+
+	files, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestLoadSnapshotDisabled(t *testing.T) {
+	_, err := loadSnapshot("")
+	assert.Error(t, err)
+
+	_, err = loadSnapshot(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
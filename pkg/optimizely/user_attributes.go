@@ -0,0 +1,108 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package optimizely //
+package optimizely
+
+import (
+	"context"
+	"errors"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/plugins/userattributes"
+	cmap "github.com/orcaman/concurrent-map"
+	"go.opentelemetry.io/otel"
+)
+
+// ErrUserAttributesUninitialized is returned when no user attributes provider is configured
+var ErrUserAttributesUninitialized = errors.New("client user attributes provider not initialized")
+
+// UserAttributes model describing the attributes stored for a user
+type UserAttributes struct {
+	UserID     string                 `json:"userId"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// newUserAttributesProvider returns the configured user attributes provider, nil when there is none.
+// A single provider is shared by every client since attributes are kept per SDK key.
+func newUserAttributesProvider(conf config.UserAttributesConfigs) (userattributes.Provider, string) {
+	name := getServiceName("", cmap.New(), conf)
+	rawProvider := getServiceWithType(userAttributesPlugin, "", cmap.New(), conf)
+	if provider, ok := rawProvider.(userattributes.Provider); ok && provider != nil {
+		return provider, name
+	}
+	return nil, ""
+}
+
+// GetUserAttributes returns the attributes stored for the user
+func (c *OptlyClient) GetUserAttributes(ctx context.Context, userID string) (*UserAttributes, error) {
+	_, span := otel.Tracer("userAttributesHandler").Start(ctx, "GetUserAttributes")
+	defer span.End()
+
+	if c.UserAttributes == nil {
+		return &UserAttributes{}, ErrUserAttributesUninitialized
+	}
+
+	found, err := c.UserAttributes.Lookup(c.sdkKey, userID)
+	if err != nil {
+		return &UserAttributes{}, err
+	}
+	if found == nil {
+		found = map[string]interface{}{}
+	}
+	return &UserAttributes{UserID: userID, Attributes: found}, nil
+}
+
+// UpsertUserAttributes stores the given attributes of the user, an attribute set to nil is removed.
+// It returns every attribute stored for the user afterwards.
+func (c *OptlyClient) UpsertUserAttributes(ctx context.Context, userID string, attributes map[string]interface{}) (*UserAttributes, error) {
+	_, span := otel.Tracer("userAttributesHandler").Start(ctx, "UpsertUserAttributes")
+	defer span.End()
+
+	if c.UserAttributes == nil {
+		return &UserAttributes{}, ErrUserAttributesUninitialized
+	}
+
+	if err := c.UserAttributes.Upsert(c.sdkKey, userID, attributes); err != nil {
+		return &UserAttributes{}, err
+	}
+	return c.GetUserAttributes(ctx, userID)
+}
+
+// MergeUserAttributes returns the attributes stored for the user overridden by the given ones.
+// The given attributes are returned unchanged, along with the error, when the stored ones can't be looked up.
+func (c *OptlyClient) MergeUserAttributes(userID string, attributes map[string]interface{}) (map[string]interface{}, error) {
+	if c.UserAttributes == nil || userID == "" {
+		return attributes, nil
+	}
+
+	stored, err := c.UserAttributes.Lookup(c.sdkKey, userID)
+	if err != nil {
+		return attributes, err
+	}
+	if len(stored) == 0 {
+		return attributes, nil
+	}
+
+	merged := make(map[string]interface{}, len(stored)+len(attributes))
+	for key, value := range stored {
+		merged[key] = value
+	}
+	for key, value := range attributes {
+		merged[key] = value
+	}
+	return merged, nil
+}
@@ -0,0 +1,119 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package overrides //
+package overrides
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/optimizely/go-sdk/pkg/decision"
+)
+
+// InMemoryStore keeps the overrides of a client in memory, they are lost when the client is evicted
+type InMemoryStore struct {
+	mu        sync.Mutex
+	overrides map[decision.ExperimentOverrideKey]Override
+}
+
+// NewInMemoryStore returns an empty in-memory store
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{overrides: make(map[decision.ExperimentOverrideKey]Override)}
+}
+
+// GetVariation returns the variation forced for the user in the experiment
+func (s *InMemoryStore) GetVariation(key decision.ExperimentOverrideKey) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	override, ok := s.lookup(key, time.Now())
+	return override.VariationKey, ok
+}
+
+// Set stores the override
+func (s *InMemoryStore) Set(_ context.Context, override Override) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, _ := s.lookup(override.Key(), time.Now())
+	s.overrides[override.Key()] = override
+	return prev.VariationKey, nil
+}
+
+// Remove deletes the override
+func (s *InMemoryStore) Remove(_ context.Context, key decision.ExperimentOverrideKey) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, _ := s.lookup(key, time.Now())
+	delete(s.overrides, key)
+	return prev.VariationKey, nil
+}
+
+// List returns the overrides selected by the filter
+func (s *InMemoryStore) List(_ context.Context, filter Filter) ([]Override, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	overrides := []Override{}
+	for key, override := range s.overrides {
+		if override.Expired(now) {
+			delete(s.overrides, key)
+			continue
+		}
+		if filter.Match(override) {
+			overrides = append(overrides, override)
+		}
+	}
+	sortOverrides(overrides)
+	return overrides, nil
+}
+
+// Clear deletes the overrides selected by the filter
+func (s *InMemoryStore) Clear(_ context.Context, filter Filter) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	cleared := 0
+	for key, override := range s.overrides {
+		if override.Expired(now) {
+			delete(s.overrides, key)
+			continue
+		}
+		if filter.Match(override) {
+			delete(s.overrides, key)
+			cleared++
+		}
+	}
+	return cleared, nil
+}
+
+// lookup returns the override with the given key, an expired override is deleted
+func (s *InMemoryStore) lookup(key decision.ExperimentOverrideKey, now time.Time) (Override, bool) {
+	override, ok := s.overrides[key]
+	if !ok {
+		return Override{}, false
+	}
+	if override.Expired(now) {
+		delete(s.overrides, key)
+		return Override{}, false
+	}
+	return override, true
+}
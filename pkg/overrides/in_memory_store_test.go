@@ -0,0 +1,117 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package overrides //
+package overrides
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/optimizely/go-sdk/pkg/decision"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	key := decision.ExperimentOverrideKey{UserID: "user", ExperimentKey: "experiment"}
+
+	_, ok := store.GetVariation(key)
+	assert.False(t, ok)
+
+	prev, err := store.Set(ctx, Override{UserID: "user", ExperimentKey: "experiment", VariationKey: "a"})
+	assert.NoError(t, err)
+	assert.Empty(t, prev)
+
+	prev, err = store.Set(ctx, Override{UserID: "user", ExperimentKey: "experiment", VariationKey: "b"})
+	assert.NoError(t, err)
+	assert.Equal(t, "a", prev)
+
+	variation, ok := store.GetVariation(key)
+	assert.True(t, ok)
+	assert.Equal(t, "b", variation)
+
+	prev, err = store.Remove(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", prev)
+
+	_, ok = store.GetVariation(key)
+	assert.False(t, ok)
+
+	prev, err = store.Remove(ctx, key)
+	assert.NoError(t, err)
+	assert.Empty(t, prev)
+}
+
+func TestInMemoryStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	past := time.Now().Add(-time.Second)
+	future := time.Now().Add(time.Hour)
+
+	_, _ = store.Set(ctx, Override{UserID: "user", ExperimentKey: "expired", VariationKey: "a", ExpiresAt: &past})
+	_, _ = store.Set(ctx, Override{UserID: "user", ExperimentKey: "active", VariationKey: "b", ExpiresAt: &future})
+
+	_, ok := store.GetVariation(decision.ExperimentOverrideKey{UserID: "user", ExperimentKey: "expired"})
+	assert.False(t, ok)
+	variation, ok := store.GetVariation(decision.ExperimentOverrideKey{UserID: "user", ExperimentKey: "active"})
+	assert.True(t, ok)
+	assert.Equal(t, "b", variation)
+
+	// Replacing an expired override doesn't report it
+	prev, err := store.Set(ctx, Override{UserID: "user", ExperimentKey: "expired", VariationKey: "c", ExpiresAt: &past})
+	assert.NoError(t, err)
+	assert.Empty(t, prev)
+
+	overrides, err := store.List(ctx, Filter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []Override{{UserID: "user", ExperimentKey: "active", VariationKey: "b", ExpiresAt: &future}}, overrides)
+	assert.Len(t, store.overrides, 1)
+}
+
+func TestInMemoryStoreListAndClear(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	_, _ = store.Set(ctx, Override{UserID: "user2", ExperimentKey: "exp1", VariationKey: "a"})
+	_, _ = store.Set(ctx, Override{UserID: "user1", ExperimentKey: "exp2", VariationKey: "b"})
+	_, _ = store.Set(ctx, Override{UserID: "user1", ExperimentKey: "exp1", VariationKey: "c"})
+
+	overrides, err := store.List(ctx, Filter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []Override{
+		{UserID: "user1", ExperimentKey: "exp1", VariationKey: "c"},
+		{UserID: "user1", ExperimentKey: "exp2", VariationKey: "b"},
+		{UserID: "user2", ExperimentKey: "exp1", VariationKey: "a"},
+	}, overrides)
+
+	overrides, err = store.List(ctx, Filter{ExperimentKey: "exp1"})
+	assert.NoError(t, err)
+	assert.Len(t, overrides, 2)
+
+	overrides, err = store.List(ctx, Filter{UserID: "user1", ExperimentKey: "exp2"})
+	assert.NoError(t, err)
+	assert.Equal(t, []Override{{UserID: "user1", ExperimentKey: "exp2", VariationKey: "b"}}, overrides)
+
+	cleared, err := store.Clear(ctx, Filter{UserID: "user1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, cleared)
+
+	overrides, err = store.List(ctx, Filter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []Override{{UserID: "user2", ExperimentKey: "exp1", VariationKey: "a"}}, overrides)
+}
@@ -0,0 +1,295 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package overrides //
+package overrides
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/go-sdk/pkg/decision"
+	"github.com/rs/zerolog/log"
+)
+
+// deleteIfUnchanged deletes the field of the hash only when it still holds the given value
+const deleteIfUnchanged = `
+if redis.call("HGET", KEYS[1], ARGV[1]) == ARGV[2] then
+	return redis.call("HDEL", KEYS[1], ARGV[1])
+end
+return 0`
+
+// defaultRefreshInterval is the time between refreshes of the local copy of the overrides when none is configured
+const defaultRefreshInterval = time.Second
+
+// refreshTimeout is the time limit of a refresh of the local copy of the overrides
+const refreshTimeout = time.Second
+
+// refreshWarningInterval is the minimum time between two warnings about failed refreshes
+const refreshWarningInterval = time.Minute
+
+// RedisStore keeps the overrides of a client in a Redis hash shared by every Agent node.
+// The fields of the hash are the user and experiment of the overrides.
+// Variations are looked up in a local copy of the hash so that decisions never wait on Redis, the copy is refreshed
+// in the background every refresh interval and updated right away by the changes made through the store.
+type RedisStore struct {
+	client          *redis.Client
+	key             string
+	refreshInterval time.Duration
+
+	loadOnce    sync.Once
+	mu          sync.Mutex
+	overrides   map[string]Override
+	refreshedAt time.Time
+	refreshing  bool
+	writes      uint64
+	warnedAt    time.Time
+}
+
+func newRedisClient(conf config.OverridesRedisConfig) (*redis.Client, error) {
+	if conf.Host == "" {
+		return nil, errors.New("overrides redis host not provided")
+	}
+	if conf.Prefix == "" {
+		return nil, errors.New("overrides redis prefix not provided")
+	}
+
+	return redis.NewClient(&redis.Options{
+		Addr:     conf.Host,
+		Password: conf.Password,
+		DB:       conf.Database,
+	}), nil
+}
+
+// NewRedisStore returns the store of the overrides of the client with the given SDK key.
+// The local copy of the overrides is refreshed every refreshInterval, 0 uses the default.
+func NewRedisStore(client *redis.Client, prefix, sdkKey string, refreshInterval time.Duration) *RedisStore {
+	if refreshInterval <= 0 {
+		refreshInterval = defaultRefreshInterval
+	}
+	return &RedisStore{
+		client:          client,
+		key:             prefix + ":" + sdkKey,
+		refreshInterval: refreshInterval,
+		overrides:       make(map[string]Override),
+	}
+}
+
+// GetVariation returns the variation forced for the user in the experiment, as of the last refresh.
+// The first lookup waits for the overrides to be loaded, the next ones start a refresh once the copy is stale.
+// No variation is forced when Redis couldn't be reached yet.
+func (s *RedisStore) GetVariation(key decision.ExperimentOverrideKey) (string, bool) {
+	s.loadOnce.Do(s.refresh)
+
+	s.mu.Lock()
+	if !s.refreshing && time.Since(s.refreshedAt) >= s.refreshInterval {
+		s.refreshing = true
+		go s.refresh()
+	}
+	override, ok := s.overrides[field(key)]
+	s.mu.Unlock()
+
+	if !ok || override.Expired(time.Now()) {
+		return "", false
+	}
+	return override.VariationKey, true
+}
+
+// refresh replaces the local copy with the overrides of the hash. The copy is kept when the refresh fails,
+// or when the store changed it during the refresh so that the change is not undone.
+func (s *RedisStore) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+	defer cancel()
+
+	s.mu.Lock()
+	writes := s.writes
+	s.mu.Unlock()
+
+	overrides := make(map[string]Override)
+	err := s.scan(ctx, func(f string, override Override) {
+		overrides[f] = override
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshing = false
+	s.refreshedAt = time.Now()
+	if err != nil {
+		if time.Since(s.warnedAt) >= refreshWarningInterval {
+			log.Warn().Err(err).Msg("Failed to refresh overrides, using the last ones loaded")
+			s.warnedAt = time.Now()
+		}
+		return
+	}
+	if s.writes == writes {
+		s.overrides = overrides
+	}
+}
+
+// update applies a change made through the store to the local copy
+func (s *RedisStore) update(fn func(overrides map[string]Override)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writes++
+	fn(s.overrides)
+}
+
+// Set stores the override
+func (s *RedisStore) Set(ctx context.Context, override Override) (string, error) {
+	b, err := json.Marshal(override)
+	if err != nil {
+		return "", err
+	}
+
+	var prev *redis.StringCmd
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		prev = pipe.HGet(ctx, s.key, field(override.Key()))
+		pipe.HSet(ctx, s.key, field(override.Key()), b)
+		return nil
+	})
+	prevVariationKey, err := prevVariationKey(prev, err)
+	if err == nil {
+		s.update(func(overrides map[string]Override) {
+			overrides[field(override.Key())] = override
+		})
+	}
+	return prevVariationKey, err
+}
+
+// Remove deletes the override
+func (s *RedisStore) Remove(ctx context.Context, key decision.ExperimentOverrideKey) (string, error) {
+	var prev *redis.StringCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		prev = pipe.HGet(ctx, s.key, field(key))
+		pipe.HDel(ctx, s.key, field(key))
+		return nil
+	})
+	prevVariationKey, err := prevVariationKey(prev, err)
+	if err == nil {
+		s.update(func(overrides map[string]Override) {
+			delete(overrides, field(key))
+		})
+	}
+	return prevVariationKey, err
+}
+
+// List returns the overrides selected by the filter
+func (s *RedisStore) List(ctx context.Context, filter Filter) ([]Override, error) {
+	overrides := []Override{}
+	err := s.scan(ctx, func(_ string, override Override) {
+		if filter.Match(override) {
+			overrides = append(overrides, override)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortOverrides(overrides)
+	return overrides, nil
+}
+
+// Clear deletes the overrides selected by the filter
+func (s *RedisStore) Clear(ctx context.Context, filter Filter) (int, error) {
+	fields := []string{}
+	err := s.scan(ctx, func(f string, override Override) {
+		if filter.Match(override) {
+			fields = append(fields, f)
+		}
+	})
+	if err != nil || len(fields) == 0 {
+		return 0, err
+	}
+
+	cleared, err := s.client.HDel(ctx, s.key, fields...).Result()
+	if err == nil {
+		s.update(func(overrides map[string]Override) {
+			for _, f := range fields {
+				delete(overrides, f)
+			}
+		})
+	}
+	return int(cleared), err
+}
+
+// scan calls fn with every override of the hash, expired and unreadable overrides are deleted
+func (s *RedisStore) scan(ctx context.Context, fn func(field string, override Override)) error {
+	values, err := s.client.HGetAll(ctx, s.key).Result()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	stale := make(map[string]string)
+	for f, value := range values {
+		override, ok := decode(value, now)
+		if !ok {
+			stale[f] = value
+			continue
+		}
+		fn(f, override)
+	}
+
+	if len(stale) > 0 {
+		_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for f, value := range stale {
+				pipe.Eval(ctx, deleteIfUnchanged, []string{s.key}, f, value)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to delete expired overrides")
+		}
+	}
+	return nil
+}
+
+// prevVariationKey returns the variation of the override read before it was replaced or deleted
+func prevVariationKey(prev *redis.StringCmd, err error) (string, error) {
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+	value, err := prev.Result()
+	if err != nil {
+		return "", nil
+	}
+
+	override, _ := decode(value, time.Now())
+	return override.VariationKey, nil
+}
+
+// decode reads a stored override, ok is false when it can't be read or has expired
+func decode(value string, now time.Time) (override Override, ok bool) {
+	if err := json.Unmarshal([]byte(value), &override); err != nil {
+		log.Warn().Err(err).Msg("Ignoring unreadable override")
+		return Override{}, false
+	}
+	if override.Expired(now) {
+		return Override{}, false
+	}
+	return override, true
+}
+
+// field returns the hash field of the override of the user in the experiment
+func field(key decision.ExperimentOverrideKey) string {
+	b, _ := json.Marshal([]string{key.UserID, key.ExperimentKey})
+	return string(b)
+}
@@ -0,0 +1,138 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package overrides //
+package overrides
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/go-sdk/pkg/decision"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRedisClient(t *testing.T) {
+	_, err := newRedisClient(config.OverridesRedisConfig{Prefix: "overrides"})
+	assert.Error(t, err)
+
+	_, err = newRedisClient(config.OverridesRedisConfig{Host: "localhost:6379"})
+	assert.Error(t, err)
+
+	client, err := newRedisClient(config.OverridesRedisConfig{Host: "localhost:6379", Prefix: "overrides"})
+	assert.NoError(t, err)
+	assert.Equal(t, "overrides:sdkKey", NewRedisStore(client, "overrides", "sdkKey", 0).key)
+}
+
+func TestRedisStoreUnavailable(t *testing.T) {
+	client, err := newRedisClient(config.OverridesRedisConfig{Host: "127.0.0.1:1", Prefix: "overrides"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	store := NewRedisStore(client, "overrides", "sdkKey", 0)
+	key := decision.ExperimentOverrideKey{UserID: "user", ExperimentKey: "experiment"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, ok := store.GetVariation(key)
+	assert.False(t, ok)
+	_, err = store.Set(ctx, Override{UserID: "user", ExperimentKey: "experiment", VariationKey: "a"})
+	assert.Error(t, err)
+	_, err = store.Remove(ctx, key)
+	assert.Error(t, err)
+	_, err = store.List(ctx, Filter{})
+	assert.Error(t, err)
+	_, err = store.Clear(ctx, Filter{})
+	assert.Error(t, err)
+}
+
+func TestRedisStoreLocalCopy(t *testing.T) {
+	client, err := newRedisClient(config.OverridesRedisConfig{Host: "127.0.0.1:1", Prefix: "overrides"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	store := NewRedisStore(client, "overrides", "sdkKey", time.Hour)
+	assert.Equal(t, time.Hour, store.refreshInterval)
+	assert.Equal(t, defaultRefreshInterval, NewRedisStore(client, "overrides", "sdkKey", 0).refreshInterval)
+
+	// Loaded and fresh, so the lookups never reach Redis
+	store.loadOnce.Do(func() {})
+	store.refreshedAt = time.Now()
+	past := time.Now().Add(-time.Second)
+	store.update(func(overrides map[string]Override) {
+		a := Override{UserID: "user", ExperimentKey: "a", VariationKey: "a1"}
+		b := Override{UserID: "user", ExperimentKey: "b", VariationKey: "b1", ExpiresAt: &past}
+		overrides[field(a.Key())] = a
+		overrides[field(b.Key())] = b
+	})
+
+	variation, ok := store.GetVariation(decision.ExperimentOverrideKey{UserID: "user", ExperimentKey: "a"})
+	assert.True(t, ok)
+	assert.Equal(t, "a1", variation)
+	_, ok = store.GetVariation(decision.ExperimentOverrideKey{UserID: "user", ExperimentKey: "b"})
+	assert.False(t, ok)
+	_, ok = store.GetVariation(decision.ExperimentOverrideKey{UserID: "other", ExperimentKey: "a"})
+	assert.False(t, ok)
+	assert.False(t, store.refreshing)
+}
+
+func TestRedisStoreRefreshFailure(t *testing.T) {
+	client, err := newRedisClient(config.OverridesRedisConfig{Host: "127.0.0.1:1", Prefix: "overrides"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	store := NewRedisStore(client, "overrides", "sdkKey", time.Hour)
+	override := Override{UserID: "user", ExperimentKey: "a", VariationKey: "a1"}
+	store.update(func(overrides map[string]Override) {
+		overrides[field(override.Key())] = override
+	})
+
+	// The failed load keeps the overrides known so far and warns once
+	variation, ok := store.GetVariation(override.Key())
+	assert.True(t, ok)
+	assert.Equal(t, "a1", variation)
+	warnedAt := store.warnedAt
+	assert.False(t, warnedAt.IsZero())
+	assert.False(t, store.refreshedAt.IsZero())
+
+	store.refresh()
+	assert.Equal(t, warnedAt, store.warnedAt)
+	_, ok = store.GetVariation(override.Key())
+	assert.True(t, ok)
+}
+
+func TestRedisField(t *testing.T) {
+	// User IDs and experiment keys may hold any character so the field can't be a plain concatenation
+	assert.Equal(t, `["a:b","c"]`, field(decision.ExperimentOverrideKey{UserID: "a:b", ExperimentKey: "c"}))
+	assert.NotEqual(t, field(decision.ExperimentOverrideKey{UserID: "a", ExperimentKey: "b:c"}),
+		field(decision.ExperimentOverrideKey{UserID: "a:b", ExperimentKey: "c"}))
+}
+
+func TestDecode(t *testing.T) {
+	past := time.Now().Add(-time.Second)
+	b := `{"userId":"user","experimentKey":"experiment","variationKey":"a","expiresAt":"` + past.Format(time.RFC3339Nano) + `"}`
+
+	_, ok := decode(b, time.Now())
+	assert.False(t, ok)
+	_, ok = decode("not json", time.Now())
+	assert.False(t, ok)
+
+	override, ok := decode(b, past.Add(-time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, "a", override.VariationKey)
+}
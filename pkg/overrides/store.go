@@ -0,0 +1,104 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package overrides keeps the forced variations set with the override endpoint
+package overrides
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/optimizely/agent/config"
+	"github.com/optimizely/go-sdk/pkg/decision"
+)
+
+// Override forces the variation of an experiment for a user
+type Override struct {
+	UserID        string     `json:"userId"`
+	ExperimentKey string     `json:"experimentKey"`
+	VariationKey  string     `json:"variationKey"`
+	ExpiresAt     *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Key returns the key of the override in the experiment overrides store
+func (o Override) Key() decision.ExperimentOverrideKey {
+	return decision.ExperimentOverrideKey{UserID: o.UserID, ExperimentKey: o.ExperimentKey}
+}
+
+// Expired returns true once the override has expired
+func (o Override) Expired(now time.Time) bool {
+	return o.ExpiresAt != nil && !now.Before(*o.ExpiresAt)
+}
+
+// Filter selects overrides by user and experiment, an empty field matches any value
+type Filter struct {
+	UserID        string
+	ExperimentKey string
+}
+
+// Match returns true when the override is selected by the filter
+func (f Filter) Match(o Override) bool {
+	return (f.UserID == "" || f.UserID == o.UserID) && (f.ExperimentKey == "" || f.ExperimentKey == o.ExperimentKey)
+}
+
+// Store holds the overrides of a client, it is used by the SDK to look up the forced variations.
+// Expired overrides are ignored and removed lazily.
+type Store interface {
+	decision.ExperimentOverrideStore
+	// Set stores the override and returns the variation it replaces, if any
+	Set(ctx context.Context, override Override) (prevVariationKey string, err error)
+	// Remove deletes the override and returns its variation, empty when there was none
+	Remove(ctx context.Context, key decision.ExperimentOverrideKey) (prevVariationKey string, err error)
+	// List returns the overrides selected by the filter, sorted by user and experiment
+	List(ctx context.Context, filter Filter) ([]Override, error)
+	// Clear deletes the overrides selected by the filter and returns how many were deleted
+	Clear(ctx context.Context, filter Filter) (int, error)
+}
+
+// StoreFactory returns the store of the client with the given SDK key
+type StoreFactory func(sdkKey string) Store
+
+// NewStoreFactory returns the factory of the stores described by conf.
+// In-memory stores are local to a client while Redis stores of every client share a connection.
+func NewStoreFactory(conf config.OverridesConfig) (StoreFactory, error) {
+	switch conf.Store {
+	case "", config.OverridesStoreInMemory:
+		return func(string) Store {
+			return NewInMemoryStore()
+		}, nil
+	case config.OverridesStoreRedis:
+		client, err := newRedisClient(conf.Redis)
+		if err != nil {
+			return nil, err
+		}
+		return func(sdkKey string) Store {
+			return NewRedisStore(client, conf.Redis.Prefix, sdkKey, conf.Redis.RefreshInterval)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown overrides store: %q", conf.Store)
+	}
+}
+
+func sortOverrides(overrides []Override) {
+	sort.Slice(overrides, func(i, j int) bool {
+		if overrides[i].UserID != overrides[j].UserID {
+			return overrides[i].UserID < overrides[j].UserID
+		}
+		return overrides[i].ExperimentKey < overrides[j].ExperimentKey
+	})
+}
@@ -0,0 +1,66 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package overrides //
+package overrides
+
+import (
+	"testing"
+	"time"
+
+	"github.com/optimizely/agent/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStoreFactory(t *testing.T) {
+	factory, err := NewStoreFactory(config.OverridesConfig{Store: config.OverridesStoreInMemory})
+	if assert.NoError(t, err) {
+		assert.IsType(t, &InMemoryStore{}, factory("sdkKey"))
+		// Every client has its own in-memory store
+		assert.NotSame(t, factory("sdkKey"), factory("sdkKey"))
+	}
+
+	factory, err = NewStoreFactory(config.OverridesConfig{Store: config.OverridesStoreRedis, Redis: config.OverridesRedisConfig{Host: "localhost:6379", Prefix: "overrides"}})
+	if assert.NoError(t, err) {
+		store := factory("sdkKey")
+		assert.IsType(t, &RedisStore{}, store)
+		assert.Same(t, store.(*RedisStore).client, factory("other").(*RedisStore).client)
+	}
+
+	_, err = NewStoreFactory(config.OverridesConfig{Store: config.OverridesStoreRedis})
+	assert.Error(t, err)
+
+	_, err = NewStoreFactory(config.OverridesConfig{Store: "unknown"})
+	assert.Error(t, err)
+}
+
+func TestOverrideExpired(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Minute)
+
+	assert.False(t, Override{}.Expired(now))
+	assert.False(t, Override{ExpiresAt: &later}.Expired(now))
+	assert.True(t, Override{ExpiresAt: &later}.Expired(later))
+}
+
+func TestFilterMatch(t *testing.T) {
+	override := Override{UserID: "user", ExperimentKey: "experiment"}
+
+	assert.True(t, Filter{}.Match(override))
+	assert.True(t, Filter{UserID: "user"}.Match(override))
+	assert.True(t, Filter{ExperimentKey: "experiment"}.Match(override))
+	assert.False(t, Filter{UserID: "user", ExperimentKey: "other"}.Match(override))
+}
@@ -22,16 +22,19 @@ import (
 	"net/http/pprof"
 
 	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/pkg/deadletter"
 	"github.com/optimizely/agent/pkg/handlers"
 	"github.com/optimizely/agent/pkg/middleware"
+	"github.com/optimizely/agent/pkg/optimizely"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"github.com/optimizely/go-sdk/pkg/event"
 	"github.com/rs/zerolog/log"
 )
 
 // NewAdminRouter returns HTTP admin router
-func NewAdminRouter(conf config.AgentConfig) http.Handler {
+func NewAdminRouter(conf config.AgentConfig, clientManager optimizely.ClientManager) http.Handler {
 	r := chi.NewRouter()
 
 	authProvider := middleware.NewAuth(&conf.Admin.Auth)
@@ -55,6 +58,27 @@ func NewAdminRouter(conf config.AgentConfig) http.Handler {
 	r.With(authProvider.AuthorizeAdmin).Get("/info", optlyAdmin.AppInfo)
 	r.With(authProvider.AuthorizeAdmin).Get("/metrics", optlyAdmin.Metrics)
 
+	if clientManager != nil {
+		clientsAdmin := handlers.NewClientsAdmin(clientManager)
+		r.With(authProvider.AuthorizeAdmin).Get("/clients", clientsAdmin.ListClients)
+		r.With(authProvider.AuthorizeAdmin).Get("/clients/{sdkKey}", clientsAdmin.GetClient)
+		r.With(authProvider.AuthorizeAdmin).Post("/clients/{sdkKey}/resync", clientsAdmin.ResyncClient)
+		r.With(authProvider.AuthorizeAdmin).Delete("/clients/{sdkKey}", clientsAdmin.EvictClient)
+	}
+
+	deadLetters, err := deadletter.NewStore(conf.Client.DeadLetter)
+	if err != nil {
+		log.Error().Err(err).Msg("unable to initialize dead-letter store.")
+	} else if deadLetters != nil {
+		deadLettersAdmin := handlers.NewDeadLettersAdmin(deadLetters, event.NewHTTPEventDispatcher("", nil, nil))
+		r.With(authProvider.AuthorizeAdmin).Get("/dead-letters", deadLettersAdmin.ListDeadLetters)
+		r.With(authProvider.AuthorizeAdmin).Delete("/dead-letters", deadLettersAdmin.PurgeDeadLetters)
+		r.With(authProvider.AuthorizeAdmin).Post("/dead-letters/replay", deadLettersAdmin.ReplayDeadLetters)
+		r.With(authProvider.AuthorizeAdmin).Get("/dead-letters/{id}", deadLettersAdmin.GetDeadLetter)
+		r.With(authProvider.AuthorizeAdmin).Delete("/dead-letters/{id}", deadLettersAdmin.DeleteDeadLetter)
+		r.With(authProvider.AuthorizeAdmin).Post("/dead-letters/{id}/replay", deadLettersAdmin.ReplayDeadLetter)
+	}
+
 	r.With(authProvider.AuthorizeAdmin).Get("/debug/pprof/*", pprof.Index)
 	r.With(authProvider.AuthorizeAdmin).Get("/debug/pprof/cmdline", pprof.Cmdline)
 	r.With(authProvider.AuthorizeAdmin).Get("/debug/pprof/profile", pprof.Profile)
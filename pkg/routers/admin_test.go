@@ -30,7 +30,7 @@ import (
 func TestAdminAllowedContentTypeMiddleware(t *testing.T) {
 
 	conf := config.NewDefaultConfig()
-	router := NewAdminRouter(*conf)
+	router := NewAdminRouter(*conf, nil)
 
 	// Testing unsupported content type
 	body := "<request> <parameters> <email>test@123.com</email> </parameters> </request>"
@@ -48,3 +48,21 @@ func TestAdminAllowedContentTypeMiddleware(t *testing.T) {
 	router.ServeHTTP(rec, req)
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
+
+func TestAdminDeadLetterRoutes(t *testing.T) {
+	conf := config.NewDefaultConfig()
+	router := NewAdminRouter(*conf, nil)
+	req := httptest.NewRequest("GET", "/dead-letters", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	conf.Client.DeadLetter.Store = config.DeadLetterStoreDisk
+	conf.Client.DeadLetter.Dir = t.TempDir()
+	router = NewAdminRouter(*conf, nil)
+	req = httptest.NewRequest("GET", "/dead-letters", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, "[]", rec.Body.String())
+}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/optimizely/agent/config"
 	"github.com/optimizely/agent/pkg/handlers"
+	"github.com/optimizely/agent/pkg/idempotency"
 	"github.com/optimizely/agent/pkg/metrics"
 	"github.com/optimizely/agent/pkg/middleware"
 	"github.com/optimizely/agent/pkg/optimizely"
@@ -38,22 +39,36 @@ import (
 
 // APIOptions defines the configuration parameters for Router.
 type APIOptions struct {
-	maxConns            int
-	sdkMiddleware       func(next http.Handler) http.Handler
-	metricsRegistry     *metrics.Registry
-	configHandler       http.HandlerFunc
-	datafileHandler     http.HandlerFunc
-	activateHandler     http.HandlerFunc
-	decideHandler       http.HandlerFunc
-	trackHandler        http.HandlerFunc
-	overrideHandler     http.HandlerFunc
-	lookupHandler       http.HandlerFunc
-	saveHandler         http.HandlerFunc
-	sendOdpEventHandler http.HandlerFunc
-	nStreamHandler      http.HandlerFunc
-	oAuthHandler        http.HandlerFunc
-	oAuthMiddleware     func(next http.Handler) http.Handler
-	corsHandler         func(next http.Handler) http.Handler
+	maxConns                    int
+	sdkMiddleware               func(next http.Handler) http.Handler
+	metricsRegistry             *metrics.Registry
+	configHandler               http.HandlerFunc
+	datafileHandler             http.HandlerFunc
+	activateHandler             http.HandlerFunc
+	decideHandler               http.HandlerFunc
+	bulkDecideHandler           http.HandlerFunc
+	explainHandler              http.HandlerFunc
+	trackHandler                http.HandlerFunc
+	trackBatchHandler           http.HandlerFunc
+	overrideHandler             http.HandlerFunc
+	listOverridesHandler        http.HandlerFunc
+	clearOverridesHandler       http.HandlerFunc
+	getForcedDecisionsHandler   http.HandlerFunc
+	saveForcedDecisionHandler   http.HandlerFunc
+	removeForcedDecisionHandler http.HandlerFunc
+	resetForcedDecisionsHandler http.HandlerFunc
+	getUserAttributesHandler    http.HandlerFunc
+	upsertUserAttributesHandler http.HandlerFunc
+	lookupHandler               http.HandlerFunc
+	saveHandler                 http.HandlerFunc
+	sendOdpEventHandler         http.HandlerFunc
+	ofrepEvaluateHandler        http.HandlerFunc
+	ofrepBulkEvaluateHandler    http.HandlerFunc
+	nStreamHandler              http.HandlerFunc
+	nWebSocketHandler           http.HandlerFunc
+	oAuthHandler                http.HandlerFunc
+	oAuthMiddleware             func(next http.Handler) http.Handler
+	corsHandler                 func(next http.Handler) http.Handler
 }
 
 func forbiddenHandler(message string) http.HandlerFunc {
@@ -77,38 +92,81 @@ func NewDefaultAPIRouter(optlyCache optimizely.Cache, conf config.AgentConfig, m
 	}
 
 	overrideHandler := handlers.Override
+	listOverridesHandler := handlers.ListOverrides
+	clearOverridesHandler := handlers.ClearOverrides
+	getForcedDecisionsHandler := handlers.GetForcedDecisions
+	saveForcedDecisionHandler := handlers.SaveForcedDecision
+	removeForcedDecisionHandler := handlers.RemoveForcedDecision
+	resetForcedDecisionsHandler := handlers.ResetForcedDecisions
 	if !conf.API.EnableOverrides {
 		overrideHandler = forbiddenHandler("Overrides not enabled")
+		listOverridesHandler = forbiddenHandler("Overrides not enabled")
+		clearOverridesHandler = forbiddenHandler("Overrides not enabled")
+		getForcedDecisionsHandler = forbiddenHandler("Overrides not enabled")
+		saveForcedDecisionHandler = forbiddenHandler("Overrides not enabled")
+		removeForcedDecisionHandler = forbiddenHandler("Overrides not enabled")
+		resetForcedDecisionsHandler = forbiddenHandler("Overrides not enabled")
+	}
+
+	getUserAttributesHandler := handlers.GetUserAttributes
+	upsertUserAttributesHandler := handlers.UpsertUserAttributes
+	if !conf.API.EnableUserAttributes {
+		getUserAttributesHandler = forbiddenHandler("User attributes not enabled")
+		upsertUserAttributesHandler = forbiddenHandler("User attributes not enabled")
 	}
 
 	nStreamHandler := forbiddenHandler("Notification stream not enabled")
+	nWebSocketHandler := forbiddenHandler("Notification stream not enabled")
 	if conf.API.EnableNotifications {
-		nStreamHandler = handlers.NotificationEventStreamHandler(handlers.DefaultNotificationReceiver)
+		notificationReceiver := handlers.DefaultNotificationReceiver
 		if conf.Synchronization.Notification.Enable {
-			nStreamHandler = handlers.NotificationEventStreamHandler(handlers.RedisNotificationReceiver(conf.Synchronization))
+			notificationReceiver = handlers.RedisNotificationReceiver(conf.Synchronization)
 		}
+		nStreamHandler = handlers.NotificationEventStreamHandler(notificationReceiver, conf.API.EventStream)
+		nWebSocketHandler = handlers.NotificationWebSocketHandler(notificationReceiver, conf.API.WebSocket, conf.API.CORS.AllowedOrigins)
+	}
+
+	trackBatchHandler := forbiddenHandler("Batch track not enabled")
+	if idempotencyKeys, err := idempotency.NewStore(conf.API.TrackBatch.Idempotency); err != nil {
+		log.Error().Err(err).Msg("unable to initialize batch track idempotency store, batch track is disabled.")
+	} else {
+		trackBatchHandler = handlers.TrackBatch(conf.API.TrackBatch, idempotencyKeys)
 	}
 
 	mw := middleware.CachedOptlyMiddleware{Cache: optlyCache}
 	corsHandler := createCorsHandler(conf.API.CORS)
 
 	spec := &APIOptions{
-		maxConns:            conf.API.MaxConns,
-		metricsRegistry:     metricsRegistry,
-		configHandler:       handlers.OptimizelyConfig,
-		datafileHandler:     handlers.GetDatafile,
-		activateHandler:     handlers.Activate,
-		decideHandler:       handlers.Decide,
-		overrideHandler:     overrideHandler,
-		lookupHandler:       handlers.Lookup,
-		saveHandler:         handlers.Save,
-		trackHandler:        handlers.TrackEvent,
-		sendOdpEventHandler: handlers.SendOdpEvent,
-		sdkMiddleware:       mw.ClientCtx,
-		nStreamHandler:      nStreamHandler,
-		oAuthHandler:        authHandler.CreateAPIAccessToken,
-		oAuthMiddleware:     authProvider.AuthorizeAPI,
-		corsHandler:         corsHandler,
+		maxConns:                    conf.API.MaxConns,
+		metricsRegistry:             metricsRegistry,
+		configHandler:               handlers.OptimizelyConfig,
+		datafileHandler:             handlers.GetDatafile,
+		activateHandler:             handlers.Activate,
+		decideHandler:               handlers.Decide,
+		bulkDecideHandler:           handlers.BulkDecide(conf.API.BulkDecide),
+		explainHandler:              handlers.DecideExplain,
+		overrideHandler:             overrideHandler,
+		listOverridesHandler:        listOverridesHandler,
+		clearOverridesHandler:       clearOverridesHandler,
+		getForcedDecisionsHandler:   getForcedDecisionsHandler,
+		saveForcedDecisionHandler:   saveForcedDecisionHandler,
+		removeForcedDecisionHandler: removeForcedDecisionHandler,
+		resetForcedDecisionsHandler: resetForcedDecisionsHandler,
+		getUserAttributesHandler:    getUserAttributesHandler,
+		upsertUserAttributesHandler: upsertUserAttributesHandler,
+		lookupHandler:               handlers.Lookup,
+		saveHandler:                 handlers.Save,
+		trackHandler:                handlers.TrackEvent,
+		trackBatchHandler:           trackBatchHandler,
+		sendOdpEventHandler:         handlers.SendOdpEvent,
+		ofrepEvaluateHandler:        handlers.OFREPEvaluateFlag,
+		ofrepBulkEvaluateHandler:    handlers.OFREPEvaluateFlags,
+		sdkMiddleware:               mw.ClientCtx,
+		nStreamHandler:              nStreamHandler,
+		nWebSocketHandler:           nWebSocketHandler,
+		oAuthHandler:                authHandler.CreateAPIAccessToken,
+		oAuthMiddleware:             authProvider.AuthorizeAPI,
+		corsHandler:                 corsHandler,
 	}
 
 	return NewAPIRouter(spec)
@@ -128,11 +186,24 @@ func WithAPIRouter(opt *APIOptions, r chi.Router) {
 	getDatafileTimer := middleware.Metricize("get-datafile", opt.metricsRegistry)
 	activateTimer := middleware.Metricize("activate", opt.metricsRegistry)
 	decideTimer := middleware.Metricize("decide", opt.metricsRegistry)
+	bulkDecideTimer := middleware.Metricize("decide-bulk", opt.metricsRegistry)
+	explainTimer := middleware.Metricize("decide-explain", opt.metricsRegistry)
 	overrideTimer := middleware.Metricize("override", opt.metricsRegistry)
+	listOverridesTimer := middleware.Metricize("list-overrides", opt.metricsRegistry)
+	clearOverridesTimer := middleware.Metricize("clear-overrides", opt.metricsRegistry)
+	getForcedDecisionsTimer := middleware.Metricize("get-forced-decisions", opt.metricsRegistry)
+	saveForcedDecisionTimer := middleware.Metricize("save-forced-decision", opt.metricsRegistry)
+	removeForcedDecisionTimer := middleware.Metricize("remove-forced-decision", opt.metricsRegistry)
+	resetForcedDecisionsTimer := middleware.Metricize("reset-forced-decisions", opt.metricsRegistry)
+	getUserAttributesTimer := middleware.Metricize("get-user-attributes", opt.metricsRegistry)
+	upsertUserAttributesTimer := middleware.Metricize("upsert-user-attributes", opt.metricsRegistry)
 	lookupTimer := middleware.Metricize("lookup", opt.metricsRegistry)
 	saveTimer := middleware.Metricize("save", opt.metricsRegistry)
 	trackTimer := middleware.Metricize("track-event", opt.metricsRegistry)
+	trackBatchTimer := middleware.Metricize("track-batch", opt.metricsRegistry)
 	sendOdpEventTimer := middleware.Metricize("send-odp-event", opt.metricsRegistry)
+	ofrepEvaluateTimer := middleware.Metricize("ofrep-evaluate-flag", opt.metricsRegistry)
+	ofrepBulkEvaluateTimer := middleware.Metricize("ofrep-evaluate-flags", opt.metricsRegistry)
 	createAccesstokenTimer := middleware.Metricize("create-api-access-token", opt.metricsRegistry)
 	contentTypeMiddleware := chimw.AllowContentType("application/json")
 
@@ -140,12 +211,26 @@ func WithAPIRouter(opt *APIOptions, r chi.Router) {
 	datafileTracer := middleware.AddTracing("datafileHandler", "OptimizelyDatafile")
 	activateTracer := middleware.AddTracing("activateHandler", "Activate")
 	decideTracer := middleware.AddTracing("decideHandler", "Decide")
+	bulkDecideTracer := middleware.AddTracing("bulkDecideHandler", "BulkDecide")
+	explainTracer := middleware.AddTracing("explainHandler", "DecideExplain")
 	trackTracer := middleware.AddTracing("trackHandler", "Track")
+	trackBatchTracer := middleware.AddTracing("trackBatchHandler", "TrackBatch")
 	overrideTracer := middleware.AddTracing("overrideHandler", "Override")
+	listOverridesTracer := middleware.AddTracing("overrideHandler", "ListOverrides")
+	clearOverridesTracer := middleware.AddTracing("overrideHandler", "ClearOverrides")
+	getForcedDecisionsTracer := middleware.AddTracing("forcedDecisionsHandler", "GetForcedDecisions")
+	saveForcedDecisionTracer := middleware.AddTracing("forcedDecisionsHandler", "SaveForcedDecision")
+	removeForcedDecisionTracer := middleware.AddTracing("forcedDecisionsHandler", "RemoveForcedDecision")
+	resetForcedDecisionsTracer := middleware.AddTracing("forcedDecisionsHandler", "ResetForcedDecisions")
+	getUserAttributesTracer := middleware.AddTracing("userAttributesHandler", "GetUserAttributes")
+	upsertUserAttributesTracer := middleware.AddTracing("userAttributesHandler", "UpsertUserAttributes")
 	lookupTracer := middleware.AddTracing("lookupHandler", "Lookup")
 	saveTracer := middleware.AddTracing("saveHandler", "Save")
 	sendOdpEventTracer := middleware.AddTracing("sendOdpEventHandler", "SendOdpEvent")
+	ofrepEvaluateTracer := middleware.AddTracing("ofrepHandler", "EvaluateFlag")
+	ofrepBulkEvaluateTracer := middleware.AddTracing("ofrepHandler", "EvaluateFlags")
 	nStreamTracer := middleware.AddTracing("notificationHandler", "SendNotificationEvent")
+	nWebSocketTracer := middleware.AddTracing("notificationHandler", "SendNotificationWebSocket")
 	authTracer := middleware.AddTracing("authHandler", "AuthToken")
 
 	if opt.maxConns > 0 {
@@ -162,12 +247,31 @@ func WithAPIRouter(opt *APIOptions, r chi.Router) {
 		r.With(getDatafileTimer, opt.oAuthMiddleware, datafileTracer).Get("/datafile", opt.datafileHandler)
 		r.With(activateTimer, opt.oAuthMiddleware, contentTypeMiddleware, activateTracer).Post("/activate", opt.activateHandler)
 		r.With(decideTimer, opt.oAuthMiddleware, contentTypeMiddleware, decideTracer).Post("/decide", opt.decideHandler)
+		r.With(bulkDecideTimer, opt.oAuthMiddleware, contentTypeMiddleware, bulkDecideTracer).Post("/decide/bulk", opt.bulkDecideHandler)
+		r.With(explainTimer, opt.oAuthMiddleware, contentTypeMiddleware, explainTracer).Post("/decide/explain", opt.explainHandler)
 		r.With(trackTimer, opt.oAuthMiddleware, contentTypeMiddleware, trackTracer).Post("/track", opt.trackHandler)
+		r.With(trackBatchTimer, opt.oAuthMiddleware, contentTypeMiddleware, trackBatchTracer).Post("/track/batch", opt.trackBatchHandler)
 		r.With(overrideTimer, opt.oAuthMiddleware, contentTypeMiddleware, overrideTracer).Post("/override", opt.overrideHandler)
+		r.With(listOverridesTimer, opt.oAuthMiddleware, listOverridesTracer).Get("/overrides", opt.listOverridesHandler)
+		r.With(clearOverridesTimer, opt.oAuthMiddleware, clearOverridesTracer).Delete("/overrides", opt.clearOverridesHandler)
+		r.With(getForcedDecisionsTimer, opt.oAuthMiddleware, getForcedDecisionsTracer).Get("/forced-decisions/{userId}", opt.getForcedDecisionsHandler)
+		r.With(saveForcedDecisionTimer, opt.oAuthMiddleware, contentTypeMiddleware, saveForcedDecisionTracer).Put("/forced-decisions/{userId}", opt.saveForcedDecisionHandler)
+		r.With(resetForcedDecisionsTimer, opt.oAuthMiddleware, resetForcedDecisionsTracer).Delete("/forced-decisions/{userId}", opt.resetForcedDecisionsHandler)
+		r.With(removeForcedDecisionTimer, opt.oAuthMiddleware, removeForcedDecisionTracer).Delete("/forced-decisions/{userId}/{flagKey}", opt.removeForcedDecisionHandler)
+		r.With(getUserAttributesTimer, opt.oAuthMiddleware, getUserAttributesTracer).Get("/user-attributes/{userId}", opt.getUserAttributesHandler)
+		r.With(upsertUserAttributesTimer, opt.oAuthMiddleware, contentTypeMiddleware, upsertUserAttributesTracer).Put("/user-attributes/{userId}", opt.upsertUserAttributesHandler)
 		r.With(lookupTimer, opt.oAuthMiddleware, contentTypeMiddleware, lookupTracer).Post("/lookup", opt.lookupHandler)
 		r.With(saveTimer, opt.oAuthMiddleware, contentTypeMiddleware, saveTracer).Post("/save", opt.saveHandler)
 		r.With(sendOdpEventTimer, opt.oAuthMiddleware, contentTypeMiddleware, sendOdpEventTracer).Post("/send-odp-event", opt.sendOdpEventHandler)
 		r.With(opt.oAuthMiddleware, nStreamTracer).Get("/notifications/event-stream", opt.nStreamHandler)
+		r.With(opt.oAuthMiddleware, nWebSocketTracer).Get("/notifications/ws", opt.nWebSocketHandler)
+	})
+
+	// OpenFeature Remote Evaluation Protocol, see https://github.com/open-feature/protocol
+	r.Route("/ofrep/v1", func(r chi.Router) {
+		r.Use(opt.corsHandler, opt.sdkMiddleware)
+		r.With(ofrepEvaluateTimer, opt.oAuthMiddleware, contentTypeMiddleware, ofrepEvaluateTracer).Post("/evaluate/flags/{key}", opt.ofrepEvaluateHandler)
+		r.With(ofrepBulkEvaluateTimer, opt.oAuthMiddleware, contentTypeMiddleware, ofrepBulkEvaluateTracer).Post("/evaluate/flags", opt.ofrepBulkEvaluateHandler)
 	})
 
 	r.With(createAccesstokenTimer, authTracer).Post("/oauth/token", opt.oAuthHandler)
@@ -112,21 +112,35 @@ func (suite *APIV1TestSuite) SetupTest() {
 	suite.tc = testClient
 
 	opts = &APIOptions{
-		maxConns:            1,
-		sdkMiddleware:       testOptlyMiddleware,
-		configHandler:       testHandler("config"),
-		datafileHandler:     testHandler("datafile"),
-		activateHandler:     testHandler("activate"),
-		overrideHandler:     testHandler("override"),
-		lookupHandler:       testHandler("lookup"),
-		saveHandler:         testHandler("save"),
-		trackHandler:        testHandler("track"),
-		sendOdpEventHandler: testHandler("send-odp-event"),
-		nStreamHandler:      testHandler("notifications/event-stream"),
-		oAuthHandler:        testHandler("oauth/token"),
-		oAuthMiddleware:     testAuthMiddleware,
-		metricsRegistry:     metricsRegistry,
-		corsHandler:         testCorsHandler,
+		maxConns:                    1,
+		sdkMiddleware:               testOptlyMiddleware,
+		configHandler:               testHandler("config"),
+		datafileHandler:             testHandler("datafile"),
+		activateHandler:             testHandler("activate"),
+		bulkDecideHandler:           testHandler("decide/bulk"),
+		explainHandler:              testHandler("decide/explain"),
+		overrideHandler:             testHandler("override"),
+		listOverridesHandler:        testHandler("overrides"),
+		clearOverridesHandler:       testHandler("overrides"),
+		getForcedDecisionsHandler:   testHandler("forced-decisions/user1"),
+		saveForcedDecisionHandler:   testHandler("forced-decisions/user1"),
+		removeForcedDecisionHandler: testHandler("forced-decisions/user1/flag"),
+		resetForcedDecisionsHandler: testHandler("forced-decisions/user1"),
+		getUserAttributesHandler:    testHandler("user-attributes/user1"),
+		upsertUserAttributesHandler: testHandler("user-attributes/user1"),
+		lookupHandler:               testHandler("lookup"),
+		saveHandler:                 testHandler("save"),
+		trackHandler:                testHandler("track"),
+		trackBatchHandler:           testHandler("track/batch"),
+		sendOdpEventHandler:         testHandler("send-odp-event"),
+		ofrepEvaluateHandler:        testHandler("evaluate/flags/flag"),
+		ofrepBulkEvaluateHandler:    testHandler("evaluate/flags"),
+		nStreamHandler:              testHandler("notifications/event-stream"),
+		nWebSocketHandler:           testHandler("notifications/ws"),
+		oAuthHandler:                testHandler("oauth/token"),
+		oAuthMiddleware:             testAuthMiddleware,
+		metricsRegistry:             metricsRegistry,
+		corsHandler:                 testCorsHandler,
 	}
 
 	suite.mux = NewAPIRouter(opts)
@@ -150,12 +164,24 @@ func (suite *APIV1TestSuite) TestValidRoutes() {
 		{"GET", "config"},
 		{"GET", "datafile"},
 		{"POST", "activate"},
+		{"POST", "decide/bulk"},
+		{"POST", "decide/explain"},
 		{"POST", "track"},
+		{"POST", "track/batch"},
 		{"POST", "override"},
+		{"GET", "overrides"},
+		{"DELETE", "overrides"},
+		{"GET", "forced-decisions/user1"},
+		{"PUT", "forced-decisions/user1"},
+		{"DELETE", "forced-decisions/user1"},
+		{"DELETE", "forced-decisions/user1/flag"},
+		{"GET", "user-attributes/user1"},
+		{"PUT", "user-attributes/user1"},
 		{"POST", "lookup"},
 		{"POST", "save"},
 		{"POST", "send-odp-event"},
 		{"GET", "notifications/event-stream"},
+		{"GET", "notifications/ws"},
 	}
 
 	for _, route := range routes {
@@ -171,6 +197,21 @@ func (suite *APIV1TestSuite) TestValidRoutes() {
 	}
 }
 
+func (suite *APIV1TestSuite) TestOFREPRoutes() {
+	routes := []string{"evaluate/flags/flag", "evaluate/flags"}
+
+	for _, route := range routes {
+		req := httptest.NewRequest("POST", "/ofrep/v1/"+route, nil)
+		rec := httptest.NewRecorder()
+		suite.mux.ServeHTTP(rec, req)
+		suite.Equal(http.StatusOK, rec.Code)
+
+		suite.Equal("expected", rec.Header().Get(clientHeaderKey))
+		suite.Equal(route, rec.Header().Get(methodHeaderKey))
+		suite.Equal("mockMiddleware", rec.Header().Get(middlewareHeaderKey))
+	}
+}
+
 // TODO: this test fails because odp hasn't been added to the open api schema yet?
 func (suite *APIV1TestSuite) TestStaticContent() {
 	routes := []struct {
@@ -384,7 +425,17 @@ func TestForbiddenRoutes(t *testing.T) {
 		error  string
 	}{
 		{"POST", "override", "Overrides not enabled\n"},
+		{"GET", "overrides", "Overrides not enabled\n"},
+		{"DELETE", "overrides", "Overrides not enabled\n"},
+		{"GET", "forced-decisions/user1", "Overrides not enabled\n"},
+		{"PUT", "forced-decisions/user1", "Overrides not enabled\n"},
+		{"DELETE", "forced-decisions/user1", "Overrides not enabled\n"},
+		{"DELETE", "forced-decisions/user1/flag", "Overrides not enabled\n"},
+		{"GET", "user-attributes/user1", "User attributes not enabled\n"},
+		{"PUT", "user-attributes/user1", "User attributes not enabled\n"},
 		{"GET", "notifications/event-stream", "Notification stream not enabled\n"},
+		{"GET", "notifications/ws", "Notification stream not enabled\n"},
+		{"POST", "track/batch", "Batch track not enabled\n"},
 	}
 
 	for _, route := range routes {
@@ -406,8 +457,13 @@ func (suite *APIV1TestSuite) TestAllowedContentTypeMiddleware() {
 		path   string
 	}{
 		{"POST", "activate"},
+		{"POST", "decide/bulk"},
+		{"POST", "decide/explain"},
 		{"POST", "track"},
+		{"POST", "track/batch"},
 		{"POST", "override"},
+		{"PUT", "forced-decisions/user1"},
+		{"PUT", "user-attributes/user1"},
 	}
 
 	for _, route := range routes {
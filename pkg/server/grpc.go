@@ -0,0 +1,151 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package server provides a basic HTTP server wrapper
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/optimizely/agent/config"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// GRPCService registers gRPC services along with the server options they need
+type GRPCService interface {
+	Register(registrar grpc.ServiceRegistrar)
+	ServerOptions() []grpc.ServerOption
+}
+
+// GRPCServer serves gRPC services, with the TLS and shutdown settings of the http servers
+type GRPCServer struct {
+	srv             *grpc.Server
+	addr            string
+	logger          zerolog.Logger
+	shutdown        chan struct{}
+	shutdownTimeout time.Duration
+}
+
+// NewGRPCServer initializes a new gRPC server for the service
+func NewGRPCServer(name, port string, service GRPCService, conf config.ServerConfig) (GRPCServer, error) {
+	if service == nil {
+		return GRPCServer{}, fmt.Errorf(`%q service is not initialized`, name)
+	}
+
+	shutdown := make(chan struct{})
+	opts := append(service.ServerOptions(), grpc.ChainStreamInterceptor(shutdownStreamInterceptor(shutdown)))
+
+	if conf.KeyFile != "" && conf.CertFile != "" {
+		cfg, err := makeTLSConfig(conf)
+		if err != nil {
+			return GRPCServer{}, err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(cfg)))
+	}
+
+	srv := grpc.NewServer(opts...)
+	service.Register(srv)
+
+	logger := log.With().Str("port", port).Str("name", name).Str("host", conf.Host).Logger()
+	return GRPCServer{
+		srv:             srv,
+		addr:            conf.Host + ":" + port,
+		logger:          logger,
+		shutdown:        shutdown,
+		shutdownTimeout: conf.ShutdownTimeout,
+	}, nil
+}
+
+// ListenAndServe starts the server
+func (s GRPCServer) ListenAndServe() error {
+	lis, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Server failed.")
+		return err
+	}
+
+	s.logger.Info().Msg("Starting gRPC server.")
+	if err := s.srv.Serve(lis); err != nil {
+		s.logger.Error().Err(err).Msg("Server failed.")
+		return err
+	}
+
+	return nil
+}
+
+// Shutdown server gracefully. The server stops accepting connections, ends the streams
+// and waits for the calls in flight until the shutdown timeout, 0 waits without limit.
+func (s GRPCServer) Shutdown() {
+	s.logger.Info().Msg("Shutting down server.")
+	close(s.shutdown)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.srv.GracefulStop()
+		close(stopped)
+	}()
+
+	if s.shutdownTimeout > 0 {
+		select {
+		case <-stopped:
+		case <-time.After(s.shutdownTimeout):
+			s.logger.Error().Msg("Failed graceful shutdown, closing the remaining connections.")
+			s.srv.Stop()
+			<-stopped
+			return
+		}
+	}
+
+	<-stopped
+	s.logger.Info().Msg("Server shut down.")
+}
+
+// shutdownStreamInterceptor cancels the context of the streams once the server starts shutting down,
+// so that long lived streams, like notifications, don't hold up the shutdown
+func shutdownStreamInterceptor(shutdown <-chan struct{}) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel := context.WithCancel(ss.Context())
+		defer cancel()
+
+		go func() {
+			select {
+			case <-shutdown:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// serverStream overrides the context of a grpc.ServerStream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context of the stream
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
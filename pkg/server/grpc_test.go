@@ -0,0 +1,109 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package server provides a basic HTTP server wrapper
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/optimizely/agent/config"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// healthService serves the gRPC health service, whose Watch calls stream until they are canceled
+type healthService struct{}
+
+func (healthService) Register(registrar grpc.ServiceRegistrar) {
+	healthpb.RegisterHealthServer(registrar, health.NewServer())
+}
+
+func (healthService) ServerOptions() []grpc.ServerOption {
+	return nil
+}
+
+func TestGRPCStartAndShutdown(t *testing.T) {
+	srv, err := NewGRPCServer("valid", "6010", healthService{}, config.ServerConfig{Host: "127.0.0.1"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	finish := make(chan error)
+	go func() {
+		finish <- srv.ListenAndServe()
+	}()
+
+	conn, err := grpc.Dial("127.0.0.1:6010", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true))
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	// The stream is ended by the shutdown instead of holding it up
+	stream, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = stream.Recv()
+	assert.NoError(t, err)
+
+	srv.Shutdown()
+	assert.NoError(t, <-finish)
+
+	_, err = stream.Recv()
+	assert.Error(t, err)
+}
+
+func TestGRPCNotInitialized(t *testing.T) {
+	_, err := NewGRPCServer("invalid", "6011", nil, conf)
+	assert.EqualError(t, err, `"invalid" service is not initialized`)
+}
+
+func TestGRPCInvalidTLS(t *testing.T) {
+	_, err := NewGRPCServer("invalid", "6012", healthService{}, config.ServerConfig{CertFile: "missing.crt", KeyFile: "missing.key"})
+	assert.Error(t, err)
+}
+
+func TestGRPCServeAndShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sg := NewGroup(ctx, conf)
+
+	sg.GoServeGRPC("grpc", "6013", healthService{})
+	sg.GoListenAndServe("valid", "6014", handler)
+
+	cancel()
+	sg.Wait()
+}
+
+func TestGRPCNotEnabledServerGroup(t *testing.T) {
+	sg := NewGroup(context.Background(), conf)
+	sg.GoServeGRPC("disabled", "0", nil)
+
+	sg.Wait() // server should terminate by itself
+}
@@ -0,0 +1,160 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package server provides a basic HTTP server wrapper
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+const (
+	// StatusReady is reported when every readiness check passed
+	StatusReady = "ready"
+	// StatusNotReady is reported when at least one readiness check failed
+	StatusNotReady = "not ready"
+
+	checkStatusOK    = "ok"
+	checkStatusError = "error"
+
+	// readinessCheckTimeout bounds the time spent by a single check
+	readinessCheckTimeout = 2 * time.Second
+)
+
+// errCheckTimedOut is reported for the checks which don't return within the timeout
+var errCheckTimedOut = errors.New("timed out")
+
+// ReadinessCheck returns an error while the dependency it checks can't serve requests
+type ReadinessCheck func(ctx context.Context) error
+
+// ReadinessInfo is holding info about readiness checks
+type ReadinessInfo struct {
+	Status string               `json:"status"`
+	Checks map[string]CheckInfo `json:"checks,omitempty"`
+}
+
+// CheckInfo is holding the outcome of a single readiness check
+type CheckInfo struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Readiness holds the named checks which must pass before Agent receives traffic
+type Readiness struct {
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	checks map[string]ReadinessCheck
+}
+
+// NewReadiness returns a Readiness without any checks
+func NewReadiness() *Readiness {
+	return &Readiness{timeout: readinessCheckTimeout, checks: make(map[string]ReadinessCheck)}
+}
+
+// AddCheck registers a check under the given name, replacing any check with the same name
+func (r *Readiness) AddCheck(name string, check ReadinessCheck) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[name] = check
+}
+
+// Check runs every check concurrently and reports their outcome. A check which doesn't return within the timeout,
+// whether or not it honors its context, is reported as timed out and left to finish in the background.
+func (r *Readiness) Check(ctx context.Context) ReadinessInfo {
+	info := ReadinessInfo{Status: StatusReady}
+	if r == nil {
+		return info
+	}
+
+	r.mu.RLock()
+	checks := make(map[string]ReadinessCheck, len(r.checks))
+	for name, check := range r.checks {
+		checks[name] = check
+	}
+	r.mu.RUnlock()
+
+	if len(checks) == 0 {
+		return info
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	info.Checks = make(map[string]CheckInfo, len(checks))
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check ReadinessCheck) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, r.timeout)
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() {
+				done <- check(checkCtx)
+			}()
+
+			var err error
+			select {
+			case err = <-done:
+			case <-checkCtx.Done():
+				err = errCheckTimedOut
+			}
+
+			result := CheckInfo{Status: checkStatusOK}
+			if err != nil {
+				result = CheckInfo{Status: checkStatusError, Error: err.Error()}
+			}
+
+			mu.Lock()
+			info.Checks[name] = result
+			if result.Status != checkStatusOK {
+				info.Status = StatusNotReady
+			}
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	return info
+}
+
+// readinessMW intercepts requests for the given path to report the readiness checks.
+// It responds with a StatusServiceUnavailable until every check passes.
+func readinessMW(next http.Handler, path string, readiness *Readiness) http.Handler {
+	if path == "" {
+		return next
+	}
+
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && strings.HasSuffix(strings.ToLower(r.URL.Path), path) {
+			info := readiness.Check(r.Context())
+			if info.Status != StatusReady {
+				render.Status(r, http.StatusServiceUnavailable)
+			}
+			render.JSON(w, r, info)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
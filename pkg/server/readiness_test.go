@@ -0,0 +1,142 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/optimizely/agent/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadinessWithoutChecks(t *testing.T) {
+	assert.Equal(t, ReadinessInfo{Status: StatusReady}, NewReadiness().Check(context.Background()))
+
+	var readiness *Readiness
+	assert.Equal(t, ReadinessInfo{Status: StatusReady}, readiness.Check(context.Background()))
+}
+
+func TestReadinessCheck(t *testing.T) {
+	readiness := NewReadiness()
+	readiness.AddCheck("one", func(ctx context.Context) error { return nil })
+	readiness.AddCheck("two", func(ctx context.Context) error { return errors.New("failed") })
+
+	info := readiness.Check(context.Background())
+	assert.Equal(t, StatusNotReady, info.Status)
+	assert.Equal(t, CheckInfo{Status: "ok"}, info.Checks["one"])
+	assert.Equal(t, CheckInfo{Status: "error", Error: "failed"}, info.Checks["two"])
+
+	readiness.AddCheck("two", func(ctx context.Context) error { return nil })
+	info = readiness.Check(context.Background())
+	assert.Equal(t, StatusReady, info.Status)
+	assert.Len(t, info.Checks, 2)
+}
+
+func TestReadinessCheckTimeout(t *testing.T) {
+	readiness := NewReadiness()
+	readiness.timeout = 10 * time.Millisecond
+
+	// The check ignores its context
+	release := make(chan struct{})
+	defer close(release)
+	readiness.AddCheck("slow", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	readiness.AddCheck("fast", func(ctx context.Context) error { return nil })
+
+	start := time.Now()
+	info := readiness.Check(context.Background())
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, StatusNotReady, info.Status)
+	assert.Equal(t, CheckInfo{Status: "error", Error: "timed out"}, info.Checks["slow"])
+	assert.Equal(t, CheckInfo{Status: "ok"}, info.Checks["fast"])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	info = readiness.Check(ctx)
+	assert.Equal(t, StatusNotReady, info.Status)
+	assert.Equal(t, "error", info.Checks["slow"].Status)
+}
+
+func TestReadinessMW(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	ready := true
+	readiness := NewReadiness()
+	readiness.AddCheck("datafiles", func(ctx context.Context) error {
+		if ready {
+			return nil
+		}
+		return errors.New("1 of 1 SDK keys have no datafile")
+	})
+	mw := readinessMW(nextHandler, "/ready", readiness)
+
+	rec := httptest.NewRecorder()
+	mw.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ready","checks":{"datafiles":{"status":"ok"}}}`, rec.Body.String())
+
+	ready = false
+	rec = httptest.NewRecorder()
+	mw.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"status":"not ready","checks":{"datafiles":{"status":"error","error":"1 of 1 SDK keys have no datafile"}}}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	mw.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/config", nil))
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+}
+
+func TestReadinessMWDisabled(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	mw := readinessMW(nextHandler, "", NewReadiness())
+
+	rec := httptest.NewRecorder()
+	mw.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+}
+
+func TestNewServerHandlerServesReadiness(t *testing.T) {
+	readiness := NewReadiness()
+	readiness.AddCheck("failing", func(ctx context.Context) error { return errors.New("failed") })
+	srv, err := NewServer("readiness", "1000", handler, config.ServerConfig{
+		AllowedHosts:       []string{"example.com"},
+		HealthCheckPath:    "/health",
+		ReadinessCheckPath: "/ready",
+	}, readiness)
+	assert.NoError(t, err)
+
+	// Probes usually reach the pod by IP, so neither check is subject to the allowed hosts
+	rec := httptest.NewRecorder()
+	srv.srv.Handler.ServeHTTP(rec, httptest.NewRequest("GET", "http://10.0.0.1/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	rec = httptest.NewRecorder()
+	srv.srv.Handler.ServeHTTP(rec, httptest.NewRequest("GET", "http://10.0.0.1/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+}
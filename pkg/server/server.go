@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -38,8 +39,10 @@ import (
 
 // Server has generic functionality for service: it starts the service and performs basic checks
 type Server struct {
-	srv    *http.Server
-	logger zerolog.Logger
+	srv             *http.Server
+	logger          zerolog.Logger
+	shutdown        chan struct{}
+	shutdownTimeout time.Duration
 }
 
 // HealthInfo is holding info about health checks
@@ -47,8 +50,8 @@ type HealthInfo struct {
 	Status string `json:"status,omitempty"`
 }
 
-// NewServer initializes new service.
-func NewServer(name, port string, handler http.Handler, conf config.ServerConfig) (Server, error) {
+// NewServer initializes new service. The readiness checks are reported on conf.ReadinessCheckPath.
+func NewServer(name, port string, handler http.Handler, conf config.ServerConfig, readiness *Readiness) (Server, error) {
 
 	if handler == nil {
 		return Server{}, fmt.Errorf(`%q handler is not initialized`, name)
@@ -57,14 +60,19 @@ func NewServer(name, port string, handler http.Handler, conf config.ServerConfig
 	handler = middleware.BatchRouter(conf.BatchRequests)(handler)
 	handler = middleware.AllowedHosts(conf.GetAllowedHosts())(handler)
 	handler = healthMW(handler, conf.HealthCheckPath)
+	handler = readinessMW(handler, conf.ReadinessCheckPath, readiness)
 	handler = wrapWithInterceptors(handler, conf.Interceptors)
 
 	logger := log.With().Str("port", port).Str("name", name).Str("host", conf.Host).Logger()
+	shutdown := make(chan struct{})
 	srv := &http.Server{
 		Addr:         conf.Host + ":" + port,
 		Handler:      handler,
 		ReadTimeout:  conf.ReadTimeout,
 		WriteTimeout: conf.WriteTimeout,
+		BaseContext: func(net.Listener) context.Context {
+			return middleware.WithShutdown(context.Background(), shutdown)
+		},
 	}
 
 	if conf.KeyFile != "" && conf.CertFile != "" {
@@ -75,7 +83,7 @@ func NewServer(name, port string, handler http.Handler, conf config.ServerConfig
 		srv.TLSConfig = cfg
 	}
 
-	return Server{srv: srv, logger: logger}, nil
+	return Server{srv: srv, logger: logger, shutdown: shutdown, shutdownTimeout: conf.ShutdownTimeout}, nil
 }
 
 // ListenAndServe starts the server
@@ -97,14 +105,23 @@ func (s Server) ListenAndServe() (err error) {
 	return nil
 }
 
-// Shutdown server gracefully
+// Shutdown server gracefully. The server stops accepting connections, closes the event streams
+// and waits for the requests in flight until the shutdown timeout, 0 waits without limit.
 func (s Server) Shutdown() {
 	s.logger.Info().Msg("Shutting down server.")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	ctx := context.Background()
+	if s.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.shutdownTimeout)
+		defer cancel()
+	}
+
+	close(s.shutdown)
 	if err := s.srv.Shutdown(ctx); err != nil {
 		s.logger.Error().Err(err).Msg("Failed shutdown.")
+		return
 	}
+	s.logger.Info().Msg("Server shut down.")
 }
 
 func wrapWithInterceptors(handler http.Handler, conf config.PluginConfigs) http.Handler {
@@ -31,10 +31,11 @@ import (
 
 // Group encapsulates managing multiple Server instances
 type Group struct {
-	stop context.CancelFunc
-	eg   *errgroup.Group
-	ctx  context.Context
-	conf config.ServerConfig
+	stop      context.CancelFunc
+	eg        *errgroup.Group
+	ctx       context.Context
+	conf      config.ServerConfig
+	readiness *Readiness
 }
 
 // NewGroup creares a new server group.
@@ -43,13 +44,19 @@ func NewGroup(ctx context.Context, conf config.ServerConfig) *Group {
 	eg, gctx := errgroup.WithContext(nctx)
 
 	return &Group{
-		stop: stop,
-		eg:   eg,
-		ctx:  gctx,
-		conf: conf,
+		stop:      stop,
+		eg:        eg,
+		ctx:       gctx,
+		conf:      conf,
+		readiness: NewReadiness(),
 	}
 }
 
+// Readiness returns the readiness checks reported by every server of the Group
+func (g *Group) Readiness() *Readiness {
+	return g.readiness
+}
+
 // GoListenAndServe constructs a NewServer and adds it to the Group.
 // Two goroutines are started. One for the http listener and one
 // to initiate a graceful shutdown. This method blocks on adding the
@@ -61,7 +68,44 @@ func (g *Group) GoListenAndServe(name, port string, handler http.Handler) {
 		return
 	}
 
-	server, err := NewServer(name, port, handler, g.conf)
+	server, err := NewServer(name, port, handler, g.conf, g.readiness)
+
+	if err != nil {
+		log.Error().Err(err).Msg("Failed starting server")
+		g.stop()
+		return
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	g.eg.Go(func() error {
+		wg.Done()
+		defer g.stop()
+		return server.ListenAndServe()
+	})
+
+	// Shutdown on signal
+	wg.Add(1)
+	g.eg.Go(func() error {
+		wg.Done()
+		<-g.ctx.Done()
+		server.Shutdown()
+		return g.ctx.Err()
+	})
+
+	wg.Wait()
+}
+
+// GoServeGRPC constructs a NewGRPCServer for the service and adds it to the Group,
+// starting it as GoListenAndServe does.
+func (g *Group) GoServeGRPC(name, port string, service GRPCService) {
+
+	if port == "0" {
+		log.Info().Msg(fmt.Sprintf(`%q not enabled`, name))
+		return
+	}
+
+	server, err := NewGRPCServer(name, port, service, g.conf)
 
 	if err != nil {
 		log.Error().Err(err).Msg("Failed starting server")
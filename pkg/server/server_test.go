@@ -19,6 +19,7 @@ package server
 import (
 	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"sync"
@@ -26,6 +27,7 @@ import (
 	"time"
 
 	"github.com/optimizely/agent/config"
+	"github.com/optimizely/agent/pkg/middleware"
 	"github.com/optimizely/agent/plugins/interceptors"
 
 	"github.com/stretchr/testify/assert"
@@ -38,7 +40,7 @@ var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 var conf = config.ServerConfig{}
 
 func TestStartAndShutdown(t *testing.T) {
-	srv, err := NewServer("valid", "6000", handler, conf)
+	srv, err := NewServer("valid", "6000", handler, conf, nil)
 	if !assert.NoError(t, err) {
 		return
 	}
@@ -55,19 +57,57 @@ func TestStartAndShutdown(t *testing.T) {
 	assert.NoError(t, <-finish)
 }
 
+func TestShutdownClosesStreams(t *testing.T) {
+	streaming := make(chan struct{})
+	streamHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		close(streaming)
+		<-middleware.ShuttingDown(r)
+	})
+
+	cfg := config.ServerConfig{
+		AllowedHosts:    []string{"127.0.0.1"},
+		HealthCheckPath: "/health",
+		ShutdownTimeout: 5 * time.Second,
+	}
+	srv, err := NewServer("streams", "0", streamHandler, cfg, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	go func() {
+		_ = srv.srv.Serve(listener)
+	}()
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/v1/notifications/event-stream")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	<-streaming
+
+	start := time.Now()
+	srv.Shutdown()
+	assert.Less(t, time.Since(start), srv.shutdownTimeout)
+}
+
 func TestNoHandler(t *testing.T) {
-	ns, err := NewServer("test", "0", nil, conf)
+	ns, err := NewServer("test", "0", nil, conf, nil)
 	assert.Error(t, err)
 	assert.Equal(t, ns, Server{})
 }
 
 func TestNotEnabledServer(t *testing.T) {
-	_, err := NewServer("not-enabled", "0", handler, conf)
+	_, err := NewServer("not-enabled", "0", handler, conf, nil)
 	assert.NoError(t, err) // this is checked in server group
 }
 
 func TestFailedStartService(t *testing.T) {
-	ns, err := NewServer("test", "-9", handler, conf)
+	ns, err := NewServer("test", "-9", handler, conf, nil)
 	assert.NoError(t, err)
 	ns.ListenAndServe()
 }
@@ -79,7 +119,7 @@ func TestFailedTLSStartService(t *testing.T) {
 		CertFile:     "testdata/example-cert.pem",
 		KeyFile:      "testdata/example-key.pem1",
 	}
-	ns, err := NewServer("test", "9", handler, cfg)
+	ns, err := NewServer("test", "9", handler, cfg, nil)
 	assert.Error(t, err)
 	assert.Equal(t, ns, Server{})
 }
@@ -89,7 +129,7 @@ func TestServerConfigs(t *testing.T) {
 		ReadTimeout:  3 * time.Second,
 		WriteTimeout: 8 * time.Second,
 	}
-	ns, err := NewServer("test", "1000", handler, cfg)
+	ns, err := NewServer("test", "1000", handler, cfg, nil)
 	assert.NoError(t, err)
 
 	assert.Equal(t, cfg.ReadTimeout, ns.srv.ReadTimeout)
@@ -103,7 +143,7 @@ func TestTLSServerConfigs(t *testing.T) {
 		CertFile:     "testdata/example-cert.pem",
 		KeyFile:      "testdata/example-key.pem",
 	}
-	ns, err := NewServer("test", "1000", handler, cfg)
+	ns, err := NewServer("test", "1000", handler, cfg, nil)
 	assert.NoError(t, err)
 
 	assert.Equal(t, cfg.ReadTimeout, ns.srv.ReadTimeout)
@@ -156,7 +196,7 @@ func TestNewServerHandlerRejectsInvalidHost(t *testing.T) {
 		HealthCheckPath: "/health",
 		Host:            "127.0.0.1",
 	}
-	srv, err := NewServer("valid_hosts", "1000", handler, confWithAllowedHosts)
+	srv, err := NewServer("valid_hosts", "1000", handler, confWithAllowedHosts, nil)
 	assert.NoError(t, err)
 
 	req := httptest.NewRequest("GET", "http://evil.com:1000/v1/config", nil)
@@ -176,7 +216,7 @@ func TestNewServerHandlerAllowsValidHost(t *testing.T) {
 		HealthCheckPath: "/health",
 		Host:            "127.0.0.1",
 	}
-	srv, err := NewServer("valid_hosts", "1000", handler, confWithAllowedHosts)
+	srv, err := NewServer("valid_hosts", "1000", handler, confWithAllowedHosts, nil)
 	assert.NoError(t, err)
 
 	req := httptest.NewRequest("GET", "http://example.com:1000/v1/config", nil)
@@ -0,0 +1,38 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package eventdispatcher //
+package eventdispatcher
+
+import (
+	"fmt"
+
+	"github.com/optimizely/go-sdk/pkg/event"
+)
+
+// Creator type defines a function for creating an instance of an event Dispatcher
+type Creator func() event.Dispatcher
+
+// Creators stores the mapping of Creator against eventDispatcherName
+var Creators = map[string]Creator{}
+
+// Add registers a creator against eventDispatcherName
+func Add(eventDispatcherName string, creator Creator) {
+	if _, ok := Creators[eventDispatcherName]; ok {
+		panic(fmt.Sprintf("Event Dispatcher with name %q already exists", eventDispatcherName))
+	}
+	Creators[eventDispatcherName] = creator
+}
@@ -0,0 +1,66 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package eventdispatcher //
+package eventdispatcher
+
+import (
+	"testing"
+
+	"github.com/optimizely/go-sdk/pkg/event"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockEventDispatcher struct {
+}
+
+// DispatchEvent is used to dispatch events
+func (m *MockEventDispatcher) DispatchEvent(logEvent event.LogEvent) (bool, error) {
+	return true, nil
+}
+
+func TestAdd(t *testing.T) {
+	mockEventDispatcherCreator := func() event.Dispatcher {
+		return &MockEventDispatcher{}
+	}
+
+	Add("mock", mockEventDispatcherCreator)
+	creator := Creators["mock"]()
+	if _, ok := creator.(*MockEventDispatcher); !ok {
+		assert.Fail(t, "Cannot convert to type MockEventDispatcher")
+	}
+}
+
+func TestDuplicateKeys(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			assert.Fail(t, "Should have recovered")
+		}
+	}()
+
+	mockEventDispatcherCreator := func() event.Dispatcher {
+		return &MockEventDispatcher{}
+	}
+
+	Add("mock", mockEventDispatcherCreator)
+	Add("mock", mockEventDispatcherCreator)
+	assert.Fail(t, "Should have panicked")
+}
+
+func TestDoesNotExist(t *testing.T) {
+	dne := Creators["DNE"]
+	assert.Nil(t, dne)
+}
@@ -0,0 +1,151 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package services //
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/optimizely/agent/plugins/eventdispatcher"
+	"github.com/optimizely/go-sdk/pkg/event"
+)
+
+// defaultMaxFiles is the number of rotated files kept when maxFiles is not provided
+const defaultMaxFiles = 5
+
+// FileSink writes every event batch as a line of JSON to a file. Once the file reaches maxBytes
+// it is renamed with a numbered suffix and a new file is started, keeping the last maxFiles rotated files.
+type FileSink struct {
+	Path     string `json:"path"`
+	MaxBytes int64  `json:"maxBytes"`
+	MaxFiles int    `json:"maxFiles"`
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// DispatchEvent appends the event batch to the file
+func (f *FileSink) DispatchEvent(logEvent event.LogEvent) (bool, error) {
+	line, err := json.Marshal(logEvent.Event)
+	if err != nil {
+		return false, err
+	}
+	line = append(line, '\n')
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.MaxBytes > 0 && f.size > 0 && f.size+int64(len(line)) > f.MaxBytes {
+		if err := f.rotate(); err != nil {
+			return false, err
+		}
+	}
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return false, err
+		}
+	}
+
+	n, err := f.file.Write(line)
+	f.size += int64(n)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Close closes the file
+func (f *FileSink) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *FileSink) open() error {
+	if f.Path == "" {
+		return errors.New("file sink path not provided")
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// rotate shifts the rotated files by one, dropping the oldest, and moves the current file to the first one
+func (f *FileSink) rotate() error {
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return err
+		}
+		f.file = nil
+	}
+
+	maxFiles := f.MaxFiles
+	if maxFiles <= 0 {
+		maxFiles = defaultMaxFiles
+	}
+
+	if err := os.Remove(rotatedPath(f.Path, maxFiles)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for i := maxFiles - 1; i > 0; i-- {
+		if err := os.Rename(rotatedPath(f.Path, i), rotatedPath(f.Path, i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(f.Path, rotatedPath(f.Path, 1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	f.size = 0
+	return nil
+}
+
+func rotatedPath(path string, index int) string {
+	return fmt.Sprintf("%s.%d", path, index)
+}
+
+func init() {
+	fileSinkCreator := func() event.Dispatcher {
+		return &FileSink{}
+	}
+	eventdispatcher.Add("file", fileSinkCreator)
+}
@@ -0,0 +1,110 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package services //
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/optimizely/go-sdk/pkg/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLogEvent(revision string) event.LogEvent {
+	return event.LogEvent{
+		EndPoint: "https://logx.optimizely.com/v1/events",
+		Event:    event.Batch{Revision: revision, AccountID: "account", ProjectID: "project"},
+	}
+}
+
+func readBatches(t *testing.T, path string) []string {
+	f, err := os.Open(path)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	defer f.Close()
+
+	revisions := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var batch event.Batch
+		if assert.NoError(t, json.Unmarshal(scanner.Bytes(), &batch)) {
+			revisions = append(revisions, batch.Revision)
+		}
+	}
+	return revisions
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events", "events.ndjson")
+	sink := &FileSink{Path: path}
+	defer sink.Close()
+
+	for _, revision := range []string{"1", "2", "3"} {
+		success, err := sink.DispatchEvent(newLogEvent(revision))
+		assert.NoError(t, err)
+		assert.True(t, success)
+	}
+	assert.Equal(t, []string{"1", "2", "3"}, readBatches(t, path))
+}
+
+func TestFileSinkRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	line, err := json.Marshal(newLogEvent("1").Event)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Every file holds two batches
+	sink := &FileSink{Path: path, MaxBytes: 2 * int64(len(line)+1), MaxFiles: 2}
+	defer sink.Close()
+
+	for _, revision := range []string{"1", "2", "3", "4", "5", "6", "7"} {
+		_, err := sink.DispatchEvent(newLogEvent(revision))
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{"7"}, readBatches(t, path))
+	assert.Equal(t, []string{"5", "6"}, readBatches(t, path+".1"))
+	assert.Equal(t, []string{"3", "4"}, readBatches(t, path+".2"))
+	assert.NoFileExists(t, path+".3")
+}
+
+func TestFileSinkAppendsToExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	sink := &FileSink{Path: path}
+	_, err := sink.DispatchEvent(newLogEvent("1"))
+	assert.NoError(t, err)
+	assert.NoError(t, sink.Close())
+
+	sink = &FileSink{Path: path}
+	defer sink.Close()
+	_, err = sink.DispatchEvent(newLogEvent("2"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, readBatches(t, path))
+}
+
+func TestFileSinkWithoutPath(t *testing.T) {
+	sink := &FileSink{}
+	success, err := sink.DispatchEvent(newLogEvent("1"))
+	assert.Error(t, err)
+	assert.False(t, success)
+}
@@ -0,0 +1,93 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package services //
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/optimizely/agent/plugins/eventdispatcher"
+	"github.com/optimizely/agent/plugins/utils"
+	"github.com/optimizely/go-sdk/pkg/event"
+)
+
+// defaultForwardTimeout is the request timeout used when timeout is not provided
+const defaultForwardTimeout = 5 * time.Second
+
+// HTTPForwarder posts every event batch as JSON to the given URL along with the configured headers
+type HTTPForwarder struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Timeout utils.Duration    `json:"timeout"`
+
+	once   sync.Once
+	client *http.Client
+}
+
+// DispatchEvent posts the event batch, any response other than a 2xx is reported as a failure
+func (h *HTTPForwarder) DispatchEvent(logEvent event.LogEvent) (bool, error) {
+	if h.URL == "" {
+		return false, errors.New("http forwarder url not provided")
+	}
+	h.once.Do(h.initClient)
+
+	body, err := json.Marshal(logEvent.Event)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range h.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return false, fmt.Errorf("http forwarder received status %d", resp.StatusCode)
+	}
+	return true, nil
+}
+
+func (h *HTTPForwarder) initClient() {
+	timeout := h.Timeout.Duration
+	if timeout <= 0 {
+		timeout = defaultForwardTimeout
+	}
+	h.client = &http.Client{Timeout: timeout}
+}
+
+func init() {
+	httpForwarderCreator := func() event.Dispatcher {
+		return &HTTPForwarder{}
+	}
+	eventdispatcher.Add("http", httpForwarderCreator)
+}
@@ -0,0 +1,82 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package services //
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/optimizely/agent/plugins/utils"
+	"github.com/optimizely/go-sdk/pkg/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPForwarder(t *testing.T) {
+	var batch event.Batch
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		_ = json.NewDecoder(r.Body).Decode(&batch)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	forwarder := &HTTPForwarder{
+		URL:     server.URL,
+		Headers: map[string]string{"authorization": "Bearer token"},
+		Timeout: utils.Duration{Duration: time.Second},
+	}
+	success, err := forwarder.DispatchEvent(newLogEvent("1"))
+	assert.NoError(t, err)
+	assert.True(t, success)
+
+	assert.Equal(t, "1", batch.Revision)
+	assert.Equal(t, "Bearer token", header.Get("Authorization"))
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+}
+
+func TestHTTPForwarderFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	forwarder := &HTTPForwarder{URL: server.URL}
+	success, err := forwarder.DispatchEvent(newLogEvent("1"))
+	assert.Error(t, err)
+	assert.False(t, success)
+}
+
+func TestHTTPForwarderWithoutURL(t *testing.T) {
+	forwarder := &HTTPForwarder{}
+	success, err := forwarder.DispatchEvent(newLogEvent("1"))
+	assert.Error(t, err)
+	assert.False(t, success)
+}
+
+func TestHTTPForwarderUnmarshal(t *testing.T) {
+	forwarder := &HTTPForwarder{}
+	err := json.Unmarshal([]byte(`{"url":"http://localhost","headers":{"x-api-key":"key"},"timeout":"2s"}`), forwarder)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://localhost", forwarder.URL)
+	assert.Equal(t, map[string]string{"x-api-key": "key"}, forwarder.Headers)
+	assert.Equal(t, 2*time.Second, forwarder.Timeout.Duration)
+}
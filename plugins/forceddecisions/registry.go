@@ -0,0 +1,63 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package forceddecisions //
+package forceddecisions
+
+import (
+	"fmt"
+	"time"
+)
+
+// ForcedDecision is a variation a user is pinned to for a flag, or for a rule of the flag when RuleKey is set
+type ForcedDecision struct {
+	FlagKey      string     `json:"flagKey"`
+	RuleKey      string     `json:"ruleKey,omitempty"`
+	VariationKey string     `json:"variationKey"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Expired returns true once the forced decision has expired, forced decisions without an expiry never expire
+func (fd ForcedDecision) Expired(now time.Time) bool {
+	return fd.ExpiresAt != nil && !now.Before(*fd.ExpiresAt)
+}
+
+// Store persists the forced decisions of users, keyed by SDK key and user ID.
+// A forced decision is identified by its flag and rule keys, saving one replaces any previous one with the same keys.
+type Store interface {
+	// Lookup returns the forced decisions of the user which have not expired
+	Lookup(sdkKey, userID string) ([]ForcedDecision, error)
+	// Save saves the forced decision of the user
+	Save(sdkKey, userID string, forcedDecision ForcedDecision) error
+	// Remove removes the forced decision of the user for the flag and rule keys, it returns false when there was none
+	Remove(sdkKey, userID, flagKey, ruleKey string) (bool, error)
+	// Reset removes every forced decision of the user
+	Reset(sdkKey, userID string) error
+}
+
+// Creator type defines a function for creating an instance of a Store
+type Creator func() Store
+
+// Creators stores the mapping of Creator against storeName
+var Creators = map[string]Creator{}
+
+// Add registers a creator against storeName
+func Add(storeName string, creator Creator) {
+	if _, ok := Creators[storeName]; ok {
+		panic(fmt.Sprintf("Forced Decisions Store with name %q already exists", storeName))
+	}
+	Creators[storeName] = creator
+}
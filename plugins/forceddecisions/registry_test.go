@@ -0,0 +1,85 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package forceddecisions //
+package forceddecisions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type MockStore struct {
+}
+
+// Lookup returns no forced decisions
+func (m *MockStore) Lookup(sdkKey, userID string) ([]ForcedDecision, error) {
+	return nil, nil
+}
+
+// Save does nothing
+func (m *MockStore) Save(sdkKey, userID string, forcedDecision ForcedDecision) error {
+	return nil
+}
+
+// Remove does nothing
+func (m *MockStore) Remove(sdkKey, userID, flagKey, ruleKey string) (bool, error) {
+	return false, nil
+}
+
+// Reset does nothing
+func (m *MockStore) Reset(sdkKey, userID string) error {
+	return nil
+}
+
+func TestAdd(t *testing.T) {
+	mockStoreCreator := func() Store {
+		return &MockStore{}
+	}
+
+	Add("mock", mockStoreCreator)
+	creator := Creators["mock"]()
+	if _, ok := creator.(*MockStore); !ok {
+		assert.Fail(t, "Cannot convert to type MockStore")
+	}
+}
+
+func TestDuplicateKeys(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			assert.Fail(t, "Should have recovered")
+		}
+	}()
+
+	mockStoreCreator := func() Store {
+		return &MockStore{}
+	}
+	Add("mock1", mockStoreCreator)
+	Add("mock1", mockStoreCreator)
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Second)
+	future := now.Add(time.Second)
+
+	assert.False(t, ForcedDecision{}.Expired(now))
+	assert.True(t, ForcedDecision{ExpiresAt: &past}.Expired(now))
+	assert.True(t, ForcedDecision{ExpiresAt: &now}.Expired(now))
+	assert.False(t, ForcedDecision{ExpiresAt: &future}.Expired(now))
+}
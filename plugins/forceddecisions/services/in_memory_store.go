@@ -0,0 +1,133 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package services //
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/optimizely/agent/plugins/forceddecisions"
+)
+
+// userKey identifies the forced decisions of a user
+type userKey struct {
+	sdkKey string
+	userID string
+}
+
+// decisionKey identifies a forced decision of a user
+type decisionKey struct {
+	flagKey string
+	ruleKey string
+}
+
+// InMemoryStore keeps the forced decisions in memory, they are lost on restart and not shared between Agent nodes
+type InMemoryStore struct {
+	mu        sync.Mutex
+	decisions map[userKey]map[decisionKey]forceddecisions.ForcedDecision
+	now       func() time.Time
+}
+
+// NewInMemoryStore returns an empty InMemoryStore
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{
+		decisions: make(map[userKey]map[decisionKey]forceddecisions.ForcedDecision),
+		now:       time.Now,
+	}
+}
+
+// Lookup returns the forced decisions of the user which have not expired, sorted by flag and rule keys.
+// Expired forced decisions are removed.
+func (s *InMemoryStore) Lookup(sdkKey, userID string) ([]forceddecisions.ForcedDecision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := userKey{sdkKey: sdkKey, userID: userID}
+	now := s.now()
+	found := []forceddecisions.ForcedDecision{}
+	for dk, fd := range s.decisions[key] {
+		if fd.Expired(now) {
+			delete(s.decisions[key], dk)
+			continue
+		}
+		found = append(found, fd)
+	}
+	if len(s.decisions[key]) == 0 {
+		delete(s.decisions, key)
+	}
+
+	sortForcedDecisions(found)
+	return found, nil
+}
+
+// Save saves the forced decision of the user, replacing any previous one for the same flag and rule keys
+func (s *InMemoryStore) Save(sdkKey, userID string, forcedDecision forceddecisions.ForcedDecision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := userKey{sdkKey: sdkKey, userID: userID}
+	if s.decisions[key] == nil {
+		s.decisions[key] = make(map[decisionKey]forceddecisions.ForcedDecision)
+	}
+	s.decisions[key][decisionKey{flagKey: forcedDecision.FlagKey, ruleKey: forcedDecision.RuleKey}] = forcedDecision
+	return nil
+}
+
+// Remove removes the forced decision of the user for the flag and rule keys
+func (s *InMemoryStore) Remove(sdkKey, userID, flagKey, ruleKey string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := userKey{sdkKey: sdkKey, userID: userID}
+	dk := decisionKey{flagKey: flagKey, ruleKey: ruleKey}
+	fd, ok := s.decisions[key][dk]
+	if !ok {
+		return false, nil
+	}
+	delete(s.decisions[key], dk)
+	if len(s.decisions[key]) == 0 {
+		delete(s.decisions, key)
+	}
+	return !fd.Expired(s.now()), nil
+}
+
+// Reset removes every forced decision of the user
+func (s *InMemoryStore) Reset(sdkKey, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.decisions, userKey{sdkKey: sdkKey, userID: userID})
+	return nil
+}
+
+func sortForcedDecisions(found []forceddecisions.ForcedDecision) {
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].FlagKey != found[j].FlagKey {
+			return found[i].FlagKey < found[j].FlagKey
+		}
+		return found[i].RuleKey < found[j].RuleKey
+	})
+}
+
+func init() {
+	inMemoryStoreCreator := func() forceddecisions.Store {
+		return NewInMemoryStore()
+	}
+	forceddecisions.Add("in-memory", inMemoryStoreCreator)
+}
@@ -0,0 +1,128 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package services //
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/optimizely/agent/plugins/forceddecisions"
+	"github.com/stretchr/testify/suite"
+)
+
+type InMemoryStoreTestSuite struct {
+	suite.Suite
+	store *InMemoryStore
+	now   time.Time
+}
+
+func (s *InMemoryStoreTestSuite) SetupTest() {
+	s.now = time.Now()
+	s.store = NewInMemoryStore()
+	s.store.now = func() time.Time { return s.now }
+}
+
+func (s *InMemoryStoreTestSuite) TestSaveAndLookup() {
+	s.NoError(s.store.Save("sdkKey", "user1", forceddecisions.ForcedDecision{FlagKey: "flag2", VariationKey: "off"}))
+	s.NoError(s.store.Save("sdkKey", "user1", forceddecisions.ForcedDecision{FlagKey: "flag1", RuleKey: "rule", VariationKey: "on"}))
+	s.NoError(s.store.Save("sdkKey", "user1", forceddecisions.ForcedDecision{FlagKey: "flag1", VariationKey: "off"}))
+	// Replaces the previous forced decision for the same keys
+	s.NoError(s.store.Save("sdkKey", "user1", forceddecisions.ForcedDecision{FlagKey: "flag2", VariationKey: "on"}))
+
+	found, err := s.store.Lookup("sdkKey", "user1")
+	s.NoError(err)
+	s.Equal([]forceddecisions.ForcedDecision{
+		{FlagKey: "flag1", VariationKey: "off"},
+		{FlagKey: "flag1", RuleKey: "rule", VariationKey: "on"},
+		{FlagKey: "flag2", VariationKey: "on"},
+	}, found)
+
+	// Users and SDK keys don't share forced decisions
+	found, err = s.store.Lookup("sdkKey", "user2")
+	s.NoError(err)
+	s.Empty(found)
+	found, err = s.store.Lookup("otherSDKKey", "user1")
+	s.NoError(err)
+	s.Empty(found)
+}
+
+func (s *InMemoryStoreTestSuite) TestExpiry() {
+	expiresAt := s.now.Add(time.Minute)
+	s.NoError(s.store.Save("sdkKey", "user1", forceddecisions.ForcedDecision{FlagKey: "flag1", VariationKey: "on", ExpiresAt: &expiresAt}))
+	s.NoError(s.store.Save("sdkKey", "user1", forceddecisions.ForcedDecision{FlagKey: "flag2", VariationKey: "on"}))
+
+	found, err := s.store.Lookup("sdkKey", "user1")
+	s.NoError(err)
+	s.Len(found, 2)
+
+	s.now = expiresAt
+	found, err = s.store.Lookup("sdkKey", "user1")
+	s.NoError(err)
+	s.Equal([]forceddecisions.ForcedDecision{{FlagKey: "flag2", VariationKey: "on"}}, found)
+	s.Len(s.store.decisions[userKey{sdkKey: "sdkKey", userID: "user1"}], 1)
+}
+
+func (s *InMemoryStoreTestSuite) TestRemove() {
+	s.NoError(s.store.Save("sdkKey", "user1", forceddecisions.ForcedDecision{FlagKey: "flag1", VariationKey: "on"}))
+	s.NoError(s.store.Save("sdkKey", "user1", forceddecisions.ForcedDecision{FlagKey: "flag1", RuleKey: "rule", VariationKey: "on"}))
+
+	removed, err := s.store.Remove("sdkKey", "user1", "flag1", "rule")
+	s.NoError(err)
+	s.True(removed)
+
+	removed, err = s.store.Remove("sdkKey", "user1", "flag1", "rule")
+	s.NoError(err)
+	s.False(removed)
+
+	found, err := s.store.Lookup("sdkKey", "user1")
+	s.NoError(err)
+	s.Equal([]forceddecisions.ForcedDecision{{FlagKey: "flag1", VariationKey: "on"}}, found)
+
+	removed, err = s.store.Remove("sdkKey", "user1", "flag1", "")
+	s.NoError(err)
+	s.True(removed)
+	s.Empty(s.store.decisions)
+}
+
+func (s *InMemoryStoreTestSuite) TestRemoveExpired() {
+	expiresAt := s.now.Add(-time.Minute)
+	s.NoError(s.store.Save("sdkKey", "user1", forceddecisions.ForcedDecision{FlagKey: "flag1", VariationKey: "on", ExpiresAt: &expiresAt}))
+
+	removed, err := s.store.Remove("sdkKey", "user1", "flag1", "")
+	s.NoError(err)
+	s.False(removed)
+	s.Empty(s.store.decisions)
+}
+
+func (s *InMemoryStoreTestSuite) TestReset() {
+	s.NoError(s.store.Save("sdkKey", "user1", forceddecisions.ForcedDecision{FlagKey: "flag1", VariationKey: "on"}))
+	s.NoError(s.store.Save("sdkKey", "user2", forceddecisions.ForcedDecision{FlagKey: "flag1", VariationKey: "on"}))
+
+	s.NoError(s.store.Reset("sdkKey", "user1"))
+	found, err := s.store.Lookup("sdkKey", "user1")
+	s.NoError(err)
+	s.Empty(found)
+
+	found, err = s.store.Lookup("sdkKey", "user2")
+	s.NoError(err)
+	s.Len(found, 1)
+}
+
+func TestInMemoryStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(InMemoryStoreTestSuite))
+}
@@ -0,0 +1,218 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package services //
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/optimizely/agent/plugins/forceddecisions"
+	"github.com/optimizely/agent/plugins/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultRedisPrefix is the prefix of the keys holding the forced decisions when none is configured
+const defaultRedisPrefix = "optimizely-forced-decisions"
+
+// defaultRedisTimeout is the time limit of the commands when none is configured. Forced decisions are looked up
+// by every decide request, which is made without them once the limit is reached.
+const defaultRedisTimeout = 100 * time.Millisecond
+
+// RedisStore keeps the forced decisions of each user in a Redis hash, so that every Agent node shares them.
+// The hash expires with the last of its forced decisions, expired forced decisions are removed on lookup.
+type RedisStore struct {
+	Client   *redis.Client
+	Address  string         `json:"host"`
+	Password string         `json:"password"`
+	Database int            `json:"database"`
+	Prefix   string         `json:"prefix"`
+	Timeout  utils.Duration `json:"timeout"`
+
+	clientOnce sync.Once
+}
+
+// Lookup returns the forced decisions of the user which have not expired, sorted by flag and rule keys
+func (s *RedisStore) Lookup(sdkKey, userID string) ([]forceddecisions.ForcedDecision, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	key := s.key(sdkKey, userID)
+	fields, err := s.client().HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	found := []forceddecisions.ForcedDecision{}
+	expired := []string{}
+	for field, value := range fields {
+		var fd forceddecisions.ForcedDecision
+		if err := json.Unmarshal([]byte(value), &fd); err != nil {
+			log.Warn().Err(err).Str("key", key).Msg("Discarding unreadable forced decision")
+			expired = append(expired, field)
+			continue
+		}
+		if fd.Expired(now) {
+			expired = append(expired, field)
+			continue
+		}
+		found = append(found, fd)
+	}
+
+	if len(expired) > 0 {
+		if err := s.client().HDel(ctx, key, expired...).Err(); err != nil {
+			log.Warn().Err(err).Str("key", key).Msg("Failed to remove expired forced decisions")
+		}
+	}
+
+	sortForcedDecisions(found)
+	return found, nil
+}
+
+// Save saves the forced decision of the user, replacing any previous one for the same flag and rule keys
+func (s *RedisStore) Save(sdkKey, userID string, forcedDecision forceddecisions.ForcedDecision) error {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	value, err := json.Marshal(forcedDecision)
+	if err != nil {
+		return err
+	}
+
+	key := s.key(sdkKey, userID)
+	if err := s.client().HSet(ctx, key, field(forcedDecision.FlagKey, forcedDecision.RuleKey), value).Err(); err != nil {
+		return err
+	}
+	return s.refreshExpiry(ctx, key)
+}
+
+// Remove removes the forced decision of the user for the flag and rule keys
+func (s *RedisStore) Remove(sdkKey, userID, flagKey, ruleKey string) (bool, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+
+	key := s.key(sdkKey, userID)
+	f := field(flagKey, ruleKey)
+	value, err := s.client().HGet(ctx, key, f).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := s.client().HDel(ctx, key, f).Err(); err != nil {
+		return false, err
+	}
+
+	var fd forceddecisions.ForcedDecision
+	if err := json.Unmarshal([]byte(value), &fd); err != nil {
+		return false, nil
+	}
+	return !fd.Expired(time.Now()), nil
+}
+
+// Reset removes every forced decision of the user
+func (s *RedisStore) Reset(sdkKey, userID string) error {
+	ctx, cancel := s.context()
+	defer cancel()
+	return s.client().Del(ctx, s.key(sdkKey, userID)).Err()
+}
+
+// refreshExpiry makes the hash expire with the last of its forced decisions, or never when one of them doesn't expire.
+// Every save refreshes the expiry after writing, so the last one to run sees every forced decision of the hash.
+func (s *RedisStore) refreshExpiry(ctx context.Context, key string) error {
+	values, err := s.client().HVals(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+
+	fds := make([]forceddecisions.ForcedDecision, 0, len(values))
+	for _, value := range values {
+		var fd forceddecisions.ForcedDecision
+		if err := json.Unmarshal([]byte(value), &fd); err == nil {
+			fds = append(fds, fd)
+		}
+	}
+
+	if expiresAt, ok := lastExpiry(fds); ok {
+		return s.client().PExpireAt(ctx, key, expiresAt).Err()
+	}
+	return s.client().Persist(ctx, key).Err()
+}
+
+func (s *RedisStore) key(sdkKey, userID string) string {
+	prefix := s.Prefix
+	if prefix == "" {
+		prefix = defaultRedisPrefix
+	}
+	return fmt.Sprintf("%s:%s:%s", prefix, sdkKey, userID)
+}
+
+// client returns the Redis client, which is created once since the store is shared by every client of Agent
+func (s *RedisStore) client() *redis.Client {
+	s.clientOnce.Do(func() {
+		if s.Client == nil {
+			s.Client = redis.NewClient(&redis.Options{
+				Addr:     s.Address,
+				Password: s.Password,
+				DB:       s.Database,
+			})
+		}
+	})
+	return s.Client
+}
+
+// context returns the context of the commands of a call, which are bounded by the timeout of the store
+func (s *RedisStore) context() (context.Context, context.CancelFunc) {
+	timeout := s.Timeout.Duration
+	if timeout <= 0 {
+		timeout = defaultRedisTimeout
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// field returns the hash field of a forced decision. Keys are JSON encoded so that no separator can be confused with them.
+func field(flagKey, ruleKey string) string {
+	b, _ := json.Marshal([]string{flagKey, ruleKey})
+	return string(b)
+}
+
+// lastExpiry returns the time at which the last of the forced decisions expires, false if one of them never expires
+func lastExpiry(fds []forceddecisions.ForcedDecision) (time.Time, bool) {
+	var last time.Time
+	for _, fd := range fds {
+		if fd.ExpiresAt == nil {
+			return time.Time{}, false
+		}
+		if fd.ExpiresAt.After(last) {
+			last = *fd.ExpiresAt
+		}
+	}
+	return last, !last.IsZero()
+}
+
+func init() {
+	redisStoreCreator := func() forceddecisions.Store {
+		return &RedisStore{}
+	}
+	forceddecisions.Add("redis", redisStoreCreator)
+}
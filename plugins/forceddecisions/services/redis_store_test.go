@@ -0,0 +1,129 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package services //
+package services
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/optimizely/agent/plugins/forceddecisions"
+	"github.com/optimizely/agent/plugins/utils"
+	"github.com/stretchr/testify/suite"
+)
+
+type RedisStoreTestSuite struct {
+	suite.Suite
+	store RedisStore
+}
+
+func (r *RedisStoreTestSuite) SetupTest() {
+	r.store = RedisStore{
+		Address:  "127.0.0.1:1",
+		Password: "10",
+		Database: 1,
+	}
+}
+
+func (r *RedisStoreTestSuite) TestClientCreatedOnce() {
+	r.Nil(r.store.Client)
+
+	// The store is shared by every client of Agent, so the first calls may run concurrently
+	var wg sync.WaitGroup
+	clients := make([]*redis.Client, 10)
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := r.store.Lookup("sdkKey", "user1")
+			r.Error(err)
+			clients[i] = r.store.client()
+		}(i)
+	}
+	wg.Wait()
+
+	r.NotNil(r.store.Client)
+	for _, c := range clients {
+		r.Same(r.store.Client, c)
+	}
+	r.Error(r.store.Save("sdkKey", "user1", forceddecisions.ForcedDecision{FlagKey: "flag1", VariationKey: "on"}))
+	r.Same(clients[0], r.store.Client)
+}
+
+func (r *RedisStoreTestSuite) TestTimeout() {
+	// A server accepting connections without ever responding
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	r.Require().NoError(err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	r.store.Address = listener.Addr().String()
+	r.store.Timeout = utils.Duration{Duration: 50 * time.Millisecond}
+
+	start := time.Now()
+	_, err = r.store.Lookup("sdkKey", "user1")
+	r.Error(err)
+	r.Less(time.Since(start), time.Second)
+}
+
+func (r *RedisStoreTestSuite) TestUnavailable() {
+	_, err := r.store.Remove("sdkKey", "user1", "flag1", "")
+	r.Error(err)
+	r.Error(r.store.Reset("sdkKey", "user1"))
+}
+
+func (r *RedisStoreTestSuite) TestKey() {
+	r.Equal("optimizely-forced-decisions:sdkKey:user1", r.store.key("sdkKey", "user1"))
+
+	r.store.Prefix = "qa"
+	r.Equal("qa:sdkKey:user1", r.store.key("sdkKey", "user1"))
+}
+
+func (r *RedisStoreTestSuite) TestField() {
+	r.Equal(`["flag1",""]`, field("flag1", ""))
+	r.NotEqual(field("a:b", "c"), field("a", "b:c"))
+}
+
+func (r *RedisStoreTestSuite) TestLastExpiry() {
+	now := time.Now()
+	later := now.Add(time.Minute)
+
+	_, ok := lastExpiry(nil)
+	r.False(ok)
+
+	last, ok := lastExpiry([]forceddecisions.ForcedDecision{{ExpiresAt: &later}, {ExpiresAt: &now}})
+	r.True(ok)
+	r.Equal(later, last)
+
+	_, ok = lastExpiry([]forceddecisions.ForcedDecision{{ExpiresAt: &later}, {}})
+	r.False(ok)
+}
+
+func TestRedisStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(RedisStoreTestSuite))
+}
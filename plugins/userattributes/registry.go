@@ -0,0 +1,45 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package userattributes //
+package userattributes
+
+import (
+	"fmt"
+)
+
+// Provider stores the attributes of users, keyed by SDK key and user ID.
+// Stored attributes are merged under the attributes sent with decide, activate and track requests.
+type Provider interface {
+	// Lookup returns the stored attributes of the user, empty when none are stored
+	Lookup(sdkKey, userID string) (map[string]interface{}, error)
+	// Upsert stores the given attributes of the user and keeps the others, an attribute set to nil is removed
+	Upsert(sdkKey, userID string, attributes map[string]interface{}) error
+}
+
+// Creator type defines a function for creating an instance of a Provider
+type Creator func() Provider
+
+// Creators stores the mapping of Creator against providerName
+var Creators = map[string]Creator{}
+
+// Add registers a creator against providerName
+func Add(providerName string, creator Creator) {
+	if _, ok := Creators[providerName]; ok {
+		panic(fmt.Sprintf("User Attributes Provider with name %q already exists", providerName))
+	}
+	Creators[providerName] = creator
+}
@@ -0,0 +1,63 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package userattributes //
+package userattributes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type MockProvider struct {
+}
+
+// Lookup returns no attributes
+func (m *MockProvider) Lookup(sdkKey, userID string) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+// Upsert does nothing
+func (m *MockProvider) Upsert(sdkKey, userID string, attributes map[string]interface{}) error {
+	return nil
+}
+
+func TestAdd(t *testing.T) {
+	mockProviderCreator := func() Provider {
+		return &MockProvider{}
+	}
+
+	Add("mock", mockProviderCreator)
+	creator := Creators["mock"]()
+	if _, ok := creator.(*MockProvider); !ok {
+		assert.Fail(t, "Cannot convert to type MockProvider")
+	}
+}
+
+func TestDuplicateKeys(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			assert.Fail(t, "Should have recovered")
+		}
+	}()
+
+	mockProviderCreator := func() Provider {
+		return &MockProvider{}
+	}
+	Add("mock1", mockProviderCreator)
+	Add("mock1", mockProviderCreator)
+}
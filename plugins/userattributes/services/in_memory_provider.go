@@ -0,0 +1,129 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package services //
+package services
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/optimizely/agent/plugins/userattributes"
+)
+
+// defaultMaxUsers is the number of users whose attributes are kept when no limit is configured
+const defaultMaxUsers = 10000
+
+// userKey identifies the attributes of a user
+type userKey struct {
+	sdkKey string
+	userID string
+}
+
+// userAttributes are the stored attributes of a user
+type userAttributes struct {
+	key        userKey
+	attributes map[string]interface{}
+}
+
+// InMemoryProvider keeps the attributes in memory, they are lost on restart and not shared between Agent nodes.
+// The attributes of at most MaxUsers users are kept, those of the least recently used users are evicted first.
+type InMemoryProvider struct {
+	MaxUsers int `json:"maxUsers"`
+
+	mu    sync.Mutex
+	users map[userKey]*list.Element
+	// order holds the users from the most to the least recently used
+	order *list.List
+}
+
+// NewInMemoryProvider returns an empty InMemoryProvider
+func NewInMemoryProvider() *InMemoryProvider {
+	return &InMemoryProvider{
+		users: make(map[userKey]*list.Element),
+		order: list.New(),
+	}
+}
+
+// Lookup returns a copy of the stored attributes of the user
+func (p *InMemoryProvider) Lookup(sdkKey, userID string) (map[string]interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	element, ok := p.users[userKey{sdkKey: sdkKey, userID: userID}]
+	if !ok {
+		return map[string]interface{}{}, nil
+	}
+	p.order.MoveToFront(element)
+
+	stored := element.Value.(*userAttributes).attributes
+	found := make(map[string]interface{}, len(stored))
+	for name, value := range stored {
+		found[name] = value
+	}
+	return found, nil
+}
+
+// Upsert stores the given attributes of the user
+func (p *InMemoryProvider) Upsert(sdkKey, userID string, attributes map[string]interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := userKey{sdkKey: sdkKey, userID: userID}
+	element, ok := p.users[key]
+	if ok {
+		p.order.MoveToFront(element)
+	} else {
+		element = p.order.PushFront(&userAttributes{key: key, attributes: make(map[string]interface{}, len(attributes))})
+		p.users[key] = element
+	}
+
+	stored := element.Value.(*userAttributes).attributes
+	for name, value := range attributes {
+		if value == nil {
+			delete(stored, name)
+			continue
+		}
+		stored[name] = value
+	}
+	if len(stored) == 0 {
+		p.remove(element)
+	}
+
+	for len(p.users) > p.maxUsers() {
+		p.remove(p.order.Back())
+	}
+	return nil
+}
+
+func (p *InMemoryProvider) remove(element *list.Element) {
+	p.order.Remove(element)
+	delete(p.users, element.Value.(*userAttributes).key)
+}
+
+func (p *InMemoryProvider) maxUsers() int {
+	if p.MaxUsers <= 0 {
+		return defaultMaxUsers
+	}
+	return p.MaxUsers
+}
+
+func init() {
+	inMemoryProviderCreator := func() userattributes.Provider {
+		return NewInMemoryProvider()
+	}
+	userattributes.Add("in-memory", inMemoryProviderCreator)
+}
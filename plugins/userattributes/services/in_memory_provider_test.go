@@ -0,0 +1,120 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package services //
+package services
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/optimizely/agent/plugins/userattributes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInMemoryProviderUpsert(t *testing.T) {
+	provider := NewInMemoryProvider()
+
+	found, err := provider.Lookup("sdkKey", "user1")
+	assert.NoError(t, err)
+	assert.Empty(t, found)
+
+	assert.NoError(t, provider.Upsert("sdkKey", "user1", map[string]interface{}{"country": "US", "age": 30}))
+	assert.NoError(t, provider.Upsert("sdkKey", "user1", map[string]interface{}{"age": 31, "plan": "gold"}))
+
+	found, err = provider.Lookup("sdkKey", "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"country": "US", "age": 31, "plan": "gold"}, found)
+
+	// Attributes are scoped to the SDK key
+	found, err = provider.Lookup("other", "user1")
+	assert.NoError(t, err)
+	assert.Empty(t, found)
+}
+
+func TestInMemoryProviderRemove(t *testing.T) {
+	provider := NewInMemoryProvider()
+	assert.NoError(t, provider.Upsert("sdkKey", "user1", map[string]interface{}{"country": "US", "age": 30}))
+
+	assert.NoError(t, provider.Upsert("sdkKey", "user1", map[string]interface{}{"country": nil}))
+	found, _ := provider.Lookup("sdkKey", "user1")
+	assert.Equal(t, map[string]interface{}{"age": 30}, found)
+
+	assert.NoError(t, provider.Upsert("sdkKey", "user1", map[string]interface{}{"age": nil}))
+	assert.Empty(t, provider.users)
+	assert.Zero(t, provider.order.Len())
+}
+
+func TestInMemoryProviderLookupReturnsCopy(t *testing.T) {
+	provider := NewInMemoryProvider()
+	assert.NoError(t, provider.Upsert("sdkKey", "user1", map[string]interface{}{"country": "US"}))
+
+	found, _ := provider.Lookup("sdkKey", "user1")
+	found["country"] = "FR"
+
+	found, _ = provider.Lookup("sdkKey", "user1")
+	assert.Equal(t, "US", found["country"])
+}
+
+func TestInMemoryProviderMaxUsers(t *testing.T) {
+	provider := NewInMemoryProvider()
+	provider.MaxUsers = 2
+
+	assert.NoError(t, provider.Upsert("sdkKey", "user1", map[string]interface{}{"country": "US"}))
+	assert.NoError(t, provider.Upsert("sdkKey", "user2", map[string]interface{}{"country": "FR"}))
+	// Looking user1 up makes user2 the least recently used
+	_, _ = provider.Lookup("sdkKey", "user1")
+	assert.NoError(t, provider.Upsert("sdkKey", "user3", map[string]interface{}{"country": "DE"}))
+
+	assert.Len(t, provider.users, 2)
+	found, _ := provider.Lookup("sdkKey", "user2")
+	assert.Empty(t, found)
+	found, _ = provider.Lookup("sdkKey", "user1")
+	assert.Equal(t, map[string]interface{}{"country": "US"}, found)
+	found, _ = provider.Lookup("sdkKey", "user3")
+	assert.Equal(t, map[string]interface{}{"country": "DE"}, found)
+
+	// Users are limited by default
+	assert.Equal(t, defaultMaxUsers, NewInMemoryProvider().maxUsers())
+}
+
+func TestInMemoryProviderConcurrency(t *testing.T) {
+	provider := NewInMemoryProvider()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			_ = provider.Upsert("sdkKey", "user1", map[string]interface{}{"count": i})
+		}(i)
+		go func() {
+			defer wg.Done()
+			_, _ = provider.Lookup("sdkKey", "user1")
+		}()
+	}
+	wg.Wait()
+
+	found, _ := provider.Lookup("sdkKey", "user1")
+	assert.Contains(t, found, "count")
+}
+
+func TestInMemoryProviderRegistered(t *testing.T) {
+	creator, ok := userattributes.Creators["in-memory"]
+	if assert.True(t, ok) {
+		assert.IsType(t, &InMemoryProvider{}, creator())
+	}
+}
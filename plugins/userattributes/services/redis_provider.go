@@ -0,0 +1,144 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package services //
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/optimizely/agent/plugins/userattributes"
+	"github.com/optimizely/agent/plugins/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultRedisPrefix is the prefix of the keys holding the attributes when none is configured
+const defaultRedisPrefix = "optimizely-user-attributes"
+
+// defaultRedisTimeout is the time limit of the commands when none is configured. Attributes are looked up
+// by every decide, activate and track request, which is made without them once the limit is reached.
+const defaultRedisTimeout = 100 * time.Millisecond
+
+// RedisProvider keeps the attributes of each user in a Redis hash, so that every Agent node shares them.
+// The fields of the hash are the attribute names and its values the JSON encoded attribute values.
+type RedisProvider struct {
+	Client   *redis.Client
+	Address  string         `json:"host"`
+	Password string         `json:"password"`
+	Database int            `json:"database"`
+	Prefix   string         `json:"prefix"`
+	Timeout  utils.Duration `json:"timeout"`
+
+	clientOnce sync.Once
+}
+
+// Lookup returns the stored attributes of the user, attributes which can't be read are skipped
+func (p *RedisProvider) Lookup(sdkKey, userID string) (map[string]interface{}, error) {
+	ctx, cancel := p.context()
+	defer cancel()
+
+	key := p.key(sdkKey, userID)
+	fields, err := p.client().HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]interface{}, len(fields))
+	for name, raw := range fields {
+		var value interface{}
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			log.Warn().Err(err).Str("key", key).Str("attribute", name).Msg("Skipping unreadable user attribute")
+			continue
+		}
+		found[name] = value
+	}
+	return found, nil
+}
+
+// Upsert stores the given attributes of the user in a single transaction
+func (p *RedisProvider) Upsert(sdkKey, userID string, attributes map[string]interface{}) error {
+	values := make([]interface{}, 0, 2*len(attributes))
+	removed := []string{}
+	for name, value := range attributes {
+		if value == nil {
+			removed = append(removed, name)
+			continue
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		values = append(values, name, b)
+	}
+
+	ctx, cancel := p.context()
+	defer cancel()
+
+	key := p.key(sdkKey, userID)
+	_, err := p.client().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(values) > 0 {
+			pipe.HSet(ctx, key, values...)
+		}
+		if len(removed) > 0 {
+			pipe.HDel(ctx, key, removed...)
+		}
+		return nil
+	})
+	return err
+}
+
+func (p *RedisProvider) key(sdkKey, userID string) string {
+	prefix := p.Prefix
+	if prefix == "" {
+		prefix = defaultRedisPrefix
+	}
+	return fmt.Sprintf("%s:%s:%s", prefix, sdkKey, userID)
+}
+
+// client returns the Redis client, which is created once since the provider is shared by every client of Agent
+func (p *RedisProvider) client() *redis.Client {
+	p.clientOnce.Do(func() {
+		if p.Client == nil {
+			p.Client = redis.NewClient(&redis.Options{
+				Addr:     p.Address,
+				Password: p.Password,
+				DB:       p.Database,
+			})
+		}
+	})
+	return p.Client
+}
+
+// context returns the context of the commands of a call, which are bounded by the timeout of the provider
+func (p *RedisProvider) context() (context.Context, context.CancelFunc) {
+	timeout := p.Timeout.Duration
+	if timeout <= 0 {
+		timeout = defaultRedisTimeout
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+func init() {
+	redisProviderCreator := func() userattributes.Provider {
+		return &RedisProvider{}
+	}
+	userattributes.Add("redis", redisProviderCreator)
+}
@@ -0,0 +1,106 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package services //
+package services
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/optimizely/agent/plugins/utils"
+	"github.com/stretchr/testify/suite"
+)
+
+type RedisProviderTestSuite struct {
+	suite.Suite
+	provider RedisProvider
+}
+
+func (r *RedisProviderTestSuite) SetupTest() {
+	r.provider = RedisProvider{
+		Address:  "127.0.0.1:1",
+		Password: "10",
+		Database: 1,
+	}
+}
+
+func (r *RedisProviderTestSuite) TestClientCreatedOnce() {
+	r.Nil(r.provider.Client)
+
+	// The provider is shared by every client of Agent, so the first calls may run concurrently
+	var wg sync.WaitGroup
+	clients := make([]*redis.Client, 10)
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := r.provider.Lookup("sdkKey", "user1")
+			r.Error(err)
+			clients[i] = r.provider.client()
+		}(i)
+	}
+	wg.Wait()
+
+	r.NotNil(r.provider.Client)
+	for _, c := range clients {
+		r.Same(r.provider.Client, c)
+	}
+	r.Error(r.provider.Upsert("sdkKey", "user1", map[string]interface{}{"country": "US", "age": nil}))
+	r.Same(clients[0], r.provider.Client)
+}
+
+func (r *RedisProviderTestSuite) TestTimeout() {
+	// A server accepting connections without ever responding
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	r.Require().NoError(err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	r.provider.Address = listener.Addr().String()
+	r.provider.Timeout = utils.Duration{Duration: 50 * time.Millisecond}
+
+	start := time.Now()
+	_, err = r.provider.Lookup("sdkKey", "user1")
+	r.Error(err)
+	r.Less(time.Since(start), time.Second)
+}
+
+func (r *RedisProviderTestSuite) TestInvalidAttribute() {
+	r.Error(r.provider.Upsert("sdkKey", "user1", map[string]interface{}{"invalid": make(chan int)}))
+}
+
+func (r *RedisProviderTestSuite) TestKey() {
+	r.Equal("optimizely-user-attributes:sdkKey:user1", r.provider.key("sdkKey", "user1"))
+
+	r.provider.Prefix = "qa"
+	r.Equal("qa:sdkKey:user1", r.provider.key("sdkKey", "user1"))
+}
+
+func TestRedisProviderTestSuite(t *testing.T) {
+	suite.Run(t, new(RedisProviderTestSuite))
+}
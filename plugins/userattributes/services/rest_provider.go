@@ -0,0 +1,165 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package services //
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/optimizely/agent/plugins/userattributes"
+	pluginutils "github.com/optimizely/agent/plugins/utils"
+	"github.com/optimizely/go-sdk/pkg/logging"
+	"github.com/optimizely/go-sdk/pkg/utils"
+)
+
+const (
+	defaultUserIDKey = "userId"
+	sdkKeyKey        = "sdkKey"
+	attributesKey    = "attributes"
+)
+
+// defaultRestTimeout is the time limit of the requests when none is configured. Attributes are looked up
+// by every decide, activate and track request, which is made without them once the limit is reached.
+const defaultRestTimeout = 200 * time.Millisecond
+
+// RestProvider looks up and stores the attributes through a REST API.
+// Requests carry the user ID, the SDK key and, for upserts, the attributes. Lookups expect
+// the attributes of the user in the "attributes" field of the response, a 404 means no attributes are stored.
+type RestProvider struct {
+	Requester    *utils.HTTPRequester
+	Host         string               `json:"host"`
+	Headers      map[string]string    `json:"headers"`
+	LookupPath   string               `json:"lookupPath"`
+	LookupMethod string               `json:"lookupMethod"`
+	UpsertPath   string               `json:"upsertPath"`
+	UpsertMethod string               `json:"upsertMethod"`
+	UserIDKey    string               `json:"userIDKey"`
+	Timeout      pluginutils.Duration `json:"timeout"`
+
+	requesterOnce sync.Once
+}
+
+// Lookup requests the attributes of the user
+func (p *RestProvider) Lookup(sdkKey, userID string) (map[string]interface{}, error) {
+	parameters := map[string]interface{}{
+		p.getUserIDKey(): userID,
+		sdkKeyKey:        sdkKey,
+	}
+	response, code, err := p.performRequest(p.LookupPath, p.LookupMethod, http.MethodGet, parameters)
+	if code == http.StatusNotFound {
+		return map[string]interface{}{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Attributes map[string]interface{} `json:"attributes"`
+	}
+	if err := json.Unmarshal(response, &body); err != nil {
+		return nil, err
+	}
+	if body.Attributes == nil {
+		body.Attributes = map[string]interface{}{}
+	}
+	return body.Attributes, nil
+}
+
+// Upsert sends the attributes of the user, the API is in charge of merging them with the stored ones
+func (p *RestProvider) Upsert(sdkKey, userID string, attributes map[string]interface{}) error {
+	parameters := map[string]interface{}{
+		p.getUserIDKey(): userID,
+		sdkKeyKey:        sdkKey,
+		attributesKey:    attributes,
+	}
+	_, _, err := p.performRequest(p.UpsertPath, p.UpsertMethod, http.MethodPost, parameters)
+	return err
+}
+
+// requester returns the requester, created on first use so that it gets the configured timeout
+func (p *RestProvider) requester() *utils.HTTPRequester {
+	p.requesterOnce.Do(func() {
+		if p.Requester == nil {
+			timeout := p.Timeout.Duration
+			if timeout <= 0 {
+				timeout = defaultRestTimeout
+			}
+			p.Requester = utils.NewHTTPRequester(logging.GetLogger("", "RestUserAttributesProvider"), utils.Timeout(timeout))
+		}
+	})
+	return p.Requester
+}
+
+func (p *RestProvider) getUserIDKey() string {
+	if p.UserIDKey == "" {
+		return defaultUserIDKey
+	}
+	return p.UserIDKey
+}
+
+// performRequest sends the parameters as query parameters of GET requests and as a JSON body otherwise
+func (p *RestProvider) performRequest(path, method, defaultMethod string, parameters map[string]interface{}) ([]byte, int, error) {
+	requestURL, err := url.Parse(p.Host + path)
+	if err != nil || requestURL.Scheme == "" || requestURL.Host == "" {
+		return nil, 0, fmt.Errorf("invalid url components")
+	}
+
+	if method == "" {
+		method = defaultMethod
+	}
+
+	var body io.Reader
+	if method == http.MethodGet {
+		query := requestURL.Query()
+		for k, v := range parameters {
+			if s, ok := v.(string); ok {
+				query.Set(k, s)
+			}
+		}
+		requestURL.RawQuery = query.Encode()
+	} else {
+		b, err := json.Marshal(parameters)
+		if err != nil {
+			return nil, 0, err
+		}
+		body = bytes.NewBuffer(b)
+	}
+
+	headers := []utils.Header{}
+	for n, v := range p.Headers {
+		headers = append(headers, utils.Header{Name: n, Value: v})
+	}
+
+	response, _, code, err := p.requester().Do(requestURL.String(), method, body, headers)
+	return response, code, err
+}
+
+func init() {
+	restProviderCreator := func() userattributes.Provider {
+		return &RestProvider{
+			Headers: map[string]string{},
+		}
+	}
+	userattributes.Add("rest", restProviderCreator)
+}
@@ -0,0 +1,153 @@
+/****************************************************************************
+ * Copyright 2023, Optimizely, Inc. and contributors                        *
+ *                                                                          *
+ * Licensed under the Apache License, Version 2.0 (the "License");          *
+ * you may not use this file except in compliance with the License.         *
+ * You may obtain a copy of the License at                                  *
+ *                                                                          *
+ *    http://www.apache.org/licenses/LICENSE-2.0                            *
+ *                                                                          *
+ * Unless required by applicable law or agreed to in writing, software      *
+ * distributed under the License is distributed on an "AS IS" BASIS,        *
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. *
+ * See the License for the specific language governing permissions and      *
+ * limitations under the License.                                           *
+ ***************************************************************************/
+
+// Package services //
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pluginutils "github.com/optimizely/agent/plugins/utils"
+	"github.com/optimizely/go-sdk/pkg/logging"
+	"github.com/optimizely/go-sdk/pkg/utils"
+	"github.com/stretchr/testify/suite"
+)
+
+type RestProviderTestSuite struct {
+	suite.Suite
+	provider RestProvider
+	server   *httptest.Server
+	method   string
+	query    map[string]string
+	body     map[string]interface{}
+	header   string
+}
+
+func (r *RestProviderTestSuite) SetupTest() {
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.method = req.Method
+		r.header = req.Header.Get("Auth-Token")
+		r.query = map[string]string{}
+		for k := range req.URL.Query() {
+			r.query[k] = req.URL.Query().Get(k)
+		}
+		r.body = nil
+		_ = json.NewDecoder(req.Body).Decode(&r.body)
+
+		switch req.URL.Path {
+		case "/attributes/lookup":
+			if r.query["custom_user_id"] == "unknown" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{"custom_user_id": "user1", "attributes": {"country": "US", "age": 30}}`))
+		case "/attributes/upsert":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	r.provider = RestProvider{
+		Requester:  utils.NewHTTPRequester(logging.GetLogger("", "")),
+		Host:       r.server.URL,
+		Headers:    map[string]string{"Auth-Token": "123"},
+		LookupPath: "/attributes/lookup",
+		UpsertPath: "/attributes/upsert",
+		UserIDKey:  "custom_user_id",
+	}
+}
+
+func (r *RestProviderTestSuite) TearDownTest() {
+	r.server.Close()
+}
+
+func (r *RestProviderTestSuite) TestLookup() {
+	found, err := r.provider.Lookup("sdkKey", "user1")
+	r.NoError(err)
+	r.Equal(map[string]interface{}{"country": "US", "age": float64(30)}, found)
+	r.Equal(http.MethodGet, r.method)
+	r.Equal(map[string]string{"custom_user_id": "user1", "sdkKey": "sdkKey"}, r.query)
+	r.Equal("123", r.header)
+}
+
+func (r *RestProviderTestSuite) TestLookupPost() {
+	r.provider.LookupMethod = http.MethodPost
+	_, err := r.provider.Lookup("sdkKey", "user1")
+	r.NoError(err)
+	r.Equal(http.MethodPost, r.method)
+	r.Equal(map[string]interface{}{"custom_user_id": "user1", "sdkKey": "sdkKey"}, r.body)
+}
+
+func (r *RestProviderTestSuite) TestLookupNotFound() {
+	found, err := r.provider.Lookup("sdkKey", "unknown")
+	r.NoError(err)
+	r.Empty(found)
+}
+
+func (r *RestProviderTestSuite) TestLookupError() {
+	r.provider.LookupPath = "/error"
+	_, err := r.provider.Lookup("sdkKey", "user1")
+	r.Error(err)
+
+	r.provider.Host = "invalid"
+	_, err = r.provider.Lookup("sdkKey", "user1")
+	r.Error(err)
+}
+
+func (r *RestProviderTestSuite) TestUpsert() {
+	r.NoError(r.provider.Upsert("sdkKey", "user1", map[string]interface{}{"country": "US", "age": nil}))
+	r.Equal(http.MethodPost, r.method)
+	r.Equal(map[string]interface{}{
+		"custom_user_id": "user1",
+		"sdkKey":         "sdkKey",
+		"attributes":     map[string]interface{}{"country": "US", "age": nil},
+	}, r.body)
+
+	r.provider.UpsertMethod = http.MethodPut
+	r.NoError(r.provider.Upsert("sdkKey", "user1", map[string]interface{}{"country": "US"}))
+	r.Equal(http.MethodPut, r.method)
+
+	r.provider.UpsertPath = "/error"
+	r.Error(r.provider.Upsert("sdkKey", "user1", map[string]interface{}{"country": "US"}))
+}
+
+func (r *RestProviderTestSuite) TestTimeout() {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	provider := &RestProvider{
+		Host:       slow.URL,
+		LookupPath: "/attributes/lookup",
+		Timeout:    pluginutils.Duration{Duration: 50 * time.Millisecond},
+	}
+	start := time.Now()
+	_, err := provider.Lookup("sdkKey", "user1")
+	r.Error(err)
+	r.Less(time.Since(start), time.Second)
+}
+
+func TestRestProviderTestSuite(t *testing.T) {
+	suite.Run(t, new(RestProviderTestSuite))
+}